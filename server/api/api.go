@@ -22,8 +22,8 @@ import (
 // @Router /api/apis [get]
 func GetApiList(c *gin.Context) {
 	var apis []model.Api
-	// 从数据库中获取所有API
-	if err := global.DB.Find(&apis).Error; err != nil {
+	// 从数据库中获取当前租户的API
+	if err := global.DB.WithContext(c.Request.Context()).Where("tenant_id = ?", c.GetUint("tenant_id")).Find(&apis).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "获取API列表失败",
@@ -60,8 +60,8 @@ func CreateApi(c *gin.Context) {
 		return
 	}
 
-	// 保存到数据库
-	if err := global.DB.Create(&api).Error; err != nil {
+	// 保存到数据库，租户ID取当前租户
+	if err := global.DB.WithContext(c.Request.Context()).Create(&api).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "创建API失败",
@@ -109,8 +109,8 @@ func UpdateApi(c *gin.Context) {
 		return
 	}
 
-	// 更新数据库中的API
-	if err := global.DB.Model(&model.Api{}).Where("id = ?", id).Updates(api).Error; err != nil {
+	// 更新数据库中当前租户的API
+	if err := global.DB.WithContext(c.Request.Context()).Model(&model.Api{}).Where("id = ?", id).Updates(api).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "更新API失败",
@@ -147,8 +147,8 @@ func DeleteApi(c *gin.Context) {
 		return
 	}
 
-	// 从数据库中删除API
-	if err := global.DB.Delete(&model.Api{}, id).Error; err != nil {
+	// 从数据库中删除当前租户的API
+	if err := global.DB.WithContext(c.Request.Context()).Delete(&model.Api{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "删除API失败",
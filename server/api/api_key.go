@@ -0,0 +1,402 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var apiKeyService = &service.ApiKeyService{}
+
+// CreateApiKeyRequest 创建API Key请求
+type CreateApiKeyRequest struct {
+	Name           string     `json:"name" binding:"required"`
+	AllowedRoutes  []string   `json:"allowed_routes"`  // 如 /api/v1/dynamicData/:tableName/create
+	AllowedMethods []string   `json:"allowed_methods"` // 如 POST
+	AllowedIPs     []string   `json:"allowed_ips"`     // IP或CIDR
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// CreateServiceApiKeyRequest 为服务账号创建API Key请求
+type CreateServiceApiKeyRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+	CreateApiKeyRequest
+}
+
+// RevokeApiKeyRequest 吊销API Key请求
+type RevokeApiKeyRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// CreateServiceAccountRequest 创建服务账号请求
+type CreateServiceAccountRequest struct {
+	Username string `json:"username" binding:"required"`
+	Nickname string `json:"nickname"`
+}
+
+func (r *CreateApiKeyRequest) options() service.ApiKeyOptions {
+	return service.ApiKeyOptions{
+		Name:           r.Name,
+		AllowedRoutes:  r.AllowedRoutes,
+		AllowedMethods: r.AllowedMethods,
+		AllowedIPs:     r.AllowedIPs,
+		ExpiresAt:      r.ExpiresAt,
+	}
+}
+
+// GetMyApiKeys 获取当前用户的API Key
+// @Summary 获取我的API Key
+// @Description 获取当前用户创建的个人API Key，不包含密钥
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"keys":[]model.ApiKey}"
+// @Router /api/v1/apikey/list [get]
+func GetMyApiKeys(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	keys, err := apiKeyService.ListKeys(claims.TenantID, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取API Key列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取API Key列表成功",
+		"keys":    keys,
+	})
+}
+
+// CreateMyApiKey 创建个人API Key
+// @Summary 创建个人API Key
+// @Description 创建以当前用户身份访问的API Key，明文Key只在本次返回
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateApiKeyRequest true "API Key参数"
+// @Success 200 {object} map[string]interface{} "{"key":"string","api_key":"model.ApiKey"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"请求参数错误"}"
+// @Router /api/v1/apikey/create [post]
+func CreateMyApiKey(c *gin.Context) {
+	user, claims, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateApiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	createApiKey(c, user, claims.UserID, &req)
+}
+
+// RevokeMyApiKey 吊销个人API Key
+// @Summary 吊销个人API Key
+// @Description 吊销当前用户自己的API Key，吊销后立即失效
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RevokeApiKeyRequest true "API Key ID"
+// @Success 200 {object} map[string]interface{} "{"message":"API Key已吊销"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"API Key不存在"}"
+// @Router /api/v1/apikey/revoke [post]
+func RevokeMyApiKey(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req RevokeApiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	revokeApiKey(c, apiKeyService.RevokeKey(req.ID, claims.TenantID, claims.UserID, claims.UserID))
+}
+
+// GetTenantApiKeys 管理员获取租户内的API Key
+// @Summary 获取租户API Key
+// @Description 获取租户内全部API Key，可按user_id过滤
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} map[string]interface{} "{"keys":[]model.ApiKey}"
+// @Router /api/v1/admin/apikeys [get]
+func GetTenantApiKeys(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	userID, _ := strconv.Atoi(c.Query("user_id"))
+	keys, err := apiKeyService.ListKeys(claims.TenantID, uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取API Key列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取API Key列表成功",
+		"keys":    keys,
+	})
+}
+
+// RevokeTenantApiKey 管理员吊销租户内的API Key
+// @Summary 吊销租户API Key
+// @Description 吊销租户内任意用户或服务账号的API Key
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RevokeApiKeyRequest true "API Key ID"
+// @Success 200 {object} map[string]interface{} "{"message":"API Key已吊销"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"API Key不存在"}"
+// @Router /api/v1/admin/apikeys/revoke [post]
+func RevokeTenantApiKey(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req RevokeApiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	revokeApiKey(c, apiKeyService.RevokeKey(req.ID, claims.TenantID, 0, claims.UserID))
+}
+
+// GetServiceAccounts 获取租户内的服务账号
+// @Summary 获取服务账号列表
+// @Description 获取租户内的服务账号，服务账号的角色通过用户角色分配接口设置
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"accounts":[]model.User}"
+// @Router /api/v1/admin/service-accounts [get]
+func GetServiceAccounts(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	accounts, err := apiKeyService.ListServiceAccounts(claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取服务账号列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "获取服务账号列表成功",
+		"accounts": accounts,
+	})
+}
+
+// CreateServiceAccount 创建服务账号
+// @Summary 创建服务账号
+// @Description 在当前租户内创建只能通过API Key访问的服务账号
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateServiceAccountRequest true "服务账号参数"
+// @Success 200 {object} map[string]interface{} "{"account":"model.User"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"用户名已存在"}"
+// @Router /api/v1/admin/service-accounts/create [post]
+func CreateServiceAccount(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(strings.TrimSpace(req.Username)) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	// 用户名全局唯一，检查重名时跳过租户隔离
+	var count int64
+	global.DB.WithContext(global.WithoutTenantScope(c.Request.Context())).Model(&model.User{}).Where("username = ?", req.Username).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "用户名已存在",
+		})
+		return
+	}
+
+	account, err := apiKeyService.CreateServiceAccount(claims.TenantID, strings.TrimSpace(req.Username), req.Nickname)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建服务账号失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建服务账号成功",
+		"account": account,
+	})
+}
+
+// CreateServiceApiKey 为服务账号创建API Key
+// @Summary 创建服务账号API Key
+// @Description 为租户内的服务账号创建API Key，明文Key只在本次返回
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateServiceApiKeyRequest true "API Key参数"
+// @Success 200 {object} map[string]interface{} "{"key":"string","api_key":"model.ApiKey"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"服务账号不存在"}"
+// @Router /api/v1/admin/service-accounts/apikey [post]
+func CreateServiceApiKey(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req CreateServiceApiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	var account model.User
+	if err := global.DB.WithContext(c.Request.Context()).Where("id = ? AND tenant_id = ? AND type = ?", req.UserID, claims.TenantID, model.UserTypeService).
+		First(&account).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "服务账号不存在",
+		})
+		return
+	}
+
+	createApiKey(c, &account, claims.UserID, &req.CreateApiKeyRequest)
+}
+
+// createApiKey 创建API Key并返回一次性明文
+func createApiKey(c *gin.Context, owner *model.User, createdBy uint, req *CreateApiKeyRequest) {
+	key, plaintext, err := apiKeyService.CreateKey(owner, createdBy, req.options())
+	if err != nil {
+		if errors.Is(err, service.ErrApiKeyOptionInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建API Key失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建API Key成功，请立即保存，关闭后将无法再次查看",
+		"key":     plaintext,
+		"api_key": key,
+	})
+}
+
+// revokeApiKey 返回吊销结果
+func revokeApiKey(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "API Key已吊销",
+		})
+	case errors.Is(err, service.ErrApiKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "吊销API Key失败",
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var approvalService = &service.ApprovalService{}
+
+// ApprovalDecisionRequest 审批意见
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// approvalError 把审批服务的错误转换为响应
+func approvalError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrApprovalPolicyInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrChangeRequestNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrNotApprover), errors.Is(err, service.ErrSelfApproval):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrChangeRequestNotPending), errors.Is(err, service.ErrChangeRequestExpired), errors.Is(err, service.ErrAlreadyApproved):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// changeRequestID 解析路径中的变更申请ID
+func changeRequestID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的申请ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// listChangeRequests 按查询参数分页返回变更申请
+func listChangeRequests(c *gin.Context, tenantID, requesterID uint) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	requests, total, err := approvalService.ListRequests(tenantID, requesterID, c.Query("change_type"), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取变更申请失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "获取变更申请成功",
+		"requests": requests,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// GetChangeRequestList 获取变更申请列表
+// @Summary 获取变更申请列表
+// @Description 查询租户内全部权限变更申请，包括已处理的历史申请
+// @Tags 权限审批
+// @Produce json
+// @Security ApiKeyAuth
+// @Param change_type query string false "变更类型（role_permissions/user_roles/role_parents）"
+// @Param status query string false "状态（pending/applied/rejected/expired/cancelled/failed）"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "{"requests":[]model.ChangeRequest,"total":int}"
+// @Router /api/v1/approval/list [get]
+func GetChangeRequestList(c *gin.Context) {
+	listChangeRequests(c, c.GetUint("tenant_id"), 0)
+}
+
+// GetMyChangeRequests 获取本人提交的变更申请
+// @Summary 获取本人提交的变更申请
+// @Tags 权限审批
+// @Produce json
+// @Security ApiKeyAuth
+// @Param change_type query string false "变更类型（role_permissions/user_roles/role_parents）"
+// @Param status query string false "状态（pending/applied/rejected/expired/cancelled/failed）"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "{"requests":[]model.ChangeRequest,"total":int}"
+// @Router /api/v1/approval/mine [get]
+func GetMyChangeRequests(c *gin.Context) {
+	listChangeRequests(c, c.GetUint("tenant_id"), c.GetUint("user_id"))
+}
+
+// GetChangeRequest 获取变更申请详情
+// @Summary 获取变更申请详情
+// @Description 返回变更差异和提交、审批、生效的完整历史
+// @Tags 权限审批
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} map[string]interface{} "{"request":model.ChangeRequest}"
+// @Router /api/v1/approval/get/{id} [get]
+func GetChangeRequest(c *gin.Context) {
+	id, ok := changeRequestID(c)
+	if !ok {
+		return
+	}
+
+	request, err := approvalService.GetRequest(c.GetUint("tenant_id"), id)
+	if err != nil {
+		approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取变更申请成功",
+		"request": request,
+	})
+}
+
+// ApproveChangeRequest 同意变更申请
+// @Summary 同意变更申请
+// @Description 审批人同意变更，同意人数达到配置要求时变更立即生效，不能审批自己提交的申请
+// @Tags 权限审批
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param data body ApprovalDecisionRequest false "审批意见"
+// @Success 200 {object} map[string]interface{} "{"request":model.ChangeRequest}"
+// @Router /api/v1/approval/approve/{id} [post]
+func ApproveChangeRequest(c *gin.Context) {
+	decideChangeRequest(c, approvalService.Approve, "已同意变更申请")
+}
+
+// RejectChangeRequest 拒绝变更申请
+// @Summary 拒绝变更申请
+// @Description 任一审批人拒绝即结束申请，变更不会生效
+// @Tags 权限审批
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param data body ApprovalDecisionRequest false "审批意见"
+// @Success 200 {object} map[string]interface{} "{"request":model.ChangeRequest}"
+// @Router /api/v1/approval/reject/{id} [post]
+func RejectChangeRequest(c *gin.Context) {
+	decideChangeRequest(c, approvalService.Reject, "已拒绝变更申请")
+}
+
+// decideChangeRequest 审批变更申请的公共流程
+func decideChangeRequest(c *gin.Context, decide func(uint, uint, uint, string) (*model.ChangeRequest, error), message string) {
+	id, ok := changeRequestID(c)
+	if !ok {
+		return
+	}
+	var req ApprovalDecisionRequest
+	// 审批意见可以为空
+	_ = c.ShouldBindJSON(&req)
+
+	request, err := decide(c.GetUint("tenant_id"), id, c.GetUint("user_id"), req.Comment)
+	if err != nil {
+		approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"request": request,
+	})
+}
+
+// CancelChangeRequest 撤回变更申请
+// @Summary 撤回变更申请
+// @Description 申请人撤回尚未审批完成的申请
+// @Tags 权限审批
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} map[string]interface{} "{"request":model.ChangeRequest}"
+// @Router /api/v1/approval/cancel/{id} [post]
+func CancelChangeRequest(c *gin.Context) {
+	id, ok := changeRequestID(c)
+	if !ok {
+		return
+	}
+
+	request, err := approvalService.Cancel(c.GetUint("tenant_id"), id, c.GetUint("user_id"))
+	if err != nil {
+		approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "变更申请已撤回",
+		"request": request,
+	})
+}
+
+// GetApprovalPolicies 获取审批配置
+// @Summary 获取审批配置
+// @Description 返回每类权限变更的审批配置，未配置的类型不需要审批
+// @Tags 权限审批
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"policies":[]model.ApprovalPolicy}"
+// @Router /api/v1/approval/policy [get]
+func GetApprovalPolicies(c *gin.Context) {
+	policies, err := approvalService.GetPolicies(c.GetUint("tenant_id"))
+	if err != nil {
+		approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "获取审批配置成功",
+		"policies": policies,
+	})
+}
+
+// SaveApprovalPolicy 保存审批配置
+// @Summary 保存审批配置
+// @Description 配置一类权限变更的审批人和需要的同意人数，启用时审批人数不能少于同意人数
+// @Tags 权限审批
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body model.ApprovalPolicy true "审批配置"
+// @Success 200 {object} map[string]interface{} "{"policy":model.ApprovalPolicy}"
+// @Router /api/v1/approval/policy [post]
+func SaveApprovalPolicy(c *gin.Context) {
+	var policy model.ApprovalPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	saved, err := approvalService.SavePolicy(c.GetUint("tenant_id"), &policy)
+	if err != nil {
+		approvalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "审批配置已保存",
+		"policy":  saved,
+	})
+}
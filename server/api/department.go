@@ -0,0 +1,357 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var departmentService = &service.DepartmentService{}
+
+// MoveDepartmentRequest 移动部门请求
+type MoveDepartmentRequest struct {
+	ParentID uint `json:"parent_id"` // 0表示移动为顶级部门
+}
+
+// SetDepartmentManagersRequest 设置部门负责人请求
+type SetDepartmentManagersRequest struct {
+	UserIDs []uint `json:"user_ids"`
+}
+
+// SetUserDepartmentsRequest 设置用户部门请求
+type SetUserDepartmentsRequest struct {
+	PrimaryDeptID    uint   `json:"primary_dept_id"`
+	SecondaryDeptIDs []uint `json:"secondary_dept_ids"`
+}
+
+// departmentError 把部门服务的错误转换为响应
+func departmentError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrDepartmentNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrDepartmentHasChildren), errors.Is(err, service.ErrDepartmentHasMembers), errors.Is(err, service.ErrDepartmentCycle):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// departmentID 解析路径中的部门ID
+func departmentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的部门ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetDepartmentTree 获取部门树
+// @Summary 获取部门树
+// @Description 获取当前租户的部门树
+// @Tags 部门管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"departments":[]model.Department}"
+// @Router /api/v1/department/tree [get]
+func GetDepartmentTree(c *gin.Context) {
+	tree, err := departmentService.GetTree(c.GetUint("tenant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取部门树失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "获取部门树成功",
+		"departments": tree,
+	})
+}
+
+// CreateDepartment 创建部门
+// @Summary 创建部门
+// @Description 在指定上级部门下创建部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param department body model.Department true "部门信息"
+// @Success 200 {object} map[string]interface{} "{"department":model.Department}"
+// @Router /api/v1/department/create [post]
+func CreateDepartment(c *gin.Context) {
+	var dept model.Department
+	if err := c.ShouldBindJSON(&dept); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := departmentService.CreateDepartment(c.GetUint("tenant_id"), &dept); err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "部门创建成功",
+		"department": dept,
+	})
+}
+
+// UpdateDepartment 更新部门
+// @Summary 更新部门
+// @Description 更新部门名称、编码、排序和状态
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param department body model.Department true "部门信息"
+// @Success 200 {object} map[string]interface{} "{"department":model.Department}"
+// @Router /api/v1/department/update/{id} [put]
+func UpdateDepartment(c *gin.Context) {
+	id, ok := departmentID(c)
+	if !ok {
+		return
+	}
+	var dept model.Department
+	if err := c.ShouldBindJSON(&dept); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	updated, err := departmentService.UpdateDepartment(c.GetUint("tenant_id"), id, &dept)
+	if err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "部门更新成功",
+		"department": updated,
+	})
+}
+
+// DeleteDepartment 删除部门
+// @Summary 删除部门
+// @Description 删除没有子部门和成员的部门
+// @Tags 部门管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Success 200 {object} map[string]interface{} "{"message":"部门删除成功"}"
+// @Router /api/v1/department/delete/{id} [delete]
+func DeleteDepartment(c *gin.Context) {
+	id, ok := departmentID(c)
+	if !ok {
+		return
+	}
+
+	if err := departmentService.DeleteDepartment(c.GetUint("tenant_id"), id); err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "部门删除成功",
+	})
+}
+
+// MoveDepartment 移动部门
+// @Summary 移动部门
+// @Description 把部门连同下级部门移动到新的上级部门之下
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param data body MoveDepartmentRequest true "新的上级部门"
+// @Success 200 {object} map[string]interface{} "{"department":model.Department}"
+// @Router /api/v1/department/move/{id} [post]
+func MoveDepartment(c *gin.Context) {
+	id, ok := departmentID(c)
+	if !ok {
+		return
+	}
+	var req MoveDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	dept, err := departmentService.MoveDepartment(c.GetUint("tenant_id"), id, req.ParentID)
+	if err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "部门移动成功",
+		"department": dept,
+	})
+}
+
+// GetDepartmentMembers 获取部门成员
+// @Summary 获取部门成员
+// @Description 获取部门成员，include_children=true时包含下级部门的成员
+// @Tags 部门管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param include_children query bool false "是否包含下级部门"
+// @Success 200 {object} map[string]interface{} "{"members":[]model.UserDepartment}"
+// @Router /api/v1/department/members/{id} [get]
+func GetDepartmentMembers(c *gin.Context) {
+	id, ok := departmentID(c)
+	if !ok {
+		return
+	}
+
+	members, err := departmentService.ListMembers(c.GetUint("tenant_id"), id, c.Query("include_children") == "true")
+	if err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取部门成员成功",
+		"members": members,
+	})
+}
+
+// SetDepartmentManagers 设置部门负责人
+// @Summary 设置部门负责人
+// @Description 替换部门负责人，尚未加入部门的用户以兼职身份加入
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param data body SetDepartmentManagersRequest true "负责人用户ID"
+// @Success 200 {object} map[string]interface{} "{"message":"部门负责人设置成功"}"
+// @Router /api/v1/department/managers/{id} [post]
+func SetDepartmentManagers(c *gin.Context) {
+	id, ok := departmentID(c)
+	if !ok {
+		return
+	}
+	var req SetDepartmentManagersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := departmentService.SetManagers(c.GetUint("tenant_id"), id, req.UserIDs); err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "部门负责人设置成功",
+	})
+}
+
+// GetUserDepartments 获取用户所属部门
+// @Summary 获取用户所属部门
+// @Tags 部门管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{} "{"departments":[]model.UserDepartment}"
+// @Router /api/v1/user/departments/{id} [get]
+func GetUserDepartments(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	members, err := departmentService.GetUserDepartments(c.GetUint("tenant_id"), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取用户部门失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "获取用户部门成功",
+		"departments": members,
+	})
+}
+
+// SetUserDepartments 设置用户所属部门
+// @Summary 设置用户所属部门
+// @Description 替换用户的主部门和兼职部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param data body SetUserDepartmentsRequest true "用户部门"
+// @Success 200 {object} map[string]interface{} "{"message":"用户部门设置成功"}"
+// @Router /api/v1/user/departments/{id} [post]
+func SetUserDepartments(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+	var req SetUserDepartmentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	err = departmentService.SetUserDepartments(c.GetUint("tenant_id"), uint(userID), req.PrimaryDeptID, req.SecondaryDeptIDs)
+	if err != nil {
+		departmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "用户部门设置成功",
+	})
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var roleElevationService = &service.RoleElevationService{}
+
+// ElevationRequest 临时提权申请
+type ElevationRequest struct {
+	RoleID uint   `json:"role_id" binding:"required"`
+	Hours  int    `json:"hours" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ElevationDecisionRequest 审批意见
+type ElevationDecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// elevationError 把临时提权服务的错误转换为响应
+func elevationError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrElevationInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrElevationNotFound), errors.Is(err, service.ErrRoleNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrElevationSelfApproval):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrElevationNotPending), errors.Is(err, service.ErrElevationDuplicate), errors.Is(err, service.ErrElevationHasRole):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// elevationID 解析路径中的申请ID
+func elevationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的申请ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// listElevations 按查询参数分页返回提权申请
+func listElevations(c *gin.Context, tenantID, userID uint) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	elevations, total, err := roleElevationService.ListElevations(tenantID, userID, c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取提权申请失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "获取提权申请成功",
+		"elevations": elevations,
+		"total":      total,
+		"page":       page,
+		"pageSize":   pageSize,
+	})
+}
+
+// RequestElevation 申请临时角色
+// @Summary 申请临时角色
+// @Description 申请在指定小时数内临时拥有角色，审批通过后立即生效，到期自动收回
+// @Tags 临时提权
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body ElevationRequest true "申请信息"
+// @Success 200 {object} map[string]interface{} "{"elevation":model.RoleElevation}"
+// @Failure 409 {object} map[string]interface{} "{"message":"该角色已有待审批的申请"}"
+// @Router /api/v1/elevation/request [post]
+func RequestElevation(c *gin.Context) {
+	user, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req ElevationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	elevation, err := roleElevationService.RequestElevation(user, req.RoleID, req.Hours, req.Reason)
+	if err != nil {
+		elevationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "提权申请已提交",
+		"elevation": elevation,
+	})
+}
+
+// GetMyElevations 获取本人的提权申请
+// @Summary 获取本人的提权申请
+// @Tags 临时提权
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "状态（pending/approved/rejected/cancelled/expired）"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "{"elevations":[]model.RoleElevation,"total":int}"
+// @Router /api/v1/elevation/mine [get]
+func GetMyElevations(c *gin.Context) {
+	listElevations(c, c.GetUint("tenant_id"), c.GetUint("user_id"))
+}
+
+// CancelElevation 撤回提权申请
+// @Summary 撤回提权申请
+// @Description 申请人撤回尚未审批的申请
+// @Tags 临时提权
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} map[string]interface{} "{"elevation":model.RoleElevation}"
+// @Router /api/v1/elevation/cancel/{id} [post]
+func CancelElevation(c *gin.Context) {
+	id, ok := elevationID(c)
+	if !ok {
+		return
+	}
+	user, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	elevation, err := roleElevationService.Cancel(id, user)
+	if err != nil {
+		elevationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "提权申请已撤回",
+		"elevation": elevation,
+	})
+}
+
+// GetElevationList 获取提权申请列表
+// @Summary 获取提权申请列表
+// @Description 审批人查看租户内的提权申请
+// @Tags 临时提权
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "状态（pending/approved/rejected/cancelled/expired）"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "{"elevations":[]model.RoleElevation,"total":int}"
+// @Router /api/v1/elevation/list [get]
+func GetElevationList(c *gin.Context) {
+	listElevations(c, c.GetUint("tenant_id"), 0)
+}
+
+// ApproveElevation 批准提权申请
+// @Summary 批准提权申请
+// @Description 批准后申请人立即获得角色，到期自动收回，不能审批自己的申请
+// @Tags 临时提权
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param data body ElevationDecisionRequest false "审批意见"
+// @Success 200 {object} map[string]interface{} "{"elevation":model.RoleElevation}"
+// @Router /api/v1/elevation/approve/{id} [post]
+func ApproveElevation(c *gin.Context) {
+	decideElevation(c, roleElevationService.Approve, "提权申请已批准")
+}
+
+// RejectElevation 拒绝提权申请
+// @Summary 拒绝提权申请
+// @Tags 临时提权
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param data body ElevationDecisionRequest false "审批意见"
+// @Success 200 {object} map[string]interface{} "{"elevation":model.RoleElevation}"
+// @Router /api/v1/elevation/reject/{id} [post]
+func RejectElevation(c *gin.Context) {
+	decideElevation(c, roleElevationService.Reject, "提权申请已拒绝")
+}
+
+// decideElevation 审批提权申请的公共流程
+func decideElevation(c *gin.Context, decide func(uint, *model.User, string) (*model.RoleElevation, error), message string) {
+	id, ok := elevationID(c)
+	if !ok {
+		return
+	}
+	approver, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req ElevationDecisionRequest
+	// 审批意见可以为空
+	_ = c.ShouldBindJSON(&req)
+
+	elevation, err := decide(id, approver, req.Comment)
+	if err != nil {
+		elevationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   message,
+		"elevation": elevation,
+	})
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"go-react-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS 获取访问令牌的公钥
+// @Summary 获取JWT公钥
+// @Description 以JSON Web Key Set格式返回访问令牌签名公钥，包含保留期内的退役密钥；使用HS256签名时为空
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "{"keys":[]}"
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JwtKeyring().JWKS())
+}
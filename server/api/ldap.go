@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	authService = &service.AuthService{}
+	ldapService = &service.LdapService{}
+)
+
+// SaveLdapConfigRequest 保存LDAP配置请求
+type SaveLdapConfigRequest struct {
+	Enabled            bool              `json:"enabled"` // 是否将租户的身份验证方式切换为LDAP
+	URL                string            `json:"url" binding:"required"`
+	StartTLS           bool              `json:"start_tls"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"`
+	CACert             string            `json:"ca_cert"`
+	BindDN             string            `json:"bind_dn"`
+	BindPassword       string            `json:"bind_password"` // 为空表示保持不变
+	UserBaseDN         string            `json:"user_base_dn" binding:"required"`
+	UserFilter         string            `json:"user_filter" binding:"required"`
+	UsernameAttr       string            `json:"username_attr"`
+	EmailAttr          string            `json:"email_attr"`
+	NameAttr           string            `json:"name_attr"`
+	GroupBaseDN        string            `json:"group_base_dn"`
+	GroupFilter        string            `json:"group_filter"`
+	GroupRoleMapping   map[string]string `json:"group_role_mapping"`
+	AutoProvision      bool              `json:"auto_provision"`
+	SyncInterval       int               `json:"sync_interval"`
+}
+
+// GetLdapConfig 管理员获取租户的LDAP配置
+// @Summary 获取LDAP配置
+// @Description 获取当前租户的LDAP配置和身份验证方式，不包含服务账号密码
+// @Tags 身份验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"enabled":"bool","config":"model.LdapConfig"}"
+// @Router /api/v1/admin/ldap/config [get]
+func GetLdapConfig(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	config, err := ldapService.GetConfig(claims.TenantID)
+	if err != nil && !errors.Is(err, service.ErrLdapNotConfigured) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取LDAP配置失败",
+		})
+		return
+	}
+	var tenant model.Tenant
+	global.DB.WithContext(c.Request.Context()).Where("id = ?", claims.TenantID).First(&tenant)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取LDAP配置成功",
+		"enabled": tenant.AuthProvider == model.AuthProviderLDAP,
+		"config":  config,
+	})
+}
+
+// SaveLdapConfig 管理员保存租户的LDAP配置
+// @Summary 保存LDAP配置
+// @Description 保存当前租户的LDAP配置，enabled为true时租户用户改为通过LDAP目录登录；group_role_mapping为组DN到本地角色名的映射
+// @Tags 身份验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body SaveLdapConfigRequest true "LDAP配置"
+// @Success 200 {object} map[string]interface{} "{"config":"model.LdapConfig"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"请求参数错误"}"
+// @Router /api/v1/admin/ldap/config/save [post]
+func SaveLdapConfig(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req SaveLdapConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil ||
+		!(strings.HasPrefix(req.URL, "ldap://") || strings.HasPrefix(req.URL, "ldaps://")) ||
+		!strings.Contains(req.UserFilter, "%s") ||
+		(req.GroupFilter != "" && !strings.Contains(req.GroupFilter, "%s")) ||
+		req.SyncInterval < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+	if req.StartTLS && strings.HasPrefix(req.URL, "ldaps://") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ldaps://地址不能同时使用StartTLS",
+		})
+		return
+	}
+
+	config := &model.LdapConfig{
+		TenantID:           claims.TenantID,
+		URL:                req.URL,
+		StartTLS:           req.StartTLS,
+		InsecureSkipVerify: req.InsecureSkipVerify,
+		CACert:             req.CACert,
+		BindDN:             req.BindDN,
+		BindPassword:       req.BindPassword,
+		UserBaseDN:         req.UserBaseDN,
+		UserFilter:         req.UserFilter,
+		UsernameAttr:       req.UsernameAttr,
+		EmailAttr:          req.EmailAttr,
+		NameAttr:           req.NameAttr,
+		GroupBaseDN:        req.GroupBaseDN,
+		GroupFilter:        req.GroupFilter,
+		GroupRoleMapping:   req.GroupRoleMapping,
+		AutoProvision:      req.AutoProvision,
+		SyncInterval:       req.SyncInterval,
+	}
+	if err := ldapService.SaveConfig(config, req.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "保存LDAP配置失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "保存LDAP配置成功",
+		"config":  config,
+	})
+}
+
+// SyncLdapGroups 管理员立即同步LDAP组
+// @Summary 同步LDAP组
+// @Description 按目录中的组成员关系立即同步当前租户所有LDAP用户的角色
+// @Tags 身份验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"result":"service.LdapSyncResult"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"租户未配置LDAP"}"
+// @Failure 409 {object} map[string]interface{} "{"message":"LDAP组同步正在进行，请稍后再试"}"
+// @Failure 502 {object} map[string]interface{} "{"message":"LDAP组同步失败"}"
+// @Router /api/v1/admin/ldap/sync [post]
+func SyncLdapGroups(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	result, err := ldapService.SyncTenant(claims.TenantID)
+	if err != nil {
+		status, message := http.StatusBadGateway, "LDAP组同步失败"
+		switch {
+		case errors.Is(err, service.ErrLdapNotConfigured):
+			status, message = http.StatusNotFound, err.Error()
+		case errors.Is(err, service.ErrLdapSyncRunning):
+			status, message = http.StatusConflict, err.Error()
+		}
+		fmt.Printf("LDAP组同步失败: %v\n", err)
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+			"result":  result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "LDAP组同步完成",
+		"result":  result,
+	})
+}
@@ -13,7 +13,7 @@ import (
 
 // GetLogList 获取日志列表
 // @Summary 获取日志列表
-// @Description 获取系统日志列表，支持分页、搜索和筛选
+// @Description 获取当前租户的系统日志列表，支持分页、搜索和筛选；平台管理员可以查询全部租户的日志
 // @Tags 日志管理
 // @Accept json
 // @Produce json
@@ -22,9 +22,11 @@ import (
 // @Param pageSize query int false "每页数量" default(10) minimum(1) maximum(100)
 // @Param username query string false "用户名搜索"
 // @Param method query string false "HTTP方法"
+// @Param type query string false "日志类型（operation/login/logout/lockout/unlock/denied/role_grant/role_expire/elevation）"
 // @Param statusCode query int false "状态码"
 // @Param startDate query string false "开始日期" format(date)
 // @Param endDate query string false "结束日期" format(date)
+// @Param tenant_id query int false "租户ID，只有平台管理员可以查询其他租户的日志"
 // @Success 200 {object} map[string]interface{} "{"logs":[]model.Log,"total":int,"page":int,"pageSize":int}"
 // @Failure 500 {object} map[string]interface{} "{"error":"获取日志列表失败"}"
 // @Router /api/logs [get]
@@ -45,12 +47,18 @@ func GetLogList(c *gin.Context) {
 	// 获取搜索参数
 	username := c.Query("username")
 	method := c.Query("method")
+	logType := c.Query("type")
 	statusCode := c.Query("statusCode")
 	startDate := c.Query("startDate")
 	endDate := c.Query("endDate")
 
-	// 构建查询条件
-	db := global.DB.Model(&model.Log{})
+	// 构建查询条件，平台管理员跳过租户隔离，可以按租户筛选
+	db := global.DB.WithContext(platformContext(c)).Model(&model.Log{})
+	if tenantID := c.Query("tenant_id"); tenantID != "" && isPlatformAdmin(c) {
+		if id, err := strconv.Atoi(tenantID); err == nil {
+			db = db.Where("tenant_id = ?", id)
+		}
+	}
 
 	if username != "" {
 		db = db.Where("username LIKE ?", "%"+username+"%")
@@ -58,6 +66,9 @@ func GetLogList(c *gin.Context) {
 	if method != "" {
 		db = db.Where("method = ?", method)
 	}
+	if logType != "" {
+		db = db.Where("type = ?", logType)
+	}
 	if statusCode != "" {
 		if code, err := strconv.Atoi(statusCode); err == nil {
 			db = db.Where("status_code = ?", code)
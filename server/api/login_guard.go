@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var loginGuardService = &service.LoginGuardService{}
+
+// UnlockLoginRequest 解除登录锁定请求，username和ip至少填写一个
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+// respondLoginGuard 返回登录限制信息，需要验证码时附带新的验证码
+func respondLoginGuard(c *gin.Context, status int, message string, result *service.LoginCheckResult) {
+	resp := gin.H{
+		"success": false,
+		"message": message,
+	}
+	if result != nil {
+		if result.RetryAfter > 0 {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			resp["retry_after"] = retryAfter
+			c.Header("Retry-After", fmt.Sprint(retryAfter))
+		}
+		if result.Locked {
+			resp["locked"] = true
+		}
+		if result.CaptchaRequired && !result.Locked {
+			resp["captcha_required"] = true
+			if challenge, err := service.GetLoginChallenge().Issue(); err == nil {
+				resp["captcha"] = challenge
+			} else {
+				fmt.Printf("生成验证码失败: %v\n", err)
+			}
+		}
+	}
+	c.JSON(status, resp)
+}
+
+// GetCaptcha 获取登录验证码
+// @Summary 获取登录验证码
+// @Description 登录失败次数过多时需要提交验证码，用于刷新验证码图片
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "{"captcha":{"captcha_id":"string","captcha_type":"image","captcha_data":"string"}}"
+// @Failure 429 {object} map[string]interface{} "{"message":"获取验证码过于频繁，请稍后再试","retry_after":"int"}"
+// @Failure 500 {object} map[string]interface{} "{"message":"生成验证码失败"}"
+// @Router /api/v1/captcha [get]
+func GetCaptcha(c *gin.Context) {
+	wait, err := loginGuardService.AllowCaptcha(c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成验证码失败",
+		})
+		return
+	}
+	if wait > 0 {
+		respondLoginGuard(c, http.StatusTooManyRequests, "获取验证码过于频繁，请稍后再试", &service.LoginCheckResult{RetryAfter: wait})
+		return
+	}
+
+	challenge, err := service.GetLoginChallenge().Issue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成验证码失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取验证码成功",
+		"captcha": challenge,
+	})
+}
+
+// UnlockLogin 管理员解除登录锁定
+// @Summary 解除登录锁定
+// @Description 清除用户名或IP的登录失败计数和锁定状态
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body UnlockLoginRequest true "用户名或IP"
+// @Success 200 {object} map[string]interface{} "{"message":"已解除锁定"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"请求参数错误"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"用户不存在"}"
+// @Router /api/v1/admin/login-guard/unlock [post]
+func UnlockLogin(c *gin.Context) {
+	operator, claims, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req UnlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Username == "" && req.IP == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	// 只能解除本租户用户的锁定
+	if req.Username != "" {
+		var count int64
+		global.DB.WithContext(c.Request.Context()).Model(&model.User{}).Where("username = ? AND tenant_id = ?", req.Username, claims.TenantID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "用户不存在",
+			})
+			return
+		}
+	}
+
+	if err := loginGuardService.Unlock(req.Username, req.IP, operator); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "解除锁定失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已解除锁定",
+	})
+}
@@ -1,18 +1,44 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
-	"go-react-admin/global"
 	"go-react-admin/model"
+	"go-react-admin/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+var menuService = &service.MenuService{}
+
+// menuError 把菜单服务的错误转换为响应
+func menuError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrActionCodeInvalid), errors.Is(err, service.ErrActionParent), errors.Is(err, service.ErrMenuApiInvalid):
+		status = http.StatusBadRequest
+		message = err.Error()
+	case errors.Is(err, service.ErrMenuNotFound):
+		status = http.StatusNotFound
+		message = err.Error()
+	case errors.Is(err, service.ErrMenuShared):
+		status = http.StatusForbidden
+		message = err.Error()
+	case errors.Is(err, service.ErrActionCodeDuplicate), errors.Is(err, service.ErrChangeRequestDuplicate):
+		status = http.StatusConflict
+		message = err.Error()
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
+
 // GetMenuList 获取菜单列表
 // @Summary 获取菜单列表
-// @Description 获取所有菜单的列表
+// @Description 获取所有菜单的列表，操作类型的菜单附带关联的API（api_ids）
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
@@ -21,9 +47,8 @@ import (
 // @Failure 500 {object} map[string]interface{} "{"error":"获取菜单列表失败"}"
 // @Router /api/menus [get]
 func GetMenuList(c *gin.Context) {
-	var menus []model.Menu
-	// 从数据库中获取所有菜单
-	if err := global.DB.Find(&menus).Error; err != nil {
+	menus, err := menuService.ListMenus(c.Request.Context())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "获取菜单列表失败",
@@ -40,18 +65,19 @@ func GetMenuList(c *gin.Context) {
 
 // GetUserMenus 获取用户菜单
 // @Summary 获取用户菜单
-// @Description 获取当前用户的菜单列表
+// @Description 获取当前用户在租户内有权访问的菜单树，按sort排序；携带If-None-Match且菜单树未变化时返回304
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param If-None-Match header string false "上次返回的ETag"
 // @Success 200 {object} map[string]interface{} "{"code":200,"data":[]model.Menu,"message":"获取用户菜单成功"}"
+// @Success 304 "菜单树未变化"
 // @Failure 500 {object} map[string]interface{} "{"error":"获取用户菜单失败"}"
 // @Router /api/menus/user [get]
 func GetUserMenus(c *gin.Context) {
-	var menus []model.Menu
-	// 暂时返回所有菜单，后续可以根据用户权限过滤
-	if err := global.DB.Where("status = ?", 1).Order("sort ASC").Find(&menus).Error; err != nil {
+	menus, err := menuService.GetUserMenuTree(c.GetUint("user_id"), c.GetUint("tenant_id"))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "获取用户菜单失败",
@@ -59,6 +85,16 @@ func GetUserMenus(c *gin.Context) {
 		return
 	}
 
+	// 菜单树按用户区分，只允许浏览器缓存，并且每次使用前都要验证
+	c.Header("Cache-Control", "private, no-cache")
+	if etag, err := service.MenuTreeETag(menus); err == nil {
+		c.Header("ETag", etag)
+		if service.ETagMatch(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"code":    200,
@@ -69,7 +105,7 @@ func GetUserMenus(c *gin.Context) {
 
 // CreateMenu 创建菜单
 // @Summary 创建菜单
-// @Description 创建新菜单
+// @Description 创建新菜单，type为action时code为操作权限码，api_ids为授予操作时一并授予的API
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
@@ -91,11 +127,8 @@ func CreateMenu(c *gin.Context) {
 	}
 
 	// 创建菜单
-	if err := global.DB.Create(&menu).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "创建菜单失败",
-		})
+	if err := menuService.CreateMenu(c.Request.Context(), &menu); err != nil {
+		menuError(c, err, "创建菜单失败")
 		return
 	}
 
@@ -108,7 +141,7 @@ func CreateMenu(c *gin.Context) {
 
 // UpdateMenu 更新菜单
 // @Summary 更新菜单
-// @Description 根据菜单ID更新菜单信息
+// @Description 根据菜单ID更新菜单信息，修改操作关联的API后拥有该操作的角色立即生效，租户启用了角色权限审批时提交变更申请并返回202
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
@@ -120,7 +153,14 @@ func CreateMenu(c *gin.Context) {
 // @Failure 500 {object} map[string]interface{} "{"error":"更新菜单失败"}"
 // @Router /api/menus/{id} [put]
 func UpdateMenu(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的菜单ID",
+		})
+		return
+	}
 	var menu model.Menu
 	// 绑定JSON到menu
 	if err := c.ShouldBindJSON(&menu); err != nil {
@@ -132,10 +172,16 @@ func UpdateMenu(c *gin.Context) {
 	}
 
 	// 更新菜单
-	if err := global.DB.Model(&model.Menu{}).Where("id = ?", id).Updates(menu).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "更新菜单失败",
+	request, err := menuService.UpdateMenu(c.Request.Context(), uint(id), &menu, c.GetUint("user_id"))
+	if err != nil {
+		menuError(c, err, "更新菜单失败")
+		return
+	}
+	if request != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "菜单已更新，关联API的变更已提交审批",
+			"data":    request,
 		})
 		return
 	}
@@ -170,11 +216,8 @@ func DeleteMenu(c *gin.Context) {
 	}
 
 	// 删除菜单
-	if err := global.DB.Delete(&model.Menu{}, menuID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "删除菜单失败",
-		})
+	if err := menuService.DeleteMenu(c.Request.Context(), uint(menuID)); err != nil {
+		menuError(c, err, "删除菜单失败")
 		return
 	}
 
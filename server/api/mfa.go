@@ -0,0 +1,512 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/service"
+	"go-react-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+var mfaService = &service.MfaService{}
+
+// MfaChallengeRequest 使用挑战令牌的请求
+type MfaChallengeRequest struct {
+	MfaToken string `json:"mfa_token" binding:"required"`
+}
+
+// MfaVerifyRequest 两步验证请求，code可以是TOTP验证码或恢复码
+type MfaVerifyRequest struct {
+	MfaToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MfaCodeRequest 已登录用户提交验证码的请求
+type MfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MfaResetRequest 管理员重置两步验证请求
+type MfaResetRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// challengeUser 校验挑战令牌并获取对应用户
+func challengeUser(c *gin.Context, tokenString, purpose string) (*model.User, *utils.MfaClaims, bool) {
+	claims, err := mfaService.CheckChallenge(tokenString, purpose)
+	if err != nil {
+		respondMfaError(c, err)
+		return nil, nil, false
+	}
+
+	var user model.User
+	if err := global.DB.WithContext(global.WithTenant(c.Request.Context(), claims.TenantID)).Where("id = ? AND tenant_id = ?", claims.UserID, claims.TenantID).First(&user).Error; err != nil {
+		respondMfaError(c, service.ErrMfaTokenInvalid)
+		return nil, nil, false
+	}
+	if err := authStateService.CheckLogin(&user); err != nil {
+		respondMfaError(c, err)
+		return nil, nil, false
+	}
+	return &user, claims, true
+}
+
+// checkMfaGuard 验证码与密码共用用户名的登录失败计数，锁定或退避期间不接受验证码
+func checkMfaGuard(c *gin.Context, user *model.User) bool {
+	guard, err := loginGuardService.Check(user.Username, c.ClientIP())
+	if err != nil {
+		respondMfaError(c, err)
+		return false
+	}
+	if guard.Locked {
+		respondLoginGuard(c, http.StatusTooManyRequests, "登录失败次数过多，已被临时锁定，请稍后再试", guard)
+		return false
+	}
+	if guard.RetryAfter > 0 {
+		respondLoginGuard(c, http.StatusTooManyRequests, "登录过于频繁，请稍后再试", guard)
+		return false
+	}
+	return true
+}
+
+// respondMfaFailure 验证码错误时同时计入挑战令牌和用户名的失败次数，用户名被锁定时作废挑战令牌
+func respondMfaFailure(c *gin.Context, user *model.User, claims *utils.MfaClaims, err error) {
+	if !errors.Is(err, service.ErrMfaCodeInvalid) {
+		respondMfaError(c, err)
+		return
+	}
+
+	result, guardErr := loginGuardService.RecordFailure(user.Username, c.ClientIP(), c.Request.UserAgent())
+	if guardErr != nil {
+		fmt.Printf("记录登录失败次数失败: %v\n", guardErr)
+	}
+	if result != nil && result.Locked {
+		if err := mfaService.CompleteChallenge(claims); err != nil {
+			fmt.Printf("作废挑战令牌失败: %v\n", err)
+		}
+		respondLoginGuard(c, http.StatusTooManyRequests, "登录失败次数过多，已被临时锁定，请稍后再试", result)
+		return
+	}
+	if failErr := mfaService.RecordChallengeFailure(claims); failErr != nil {
+		err = failErr
+	}
+	respondMfaError(c, err)
+}
+
+// currentUser 获取当前登录用户
+func currentUser(c *gin.Context) (*model.User, *utils.CustomClaims, bool) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return nil, nil, false
+	}
+
+	var user model.User
+	if err := global.DB.WithContext(c.Request.Context()).First(&user, claims.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return nil, nil, false
+	}
+	return &user, claims, true
+}
+
+// VerifyMfa 完成两步验证
+// @Summary 完成两步验证
+// @Description 使用登录返回的挑战令牌和TOTP验证码（或恢复码）换取访问令牌
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Param request body MfaVerifyRequest true "挑战令牌和验证码"
+// @Success 200 {object} map[string]interface{} "{"token":"string","refresh_token":"string","expires_in":"int","userId":"uint"}"
+// @Failure 401 {object} map[string]interface{} "{"message":"验证码错误或已被使用"}"
+// @Router /api/v1/mfa/verify [post]
+func VerifyMfa(c *gin.Context) {
+	var req MfaVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	user, claims, ok := challengeUser(c, req.MfaToken, utils.MfaPurposeVerify)
+	if !ok {
+		return
+	}
+
+	if !checkMfaGuard(c, user) {
+		return
+	}
+
+	if err := mfaService.Verify(user, req.Code); err != nil {
+		respondMfaFailure(c, user, claims, err)
+		return
+	}
+
+	if err := mfaService.CompleteChallenge(claims); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	respondLoginTokens(c, user, true, nil)
+}
+
+// SetupMfaEnrollment 登录过程中绑定两步验证
+// @Summary 登录时绑定两步验证
+// @Description 角色要求两步验证但尚未绑定的用户，使用挑战令牌生成TOTP密钥
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Param request body MfaChallengeRequest true "挑战令牌"
+// @Success 200 {object} map[string]interface{} "{"secret":"string","otpauth_url":"string"}"
+// @Failure 401 {object} map[string]interface{} "{"message":"两步验证已过期，请重新登录"}"
+// @Router /api/v1/mfa/enroll/setup [post]
+func SetupMfaEnrollment(c *gin.Context) {
+	var req MfaChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	user, _, ok := challengeUser(c, req.MfaToken, utils.MfaPurposeEnroll)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := mfaService.BeginSetup(user)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "请使用验证器App扫描二维码",
+		"secret":      secret,
+		"otpauth_url": uri,
+	})
+}
+
+// ConfirmMfaEnrollment 登录过程中确认绑定两步验证
+// @Summary 登录时确认绑定两步验证
+// @Description 提交验证器App中的验证码完成绑定，返回恢复码及访问令牌
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Param request body MfaVerifyRequest true "挑战令牌和验证码"
+// @Success 200 {object} map[string]interface{} "{"token":"string","refresh_token":"string","recovery_codes":[]string}"
+// @Failure 401 {object} map[string]interface{} "{"message":"验证码错误或已被使用"}"
+// @Router /api/v1/mfa/enroll/confirm [post]
+func ConfirmMfaEnrollment(c *gin.Context) {
+	var req MfaVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	user, claims, ok := challengeUser(c, req.MfaToken, utils.MfaPurposeEnroll)
+	if !ok {
+		return
+	}
+
+	if !checkMfaGuard(c, user) {
+		return
+	}
+
+	codes, err := mfaService.Enable(user, req.Code)
+	if err != nil {
+		respondMfaFailure(c, user, claims, err)
+		return
+	}
+
+	if err := mfaService.CompleteChallenge(claims); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	respondLoginTokens(c, user, true, gin.H{"recovery_codes": codes})
+}
+
+// GetMfaStatus 获取两步验证状态
+// @Summary 获取两步验证状态
+// @Description 获取当前用户的两步验证开启情况及剩余恢复码数量
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"enabled":"bool","required":"bool","recovery_codes_remaining":"int"}"
+// @Router /api/v1/mfa/status [get]
+func GetMfaStatus(c *gin.Context) {
+	user, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	required, err := mfaService.UserRequiresMFA(user)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	remaining, err := mfaService.RemainingRecoveryCodes(user.ID)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":                  true,
+		"message":                  "获取两步验证状态成功",
+		"enabled":                  mfaService.IsEnrolled(user),
+		"required":                 required,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupMfa 生成两步验证密钥
+// @Summary 生成两步验证密钥
+// @Description 为当前用户生成TOTP密钥，需调用启用接口确认后生效
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"secret":"string","otpauth_url":"string"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"两步验证已开启"}"
+// @Router /api/v1/mfa/setup [post]
+func SetupMfa(c *gin.Context) {
+	user, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := mfaService.BeginSetup(user)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "请使用验证器App扫描二维码",
+		"secret":      secret,
+		"otpauth_url": uri,
+	})
+}
+
+// EnableMfa 启用两步验证
+// @Summary 启用两步验证
+// @Description 提交验证器App中的验证码启用两步验证，返回恢复码
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MfaCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "{"recovery_codes":[]string}"
+// @Failure 401 {object} map[string]interface{} "{"message":"验证码错误或已被使用"}"
+// @Router /api/v1/mfa/enable [post]
+func EnableMfa(c *gin.Context) {
+	user, claims, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req MfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	codes, err := mfaService.Enable(user, req.Code)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	// 当前会话已经验证过验证码
+	if err := sessionService.MarkMfaVerified(claims.SessionID); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        "两步验证已开启，请妥善保存恢复码",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMfa 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 提交验证码关闭两步验证，角色要求两步验证时无法关闭
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MfaCodeRequest true "验证码或恢复码"
+// @Success 200 {object} map[string]interface{} "{"message":"两步验证已关闭"}"
+// @Failure 403 {object} map[string]interface{} "{"message":"所属角色要求开启两步验证，无法关闭"}"
+// @Router /api/v1/mfa/disable [post]
+func DisableMfa(c *gin.Context) {
+	user, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req MfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := mfaService.Verify(user, req.Code); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	if err := mfaService.Disable(user, false); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "两步验证已关闭",
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 提交验证码重新生成恢复码，旧的恢复码全部作废
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MfaCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "{"recovery_codes":[]string}"
+// @Router /api/v1/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req MfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := mfaService.Verify(user, req.Code); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	codes, err := mfaService.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        "恢复码已重新生成",
+		"recovery_codes": codes,
+	})
+}
+
+// ResetUserMfa 管理员重置用户的两步验证
+// @Summary 重置用户两步验证
+// @Description 用户丢失验证设备时由管理员清除其两步验证，并结束该用户的所有会话
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body MfaResetRequest true "用户ID"
+// @Success 200 {object} map[string]interface{} "{"message":"两步验证已重置"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"用户不存在"}"
+// @Router /api/v1/admin/mfa/reset [post]
+func ResetUserMfa(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req MfaResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	var user model.User
+	if err := global.DB.WithContext(c.Request.Context()).Where("id = ? AND tenant_id = ?", req.UserID, claims.TenantID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return
+	}
+
+	if err := mfaService.Disable(&user, true); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	if _, err := sessionService.RevokeUserSessions(user.ID, user.TenantID, claims.UserID, ""); err != nil {
+		respondMfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "两步验证已重置",
+	})
+}
+
+// respondMfaError 返回两步验证相关错误
+func respondMfaError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := "两步验证处理失败"
+	switch {
+	case errors.Is(err, service.ErrMfaCodeInvalid), errors.Is(err, service.ErrMfaTokenInvalid),
+		errors.Is(err, service.ErrMfaTooManyAttempts):
+		status, message = http.StatusUnauthorized, err.Error()
+	case errors.Is(err, service.ErrMfaRequired), errors.Is(err, service.ErrUserDisabled),
+		errors.Is(err, service.ErrTenantDisabled):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, service.ErrMfaAlreadyEnabled), errors.Is(err, service.ErrMfaNotEnrolled):
+		status, message = http.StatusBadRequest, err.Error()
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
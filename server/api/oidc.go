@@ -0,0 +1,328 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var oidcService = &service.OidcService{}
+
+// OidcProviderItem 登录页展示的身份提供方
+type OidcProviderItem struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	TenantID uint   `json:"tenant_id"`
+}
+
+// OidcCallbackRequest 单点登录回调请求，参数来自身份提供方重定向到前端回调页的查询参数
+type OidcCallbackRequest struct {
+	State string `json:"state" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// SaveOidcProviderRequest 保存身份提供方请求，id为0时创建
+type SaveOidcProviderRequest struct {
+	ID            uint              `json:"id"`
+	Name          string            `json:"name" binding:"required"`
+	Issuer        string            `json:"issuer" binding:"required"`
+	ClientID      string            `json:"client_id" binding:"required"`
+	ClientSecret  string            `json:"client_secret"` // 更新时为空表示保持不变
+	RedirectURL   string            `json:"redirect_url" binding:"required"`
+	Scopes        []string          `json:"scopes"`
+	AutoProvision bool              `json:"auto_provision"`
+	LinkByEmail   bool              `json:"link_by_email"`
+	RoleClaim     string            `json:"role_claim"`
+	RoleMapping   map[string]string `json:"role_mapping"`
+	Status        int               `json:"status"`
+}
+
+// DeleteOidcProviderRequest 删除身份提供方请求
+type DeleteOidcProviderRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// GetOidcProviders 获取可用于登录的身份提供方
+// @Summary 获取单点登录方式
+// @Description 获取已启用的OpenID Connect身份提供方，可按tenant_id过滤
+// @Tags 单点登录
+// @Accept json
+// @Produce json
+// @Param tenant_id query int false "租户ID"
+// @Success 200 {object} map[string]interface{} "{"providers":[]OidcProviderItem}"
+// @Router /api/v1/oidc/providers [get]
+func GetOidcProviders(c *gin.Context) {
+	tenantID, _ := strconv.Atoi(c.Query("tenant_id"))
+	providers, err := oidcService.ListEnabledProviders(uint(tenantID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取单点登录方式失败",
+		})
+		return
+	}
+
+	items := make([]OidcProviderItem, 0, len(providers))
+	for _, provider := range providers {
+		items = append(items, OidcProviderItem{ID: provider.ID, Name: provider.Name, TenantID: provider.TenantID})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "获取单点登录方式成功",
+		"providers": items,
+	})
+}
+
+// OidcAuthorize 发起单点登录
+// @Summary 发起单点登录
+// @Description 生成state、nonce和PKCE参数，返回身份提供方的授权地址，前端跳转到该地址完成登录
+// @Tags 单点登录
+// @Accept json
+// @Produce json
+// @Param id path int true "身份提供方ID"
+// @Success 200 {object} map[string]interface{} "{"authorization_url":"string"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"身份提供方不存在或未启用"}"
+// @Failure 429 {object} map[string]interface{} "{"message":"登录请求过于频繁，请稍后再试"}"
+// @Failure 502 {object} map[string]interface{} "{"message":"获取身份提供方配置失败"}"
+// @Router /api/v1/oidc/authorize/{id} [get]
+func OidcAuthorize(c *gin.Context) {
+	providerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的身份提供方ID",
+		})
+		return
+	}
+	wait, err := loginGuardService.AllowOidcAuthorize(c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "发起单点登录失败",
+		})
+		return
+	}
+	if wait > 0 {
+		respondLoginGuard(c, http.StatusTooManyRequests, "登录请求过于频繁，请稍后再试", &service.LoginCheckResult{RetryAfter: wait})
+		return
+	}
+
+	authURL, err := oidcService.BeginLogin(c.Request.Context(), uint(providerID))
+	if err != nil {
+		respondOidcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"message":           "请跳转到身份提供方登录",
+		"authorization_url": authURL,
+	})
+}
+
+// OidcCallback 完成单点登录
+// @Summary 完成单点登录
+// @Description 使用身份提供方返回的state和code换取本系统的访问令牌；需要两步验证时返回mfa_pending
+// @Tags 单点登录
+// @Accept json
+// @Produce json
+// @Param request body OidcCallbackRequest true "回调参数"
+// @Success 200 {object} map[string]interface{} "{"message":"登录成功","token":"string","refresh_token":"string","expires_in":"int","userId":"uint"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"登录请求已过期，请重新登录"}"
+// @Failure 401 {object} map[string]interface{} "{"message":"ID Token校验失败"}"
+// @Failure 403 {object} map[string]interface{} "{"message":"该账号未关联系统用户，请联系管理员"}"
+// @Router /api/v1/oidc/callback [post]
+func OidcCallback(c *gin.Context) {
+	var req OidcCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	user, err := oidcService.CompleteLogin(c.Request.Context(), req.State, req.Code)
+	if err != nil {
+		respondOidcError(c, err)
+		return
+	}
+
+	completeLogin(c, user)
+}
+
+// GetTenantOidcProviders 管理员获取租户的身份提供方配置
+// @Summary 获取身份提供方配置
+// @Description 获取当前租户的全部OpenID Connect身份提供方配置，不包含客户端密钥
+// @Tags 单点登录
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"providers":[]model.OidcProvider}"
+// @Router /api/v1/admin/oidc/providers [get]
+func GetTenantOidcProviders(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	providers, err := oidcService.ListProviders(claims.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取身份提供方失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "获取身份提供方成功",
+		"providers": providers,
+	})
+}
+
+// SaveOidcProvider 管理员创建或更新身份提供方
+// @Summary 保存身份提供方配置
+// @Description 创建或更新当前租户的OpenID Connect身份提供方，role_mapping为角色声明值到本地角色名的映射
+// @Tags 单点登录
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body SaveOidcProviderRequest true "身份提供方配置"
+// @Success 200 {object} map[string]interface{} "{"provider":"model.OidcProvider"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"请求参数错误"}"
+// @Router /api/v1/admin/oidc/providers/save [post]
+func SaveOidcProvider(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req SaveOidcProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil ||
+		!(strings.HasPrefix(req.Issuer, "https://") || strings.HasPrefix(req.Issuer, "http://")) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+	if req.ID == 0 && req.ClientSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "客户端密钥不能为空",
+		})
+		return
+	}
+	if req.Status == 0 {
+		req.Status = 1
+	}
+
+	provider := &model.OidcProvider{
+		ID:            req.ID,
+		TenantID:      claims.TenantID,
+		Name:          req.Name,
+		Issuer:        strings.TrimSuffix(req.Issuer, "/"),
+		ClientID:      req.ClientID,
+		ClientSecret:  req.ClientSecret,
+		RedirectURL:   req.RedirectURL,
+		Scopes:        req.Scopes,
+		AutoProvision: req.AutoProvision,
+		LinkByEmail:   req.LinkByEmail,
+		RoleClaim:     req.RoleClaim,
+		RoleMapping:   req.RoleMapping,
+		Status:        req.Status,
+	}
+	if err := oidcService.SaveProvider(provider); err != nil {
+		respondOidcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "保存身份提供方成功",
+		"provider": provider,
+	})
+}
+
+// DeleteOidcProvider 管理员删除身份提供方
+// @Summary 删除身份提供方
+// @Description 删除当前租户的身份提供方，已关联的外部身份一并删除
+// @Tags 单点登录
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body DeleteOidcProviderRequest true "身份提供方ID"
+// @Success 200 {object} map[string]interface{} "{"message":"删除身份提供方成功"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"身份提供方不存在或未启用"}"
+// @Router /api/v1/admin/oidc/providers/delete [post]
+func DeleteOidcProvider(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req DeleteOidcProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := oidcService.DeleteProvider(req.ID, claims.TenantID); err != nil {
+		respondOidcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除身份提供方成功",
+	})
+}
+
+// respondOidcError 返回单点登录错误，身份提供方的详细错误只记录在服务端
+func respondOidcError(c *gin.Context, err error) {
+	status, message := http.StatusInternalServerError, "单点登录失败"
+	switch {
+	case errors.Is(err, service.ErrOidcProviderNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, service.ErrOidcStateInvalid):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrOidcUserNotLinked):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, service.ErrOidcIDToken):
+		status, message = http.StatusUnauthorized, service.ErrOidcIDToken.Error()
+	case errors.Is(err, service.ErrOidcDiscovery):
+		status, message = http.StatusBadGateway, service.ErrOidcDiscovery.Error()
+	case errors.Is(err, service.ErrOidcExchange):
+		status, message = http.StatusUnauthorized, service.ErrOidcExchange.Error()
+	}
+	fmt.Printf("单点登录失败: %v\n", err)
+
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
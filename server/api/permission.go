@@ -1,10 +1,13 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"go-react-admin/middleware"
+	"go-react-admin/model"
 	"go-react-admin/service"
 
 	"github.com/gin-gonic/gin"
@@ -12,23 +15,58 @@ import (
 
 type PermissionApi struct {
 	permissionService *service.PermissionService
+	approvalService   *service.ApprovalService
+	access            *middleware.RouteAccess
 }
 
-func NewPermissionApi() *PermissionApi {
+// NewPermissionApi access为Authorize中间件使用的路由授权声明，权限解释据此判断路由的授权方式
+func NewPermissionApi(access *middleware.RouteAccess) *PermissionApi {
 	return &PermissionApi{
 		permissionService: &service.PermissionService{},
+		approvalService:   &service.ApprovalService{},
+		access:            access,
 	}
 }
 
+// submittedForApproval 变更需要审批时返回202和变更申请
+func submittedForApproval(c *gin.Context, request *model.ChangeRequest) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 202,
+		"msg":  "变更已提交审批",
+		"data": request,
+	})
+}
+
+// approvalSubmitError 把提交变更时的审批错误转换为响应，返回false表示不是审批错误
+func approvalSubmitError(c *gin.Context, err error) bool {
+	status := 0
+	switch {
+	case errors.Is(err, service.ErrChangeRequestDuplicate):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrRoleNotFound):
+		status = http.StatusNotFound
+	}
+	if status == 0 {
+		return false
+	}
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+	})
+	return true
+}
+
 // AssignRolePermissions 分配角色权限
 // @Summary 分配角色权限
-// @Description 为角色分配菜单和API权限
+// @Description 为角色分配菜单和API权限，租户启用了审批时提交变更申请并返回202
 // @Tags 权限管理
 // @Accept json
 // @Produce json
 // @Param request body service.PermissionRequest true "权限分配请求"
 // @Success 200 {object} map[string]interface{} "成功"
+// @Success 202 {object} map[string]interface{} "{"data":model.ChangeRequest}"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 409 {object} map[string]interface{} "已有待审批的变更申请"
 // @Failure 500 {object} map[string]interface{} "服务器错误"
 // @Router /api/permissions/role [post]
 func (p *PermissionApi) AssignRolePermissions(c *gin.Context) {
@@ -46,13 +84,21 @@ func (p *PermissionApi) AssignRolePermissions(c *gin.Context) {
 		req.TenantID = tenantID.(uint)
 	}
 
-	if err := p.permissionService.AssignRolePermissions(&req); err != nil {
+	request, err := p.approvalService.SubmitRolePermissions(&req, c.GetUint("user_id"))
+	if err != nil {
+		if approvalSubmitError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "分配权限失败: " + err.Error(),
 		})
 		return
 	}
+	if request != nil {
+		submittedForApproval(c, request)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -62,13 +108,15 @@ func (p *PermissionApi) AssignRolePermissions(c *gin.Context) {
 
 // AssignUserRoles 分配用户角色
 // @Summary 分配用户角色
-// @Description 为用户分配角色
+// @Description 为用户分配角色，validity可按角色ID指定生效和失效时间，到期后自动收回；租户启用了审批时提交变更申请并返回202
 // @Tags 权限管理
 // @Accept json
 // @Produce json
 // @Param request body service.UserRoleRequest true "用户角色分配请求"
 // @Success 200 {object} map[string]interface{} "成功"
+// @Success 202 {object} map[string]interface{} "{"data":model.ChangeRequest}"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 409 {object} map[string]interface{} "已有待审批的变更申请"
 // @Failure 500 {object} map[string]interface{} "服务器错误"
 // @Router /api/permissions/user [post]
 func (p *PermissionApi) AssignUserRoles(c *gin.Context) {
@@ -86,13 +134,28 @@ func (p *PermissionApi) AssignUserRoles(c *gin.Context) {
 		req.TenantID = tenantID.(uint)
 	}
 
-	if err := p.permissionService.AssignUserRoles(&req); err != nil {
+	request, err := p.approvalService.SubmitUserRoles(&req, c.GetUint("user_id"))
+	if err != nil {
+		if approvalSubmitError(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidValidity) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "分配角色失败: " + err.Error(),
 		})
 		return
 	}
+	if request != nil {
+		submittedForApproval(c, request)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -311,3 +374,78 @@ func (p *PermissionApi) CheckPermission(c *gin.Context) {
 	})
 	fmt.Printf("检查权限: 资源=%s, 操作=%s, 结果=%v\n", resource, action, allowed)
 }
+
+// ExplainPermission 解释权限
+// @Summary 解释权限
+// @Description 解释用户或角色对接口的授权结果，返回匹配的策略、角色继承路径及涉及的数据表和字段权限；指定what_if时评估假设的角色变更，不会保存
+// @Tags 权限管理
+// @Accept json
+// @Produce json
+// @Param request body service.ExplainRequest true "权限解释请求"
+// @Success 200 {object} map[string]interface{} "{"data":service.ExplainResult}"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "无权解释其他租户的权限"
+// @Router /api/v1/permissions/explain [post]
+func (p *PermissionApi) ExplainPermission(c *gin.Context) {
+	var req service.ExplainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 解释其他租户的权限时，调用者在该租户也需要有本接口的权限
+	tenantID := c.GetUint("tenant_id")
+	if req.TenantID == 0 {
+		req.TenantID = tenantID
+	} else if req.TenantID != tenantID {
+		allowed, err := p.permissionService.Enforce(c.GetUint("user_id"), c.Request.URL.Path, c.Request.Method, req.TenantID)
+		if err != nil || !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code": 403,
+				"msg":  "无权解释其他租户的权限",
+			})
+			return
+		}
+	}
+
+	req.Access = explainAccess(p.access, req.Method, req.Path)
+	result, err := p.permissionService.Explain(&req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrExplainSubject):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrApiKeyNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": result,
+	})
+}
+
+// explainAccess 按Authorize中间件使用的路由声明确定被解释请求的授权方式
+func explainAccess(access *middleware.RouteAccess, method, path string) service.ExplainAccess {
+	decision, ok := access.Resolve(method, path)
+	switch {
+	case !ok:
+		return service.ExplainAccessNoRoute
+	case decision == middleware.AccessPublic:
+		return service.ExplainAccessPublic
+	case decision == middleware.AccessAuthenticated:
+		return service.ExplainAccessAuthenticated
+	default:
+		return service.ExplainAccessPolicy
+	}
+}
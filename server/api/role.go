@@ -22,8 +22,8 @@ import (
 // @Router /api/roles [get]
 func GetRoleList(c *gin.Context) {
 	var roles []model.Role
-	// 从数据库中获取所有角色
-	if err := global.DB.Find(&roles).Error; err != nil {
+	// 从数据库中获取当前租户的所有角色
+	if err := global.DB.WithContext(c.Request.Context()).Find(&roles).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "获取角色列表失败",
@@ -61,8 +61,8 @@ func CreateRole(c *gin.Context) {
 		return
 	}
 
-	// 创建角色
-	if err := global.DB.Create(&role).Error; err != nil {
+	// 创建角色，租户ID取当前租户
+	if err := global.DB.WithContext(c.Request.Context()).Create(&role).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "创建角色失败",
@@ -102,8 +102,16 @@ func UpdateRole(c *gin.Context) {
 		return
 	}
 
-	// 更新角色
-	if err := global.DB.Model(&model.Role{}).Where("id = ?", id).Updates(role).Error; err != nil {
+	// 更新角色，require_mfa为布尔值，零值不会被Updates更新，需要单独更新
+	db := global.DB.WithContext(c.Request.Context())
+	if err := db.Model(&model.Role{}).Where("id = ?", id).Updates(role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新角色失败",
+		})
+		return
+	}
+	if err := db.Model(&model.Role{}).Where("id = ?", id).Update("require_mfa", role.RequireMFA).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "更新角色失败",
@@ -140,14 +148,31 @@ func DeleteRole(c *gin.Context) {
 		return
 	}
 
-	// 删除角色
-	if err := global.DB.Delete(&model.Role{}, roleID).Error; err != nil {
+	// 删除角色，只能删除当前租户的角色
+	result := global.DB.WithContext(c.Request.Context()).Delete(&model.Role{}, roleID)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "删除角色失败",
 		})
 		return
 	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "角色不存在",
+		})
+		return
+	}
+
+	// 删除角色的继承关系
+	if err := roleHierarchyService.RemoveRole(c.GetUint("tenant_id"), uint(roleID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除角色继承关系失败",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var roleHierarchyService = &service.RoleHierarchyService{}
+
+// SetRoleParentsRequest 设置上级角色请求
+type SetRoleParentsRequest struct {
+	ParentIDs []uint `json:"parent_ids"`
+	Reason    string `json:"reason"` // 启用审批时的申请理由
+}
+
+// roleID 解析路径中的角色ID
+func roleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的角色ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// roleHierarchyError 把角色继承服务的错误转换为响应
+func roleHierarchyError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrRoleCycle), errors.Is(err, service.ErrChangeRequestDuplicate):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// GetRoleParents 获取上级角色
+// @Summary 获取上级角色
+// @Description 获取角色直接继承的上级角色
+// @Tags 角色管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} map[string]interface{} "{"roles":[]model.Role}"
+// @Router /api/v1/role/parents/{id} [get]
+func GetRoleParents(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	roles, err := roleHierarchyService.GetParents(c.GetUint("tenant_id"), id)
+	if err != nil {
+		roleHierarchyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取上级角色成功",
+		"roles":   roles,
+	})
+}
+
+// SetRoleParents 设置上级角色
+// @Summary 设置上级角色
+// @Description 替换角色的上级角色，角色继承上级角色的菜单、接口和数据表权限，不能形成循环；租户启用了审批时提交变更申请并返回202
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Param data body SetRoleParentsRequest true "上级角色ID"
+// @Success 200 {object} map[string]interface{} "{"message":"上级角色设置成功"}"
+// @Success 202 {object} map[string]interface{} "{"request":model.ChangeRequest}"
+// @Failure 409 {object} map[string]interface{} "{"message":"角色继承不能形成循环"}"
+// @Router /api/v1/role/parents/{id} [post]
+func SetRoleParents(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+	var req SetRoleParentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	request, err := approvalService.SubmitRoleParents(&service.RoleParentsRequest{
+		RoleID:    id,
+		ParentIDs: req.ParentIDs,
+		TenantID:  c.GetUint("tenant_id"),
+		Reason:    req.Reason,
+	}, c.GetUint("user_id"))
+	if err != nil {
+		roleHierarchyError(c, err)
+		return
+	}
+	if request != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "变更已提交审批",
+			"request": request,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "上级角色设置成功",
+	})
+}
+
+// GetRoleEffectivePermissions 获取角色有效权限
+// @Summary 获取角色有效权限
+// @Description 展开角色继承链，返回角色的全部有效权限及每项权限的来源角色
+// @Tags 角色管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} map[string]interface{} "{"permissions":service.EffectivePermissions}"
+// @Router /api/v1/role/effective/{id} [get]
+func GetRoleEffectivePermissions(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	permissions, err := roleHierarchyService.GetEffectivePermissions(c.GetUint("tenant_id"), id)
+	if err != nil {
+		roleHierarchyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "获取角色有效权限成功",
+		"permissions": permissions,
+	})
+}
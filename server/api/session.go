@@ -0,0 +1,291 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/service"
+	"go-react-admin/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+var sessionService = &service.SessionService{}
+
+// SessionItem 会话列表项
+type SessionItem struct {
+	model.UserSession
+	Current bool `json:"current"` // 是否为发起请求的当前会话
+}
+
+// RevokeSessionRequest 结束会话请求
+type RevokeSessionRequest struct {
+	SessionID string `json:"session_id" binding:"required"`
+}
+
+// ForceLogoutRequest 强制下线请求，指定session_id时仅结束该会话，否则结束用户全部会话
+type ForceLogoutRequest struct {
+	UserID    uint   `json:"user_id" binding:"required"`
+	SessionID string `json:"session_id"`
+}
+
+// currentClaims 获取当前请求的令牌声明
+func currentClaims(c *gin.Context) (*utils.CustomClaims, bool) {
+	val, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := val.(*utils.CustomClaims)
+	return claims, ok
+}
+
+// toSessionItems 转换会话列表并标记当前会话
+func toSessionItems(sessions []model.UserSession, currentSessionID string) []SessionItem {
+	items := make([]SessionItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, SessionItem{UserSession: session, Current: session.SessionID == currentSessionID})
+	}
+	return items
+}
+
+// GetMySessions 获取当前用户的登录会话
+// @Summary 获取我的登录会话
+// @Description 获取当前用户所有有效的登录会话
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"sessions":[]SessionItem}"
+// @Failure 500 {object} map[string]interface{} "{"message":"获取会话列表失败"}"
+// @Router /api/v1/session/list [get]
+func GetMySessions(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	sessions, err := sessionService.ListUserSessions(claims.UserID, claims.TenantID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取会话列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "获取会话列表成功",
+		"sessions": toSessionItems(sessions, claims.SessionID),
+	})
+}
+
+// RevokeMySession 结束当前用户的指定会话
+// @Summary 结束我的登录会话
+// @Description 结束当前用户的指定登录会话，该会话下的令牌立即失效
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RevokeSessionRequest true "会话ID"
+// @Success 200 {object} map[string]interface{} "{"message":"会话已结束"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"会话不存在"}"
+// @Router /api/v1/session/revoke [post]
+func RevokeMySession(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req RevokeSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := sessionService.RevokeSessionByID(req.SessionID, claims.TenantID, claims.UserID, claims.UserID); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "会话已结束",
+	})
+}
+
+// RevokeMyOtherSessions 结束当前用户除当前会话外的所有会话
+// @Summary 结束其他登录会话
+// @Description 结束当前用户在其他设备上的所有登录会话
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "{"message":"其他会话已结束","count":"int"}"
+// @Router /api/v1/session/revoke-others [post]
+func RevokeMyOtherSessions(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	count, err := sessionService.RevokeUserSessions(claims.UserID, claims.TenantID, claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "结束会话失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "其他会话已结束",
+		"count":   count,
+	})
+}
+
+// GetTenantSessions 管理员获取租户内的登录会话
+// @Summary 获取租户登录会话
+// @Description 获取当前租户内的有效登录会话，可通过user_id筛选指定用户
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} map[string]interface{} "{"sessions":[]SessionItem}"
+// @Failure 500 {object} map[string]interface{} "{"message":"获取会话列表失败"}"
+// @Router /api/v1/admin/sessions [get]
+func GetTenantSessions(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var query struct {
+		UserID uint `form:"user_id"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	var sessions []model.UserSession
+	var err error
+	if query.UserID != 0 {
+		sessions, err = sessionService.ListUserSessions(query.UserID, claims.TenantID, true)
+	} else {
+		sessions, err = sessionService.ListTenantSessions(claims.TenantID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取会话列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "获取会话列表成功",
+		"sessions": toSessionItems(sessions, claims.SessionID),
+	})
+}
+
+// ForceLogout 管理员强制用户下线
+// @Summary 强制用户下线
+// @Description 结束租户内指定用户的某个会话或全部会话，相关令牌立即失效
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ForceLogoutRequest true "下线参数"
+// @Success 200 {object} map[string]interface{} "{"message":"已强制下线","count":"int"}"
+// @Failure 404 {object} map[string]interface{} "{"message":"用户不存在"}"
+// @Router /api/v1/admin/sessions/logout [post]
+func ForceLogout(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	var req ForceLogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	// 只能操作本租户内的用户
+	var user model.User
+	if err := global.DB.WithContext(c.Request.Context()).Where("id = ? AND tenant_id = ?", req.UserID, claims.TenantID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return
+	}
+
+	count := 1
+	var err error
+	if req.SessionID != "" {
+		err = sessionService.RevokeSessionByID(req.SessionID, claims.TenantID, user.ID, claims.UserID)
+	} else {
+		count, err = sessionService.RevokeUserSessions(user.ID, claims.TenantID, claims.UserID, "")
+	}
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已强制下线",
+		"count":   count,
+	})
+}
+
+// respondSessionError 返回会话操作错误
+func respondSessionError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": "结束会话失败",
+	})
+}
@@ -1,26 +1,322 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"go-react-admin/global"
 	"go-react-admin/model"
+	"go-react-admin/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+var tenantService = &service.TenantService{}
+
+// tenantError 把租户服务的错误转换为响应
+func tenantError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrTenantInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrTenantNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTenantProtected):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrTenantExists), errors.Is(err, service.ErrTenantNotDeleted), errors.Is(err, service.ErrTenantGraceExpired):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// isPlatformUser 判断调用者是否属于平台租户，只有平台租户的用户可以管理其他租户
+func isPlatformUser(c *gin.Context) bool {
+	return c.GetUint("tenant_id") == global.GlobalConfig.MultiTenant.PlatformTenantID
+}
+
+// isPlatformAdmin 判断调用者是否为平台管理员，只有平台管理员可以跨租户管理用户和日志
+func isPlatformAdmin(c *gin.Context) bool {
+	return (&service.PermissionService{}).IsPlatformAdmin(c.GetUint("user_id"), c.GetUint("tenant_id"))
+}
+
+// platformContext 返回数据库查询使用的上下文，平台管理员跳过租户隔离，可以管理全部租户的数据
+func platformContext(c *gin.Context) context.Context {
+	if isPlatformAdmin(c) {
+		return global.WithoutTenantScope(c.Request.Context())
+	}
+	return c.Request.Context()
+}
+
+// requirePlatformUser 非平台租户的调用者返回403
+func requirePlatformUser(c *gin.Context) bool {
+	if isPlatformUser(c) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"message": "只有平台租户的用户可以管理租户",
+	})
+	return false
+}
+
+// tenantID 解析路径中的租户ID
+func tenantID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的租户ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
 // GetTenantList 获取租户列表
+// @Summary 获取租户列表
+// @Description 平台租户的用户获取全部租户，deleted=true时获取已删除、等待清除的租户；其他租户的用户只能获取自己的租户
+// @Tags 租户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param keyword query string false "名称或编码关键字"
+// @Param deleted query bool false "只查询已删除的租户"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} map[string]interface{} "{"tenants":[]model.Tenant,"total":int}"
+// @Router /api/v1/tenant/list [get]
 func GetTenantList(c *gin.Context) {
-	var tenants []model.Tenant
-	// 从数据库中获取所有租户
-	if err := global.DB.Find(&tenants).Error; err != nil {
+	if !isPlatformUser(c) {
+		tenant, err := tenantService.GetTenant(c.GetUint("tenant_id"))
+		if err != nil {
+			tenantError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "获取租户列表成功",
+			"tenants": []model.Tenant{*tenant},
+			"total":   1,
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "100"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 100
+	}
+
+	tenants, total, err := tenantService.ListTenants(c.Query("keyword"), c.Query("deleted") == "true", page, pageSize)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "获取租户列表失败",
+			"success": false,
+			"message": "获取租户列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "获取租户列表成功",
+		"tenants":  tenants,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// CreateTenant 创建租户
+// @Summary 创建租户
+// @Description 在一个事务中创建租户，复制模板租户的角色、菜单和API，创建租户管理员并写入权限策略
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body service.TenantCreateRequest true "租户信息"
+// @Success 200 {object} map[string]interface{} "{"tenant":model.Tenant}"
+// @Failure 409 {object} map[string]interface{} "{"message":"租户名称或编码已存在"}"
+// @Router /api/v1/tenant/create [post]
+func CreateTenant(c *gin.Context) {
+	if !requirePlatformUser(c) {
+		return
+	}
+	var req service.TenantCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
 		})
 		return
 	}
 
+	tenant, err := tenantService.CreateTenant(&req)
+	if err != nil {
+		tenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "租户创建成功",
+		"tenant":  tenant,
+	})
+}
+
+// UpdateTenant 更新租户
+// @Summary 更新租户
+// @Description 更新租户的名称、描述和身份验证方式，空字段不修改
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Param data body service.TenantUpdateRequest true "租户信息"
+// @Success 200 {object} map[string]interface{} "{"tenant":model.Tenant}"
+// @Router /api/v1/tenant/update/{id} [put]
+func UpdateTenant(c *gin.Context) {
+	if !requirePlatformUser(c) {
+		return
+	}
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+	var req service.TenantUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	tenant, err := tenantService.UpdateTenant(id, &req)
+	if err != nil {
+		tenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "租户更新成功",
+		"tenant":  tenant,
+	})
+}
+
+// SuspendTenant 停用租户
+// @Summary 停用租户
+// @Description 停用后租户用户无法登录，已签发的令牌也立即失效；平台租户和模板租户不能停用
+// @Tags 租户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Success 200 {object} map[string]interface{} "{"tenant":model.Tenant}"
+// @Router /api/v1/tenant/suspend/{id} [post]
+func SuspendTenant(c *gin.Context) {
+	setTenantStatus(c, model.TenantStatusSuspended, "租户已停用")
+}
+
+// ResumeTenant 启用租户
+// @Summary 启用租户
+// @Tags 租户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Success 200 {object} map[string]interface{} "{"tenant":model.Tenant}"
+// @Router /api/v1/tenant/resume/{id} [post]
+func ResumeTenant(c *gin.Context) {
+	setTenantStatus(c, model.TenantStatusActive, "租户已启用")
+}
+
+// setTenantStatus 停用或启用租户的公共流程
+func setTenantStatus(c *gin.Context, status int, message string) {
+	if !requirePlatformUser(c) {
+		return
+	}
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	tenant, err := tenantService.SetStatus(id, status)
+	if err != nil {
+		tenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"tenant":  tenant,
+	})
+}
+
+// DeleteTenant 删除租户
+// @Summary 删除租户
+// @Description 软删除租户并立即停用，宽限期（TENANT_DELETE_GRACE_DAYS）内可以恢复，到期后后台任务彻底清除租户数据
+// @Tags 租户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Success 200 {object} map[string]interface{} "{"tenant":model.Tenant}"
+// @Router /api/v1/tenant/delete/{id} [delete]
+func DeleteTenant(c *gin.Context) {
+	if !requirePlatformUser(c) {
+		return
+	}
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	tenant, err := tenantService.DeleteTenant(id)
+	if err != nil {
+		tenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "租户已删除，可在宽限期内恢复",
+		"tenant":  tenant,
+	})
+}
+
+// RestoreTenant 恢复已删除的租户
+// @Summary 恢复已删除的租户
+// @Description 在宽限期内恢复租户，恢复后仍为停用状态，需要再启用
+// @Tags 租户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Success 200 {object} map[string]interface{} "{"tenant":model.Tenant}"
+// @Router /api/v1/tenant/restore/{id} [post]
+func RestoreTenant(c *gin.Context) {
+	if !requirePlatformUser(c) {
+		return
+	}
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	tenant, err := tenantService.RestoreTenant(id)
+	if err != nil {
+		tenantError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"tenants": tenants,
+		"success": true,
+		"message": "租户已恢复",
+		"tenant":  tenant,
 	})
 }
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	tokenService     = &service.TokenService{}
+	authStateService = &service.AuthStateService{}
+)
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken 刷新访问令牌
+// @Summary 刷新访问令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即失效；重复使用已轮换的刷新令牌会吊销整个登录会话
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} map[string]interface{} "{"token":"string","refresh_token":"string","expires_in":"int"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"string"}"
+// @Failure 401 {object} map[string]interface{} "{"message":"无效的刷新令牌"}"
+// @Router /api/v1/token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	pair, err := tokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		// 账号状态与登录时相同的检查，服务账号只能使用API Key
+		if errors.Is(err, service.ErrRefreshTokenInvalid) || errors.Is(err, service.ErrRefreshTokenReused) ||
+			errors.Is(err, service.ErrUserDisabled) || errors.Is(err, service.ErrTenantDisabled) ||
+			errors.Is(err, service.ErrServiceAccountLogin) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "刷新令牌失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"message":       "刷新成功",
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+	})
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,77 +11,273 @@ import (
 
 	"go-react-admin/global"
 	"go-react-admin/model"
+	"go-react-admin/service"
 	"go-react-admin/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// LoginRequest 登录请求，登录失败次数过多时需要附带验证码
+type LoginRequest struct {
+	Username      string `json:"username" binding:"required"`
+	Password      string `json:"password" binding:"required"`
+	CaptchaID     string `json:"captcha_id"`
+	CaptchaAnswer string `json:"captcha_answer"`
+	TenantID      uint   `json:"tenant_id"` // 可选，目录用户首次登录时指定所属租户
+}
+
+// RegisterRequest 注册请求，租户、状态、部门和身份来源由服务端决定
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Password string `json:"password" binding:"required,min=6,max=100"`
+	Nickname string `json:"nickname" binding:"max=50"`
+	Email    string `json:"email" binding:"omitempty,email,max=100"`
+}
+
+// UserCreateRequest 创建用户请求，角色通过权限管理接口分配
+type UserCreateRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	TenantID uint   `json:"tenant_id"` // 只有平台管理员可以指定
+	Status   int    `json:"status"`    // 不填时启用
+}
+
+// UserUpdateRequest 更新用户请求，只修改请求中出现的字段，密码为空表示不修改
+type UserUpdateRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	Password *string `json:"password"`
+	TenantID *uint   `json:"tenant_id"`
+	Status   *int    `json:"status"`
+}
+
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录接口，验证用户名密码并返回JWT Token
+// @Description 用户登录接口，按租户配置的身份验证方式（本地或LDAP）验证用户名密码并返回JWT Token；需要两步验证时返回mfa_pending及挑战令牌mfa_token；失败次数过多时需要验证码或被临时锁定
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param user body model.User true "用户登录信息"
-// @Success 200 {object} map[string]interface{} "{"message":"登录成功","token":"string","userId":"uint"}"
-// @Failure 400 {object} map[string]interface{} "{"error":"string"}"
-// @Failure 401 {object} map[string]interface{} "{"error":"用户名或密码错误"}"
-// @Failure 500 {object} map[string]interface{} "{"error":"生成Token失败"}"
+// @Param user body LoginRequest true "用户登录信息"
+// @Success 200 {object} map[string]interface{} "{"message":"登录成功","token":"string","refresh_token":"string","expires_in":"int","userId":"uint"}"
+// @Failure 400 {object} map[string]interface{} "{"message":"请输入验证码","captcha_required":true,"captcha":{}}"
+// @Failure 401 {object} map[string]interface{} "{"message":"用户名或密码错误"}"
+// @Failure 403 {object} map[string]interface{} "{"message":"用户已被禁用"}"
+// @Failure 429 {object} map[string]interface{} "{"message":"登录失败次数过多","retry_after":"int"}"
+// @Failure 500 {object} map[string]interface{} "{"message":"生成Token失败"}"
 // @Router /api/login [post]
 func Login(c *gin.Context) {
-	var user model.User
+	var user LoginRequest
 	// 绑定JSON
 	if err := c.ShouldBindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请输入用户名和密码",
+		})
+		return
+	}
+	c.Set("username", user.Username)
+	clientIP := c.ClientIP()
+
+	// 检查锁定、退避和验证码
+	guard, err := loginGuardService.Check(user.Username, clientIP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "登录失败",
+		})
+		return
+	}
+	if guard.Locked {
+		respondLoginGuard(c, http.StatusTooManyRequests, "登录失败次数过多，已被临时锁定，请稍后再试", guard)
+		return
+	}
+	if guard.RetryAfter > 0 {
+		respondLoginGuard(c, http.StatusTooManyRequests, "登录过于频繁，请稍后再试", guard)
+		return
+	}
+	if guard.CaptchaRequired {
+		passed, err := service.GetLoginChallenge().Verify(user.CaptchaID, user.CaptchaAnswer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "验证码校验失败",
+			})
+			return
+		}
+		if !passed {
+			message := "请输入验证码"
+			if user.CaptchaAnswer != "" {
+				message = "验证码错误"
+			}
+			respondLoginGuard(c, http.StatusBadRequest, message, guard)
+			return
+		}
+	}
+
+	// 按租户配置的身份验证方式校验用户名和密码
+	dbUser, err := authService.Authenticate(c.Request.Context(), user.TenantID, user.Username, user.Password)
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		result, err := loginGuardService.RecordFailure(user.Username, clientIP, c.Request.UserAgent())
+		if err != nil {
+			fmt.Printf("记录登录失败次数失败: %v\n", err)
+		}
+		if result != nil && result.Locked {
+			respondLoginGuard(c, http.StatusTooManyRequests, "登录失败次数过多，已被临时锁定，请稍后再试", result)
+			return
+		}
+		respondLoginGuard(c, http.StatusUnauthorized, "用户名或密码错误", result)
+		return
+	case errors.Is(err, service.ErrLdapUserNotProvisioned):
+		// 目录验证已通过，只是本地没有对应用户
+		c.JSON(http.StatusForbidden, gin.H{
 			"success": false,
 			"message": err.Error(),
 		})
 		return
+	case err != nil:
+		fmt.Printf("身份验证失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "认证服务不可用",
+		})
+		return
 	}
 
-	// 在数据库中查找用户
-	var dbUser model.User
-	if err := global.DB.Where("username = ? AND password = ?", user.Username, user.Password).First(&dbUser).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+	// 失败计数在签发令牌时才清除，需要两步验证时验证码错误继续累计
+	completeLogin(c, dbUser)
+}
+
+// completeLogin 身份验证通过后检查账号状态和两步验证要求，然后签发令牌
+func completeLogin(c *gin.Context, dbUser *model.User) {
+	// 检查用户及租户状态，身份验证通过后才提示，避免泄露账号状态
+	if err := authStateService.CheckLogin(dbUser); err != nil {
+		if errors.Is(err, service.ErrUserDisabled) || errors.Is(err, service.ErrTenantDisabled) ||
+			errors.Is(err, service.ErrServiceAccountLogin) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"message": "用户名或密码错误",
+			"message": "登录失败",
 		})
 		return
 	}
 
-	// 生成JWT Token
-	token, err := utils.GenerateToken(dbUser.ID, dbUser.Username, dbUser.TenantID)
+	// 已开启两步验证，或角色要求两步验证的用户需要先完成验证
+	required, err := mfaService.UserRequiresMFA(dbUser)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"message": "生成Token失败",
+			"message": "登录失败",
+		})
+		return
+	}
+	if mfaService.IsEnrolled(dbUser) || required {
+		purpose := utils.MfaPurposeVerify
+		if !mfaService.IsEnrolled(dbUser) {
+			purpose = utils.MfaPurposeEnroll
+		}
+		mfaToken, _, err := utils.GenerateMfaToken(dbUser.ID, dbUser.TenantID, purpose)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "生成Token失败",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":             true,
+			"message":             "请完成两步验证",
+			"mfa_pending":         true,
+			"mfa_enroll_required": purpose == utils.MfaPurposeEnroll,
+			"mfa_token":           mfaToken,
+			"expires_in":          int64(utils.MfaChallengeTTL().Seconds()),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "登录成功",
-		"token":   token,
-		"userId":  dbUser.ID,
+	respondLoginTokens(c, dbUser, false, nil)
+}
+
+// respondLoginTokens 登记登录会话，签发访问令牌和刷新令牌并返回登录结果
+func respondLoginTokens(c *gin.Context, user *model.User, mfaVerified bool, extra gin.H) {
+	session, err := sessionService.CreateSession(user, service.SessionInfo{
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		DeviceLabel: c.GetHeader("X-Device-Label"),
+		MfaVerified: mfaVerified,
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建登录会话失败",
+		})
+		return
+	}
+
+	pair, err := tokenService.IssueTokenPair(user, session.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成Token失败",
+		})
+		return
+	}
+
+	// 完成全部验证后才清除失败计数
+	if err := loginGuardService.RecordSuccess(user.Username); err != nil {
+		fmt.Printf("清除登录失败次数失败: %v\n", err)
+	}
+
+	// 供登录日志中间件记录
+	c.Set("username", user.Username)
+	c.Set("user_id", user.ID)
+	c.Set("tenant_id", user.TenantID)
+
+	resp := gin.H{
+		"success":       true,
+		"message":       "登录成功",
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+		"userId":        user.ID,
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // Register 用户注册
 // @Summary 用户注册
-// @Description 用户注册接口，创建新用户
+// @Description 用户注册接口，在TENANT_REGISTER_ID指定的租户中创建启用的本地用户，不属于任何部门也没有角色
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param user body model.User true "用户注册信息"
+// @Param user body RegisterRequest true "用户注册信息"
 // @Success 200 {object} map[string]interface{} "{"message":"注册成功"}"
 // @Failure 400 {object} map[string]interface{} "{"error":"string"}"
+// @Failure 403 {object} map[string]interface{} "{"error":"未开放注册"}"
 // @Failure 500 {object} map[string]interface{} "{"error":"注册失败"}"
 // @Router /api/register [post]
 func Register(c *gin.Context) {
-	var user model.User
+	tenantID := global.GlobalConfig.MultiTenant.RegisterTenantID
+	if tenantID == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "未开放注册",
+		})
+		return
+	}
+
+	var req RegisterRequest
 	// 绑定JSON
-	if err := c.ShouldBindJSON(&user); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -88,8 +285,37 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "密码不能为空",
+		})
+		return
+	}
+
+	// 密码哈希
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "注册失败",
+		})
+		return
+	}
+	user := model.User{
+		Username:   req.Username,
+		Password:   hashed,
+		Nickname:   req.Nickname,
+		Email:      req.Email,
+		TenantID:   tenantID,
+		Status:     1,
+		Type:       model.UserTypeNormal,
+		AuthSource: model.AuthSourceLocal,
+	}
+
 	// 在数据库中创建用户
-	if err := global.DB.Create(&user).Error; err != nil {
+	db := global.DB.WithContext(global.WithTenant(c.Request.Context(), tenantID))
+	if err := db.Create(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "注册失败",
@@ -105,12 +331,12 @@ func Register(c *gin.Context) {
 
 // GetUserInfo 获取用户信息
 // @Summary 获取当前用户信息
-// @Description 根据JWT Token获取当前登录用户的信息
+// @Description 根据JWT Token获取当前登录用户的信息，permissions为用户在当前租户拥有的操作权限码
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} map[string]interface{} "{"user":model.User}"
+// @Success 200 {object} map[string]interface{} "{"user":model.User,"permissions":[]string}"
 // @Failure 401 {object} map[string]interface{} "{"error":"无法获取用户信息"}"
 // @Failure 404 {object} map[string]interface{} "{"error":"用户不存在"}"
 // @Router /api/user/info [get]
@@ -127,7 +353,7 @@ func GetUserInfo(c *gin.Context) {
 
 	// 在数据库中查找用户
 	var user model.User
-	if err := global.DB.First(&user, userID).Error; err != nil {
+	if err := global.DB.WithContext(c.Request.Context()).First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "用户不存在",
@@ -135,16 +361,26 @@ func GetUserInfo(c *gin.Context) {
 		return
 	}
 
+	codes, err := menuService.GetUserActionCodes(user.ID, c.GetUint("tenant_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取用户权限失败",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "获取用户信息成功",
-		"user":    user,
+		"success":     true,
+		"message":     "获取用户信息成功",
+		"user":        user,
+		"permissions": codes,
 	})
 }
 
 // Logout 用户登出
 // @Summary 用户登出
-// @Description 用户登出接口
+// @Description 用户登出接口，吊销当前访问令牌并结束其所属的登录会话
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -152,6 +388,16 @@ func GetUserInfo(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "{"message":"登出成功"}"
 // @Router /api/logout [post]
 func Logout(c *gin.Context) {
+	if val, exists := c.Get("claims"); exists {
+		claims := val.(*utils.CustomClaims)
+		if err := tokenService.RevokeAccessToken(claims); err != nil {
+			fmt.Printf("吊销访问令牌失败: %v\n", err)
+		}
+		if err := sessionService.RevokeSessionByID(claims.SessionID, claims.TenantID, claims.UserID, claims.UserID); err != nil {
+			fmt.Printf("结束登录会话失败: %v\n", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "登出成功",
@@ -160,18 +406,38 @@ func Logout(c *gin.Context) {
 
 // GetUserList 获取用户列表
 // @Summary 获取用户列表
-// @Description 获取所有用户的列表
+// @Description 获取所有用户的列表，指定dept_id时只返回该部门及下级部门的成员
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param dept_id query int false "部门ID"
 // @Success 200 {object} map[string]interface{} "{"users":[]model.User}"
 // @Failure 500 {object} map[string]interface{} "{"error":"获取用户列表失败"}"
 // @Router /api/users [get]
 func GetUserList(c *gin.Context) {
 	var users []model.User
-	// 从数据库中获取所有用户
-	if err := global.DB.Find(&users).Error; err != nil {
+	db := global.DB.WithContext(platformContext(c))
+	query := db.Model(&model.User{})
+	if deptParam := c.Query("dept_id"); deptParam != "" {
+		deptID, err := strconv.ParseUint(deptParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的部门ID",
+			})
+			return
+		}
+		deptIDs, err := departmentService.SubtreeIDs(c.GetUint("tenant_id"), uint(deptID))
+		if err != nil {
+			departmentError(c, err)
+			return
+		}
+		members := db.Model(&model.UserDepartment{}).Select("user_id").Where("dept_id IN ?", deptIDs)
+		query = query.Where("id IN (?)", members)
+	}
+	// 从数据库中获取用户
+	if err := query.Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "获取用户列表失败",
@@ -188,29 +454,21 @@ func GetUserList(c *gin.Context) {
 
 // CreateUser 创建用户
 // @Summary 创建用户
-// @Description 创建新用户
+// @Description 创建新用户，只有平台管理员可以指定其他租户；角色通过 /api/v1/permissions/user 分配
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param user body model.User true "用户创建信息"
+// @Param user body UserCreateRequest true "用户创建信息"
 // @Success 200 {object} map[string]interface{} "{"message":"用户创建成功","user":model.User}"
 // @Failure 400 {object} map[string]interface{} "{"error":"请求参数错误"}"
 // @Failure 500 {object} map[string]interface{} "{"error":"创建用户失败"}"
 // @Router /api/users [post]
 func CreateUser(c *gin.Context) {
-	var requestData struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		TenantID uint   `json:"tenant_id"`
-		Status   int    `json:"status"`
-		RoleIDs  []uint  `json:"role_ids"`
-	}
-	
+	var requestData UserCreateRequest
+
 	// 绑定JSON到requestData
-	if err := c.ShouldBindJSON(&requestData); err != nil {
-		fmt.Printf("绑定JSON错误: %v\n", err)
+	if err := c.ShouldBindJSON(&requestData); err != nil || !validUserStatus(requestData.Status, true) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "请求参数错误",
@@ -227,19 +485,34 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// 创建用户对象
+	// 密码哈希
+	hashedPassword, err := utils.HashPassword(requestData.Password)
+	if err != nil {
+		fmt.Printf("密码哈希错误: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建用户失败",
+		})
+		return
+	}
+
+	// 创建用户对象，只有平台管理员可以在其他租户创建用户，未指定状态时启用
 	user := model.User{
 		Username: requestData.Username,
 		Email:    requestData.Email,
-		Password: requestData.Password,
-		TenantID: requestData.TenantID,
+		Password: hashedPassword,
+		TenantID: c.GetUint("tenant_id"),
 		Status:   requestData.Status,
 	}
+	if requestData.TenantID != 0 && isPlatformAdmin(c) {
+		user.TenantID = requestData.TenantID
+	}
+	if user.Status == 0 {
+		user.Status = 1
+	}
 
-	fmt.Printf("接收到的用户数据: %+v\n", user)
-
-	// 创建用户
-	if err := global.DB.Create(&user).Error; err != nil {
+	db := global.DB.WithContext(platformContext(c))
+	if err := db.Create(&user).Error; err != nil {
 		fmt.Printf("创建用户错误: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -248,25 +521,6 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// 分配角色
-	if len(requestData.RoleIDs) > 0 {
-		var userRoles []model.UserRole
-		for _, roleID := range requestData.RoleIDs {
-			userRoles = append(userRoles, model.UserRole{
-				UserID:   user.ID,
-				RoleID:   roleID,
-				TenantID: user.TenantID,
-			})
-		}
-		
-		if err := global.DB.Create(&userRoles).Error; err != nil {
-			fmt.Printf("分配角色错误: %v\n", err)
-			// 如果角色分配失败，不返回错误，只记录日志
-			fmt.Printf("用户创建成功但角色分配失败: %v\n", err)
-		}
-	}
-
-	fmt.Printf("用户创建成功: %+v\n", user)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "用户创建成功",
@@ -274,32 +528,38 @@ func CreateUser(c *gin.Context) {
 	})
 }
 
+// validUserStatus 检查用户状态，allowZero表示可以不填
+func validUserStatus(status int, allowZero bool) bool {
+	return status == 1 || status == 2 || (allowZero && status == 0)
+}
+
 // UpdateUser 更新用户
 // @Summary 更新用户
-// @Description 根据用户ID更新用户信息
+// @Description 根据用户ID更新用户信息，只修改请求中出现的字段，密码为空表示不修改；角色通过 /api/v1/permissions/user 分配
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path int true "用户ID"
-// @Param user body model.User true "用户更新信息"
+// @Param user body UserUpdateRequest true "用户更新信息"
 // @Success 200 {object} map[string]interface{} "{"message":"用户更新成功"}"
 // @Failure 400 {object} map[string]interface{} "{"error":"请求参数错误"}"
+// @Failure 409 {object} map[string]interface{} "{"error":"请先收回用户的角色再修改所属租户"}"
 // @Failure 500 {object} map[string]interface{} "{"error":"更新用户失败"}"
 // @Router /api/users/{id} [put]
 func UpdateUser(c *gin.Context) {
-	id := c.Param("id")
-	var requestData struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		TenantID uint   `json:"tenant_id"`
-		Status   int    `json:"status"`
-		RoleIDs  []uint  `json:"role_ids"`
-	}
-	
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+	var requestData UserUpdateRequest
+
 	// 绑定JSON到requestData
-	if err := c.ShouldBindJSON(&requestData); err != nil {
+	if err := c.ShouldBindJSON(&requestData); err != nil || (requestData.Status != nil && !validUserStatus(*requestData.Status, false)) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "请求参数错误",
@@ -307,54 +567,86 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	// 如果密码为空，则不更新密码
-	updateData := map[string]interface{}{
-		"username":  requestData.Username,
-		"email":     requestData.Email,
-		"status":    requestData.Status,
-		"tenant_id": requestData.TenantID,
+	// 只能更新当前租户的用户，平台管理员可以更新全部租户的用户
+	db := global.DB.WithContext(platformContext(c))
+	var existing model.User
+	if err := db.Select("id", "tenant_id").First(&existing, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return
 	}
 
-	// 如果有密码，则添加密码更新
-	if requestData.Password != "" {
-		updateData["password"] = requestData.Password
+	// 只修改请求中出现的字段
+	updateData := map[string]interface{}{}
+	if requestData.Username != nil {
+		updateData["username"] = *requestData.Username
+	}
+	if requestData.Email != nil {
+		updateData["email"] = *requestData.Email
+	}
+	if requestData.Status != nil {
+		updateData["status"] = *requestData.Status
 	}
 
-	// 更新用户
-	if err := global.DB.Model(&model.User{}).Where("id = ?", id).Updates(updateData).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "更新用户失败",
-		})
-		return
+	// 只有平台管理员可以修改所属租户，用户在原租户的角色需要先收回
+	if requestData.TenantID != nil && *requestData.TenantID != 0 && *requestData.TenantID != existing.TenantID {
+		if !isPlatformAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "不能修改用户所属租户",
+			})
+			return
+		}
+		var roles int64
+		if err := db.Model(&model.UserRole{}).Where("user_id = ? AND tenant_id = ?", existing.ID, existing.TenantID).Count(&roles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "更新用户失败",
+			})
+			return
+		}
+		if roles > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "请先收回用户的角色再修改所属租户",
+			})
+			return
+		}
+		updateData["tenant_id"] = *requestData.TenantID
 	}
 
-	// 更新角色关联
-	if requestData.RoleIDs != nil {
-		// 先删除现有的角色关联
-		if err := global.DB.Where("user_id = ?", id).Delete(&model.UserRole{}).Error; err != nil {
-			fmt.Printf("删除旧角色关联错误: %v\n", err)
+	// 密码为空表示不修改
+	if requestData.Password != nil && *requestData.Password != "" {
+		hashedPassword, err := utils.HashPassword(*requestData.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "更新用户失败",
+			})
+			return
 		}
+		updateData["password"] = hashedPassword
+	}
 
-		// 如果有角色ID，则添加新的角色关联
-		if len(requestData.RoleIDs) > 0 {
-			var userRoles []model.UserRole
-			userID, _ := strconv.Atoi(id)
-			for _, roleID := range requestData.RoleIDs {
-				userRoles = append(userRoles, model.UserRole{
-					UserID:   uint(userID),
-					RoleID:   roleID,
-					TenantID: requestData.TenantID,
-				})
-			}
-			
-			if err := global.DB.Create(&userRoles).Error; err != nil {
-				fmt.Printf("分配新角色错误: %v\n", err)
-				// 如果角色分配失败，不返回错误，只记录日志
-			}
+	if len(updateData) > 0 {
+		if err := db.Model(&model.User{}).Where("id = ?", existing.ID).Updates(updateData).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "更新用户失败",
+			})
+			return
 		}
 	}
 
+	// 禁用用户时立即使其所有令牌和会话失效，其他修改清除状态缓存即可
+	if requestData.Status != nil && *requestData.Status == 2 {
+		disableUserAccess(c, existing.ID)
+	} else if err := authStateService.InvalidateUser(existing.ID); err != nil {
+		fmt.Printf("清除用户状态缓存失败: %v\n", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "用户更新成功",
@@ -383,13 +675,36 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// 删除用户
-	if err := global.DB.Delete(&model.User{}, userID).Error; err != nil {
+	// 删除用户，只能删除当前租户的用户，平台管理员可以删除全部租户的用户
+	db := global.DB.WithContext(platformContext(c))
+	result := db.Delete(&model.User{}, userID)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "删除用户失败",
 		})
 		return
 	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "用户不存在",
+		})
+		return
+	}
+	disableUserAccess(c, uint(userID))
+
+	// 移出所属部门，避免部门因残留成员无法删除
+	if err := db.Where("user_id = ?", userID).Delete(&model.UserDepartment{}).Error; err != nil {
+		fmt.Printf("移除用户部门失败: %v\n", err)
+	}
+
+	// 删除的用户不会再被启用，同时吊销其API Key
+	var operatorID uint
+	if claims, ok := currentClaims(c); ok {
+		operatorID = claims.UserID
+	}
+	if err := apiKeyService.RevokeUserKeys(uint(userID), operatorID); err != nil {
+		fmt.Printf("吊销用户API Key失败: %v\n", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -397,6 +712,25 @@ func DeleteUser(c *gin.Context) {
 	})
 }
 
+// disableUserAccess 使用户已签发的令牌立即失效并结束其所有会话
+func disableUserAccess(c *gin.Context, userID uint) {
+	if err := authStateService.RevokeUserTokens(userID); err != nil {
+		fmt.Printf("吊销用户令牌失败: %v\n", err)
+	}
+
+	var operatorID uint
+	if claims, ok := currentClaims(c); ok {
+		operatorID = claims.UserID
+	}
+	var user model.User
+	if err := global.DB.WithContext(platformContext(c)).Unscoped().Select("id", "tenant_id").First(&user, userID).Error; err != nil {
+		return
+	}
+	if _, err := sessionService.RevokeUserSessions(user.ID, user.TenantID, operatorID, ""); err != nil {
+		fmt.Printf("结束用户会话失败: %v\n", err)
+	}
+}
+
 // UploadAvatar 上传头像
 // @Summary 上传用户头像
 // @Description 上传并更新用户头像
@@ -484,7 +818,7 @@ func UploadAvatar(c *gin.Context) {
 	avatarURL := fmt.Sprintf("/uploads/avatars/%s", filename)
 
 	// 更新用户头像信息
-	if err := global.DB.Model(&model.User{}).Where("id = ?", userID).Update("avatar", avatarURL).Error; err != nil {
+	if err := global.DB.WithContext(c.Request.Context()).Model(&model.User{}).Where("id = ?", userID).Update("avatar", avatarURL).Error; err != nil {
 		// 删除已上传的文件
 		os.Remove(filepath)
 		c.JSON(http.StatusInternalServerError, gin.H{
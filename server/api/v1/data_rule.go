@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataRuleApi struct{}
+
+var dataRuleService = service.DataRuleService{}
+
+// GetDataRules 获取数据表的行级数据规则
+// @Tags DataRule
+// @Summary 获取数据表的行级数据规则
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param tableId path int true "表ID，0表示所有数据表"
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Router /dataRule/list/{tableId} [get]
+func (dra *DataRuleApi) GetDataRules(c *gin.Context) {
+	tableID, err := strconv.ParseUint(c.Param("tableId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的表ID",
+		})
+		return
+	}
+
+	rules, err := dataRuleService.ListRules(c.GetUint("tenant_id"), uint(tableID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取成功",
+		"data":    rules,
+	})
+}
+
+// SaveDataRule 创建或更新数据规则
+// @Tags DataRule
+// @Summary 创建或更新数据规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DataRule true "数据规则"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Router /dataRule/save [post]
+func (dra *DataRuleApi) SaveDataRule(c *gin.Context) {
+	var rule model.DataRule
+	if err := c.ShouldBindJSON(&rule); err != nil || rule.RoleID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	if err := dataRuleService.SaveRule(c.GetUint("tenant_id"), &rule); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrDataRuleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "保存成功",
+		"data":    rule,
+	})
+}
+
+// DeleteDataRule 删除数据规则
+// @Tags DataRule
+// @Summary 删除数据规则
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param id path int true "数据规则ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Router /dataRule/delete/{id} [delete]
+func (dra *DataRuleApi) DeleteDataRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	if err := dataRuleService.DeleteRule(c.GetUint("tenant_id"), uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDataRuleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
+
+// GetDataRulePresets 获取数据规则预设
+// @Tags DataRule
+// @Summary 获取数据规则预设
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Router /dataRule/presets [get]
+func (dra *DataRuleApi) GetDataRulePresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取成功",
+		"data":    dataRuleService.GetPresets(),
+	})
+}
+
+// tableDataScope 计算当前用户对数据表的数据范围，失败时写入响应
+func tableDataScope(c *gin.Context, table *model.DynamicTable) (*service.DataScope, bool) {
+	scope, err := dataRuleService.ResolveScope(c.GetUint("user_id"), c.GetUint("tenant_id"), table)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取数据范围失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	return scope, true
+}
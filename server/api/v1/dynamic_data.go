@@ -3,6 +3,7 @@ package v1
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"go-react-admin/model"
 	"go-react-admin/service"
@@ -19,6 +20,14 @@ var dynamicDataService = service.DynamicDataService{}
 // CreateData 创建动态数据
 func (api *DynamicDataApi) CreateData(c *gin.Context) {
 	tableName := c.Param("tableName")
+	table, permission, ok := authorizeTableName(c, tableName, service.TableActionCreate)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
 
 	var data map[string]interface{}
 	err := c.ShouldBindJSON(&data)
@@ -29,15 +38,16 @@ func (api *DynamicDataApi) CreateData(c *gin.Context) {
 		})
 		return
 	}
+	if !checkWritableFields(c, permission, service.TableActionCreate, data) {
+		return
+	}
 
-	data, err = dynamicDataService.CreateData(tableName, data)
+	data, err = dynamicDataService.CreateData(tableName, data, scope)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
+		respondDataWriteError(c, err)
 		return
 	}
+	service.MaskRecord(permission, data)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -56,6 +66,14 @@ func (api *DynamicDataApi) GetDynamicDataList(c *gin.Context) {
 		})
 		return
 	}
+	table, permission, ok := authorizeTableName(c, tableName, service.TableActionView)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
 
 	// 获取查询参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -69,8 +87,13 @@ func (api *DynamicDataApi) GetDynamicDataList(c *gin.Context) {
 			filters[key] = values[0]
 		}
 	}
+	// 不允许按不可见的字段过滤和排序
+	service.MaskRecord(permission, filters)
+	if fields := strings.Fields(orderBy); len(fields) > 0 && !permission.CanViewField(fields[0]) {
+		orderBy = ""
+	}
 
-	data, total, err := dynamicDataService.GetDataList(tableName, page, pageSize, filters, orderBy)
+	data, total, err := dynamicDataService.GetDataList(tableName, page, pageSize, filters, orderBy, scope)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"success": false,
@@ -78,6 +101,7 @@ func (api *DynamicDataApi) GetDynamicDataList(c *gin.Context) {
 		})
 		return
 	}
+	service.MaskRecords(permission, data)
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -94,6 +118,15 @@ func (api *DynamicDataApi) GetDynamicDataList(c *gin.Context) {
 // GetDataByID 根据ID获取动态数据
 func (api *DynamicDataApi) GetDataByID(c *gin.Context) {
 	tableName := c.Param("tableName")
+	table, permission, ok := authorizeTableName(c, tableName, service.TableActionView)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -103,7 +136,7 @@ func (api *DynamicDataApi) GetDataByID(c *gin.Context) {
 		return
 	}
 
-	data, err := dynamicDataService.GetDataByID(tableName, uint(id))
+	data, err := dynamicDataService.GetDataByID(tableName, uint(id), scope)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -111,6 +144,7 @@ func (api *DynamicDataApi) GetDataByID(c *gin.Context) {
 		})
 		return
 	}
+	service.MaskRecord(permission, data)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -122,6 +156,15 @@ func (api *DynamicDataApi) GetDataByID(c *gin.Context) {
 // UpdateData 更新动态数据
 func (api *DynamicDataApi) UpdateData(c *gin.Context) {
 	tableName := c.Param("tableName")
+	table, permission, ok := authorizeTableName(c, tableName, service.TableActionUpdate)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -139,15 +182,16 @@ func (api *DynamicDataApi) UpdateData(c *gin.Context) {
 		})
 		return
 	}
+	if !checkWritableFields(c, permission, service.TableActionUpdate, data) {
+		return
+	}
 
-	data, err = dynamicDataService.UpdateData(tableName, uint(id), data)
+	data, err = dynamicDataService.UpdateData(tableName, uint(id), data, scope)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
+		respondDataWriteError(c, err)
 		return
 	}
+	service.MaskRecord(permission, data)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -159,6 +203,15 @@ func (api *DynamicDataApi) UpdateData(c *gin.Context) {
 // DeleteData 删除动态数据
 func (api *DynamicDataApi) DeleteData(c *gin.Context) {
 	tableName := c.Param("tableName")
+	table, _, ok := authorizeTableName(c, tableName, service.TableActionDelete)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -168,7 +221,7 @@ func (api *DynamicDataApi) DeleteData(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicDataService.DeleteData(tableName, uint(id)); err != nil {
+	if err := dynamicDataService.DeleteData(tableName, uint(id), scope); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -185,6 +238,14 @@ func (api *DynamicDataApi) DeleteData(c *gin.Context) {
 // BatchDeleteData 批量删除动态数据
 func (api *DynamicDataApi) BatchDeleteData(c *gin.Context) {
 	tableName := c.Param("tableName")
+	table, _, ok := authorizeTableName(c, tableName, service.TableActionDelete)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
 
 	var req struct {
 		IDs []uint `json:"ids"`
@@ -197,7 +258,7 @@ func (api *DynamicDataApi) BatchDeleteData(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicDataService.BatchDeleteData(tableName, req.IDs); err != nil {
+	if err := dynamicDataService.BatchDeleteData(tableName, req.IDs, scope); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -221,8 +282,16 @@ func (api *DynamicDataApi) GetDynamicDataStatistics(c *gin.Context) {
 		})
 		return
 	}
+	table, _, ok := authorizeTableName(c, tableName, service.TableActionView)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
 
-	stats, err := dynamicDataService.GetDataStatistics(tableName)
+	stats, err := dynamicDataService.GetDataStatistics(tableName, scope)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"success": false,
@@ -241,8 +310,16 @@ func (api *DynamicDataApi) GetDynamicDataStatistics(c *gin.Context) {
 // GetDataStatistics 获取数据统计
 func (api *DynamicDataApi) GetDataStatistics(c *gin.Context) {
 	tableName := c.Param("tableName")
+	table, _, ok := authorizeTableName(c, tableName, service.TableActionView)
+	if !ok {
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
 
-	statistics, err := dynamicDataService.GetDataStatistics(tableName)
+	statistics, err := dynamicDataService.GetDataStatistics(tableName, scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -258,7 +335,7 @@ func (api *DynamicDataApi) GetDataStatistics(c *gin.Context) {
 	})
 }
 
-// CreateView 创建数据视图
+// CreateView 创建数据视图，需要数据表的更新权限
 func (api *DynamicDataApi) CreateView(c *gin.Context) {
 	var view model.DynamicView
 	if err := c.ShouldBindJSON(&view); err != nil {
@@ -268,8 +345,11 @@ func (api *DynamicDataApi) CreateView(c *gin.Context) {
 		})
 		return
 	}
+	if _, ok := authorizeTable(c, view.TableID, service.TableActionUpdate); !ok {
+		return
+	}
 
-	if err := dynamicDataService.CreateView(&view); err != nil {
+	if err := dynamicDataService.CreateView(c.Request.Context(), &view); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -294,8 +374,11 @@ func (api *DynamicDataApi) GetViewList(c *gin.Context) {
 		})
 		return
 	}
+	if _, ok := authorizeTable(c, uint(tableID), service.TableActionView); !ok {
+		return
+	}
 
-	views, err := dynamicDataService.GetViewList(uint(tableID))
+	views, err := dynamicDataService.GetViewList(c.Request.Context(), uint(tableID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -322,7 +405,7 @@ func (api *DynamicDataApi) GetViewByID(c *gin.Context) {
 		return
 	}
 
-	view, err := dynamicDataService.GetViewByID(uint(id))
+	view, err := dynamicDataService.GetViewByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -330,6 +413,9 @@ func (api *DynamicDataApi) GetViewByID(c *gin.Context) {
 		})
 		return
 	}
+	if _, ok := authorizeTable(c, view.TableID, service.TableActionView); !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -338,7 +424,7 @@ func (api *DynamicDataApi) GetViewByID(c *gin.Context) {
 	})
 }
 
-// UpdateView 更新视图
+// UpdateView 更新视图，需要原数据表和目标数据表的更新权限
 func (api *DynamicDataApi) UpdateView(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -359,7 +445,13 @@ func (api *DynamicDataApi) UpdateView(c *gin.Context) {
 	}
 
 	view.ID = uint(id)
-	if err := dynamicDataService.UpdateView(&view); err != nil {
+	if !authorizeView(c, view.ID, service.TableActionUpdate) {
+		return
+	}
+	if _, ok := authorizeTable(c, view.TableID, service.TableActionUpdate); !ok {
+		return
+	}
+	if err := dynamicDataService.UpdateView(c.Request.Context(), &view); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -374,7 +466,7 @@ func (api *DynamicDataApi) UpdateView(c *gin.Context) {
 	})
 }
 
-// DeleteView 删除视图
+// DeleteView 删除视图，需要数据表的更新权限
 func (api *DynamicDataApi) DeleteView(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -385,7 +477,11 @@ func (api *DynamicDataApi) DeleteView(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicDataService.DeleteView(uint(id)); err != nil {
+	if !authorizeView(c, uint(id), service.TableActionUpdate) {
+		return
+	}
+
+	if err := dynamicDataService.DeleteView(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -399,6 +495,20 @@ func (api *DynamicDataApi) DeleteView(c *gin.Context) {
 	})
 }
 
+// authorizeView 检查当前用户对视图所属数据表的操作权限，视图不存在或没有权限时写入响应
+func authorizeView(c *gin.Context, viewID uint, action string) bool {
+	view, err := dynamicDataService.GetViewByID(c.Request.Context(), viewID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "视图不存在",
+		})
+		return false
+	}
+	_, ok := authorizeTable(c, view.TableID, action)
+	return ok
+}
+
 // ApplyView 应用视图
 func (api *DynamicDataApi) ApplyView(c *gin.Context) {
 	viewID, err := strconv.ParseUint(c.Param("viewId"), 10, 32)
@@ -410,6 +520,31 @@ func (api *DynamicDataApi) ApplyView(c *gin.Context) {
 		return
 	}
 
+	view, err := dynamicDataService.GetViewByID(c.Request.Context(), uint(viewID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "视图不存在",
+		})
+		return
+	}
+	permission, ok := authorizeTable(c, view.TableID, service.TableActionView)
+	if !ok {
+		return
+	}
+	table, err := dynamicTableService.GetTableByID(c.Request.Context(), view.TableID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "表不存在",
+		})
+		return
+	}
+	scope, ok := tableDataScope(c, table)
+	if !ok {
+		return
+	}
+
 	var params map[string]interface{}
 	if err := c.ShouldBindJSON(&params); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -419,7 +554,9 @@ func (api *DynamicDataApi) ApplyView(c *gin.Context) {
 		return
 	}
 
-	result, err := dynamicDataService.ApplyView(uint(viewID), params)
+	// 视图参数中的过滤条件同样不能使用不可见的字段
+	service.MaskRecord(permission, params)
+	result, err := dynamicDataService.ApplyView(uint(viewID), params, permission, scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -427,6 +564,9 @@ func (api *DynamicDataApi) ApplyView(c *gin.Context) {
 		})
 		return
 	}
+	if records, ok := result["data"].([]map[string]interface{}); ok {
+		service.MaskRecords(permission, records)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -28,7 +28,7 @@ func (api *DynamicFieldApi) CreateField(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicFieldService.CreateField(&field); err != nil {
+	if err := dynamicFieldService.CreateField(c.Request.Context(), &field); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -59,7 +59,7 @@ func (api *DynamicFieldApi) GetFieldsByTableID(c *gin.Context) {
 		return
 	}
 
-	fields, err := dynamicFieldService.GetFieldsByTableID(uint(tableID))
+	fields, err := dynamicFieldService.GetFieldsByTableID(c.Request.Context(), uint(tableID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -86,7 +86,7 @@ func (api *DynamicFieldApi) GetFieldByID(c *gin.Context) {
 		return
 	}
 
-	field, err := dynamicFieldService.GetFieldByID(uint(id))
+	field, err := dynamicFieldService.GetFieldByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -123,7 +123,7 @@ func (api *DynamicFieldApi) UpdateField(c *gin.Context) {
 	}
 
 	field.ID = uint(id)
-	if err := dynamicFieldService.UpdateField(&field); err != nil {
+	if err := dynamicFieldService.UpdateField(c.Request.Context(), &field); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -149,7 +149,7 @@ func (api *DynamicFieldApi) DeleteField(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicFieldService.DeleteField(uint(id)); err != nil {
+	if err := dynamicFieldService.DeleteField(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -176,7 +176,7 @@ func (api *DynamicFieldApi) UpdateFieldOrder(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicFieldService.UpdateFieldOrder(req.FieldIDs); err != nil {
+	if err := dynamicFieldService.UpdateFieldOrder(c.Request.Context(), req.FieldIDs); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -201,7 +201,7 @@ func (api *DynamicFieldApi) ToggleFieldStatus(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicFieldService.ToggleFieldStatus(uint(id)); err != nil {
+	if err := dynamicFieldService.ToggleFieldStatus(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -226,7 +226,7 @@ func (api *DynamicFieldApi) BatchCreateFields(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicFieldService.BatchCreateFields(fields); err != nil {
+	if err := dynamicFieldService.BatchCreateFields(c.Request.Context(), fields); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -33,7 +33,7 @@ func (dta *DynamicTableApi) CreateTable(c *gin.Context) {
 		return
 	}
 
-	err := dynamicTableService.CreateTable(&table)
+	err := dynamicTableService.CreateTable(c.Request.Context(), &table)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -70,7 +70,7 @@ func (dta *DynamicTableApi) GetTableList(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
 	keyword := c.Query("keyword")
 
-	tables, total, err := dynamicTableService.GetTableList(page, pageSize, keyword)
+	tables, total, err := dynamicTableService.GetTableList(c.Request.Context(), page, pageSize, keyword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -115,7 +115,7 @@ func (dta *DynamicTableApi) GetTableByID(c *gin.Context) {
 		return
 	}
 
-	table, err := dynamicTableService.GetTableByID(uint(id))
+	table, err := dynamicTableService.GetTableByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -160,7 +160,7 @@ func (dta *DynamicTableApi) UpdateTable(c *gin.Context) {
 	}
 
 	table.ID = uint(id)
-	if err := dynamicTableService.UpdateTable(&table); err != nil {
+	if err := dynamicTableService.UpdateTable(c.Request.Context(), &table); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -194,7 +194,7 @@ func (dta *DynamicTableApi) DeleteTable(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicTableService.DeleteTable(uint(id)); err != nil {
+	if err := dynamicTableService.DeleteTable(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -227,7 +227,7 @@ func (dta *DynamicTableApi) ToggleTableStatus(c *gin.Context) {
 		return
 	}
 
-	if err := dynamicTableService.ToggleTableStatus(uint(id)); err != nil {
+	if err := dynamicTableService.ToggleTableStatus(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -253,7 +253,7 @@ func (dta *DynamicTableApi) ToggleTableStatus(c *gin.Context) {
 func (dta *DynamicTableApi) GetTableSchema(c *gin.Context) {
 	tableName := c.Param("tableName")
 
-	schema, err := dynamicTableService.GetTableSchema(tableName)
+	schema, err := dynamicTableService.GetTableSchema(c.Request.Context(), tableName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -261,6 +261,17 @@ func (dta *DynamicTableApi) GetTableSchema(c *gin.Context) {
 		return
 	}
 
+	// 标注当前用户对每个字段的访问权限，前端据此隐藏字段或渲染只读输入框
+	table := schema["table_info"].(*model.DynamicTable)
+	permission, err := tablePermissionService.GetUserPermission(c.GetUint("user_id"), c.GetUint("tenant_id"), table.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	schema["fields"] = service.AnnotateFields(permission, table.FieldDefinitions)
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": schema,
 	})
@@ -284,7 +295,7 @@ func (dta *DynamicTableApi) ValidateTableName(c *gin.Context) {
 		return
 	}
 
-	isValid, err := dynamicTableService.ValidateTableName(tableName)
+	isValid, err := dynamicTableService.ValidateTableName(c.Request.Context(), tableName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -308,12 +319,12 @@ func (dta *DynamicTableApi) CreateDynamicTable(c *gin.Context) {
 	}
 
 	// 验证表名
-	if valid, err := dynamicTableService.ValidateTableName(req.TableName); !valid {
+	if valid, err := dynamicTableService.ValidateTableName(c.Request.Context(), req.TableName); !valid {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	table, err := dynamicTableService.CreateDynamicTable(&req)
+	table, err := dynamicTableService.CreateDynamicTable(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TablePermissionApi struct{}
+
+var tablePermissionService = service.TablePermissionService{}
+
+// GetTablePermissions 获取数据表的角色权限
+// @Tags TablePermission
+// @Summary 获取数据表的角色权限
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param tableId path int true "表ID，0表示所有数据表"
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Router /tablePermission/list/{tableId} [get]
+func (tpa *TablePermissionApi) GetTablePermissions(c *gin.Context) {
+	tableID, err := strconv.ParseUint(c.Param("tableId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的表ID",
+		})
+		return
+	}
+
+	permissions, err := tablePermissionService.ListPermissions(c.GetUint("tenant_id"), uint(tableID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取成功",
+		"data":    permissions,
+	})
+}
+
+// SaveTablePermission 设置角色对数据表的权限
+// @Tags TablePermission
+// @Summary 设置角色对数据表的权限
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.PermissionRequest true "表权限"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Router /tablePermission/save [post]
+func (tpa *TablePermissionApi) SaveTablePermission(c *gin.Context) {
+	var req model.PermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RoleID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	permission, err := tablePermissionService.SavePermission(c.GetUint("tenant_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "保存成功",
+		"data":    permission,
+	})
+}
+
+// DeleteTablePermission 删除表权限
+// @Tags TablePermission
+// @Summary 删除表权限
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param id path int true "表权限ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Router /tablePermission/delete/{id} [delete]
+func (tpa *TablePermissionApi) DeleteTablePermission(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	if err := tablePermissionService.DeletePermission(c.GetUint("tenant_id"), uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTablePermissionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
+
+// GetMyTablePermission 获取当前用户对数据表的合并权限
+// @Tags TablePermission
+// @Summary 获取当前用户对数据表的权限
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param tableId path int true "表ID"
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Router /tablePermission/mine/{tableId} [get]
+func (tpa *TablePermissionApi) GetMyTablePermission(c *gin.Context) {
+	tableID, err := strconv.ParseUint(c.Param("tableId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的表ID",
+		})
+		return
+	}
+
+	permission, err := tablePermissionService.GetUserPermission(c.GetUint("user_id"), c.GetUint("tenant_id"), uint(tableID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取成功",
+		"data":    permission,
+	})
+}
+
+// authorizeTable 检查当前用户对数据表的操作权限，没有权限时写入响应
+func authorizeTable(c *gin.Context, tableID uint, action string) (*model.UserPermission, bool) {
+	permission, err := tablePermissionService.CheckPermission(c.GetUint("user_id"), c.GetUint("tenant_id"), tableID, action)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTablePermissionDenied) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return permission, true
+}
+
+// authorizeTableName 按表名查找数据表并检查操作权限
+func authorizeTableName(c *gin.Context, tableName, action string) (*model.DynamicTable, *model.UserPermission, bool) {
+	table, err := dynamicTableService.GetTableByName(c.Request.Context(), tableName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "表不存在",
+		})
+		return nil, nil, false
+	}
+	permission, ok := authorizeTable(c, table.ID, action)
+	return table, permission, ok
+}
+
+// checkWritableFields 检查新增或更新请求数据中的字段是否都可编辑，否则返回403并列出不可编辑的字段
+func checkWritableFields(c *gin.Context, permission *model.UserPermission, action string, data map[string]interface{}) bool {
+	err := service.CheckWritableFields(permission, action, data)
+	if err == nil {
+		return true
+	}
+	var fieldErr *service.FieldPermissionError
+	if errors.As(err, &fieldErr) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": fieldErr.Error(),
+			"fields":  fieldErr.Fields,
+		})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+	return false
+}
+
+// respondDataWriteError 写入了数据表未定义的列时返回400并列出这些列，其他错误返回500
+func respondDataWriteError(c *gin.Context, err error) {
+	var columnErr *service.UnknownColumnError
+	if errors.As(err, &columnErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": columnErr.Error(),
+			"fields":  columnErr.Columns,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
@@ -21,13 +21,17 @@ var (
 )
 
 type Config struct {
-	Server     ServerConfig     `yaml:"server"`
-	Mysql      MysqlConfig      `yaml:"mysql"`
-	Redis      RedisConfig      `yaml:"redis"`
-	Log        LogConfig        `yaml:"log"`
-	Jwt        JwtConfig        `yaml:"jwt"`
+	Server      ServerConfig      `yaml:"server"`
+	Mysql       MysqlConfig       `yaml:"mysql"`
+	Redis       RedisConfig       `yaml:"redis"`
+	Log         LogConfig         `yaml:"log"`
+	Jwt         JwtConfig         `yaml:"jwt"`
+	Password    PasswordConfig    `yaml:"password"`
+	Mfa         MfaConfig         `yaml:"mfa"`
+	LoginGuard  LoginGuardConfig  `yaml:"login_guard"`
+	Elevation   ElevationConfig   `yaml:"elevation"`
 	MultiTenant MultiTenantConfig `yaml:"multi_tenant"`
-	System     SystemConfig     `yaml:"system"`
+	System      SystemConfig      `yaml:"system"`
 }
 
 type ServerConfig struct {
@@ -36,14 +40,14 @@ type ServerConfig struct {
 }
 
 type MysqlConfig struct {
-	Host       string `yaml:"host"`
-	Port       int    `yaml:"port"`
-	Username   string `yaml:"username"`
-	Password   string `yaml:"password"`
-	Dbname     string `yaml:"dbname"`
-	Charset    string `yaml:"charset"`
-	ParseTime  bool   `yaml:"parseTime"`
-	Loc        string `yaml:"loc"`
+	Host      string `yaml:"host"`
+	Port      int    `yaml:"port"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+	Dbname    string `yaml:"dbname"`
+	Charset   string `yaml:"charset"`
+	ParseTime bool   `yaml:"parseTime"`
+	Loc       string `yaml:"loc"`
 }
 
 type RedisConfig struct {
@@ -60,17 +64,57 @@ type LogConfig struct {
 }
 
 type JwtConfig struct {
-	Secret string `yaml:"secret"`
-	Expire int    `yaml:"expire"`
+	Secret        string `yaml:"secret"`
+	Algorithm     string `yaml:"algorithm"`       // 访问令牌签名算法 HS256、RS256 或 EdDSA
+	AccessExpire  int    `yaml:"access_expire"`   // 访问令牌有效期（分钟）
+	RefreshExpire int    `yaml:"refresh_expire"`  // 刷新令牌有效期（小时）
+	StateCacheTTL int    `yaml:"state_cache_ttl"` // 用户及租户状态缓存时间（秒）
+}
+
+type PasswordConfig struct {
+	Algorithm     string `yaml:"algorithm"` // bcrypt 或 argon2id
+	BcryptCost    int    `yaml:"bcrypt_cost"`
+	Argon2Memory  uint32 `yaml:"argon2_memory"` // KiB
+	Argon2Time    uint32 `yaml:"argon2_time"`
+	Argon2Threads uint8  `yaml:"argon2_threads"`
+}
+
+type MfaConfig struct {
+	Issuer          string   `yaml:"issuer"`           // 验证器App中显示的签发方
+	ChallengeExpire int      `yaml:"challenge_expire"` // 两步验证挑战令牌有效期（分钟）
+	MaxAttempts     int      `yaml:"max_attempts"`     // 单个挑战令牌允许的验证次数
+	RequiredPaths   []string `yaml:"required_paths"`   // 能访问这些API的角色必须开启两步验证
+}
+
+type LoginGuardConfig struct {
+	CaptchaAfter    int `yaml:"captcha_after"`    // 用户名连续失败多少次后要求验证码
+	LockoutAfter    int `yaml:"lockout_after"`    // 用户名连续失败多少次后临时锁定
+	IPLockoutAfter  int `yaml:"ip_lockout_after"` // 同一IP失败多少次后临时锁定
+	LockoutDuration int `yaml:"lockout_duration"` // 锁定时长（分钟）
+	FailureWindow   int `yaml:"failure_window"`   // 失败次数统计窗口（分钟）
+	BackoffBase     int `yaml:"backoff_base"`     // 退避基数（秒），每次失败后等待时间翻倍
+	BackoffMax      int `yaml:"backoff_max"`      // 退避等待上限（秒）
+	CaptchaRate     int `yaml:"captcha_rate"`     // 同一IP每分钟最多获取验证码的次数
+	CaptchaMax      int `yaml:"captcha_max"`      // 未配置Redis时内存中最多保存的验证码数量
+	OidcRate        int `yaml:"oidc_rate"`        // 同一IP每分钟最多发起单点登录的次数
+	OidcStateMax    int `yaml:"oidc_state_max"`   // 未配置Redis时内存中最多保存的单点登录state数量
+}
+
+type ElevationConfig struct {
+	MaxHours int `yaml:"max_hours"` // 临时提权申请的最长时长（小时）
 }
 
 type MultiTenantConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	Mode    string `yaml:"mode"`
+	Enabled          bool   `yaml:"enabled"`
+	Mode             string `yaml:"mode"`
+	PlatformTenantID uint   `yaml:"platform_tenant_id"` // 平台租户，只有该租户的用户可以管理其他租户
+	TemplateTenantID uint   `yaml:"template_tenant_id"` // 新建租户时默认复制角色、菜单和API的模板租户
+	DeleteGraceDays  int    `yaml:"delete_grace_days"`  // 删除租户后可以恢复的天数，之后彻底清除数据
+	RegisterTenantID uint   `yaml:"register_tenant_id"` // 公开注册的用户所属租户，0表示关闭公开注册
 }
 
 type SystemConfig struct {
 	Name    string `yaml:"name"`
 	Version string `yaml:"version"`
 	Theme   string `yaml:"theme"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,50 @@
+package global
+
+import "context"
+
+// tenantKey 请求上下文中租户ID的键
+type tenantKey struct{}
+
+// tenantScope 请求上下文中的租户隔离范围
+type tenantScope struct {
+	tenantID uint
+	skip     bool
+}
+
+// WithTenant 返回绑定租户的上下文，使用该上下文的数据库查询只能读写该租户的数据
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantScope{tenantID: tenantID})
+}
+
+// WithoutTenantScope 返回跳过租户隔离的上下文，只用于平台管理员跨租户操作的代码路径
+func WithoutTenantScope(ctx context.Context) context.Context {
+	scope, _ := ctx.Value(tenantKey{}).(tenantScope)
+	scope.skip = true
+	return context.WithValue(ctx, tenantKey{}, scope)
+}
+
+// SystemContext 启动初始化、后台任务和登录认证等不属于单个租户的代码路径使用的上下文，显式跳过租户隔离
+func SystemContext() context.Context {
+	return WithoutTenantScope(context.Background())
+}
+
+// TenantFromContext 返回上下文绑定的租户，ok为false表示未绑定租户或已跳过租户隔离
+func TenantFromContext(ctx context.Context) (tenantID uint, ok bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	scope, found := ctx.Value(tenantKey{}).(tenantScope)
+	if !found || scope.skip {
+		return 0, false
+	}
+	return scope.tenantID, true
+}
+
+// TenantScopeSkipped 判断上下文是否显式跳过了租户隔离
+func TenantScopeSkipped(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	scope, found := ctx.Value(tenantKey{}).(tenantScope)
+	return found && scope.skip
+}
@@ -6,6 +6,7 @@ import (
 
 	"go-react-admin/global"
 	"go-react-admin/model"
+	"go-react-admin/utils"
 )
 
 // InitAdminUser 初始化管理员用户和超级管理员角色
@@ -15,14 +16,20 @@ func InitAdminUser() {
 
 	// 检查是否已存在管理员用户
 	var count int64
-	global.DB.Model(&model.User{}).Where("username = ? AND tenant_id = ?", "admin", defaultTenantID).Count(&count)
+	systemDB().Model(&model.User{}).Where("username = ? AND tenant_id = ?", "admin", defaultTenantID).Count(&count)
 
 	var adminUser model.User
 	if count == 0 {
+		hashedPassword, err := utils.HashPassword("123456")
+		if err != nil {
+			log.Printf("管理员密码加密失败: %v", err)
+			return
+		}
+
 		// 创建管理员用户
 		adminUser = model.User{
 			Username: "admin",
-			Password: "123456", // 实际项目中应该加密密码
+			Password: hashedPassword,
 			Nickname: "管理员",
 			Email:    "admin@example.com",
 			Phone:    "13800138000",
@@ -30,7 +37,7 @@ func InitAdminUser() {
 			TenantID: defaultTenantID,
 		}
 
-		if err := global.DB.Create(&adminUser).Error; err != nil {
+		if err := systemDB().Create(&adminUser).Error; err != nil {
 			log.Printf("创建管理员用户失败: %v", err)
 			return
 		} else {
@@ -38,13 +45,13 @@ func InitAdminUser() {
 		}
 	} else {
 		// 获取已存在的管理员用户
-		global.DB.Where("username = ? AND tenant_id = ?", "admin", defaultTenantID).First(&adminUser)
+		systemDB().Where("username = ? AND tenant_id = ?", "admin", defaultTenantID).First(&adminUser)
 		log.Println("管理员用户已存在")
 	}
 
 	// 创建或获取超级管理员角色
 	var superAdminRole model.Role
-	err := global.DB.Where("name = ? AND tenant_id = ?", "超级管理员", defaultTenantID).First(&superAdminRole).Error
+	err := systemDB().Where("name = ? AND tenant_id = ?", "超级管理员", defaultTenantID).First(&superAdminRole).Error
 	if err != nil {
 		// 创建超级管理员角色
 		superAdminRole = model.Role{
@@ -53,7 +60,7 @@ func InitAdminUser() {
 			Status:      1,
 			TenantID:    defaultTenantID,
 		}
-		if err := global.DB.Create(&superAdminRole).Error; err != nil {
+		if err := systemDB().Create(&superAdminRole).Error; err != nil {
 			log.Printf("创建超级管理员角色失败: %v", err)
 			return
 		}
@@ -62,23 +69,23 @@ func InitAdminUser() {
 		log.Println("超级管理员角色已存在")
 	}
 
-	// 获取所有菜单ID
+	// 获取所有菜单ID，租户ID为0的是各租户共用的内置菜单
 	var allMenus []model.Menu
-	if err := global.DB.Where("tenant_id = ?", defaultTenantID).Find(&allMenus).Error; err != nil {
+	if err := systemDB().Where("tenant_id IN ?", []uint{0, defaultTenantID}).Find(&allMenus).Error; err != nil {
 		log.Printf("获取菜单列表失败: %v", err)
 		return
 	}
 
 	// 获取所有API ID
 	var allApis []model.Api
-	if err := global.DB.Where("tenant_id = ?", defaultTenantID).Find(&allApis).Error; err != nil {
+	if err := systemDB().Where("tenant_id = ?", defaultTenantID).Find(&allApis).Error; err != nil {
 		log.Printf("获取API列表失败: %v", err)
 		return
 	}
 
 	// 清除现有的角色权限关联
-	global.DB.Where("role_id = ? AND tenant_id = ?", superAdminRole.ID, defaultTenantID).Delete(&model.RoleMenu{})
-	global.DB.Where("role_id = ? AND tenant_id = ?", superAdminRole.ID, defaultTenantID).Delete(&model.RoleApi{})
+	systemDB().Where("role_id = ? AND tenant_id = ?", superAdminRole.ID, defaultTenantID).Delete(&model.RoleMenu{})
+	systemDB().Where("role_id = ? AND tenant_id = ?", superAdminRole.ID, defaultTenantID).Delete(&model.RoleApi{})
 
 	// 给超级管理员角色分配所有菜单权限
 	for _, menu := range allMenus {
@@ -87,7 +94,7 @@ func InitAdminUser() {
 			MenuID:   menu.ID,
 			TenantID: defaultTenantID,
 		}
-		global.DB.Create(&roleMenu)
+		systemDB().Create(&roleMenu)
 	}
 
 	// 给超级管理员角色分配所有API权限
@@ -97,11 +104,21 @@ func InitAdminUser() {
 			ApiID:    api.ID,
 			TenantID: defaultTenantID,
 		}
-		global.DB.Create(&roleApi)
+		systemDB().Create(&roleApi)
+	}
+
+	// 给超级管理员角色分配所有数据表的全部权限
+	var tablePermission model.TablePermission
+	systemDB().Where("table_id = 0 AND role_id = ? AND tenant_id = ?", superAdminRole.ID, defaultTenantID).
+		FirstOrInit(&tablePermission, model.TablePermission{RoleID: superAdminRole.ID, TenantID: defaultTenantID})
+	tablePermission.CanView, tablePermission.CanCreate, tablePermission.CanUpdate = true, true, true
+	tablePermission.CanDelete, tablePermission.CanExport = true, true
+	if err := systemDB().Save(&tablePermission).Error; err != nil {
+		log.Printf("分配数据表权限失败: %v", err)
 	}
 
 	// 清除现有的用户角色关联
-	global.DB.Where("user_id = ? AND tenant_id = ?", adminUser.ID, defaultTenantID).Delete(&model.UserRole{})
+	systemDB().Where("user_id = ? AND tenant_id = ?", adminUser.ID, defaultTenantID).Delete(&model.UserRole{})
 
 	// 将管理员用户关联到超级管理员角色
 	userRole := model.UserRole{
@@ -109,7 +126,7 @@ func InitAdminUser() {
 		RoleID:   superAdminRole.ID,
 		TenantID: defaultTenantID,
 	}
-	if err := global.DB.Create(&userRole).Error; err != nil {
+	if err := systemDB().Create(&userRole).Error; err != nil {
 		log.Printf("关联用户角色失败: %v", err)
 	} else {
 		log.Println("管理员用户关联超级管理员角色成功")
@@ -123,6 +140,9 @@ func InitAdminUser() {
 		// 清除现有的角色策略
 		global.Enforcer.RemoveFilteredPolicy(0, roleIDStr, "", "", tenantIDStr)
 		
+		// 超级管理员可以访问全部接口，新增的路由无需单独授权
+		global.Enforcer.AddPolicy(roleIDStr, "/api/v1/*", "*", tenantIDStr)
+
 		// 添加所有API权限到超级管理员角色
 		for _, api := range allApis {
 			policy := []string{roleIDStr, api.Path, api.Method, tenantIDStr}
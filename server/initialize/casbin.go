@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"go-react-admin/global"
 )
@@ -22,7 +26,7 @@ func InitCasbin() {
 	}
 
 	// 创建Enforcer
-	enforcer, err := casbin.NewEnforcer(modelPath, adapter)
+	enforcer, err := NewEnforcer(modelPath, adapter)
 	if err != nil {
 		log.Fatalf("Failed to create casbin enforcer: %v", err)
 	}
@@ -42,6 +46,49 @@ func InitCasbin() {
 	log.Println("Casbin initialized successfully")
 }
 
+// NewEnforcer 创建Enforcer并注册匹配器使用的pathMatch函数，参数与casbin.NewEnforcer相同
+func NewEnforcer(params ...interface{}) (*casbin.Enforcer, error) {
+	enforcer, err := casbin.NewEnforcer(params...)
+	if err != nil {
+		return nil, err
+	}
+	enforcer.AddFunction("pathMatch", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, fmt.Errorf("pathMatch需要2个参数，实际%d个", len(args))
+		}
+		path, _ := args[0].(string)
+		pattern, _ := args[1].(string)
+		return PathMatch(path, pattern), nil
+	})
+	return enforcer, nil
+}
+
+// regexPolicyPrefix 以该前缀开头的策略路径按正则表达式匹配
+const regexPolicyPrefix = "regex:"
+
+// policyRegexps 缓存编译后的正则策略，编译失败时缓存nil
+var policyRegexps sync.Map
+
+// PathMatch 判断请求路径是否匹配策略路径
+// 策略路径支持Gin路由参数(:id)和通配符(*)，以regex:开头时按正则表达式匹配整个路径
+func PathMatch(path, pattern string) bool {
+	if !strings.HasPrefix(pattern, regexPolicyPrefix) {
+		return util.KeyMatch2(path, pattern)
+	}
+
+	cached, ok := policyRegexps.Load(pattern)
+	if !ok {
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, regexPolicyPrefix) + ")$")
+		if err != nil {
+			log.Printf("无效的正则策略 %s: %v", pattern, err)
+			re = nil
+		}
+		cached, _ = policyRegexps.LoadOrStore(pattern, re)
+	}
+	re := cached.(*regexp.Regexp)
+	return re != nil && re.MatchString(path)
+}
+
 // InitDefaultPolicies 初始化默认权限策略
 func InitDefaultPolicies() {
 	if global.Enforcer == nil {
@@ -49,8 +96,9 @@ func InitDefaultPolicies() {
 		return
 	}
 
-	// 清除现有策略
-	global.Enforcer.ClearPolicy()
+	// 清除旧的默认策略，角色授权和用户角色关联保持不变
+	global.Enforcer.RemoveFilteredPolicy(0, "admin")
+	global.Enforcer.RemoveFilteredPolicy(0, "user")
 
 	// 添加默认角色权限策略
 	// 超级管理员角色拥有所有权限
@@ -132,6 +180,26 @@ func RemoveUserRole(userID uint, roleID uint, tenantID uint) error {
 	return err
 }
 
+// SetRoleParents 用role_x → role_y分组策略替换角色的上级角色，Casbin据此沿继承链授权
+func SetRoleParents(roleID uint, parentIDs []uint, tenantID uint) error {
+	if global.Enforcer == nil {
+		return fmt.Errorf("casbin enforcer not initialized")
+	}
+
+	roleIDStr := fmt.Sprintf("role_%d", roleID)
+	tenantIDStr := fmt.Sprintf("%d", tenantID)
+
+	if _, err := global.Enforcer.RemoveFilteredGroupingPolicy(0, roleIDStr, "", tenantIDStr); err != nil {
+		return err
+	}
+	for _, parentID := range parentIDs {
+		if _, err := global.Enforcer.AddGroupingPolicy(roleIDStr, fmt.Sprintf("role_%d", parentID), tenantIDStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // AddRolePermission 为角色添加权限
 func AddRolePermission(roleID uint, resource, action string, tenantID uint) error {
 	if global.Enforcer == nil {
@@ -0,0 +1,139 @@
+package initialize
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+)
+
+// bracePathParam 匹配 {id} 形式的路径参数
+var bracePathParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// NormalizePolicy 将策略规整为匹配器使用的格式：
+// 路径参数统一为:id形式并去掉末尾的斜杠，请求方法统一为大写，ANY/ALL/空方法改为*
+func NormalizePolicy(rule []string) []string {
+	if len(rule) < 4 {
+		return rule
+	}
+	normalized := append([]string(nil), rule...)
+
+	path := strings.TrimSpace(normalized[1])
+	if !strings.HasPrefix(path, regexPolicyPrefix) {
+		path = bracePathParam.ReplaceAllString(path, ":$1")
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+	}
+	normalized[1] = path
+
+	method := strings.ToUpper(strings.TrimSpace(normalized[2]))
+	if method == "" || method == "ANY" || method == "ALL" {
+		method = "*"
+	}
+	normalized[2] = method
+	return normalized
+}
+
+// MigrateCasbinPolicies 改写已有的策略以适配按路由模式匹配的模型，并按角色授权和用户角色表补齐缺失的策略
+// 可以重复执行，返回改写和补齐的策略数
+func MigrateCasbinPolicies() (int, error) {
+	enforcer := global.Enforcer
+	if enforcer == nil {
+		return 0, fmt.Errorf("casbin enforcer not initialized")
+	}
+
+	// 改写格式不一致的策略
+	policies, err := enforcer.GetPolicy()
+	if err != nil {
+		return 0, err
+	}
+	var oldRules, newRules [][]string
+	for _, rule := range policies {
+		normalized := NormalizePolicy(rule)
+		if strings.Join(normalized, "\x00") != strings.Join(rule, "\x00") {
+			oldRules = append(oldRules, rule)
+			newRules = append(newRules, normalized)
+		}
+	}
+	changed := len(oldRules)
+	if changed > 0 {
+		if _, err := enforcer.RemovePolicies(oldRules); err != nil {
+			return 0, err
+		}
+		if _, err := enforcer.AddPoliciesEx(newRules); err != nil {
+			return 0, err
+		}
+	}
+
+	// 按角色授权补齐角色策略
+	var roleApis []struct {
+		RoleID   uint
+		TenantID uint
+		Path     string
+		Method   string
+	}
+	if err := systemDB().Model(&model.RoleApi{}).
+		Select("role_apis.role_id, role_apis.tenant_id, apis.path, apis.method").
+		Joins("JOIN apis ON apis.id = role_apis.api_id AND apis.deleted_at IS NULL").
+		Scan(&roleApis).Error; err != nil {
+		return changed, err
+	}
+	// 授予的操作权限码关联的API
+	var actionApis []struct {
+		RoleID   uint
+		TenantID uint
+		Path     string
+		Method   string
+	}
+	if err := systemDB().Model(&model.RoleMenu{}).
+		Select("role_menus.role_id, role_menus.tenant_id, apis.path, apis.method").
+		Joins("JOIN menus ON menus.id = role_menus.menu_id AND menus.type = ? AND menus.status = 1 AND menus.deleted_at IS NULL", model.MenuTypeAction).
+		Joins("JOIN menu_apis ON menu_apis.menu_id = menus.id").
+		Joins("JOIN apis ON apis.id = menu_apis.api_id AND apis.deleted_at IS NULL").
+		Scan(&actionApis).Error; err != nil {
+		return changed, err
+	}
+	roleApis = append(roleApis, actionApis...)
+	var rules [][]string
+	for _, ra := range roleApis {
+		rule := NormalizePolicy([]string{fmt.Sprintf("role_%d", ra.RoleID), ra.Path, ra.Method, fmt.Sprintf("%d", ra.TenantID)})
+		if ok, _ := enforcer.HasPolicy(rule); !ok {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		if _, err := enforcer.AddPoliciesEx(rules); err != nil {
+			return changed, err
+		}
+		changed += len(rules)
+	}
+
+	// 按用户角色表补齐用户角色关联，未生效或已过期的临时角色由后台任务处理
+	var userRoles []model.UserRole
+	if err := systemDB().Scopes(model.ActiveUserRoles(time.Now())).Find(&userRoles).Error; err != nil {
+		return changed, err
+	}
+	var groupings [][]string
+	for _, ur := range userRoles {
+		grouping := []string{fmt.Sprintf("%d", ur.UserID), fmt.Sprintf("role_%d", ur.RoleID), fmt.Sprintf("%d", ur.TenantID)}
+		if ok, _ := enforcer.HasGroupingPolicy(grouping); !ok {
+			groupings = append(groupings, grouping)
+		}
+	}
+	if len(groupings) > 0 {
+		if _, err := enforcer.AddGroupingPoliciesEx(groupings); err != nil {
+			return changed, err
+		}
+		changed += len(groupings)
+	}
+
+	if changed > 0 {
+		log.Printf("Casbin策略迁移完成，共改写或补齐 %d 条", changed)
+	}
+	return changed, nil
+}
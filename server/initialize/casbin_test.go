@@ -0,0 +1,221 @@
+package initialize_test
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/router"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// routeParam 匹配Gin路由中的:param和*param
+var routeParam = regexp.MustCompile(`[:*][A-Za-z_]+`)
+
+// newTestEnforcer 使用项目的模型文件创建只在内存中保存策略的Enforcer
+func newTestEnforcer(t *testing.T) *casbin.Enforcer {
+	t.Helper()
+	enforcer, err := initialize.NewEnforcer("../config/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enforcer
+}
+
+// apiRoutes 返回router.InitApiRoutes注册的全部路由
+func apiRoutes(t *testing.T) gin.RoutesInfo {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	router.InitApiRoutes(r)
+	routes := r.Routes()
+	if len(routes) == 0 {
+		t.Fatal("没有注册任何路由")
+	}
+	return routes
+}
+
+func TestCasbinMatchesRegisteredRoutes(t *testing.T) {
+	routes := apiRoutes(t)
+	enforcer := newTestEnforcer(t)
+	for _, route := range routes {
+		if _, err := enforcer.AddPolicy("role_1", route.Path, route.Method, "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := enforcer.AddGroupingPolicy("7", "role_1", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range routes {
+		path := routeParam.ReplaceAllString(route.Path, "42")
+		t.Run(route.Method+" "+route.Path, func(t *testing.T) {
+			tests := []struct {
+				name   string
+				sub    string
+				method string
+				tenant string
+				want   bool
+			}{
+				{"已授权", "7", route.Method, "1", true},
+				{"其他租户", "7", route.Method, "2", false},
+				{"未分配角色", "8", route.Method, "1", false},
+				{"其他方法", "7", "TRACE", "1", false},
+			}
+			for _, tt := range tests {
+				got, err := enforcer.Enforce(tt.sub, path, tt.method, tt.tenant)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("%s: Enforce(%s, %s, %s, %s) = %v，期望 %v", tt.name, tt.sub, path, tt.method, tt.tenant, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCasbinParameterizedRoutesRejectOtherPaths(t *testing.T) {
+	enforcer := newTestEnforcer(t)
+	for _, route := range apiRoutes(t) {
+		if !routeParam.MatchString(route.Path) {
+			continue
+		}
+		enforcer.AddPolicy("role_1", route.Path, route.Method, "1")
+	}
+	enforcer.AddGroupingPolicy("7", "role_1", "1")
+
+	tests := []struct {
+		path   string
+		method string
+		want   bool
+	}{
+		{"/api/v1/user/update/7", "PUT", true},
+		{"/api/v1/user/update/7/extra", "PUT", false},
+		{"/api/v1/user/update/", "PUT", false},
+		{"/api/v1/user/update", "PUT", false},
+		{"/api/v1/permissions/user/7/roles", "GET", true},
+		{"/api/v1/permissions/user/7/8/roles", "GET", false},
+		{"/api/v1/user/list", "GET", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			got, err := enforcer.Enforce("7", tt.path, tt.method, "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Enforce(%s %s) = %v，期望 %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCasbinWildcards(t *testing.T) {
+	enforcer := newTestEnforcer(t)
+	enforcer.AddPolicy("role_1", "/api/v1/admin/*", "GET", "1")
+	enforcer.AddPolicy("role_1", "/api/v1/logs", "*", "1")
+	enforcer.AddPolicy("role_2", "/api/v1/profile", "GET", "*")
+	enforcer.AddPolicy("role_3", `regex:/api/v1/(user|role)/delete/\d+`, "DELETE", "1")
+	enforcer.AddPolicy("role_3", `regex:/api/v1/(unclosed`, "GET", "1")
+	enforcer.AddGroupingPolicy("7", "role_1", "1")
+	enforcer.AddGroupingPolicy("7", "role_2", "1")
+	enforcer.AddGroupingPolicy("9", "role_2", "2")
+	enforcer.AddGroupingPolicy("7", "role_3", "1")
+
+	tests := []struct {
+		name   string
+		sub    string
+		path   string
+		method string
+		tenant string
+		want   bool
+	}{
+		{"路径通配符", "7", "/api/v1/admin/sessions", "GET", "1", true},
+		{"路径通配符多级", "7", "/api/v1/admin/oidc/providers", "GET", "1", true},
+		{"路径通配符方法不同", "7", "/api/v1/admin/sessions", "POST", "1", false},
+		{"方法通配符", "7", "/api/v1/logs", "DELETE", "1", true},
+		{"全局租户策略", "7", "/api/v1/profile", "GET", "1", true},
+		{"全局租户策略其他租户", "9", "/api/v1/profile", "GET", "2", true},
+		{"全局策略仍需本租户角色", "9", "/api/v1/profile", "GET", "1", false},
+		{"正则", "7", "/api/v1/role/delete/12", "DELETE", "1", true},
+		{"正则整体匹配", "7", "/api/v1/role/delete/12/x", "DELETE", "1", false},
+		{"正则不匹配", "7", "/api/v1/menu/delete/12", "DELETE", "1", false},
+		{"无效正则", "7", "/api/v1/(unclosed", "GET", "1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enforcer.Enforce(tt.sub, tt.path, tt.method, tt.tenant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Enforce(%s, %s, %s, %s) = %v，期望 %v", tt.sub, tt.path, tt.method, tt.tenant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCasbinRoleInheritance(t *testing.T) {
+	enforcer := newTestEnforcer(t)
+	saved := global.Enforcer
+	global.Enforcer = enforcer
+	defer func() { global.Enforcer = saved }()
+
+	enforcer.AddPolicy("role_1", "/api/v1/logs", "GET", "1")
+	enforcer.AddPolicy("role_2", "/api/v1/user/list", "GET", "1")
+	enforcer.AddPolicy("role_3", "/api/v1/role/list", "GET", "1")
+	enforcer.AddGroupingPolicy("7", "role_3", "1")
+	// role_3 继承 role_2，role_2 继承 role_1
+	if err := initialize.SetRoleParents(3, []uint{2}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := initialize.SetRoleParents(2, []uint{1}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(path, tenant string, want bool) {
+		t.Helper()
+		got, err := enforcer.Enforce("7", path, "GET", tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Enforce(7, %s, GET, %s) = %v，期望 %v", path, tenant, got, want)
+		}
+	}
+	check("/api/v1/role/list", "1", true)
+	check("/api/v1/user/list", "1", true)
+	check("/api/v1/logs", "1", true)
+	check("/api/v1/logs", "2", false)
+
+	// 替换上级角色后不再继承原上级角色的权限
+	if err := initialize.SetRoleParents(3, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	check("/api/v1/role/list", "1", true)
+	check("/api/v1/user/list", "1", false)
+	check("/api/v1/logs", "1", false)
+}
+
+func TestNormalizePolicy(t *testing.T) {
+	tests := []struct {
+		rule []string
+		want []string
+	}{
+		{[]string{"role_1", "/api/v1/user/update/:id", "PUT", "1"}, []string{"role_1", "/api/v1/user/update/:id", "PUT", "1"}},
+		{[]string{"role_1", "/api/v1/user/update/{id}", "put", "1"}, []string{"role_1", "/api/v1/user/update/:id", "PUT", "1"}},
+		{[]string{"role_1", "/api/v1/users/", "ANY", "1"}, []string{"role_1", "/api/v1/users", "*", "1"}},
+		{[]string{"role_1", "/", "", "*"}, []string{"role_1", "/", "*", "*"}},
+		{[]string{"role_1", `regex:/api/v1/\d+/`, "get", "1"}, []string{"role_1", `regex:/api/v1/\d+/`, "GET", "1"}},
+	}
+	for _, tt := range tests {
+		if got := initialize.NormalizePolicy(tt.rule); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizePolicy(%v) = %v，期望 %v", tt.rule, got, tt.want)
+		}
+	}
+}
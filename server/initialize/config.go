@@ -5,9 +5,11 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"go-react-admin/global"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // LoadConfig 从环境变量加载配置（完全废弃YAML配置）
@@ -55,14 +57,62 @@ func LoadConfig() {
 
 	// JWT配置
 	config.Jwt = global.JwtConfig{
-		Secret: getEnv("JWT_SECRET", "go-react-admin-secret"),
-		Expire: getEnvAsInt("JWT_EXPIRE", 24),
+		Secret:        getEnv("JWT_SECRET", defaultJwtSecret),
+		Algorithm:     getEnv("JWT_ALGORITHM", "RS256"),
+		AccessExpire:  getEnvAsInt("JWT_ACCESS_EXPIRE", 15),
+		RefreshExpire: getEnvAsInt("JWT_REFRESH_EXPIRE", 168),
+		StateCacheTTL: getEnvAsInt("JWT_STATE_CACHE_TTL", 30),
+	}
+
+	// 密码哈希配置
+	config.Password = global.PasswordConfig{
+		Algorithm:     getEnv("PASSWORD_HASH_ALGO", "bcrypt"),
+		BcryptCost:    getEnvAsInt("PASSWORD_BCRYPT_COST", bcrypt.DefaultCost),
+		Argon2Memory:  uint32(getEnvAsInt("PASSWORD_ARGON2_MEMORY", 64*1024)),
+		Argon2Time:    uint32(getEnvAsInt("PASSWORD_ARGON2_TIME", 1)),
+		Argon2Threads: uint8(getEnvAsInt("PASSWORD_ARGON2_THREADS", 4)),
+	}
+	if config.Password.BcryptCost < bcrypt.MinCost || config.Password.BcryptCost > bcrypt.MaxCost {
+		log.Printf("警告: PASSWORD_BCRYPT_COST 超出范围，使用默认值 %d", bcrypt.DefaultCost)
+		config.Password.BcryptCost = bcrypt.DefaultCost
+	}
+
+	// 两步验证配置
+	config.Mfa = global.MfaConfig{
+		Issuer:          getEnv("MFA_ISSUER", "go-react-admin"),
+		ChallengeExpire: getEnvAsInt("MFA_CHALLENGE_EXPIRE", 5),
+		MaxAttempts:     getEnvAsInt("MFA_MAX_ATTEMPTS", 5),
+		RequiredPaths:   getEnvAsSlice("MFA_REQUIRED_PATHS", []string{"/api/v1/permissions/*"}),
+	}
+
+	// 登录防暴力破解配置
+	config.LoginGuard = global.LoginGuardConfig{
+		CaptchaAfter:    getEnvAsInt("LOGIN_CAPTCHA_AFTER", 3),
+		LockoutAfter:    getEnvAsInt("LOGIN_LOCKOUT_AFTER", 10),
+		IPLockoutAfter:  getEnvAsInt("LOGIN_IP_LOCKOUT_AFTER", 50),
+		LockoutDuration: getEnvAsInt("LOGIN_LOCKOUT_DURATION", 15),
+		FailureWindow:   getEnvAsInt("LOGIN_FAILURE_WINDOW", 15),
+		BackoffBase:     getEnvAsInt("LOGIN_BACKOFF_BASE", 1),
+		BackoffMax:      getEnvAsInt("LOGIN_BACKOFF_MAX", 30),
+		CaptchaRate:     getEnvAsInt("LOGIN_CAPTCHA_RATE", 20),
+		CaptchaMax:      getEnvAsInt("LOGIN_CAPTCHA_MAX", 10000),
+		OidcRate:        getEnvAsInt("LOGIN_OIDC_RATE", 20),
+		OidcStateMax:    getEnvAsInt("LOGIN_OIDC_STATE_MAX", 10000),
+	}
+
+	// 临时提权配置
+	config.Elevation = global.ElevationConfig{
+		MaxHours: getEnvAsInt("ELEVATION_MAX_HOURS", 72),
 	}
 
 	// 多租户配置
 	config.MultiTenant = global.MultiTenantConfig{
-		Enabled: getEnvAsBool("MULTI_TENANT_ENABLED", true),
-		Mode:    getEnv("MULTI_TENANT_MODE", "shared_schema"),
+		Enabled:          getEnvAsBool("MULTI_TENANT_ENABLED", true),
+		Mode:             getEnv("MULTI_TENANT_MODE", "shared_schema"),
+		PlatformTenantID: uint(getEnvAsInt("TENANT_PLATFORM_ID", 1)),
+		TemplateTenantID: uint(getEnvAsInt("TENANT_TEMPLATE_ID", 1)),
+		DeleteGraceDays:  getEnvAsInt("TENANT_DELETE_GRACE_DAYS", 30),
+		RegisterTenantID: uint(getEnvAsInt("TENANT_REGISTER_ID", 1)),
 	}
 
 	// 系统配置
@@ -72,14 +122,31 @@ func LoadConfig() {
 		Theme:   getEnv("SYSTEM_THEME", "light"),
 	}
 
+	// 生产环境不允许使用默认的JWT密钥
+	if config.Server.Mode == "release" && isDefaultJwtSecret(config.Jwt.Secret) {
+		log.Fatalf("GIN_MODE=release 时必须通过 JWT_SECRET 设置自己的JWT密钥")
+	}
+
 	global.GlobalConfig = config
 
 	fmt.Printf("环境变量配置加载成功:\n")
-	fmt.Printf("Server: %s:%d\n", "0.0.0.0", config.Server.Port)
+	fmt.Printf("Server: %s:%s\n", "0.0.0.0", config.Server.Port)
 	fmt.Printf("Database: %s:%d/%s\n", config.Mysql.Host, config.Mysql.Port, config.Mysql.Dbname)
 	fmt.Printf("Redis: %s:%d/%d\n", config.Redis.Host, config.Redis.Port, config.Redis.Db)
 }
 
+// defaultJwtSecret 开发环境使用的默认JWT密钥
+const defaultJwtSecret = "go-react-admin-secret"
+
+// isDefaultJwtSecret 判断是否为默认或示例配置中的JWT密钥
+func isDefaultJwtSecret(secret string) bool {
+	switch secret {
+	case "", defaultJwtSecret, "go-react-admin-secret-key-change-this-in-production":
+		return true
+	}
+	return false
+}
+
 // getEnv 获取环境变量，不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -113,3 +180,18 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	log.Printf("警告: 环境变量 %s 不是有效的布尔值，使用默认值 %t", key, defaultValue)
 	return defaultValue
 }
+
+// getEnvAsSlice 获取逗号分隔的环境变量并转换为字符串切片
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, item := range strings.Split(valueStr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -30,5 +30,12 @@ func InitDB() {
 		log.Fatalf("连接数据库失败: %v", err)
 	}
 
+	// 开启多租户时注册租户隔离回调
+	if global.GlobalConfig.MultiTenant.Enabled {
+		if err := RegisterTenantScope(global.DB, global.GlobalConfig.MultiTenant.PlatformTenantID); err != nil {
+			log.Fatalf("注册租户隔离回调失败: %v", err)
+		}
+	}
+
 	fmt.Println("数据库连接成功")
 }
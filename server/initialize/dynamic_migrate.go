@@ -2,7 +2,6 @@ package initialize
 
 import (
 	"fmt"
-	"go-react-admin/global"
 	"go-react-admin/model"
 	"log"
 
@@ -11,13 +10,14 @@ import (
 
 // InitDynamicTables 初始化动态数据管理平台相关表
 func InitDynamicTables() {
-	db := global.DB
+	db := systemDB()
 	
 	// 自动迁移动态数据管理平台相关表
 	err := db.AutoMigrate(
 		&model.DynamicTable{},
 		&model.DynamicField{},
 		&model.TablePermission{},
+		&model.DataRule{},
 		&model.DynamicView{},
 		&model.DynamicImportExportLog{},
 	)
@@ -27,10 +27,36 @@ func InitDynamicTables() {
 	
 	log.Println("动态数据管理平台表迁移成功")
 	
+	// 为已有的物理表补充数据规则需要的系统列
+	migrateSystemColumns(db)
+
 	// 创建默认数据
 	createDefaultData(db)
 }
 
+// migrateSystemColumns 为已有的动态物理表补充created_by和dept_id系统列
+func migrateSystemColumns(db *gorm.DB) {
+	var tableNames []string
+	if err := db.Model(&model.DynamicTable{}).Pluck("table_name", &tableNames).Error; err != nil {
+		log.Printf("获取动态表列表失败: %v", err)
+		return
+	}
+	for _, tableName := range tableNames {
+		if !db.Migrator().HasTable(tableName) {
+			continue
+		}
+		for _, column := range []string{model.ColumnCreatedBy, model.ColumnDeptID} {
+			if db.Migrator().HasColumn(tableName, column) {
+				continue
+			}
+			sql := fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` BIGINT DEFAULT 0, ADD INDEX `idx_%s` (`%s`)", tableName, column, column, column)
+			if err := db.Exec(sql).Error; err != nil {
+				log.Printf("为动态表 %s 添加系统列 %s 失败: %v", tableName, column, err)
+			}
+		}
+	}
+}
+
 // createDefaultData 创建默认数据
 func createDefaultData(db *gorm.DB) {
 	// 检查是否已有数据，避免重复创建
@@ -112,7 +138,7 @@ func createDefaultData(db *gorm.DB) {
 
 // CreateDynamicTable 根据配置创建物理数据表
 func CreateDynamicTable(tableName string, fields []model.DynamicField) error {
-	db := global.DB
+	db := systemDB()
 	
 	// 构建CREATE TABLE语句
 	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (", tableName)
@@ -121,6 +147,8 @@ func CreateDynamicTable(tableName string, fields []model.DynamicField) error {
 	sql += "`updated_at` datetime(3) DEFAULT NULL,"
 	sql += "`deleted_at` datetime(3) DEFAULT NULL,"
 	sql += "`tenant_id` bigint unsigned DEFAULT NULL,"
+	sql += "`created_by` bigint unsigned DEFAULT 0,"
+	sql += "`dept_id` bigint unsigned DEFAULT 0,"
 	
 	// 添加自定义字段
 	for _, field := range fields {
@@ -141,7 +169,9 @@ func CreateDynamicTable(tableName string, fields []model.DynamicField) error {
 	
 	sql += "PRIMARY KEY (`id`),"
 	sql += "KEY `idx_deleted_at` (`deleted_at`),"
-	sql += "KEY `idx_tenant_id` (`tenant_id`)"
+	sql += "KEY `idx_tenant_id` (`tenant_id`),"
+	sql += "KEY `idx_created_by` (`created_by`),"
+	sql += "KEY `idx_dept_id` (`dept_id`)"
 	
 	// 添加唯一索引
 	for _, field := range fields {
@@ -184,7 +214,7 @@ func getColumnType(fieldType string) string {
 
 // DropDynamicTable 删除物理数据表
 func DropDynamicTable(tableName string) error {
-	db := global.DB
+	db := systemDB()
 	sql := fmt.Sprintf("DROP TABLE IF EXISTS `%s`", tableName)
 	return db.Exec(sql).Error
 }
\ No newline at end of file
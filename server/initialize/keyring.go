@@ -0,0 +1,129 @@
+package initialize
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+// keyringReloadInterval 定期重新加载密钥环，使其他实例或admctl轮换的密钥生效
+const keyringReloadInterval = time.Minute
+
+// signingKeyRetention 退役密钥的保留时间，覆盖退役前签发的访问令牌的有效期
+func signingKeyRetention() time.Duration {
+	return utils.AccessTokenTTL() + 2*keyringReloadInterval
+}
+
+// InitKeyring 加载访问令牌的签名密钥，没有可用的活动密钥时自动生成
+func InitKeyring() {
+	algorithm := global.GlobalConfig.Jwt.Algorithm
+	if algorithm == utils.AlgHS256 {
+		log.Println("访问令牌使用HS256共享密钥签名")
+		return
+	}
+	if algorithm != utils.AlgRS256 && algorithm != utils.AlgEdDSA {
+		log.Fatalf("不支持的JWT_ALGORITHM: %s", algorithm)
+	}
+
+	active, err := LoadKeyring()
+	if err != nil {
+		log.Fatalf("加载JWT签名密钥失败: %v", err)
+	}
+	// 首次启动或切换了签名算法时生成新的活动密钥
+	if active == nil || active.Algorithm != algorithm {
+		key, err := RotateSigningKey(algorithm)
+		if err != nil {
+			log.Fatalf("生成JWT签名密钥失败: %v", err)
+		}
+		log.Printf("已生成JWT签名密钥: kid=%s, 算法=%s", key.Kid, key.Algorithm)
+		if _, err := LoadKeyring(); err != nil {
+			log.Fatalf("加载JWT签名密钥失败: %v", err)
+		}
+	}
+
+	keyring := utils.JwtKeyring()
+	keyring.Refresh = func() error {
+		_, err := LoadKeyring()
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(keyringReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := LoadKeyring(); err != nil {
+				log.Printf("重新加载JWT签名密钥失败: %v", err)
+			}
+		}
+	}()
+	log.Println("JWT签名密钥加载完成")
+}
+
+// LoadKeyring 从数据库加载活动密钥和保留期内的退役密钥，返回最新的活动密钥
+// 多个实例同时生成密钥时可能存在多个活动密钥，以最新的一个签名，其余只用于验证
+func LoadKeyring() (*model.JwtSigningKey, error) {
+	var rows []model.JwtSigningKey
+	if err := systemDB().Where("status = ? OR retired_at > ?", model.JwtKeyActive, time.Now().Add(-signingKeyRetention())).
+		Order("id DESC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var active *model.JwtSigningKey
+	keys := make([]*utils.SigningKey, 0, len(rows))
+	for i := range rows {
+		key, err := utils.ParseSigningKey(rows[i].Kid, rows[i].Algorithm, rows[i].PrivateKey)
+		if err != nil {
+			log.Printf("跳过无效的JWT签名密钥: %v", err)
+			continue
+		}
+		keys = append(keys, key)
+		if active == nil && rows[i].Status == model.JwtKeyActive {
+			active = &rows[i]
+		}
+	}
+
+	activeKid := ""
+	if active != nil {
+		activeKid = active.Kid
+	}
+	if err := utils.JwtKeyring().Load(keys, activeKid); err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// RotateSigningKey 生成新的活动密钥，原活动密钥退役但在保留期内仍用于验证，并清理过期的退役密钥
+func RotateSigningKey(algorithm string) (*model.JwtSigningKey, error) {
+	key, privatePEM, err := utils.GenerateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	row := &model.JwtSigningKey{
+		Kid:        key.Kid,
+		Algorithm:  key.Algorithm,
+		PrivateKey: privatePEM,
+		Status:     model.JwtKeyActive,
+	}
+
+	now := time.Now()
+	err = systemDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.JwtSigningKey{}).Where("status = ?", model.JwtKeyActive).
+			Updates(map[string]interface{}{"status": model.JwtKeyRetired, "retired_at": now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+		return tx.Where("status = ? AND retired_at < ?", model.JwtKeyRetired, now.Add(-signingKeyRetention())).
+			Delete(&model.JwtSigningKey{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存JWT签名密钥失败: %w", err)
+	}
+	return row, nil
+}
@@ -1,7 +1,6 @@
 package initialize
 
 import (
-	"go-react-admin/global"
 	"go-react-admin/model"
 	"log"
 )
@@ -10,7 +9,7 @@ import (
 func InitMenuData() {
 	// 检查是否已经初始化过菜单
 	var count int64
-	global.DB.Model(&model.Menu{}).Count(&count)
+	systemDB().Model(&model.Menu{}).Count(&count)
 	if count > 0 {
 		log.Println("菜单数据已存在，跳过初始化")
 		return
@@ -176,10 +175,58 @@ func InitMenuData() {
 
 	// 批量创建菜单
 	for _, menu := range menus {
-		if err := global.DB.Create(&menu).Error; err != nil {
+		if err := systemDB().Create(&menu).Error; err != nil {
 			log.Printf("创建菜单失败: %v", err)
 		}
 	}
 
 	log.Println("菜单数据初始化完成")
-}
\ No newline at end of file
+}
+// defaultActions 内置的操作权限码及其关联的接口
+var defaultActions = []struct {
+	Parent string
+	Code   string
+	Title  string
+	Method string
+	Path   string
+}{
+	{"users", "user:create", "创建用户", "POST", "/api/v1/user/create"},
+	{"users", "user:update", "编辑用户", "PUT", "/api/v1/user/update/:id"},
+	{"users", "user:delete", "删除用户", "DELETE", "/api/v1/user/delete/:id"},
+}
+
+// InitActionMenus 补齐内置的操作权限码，已存在或被删除过的权限码不会重新创建
+// 需要在同步API目录之后执行，以便关联各租户的接口
+func InitActionMenus() {
+	for i, action := range defaultActions {
+		var count int64
+		systemDB().Unscoped().Model(&model.Menu{}).Where("code = ? AND type = ?", action.Code, model.MenuTypeAction).Count(&count)
+		if count > 0 {
+			continue
+		}
+		var parent model.Menu
+		if err := systemDB().Where("name = ? AND tenant_id = 0", action.Parent).First(&parent).Error; err != nil {
+			continue
+		}
+
+		menu := model.Menu{
+			Name:     action.Code,
+			Title:    action.Title,
+			ParentID: parent.ID,
+			Sort:     i + 1,
+			Level:    parent.Level + 1,
+			Type:     model.MenuTypeAction,
+			Code:     action.Code,
+			Status:   1,
+		}
+		if err := systemDB().Create(&menu).Error; err != nil {
+			log.Printf("创建操作权限码%s失败: %v", action.Code, err)
+			continue
+		}
+		var apiIDs []uint
+		systemDB().Model(&model.Api{}).Where("path = ? AND method = ?", action.Path, action.Method).Pluck("id", &apiIDs)
+		for _, apiID := range apiIDs {
+			systemDB().Create(&model.MenuApi{MenuID: menu.ID, ApiID: apiID})
+		}
+	}
+}
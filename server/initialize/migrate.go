@@ -3,34 +3,61 @@ package initialize
 import (
 	"log"
 
-	"go-react-admin/global"
 	"go-react-admin/model"
 )
 
+// migrateModels 需要迁移的全部模型，租户隔离测试按此列表检查每个有tenant_id列的模型
+var migrateModels = []interface{}{
+	&model.User{},
+	&model.Role{},
+	&model.Menu{},
+	&model.Api{},
+	&model.Log{},
+	&model.Tenant{},
+	&model.UserRole{},
+	&model.Department{},
+	&model.UserDepartment{},
+	&model.RoleMenu{},
+	&model.RoleApi{},
+	&model.MenuApi{},
+	&model.RoleInheritance{},
+	&model.RoleElevation{},
+	&model.ApprovalPolicy{},
+	&model.ChangeRequest{},
+	&model.ChangeRequestEvent{},
+	&model.RefreshToken{},
+	&model.TokenDenylist{},
+	&model.UserSession{},
+	&model.MfaRecoveryCode{},
+	&model.ApiKey{},
+	&model.OidcProvider{},
+	&model.UserIdentity{},
+	&model.LdapConfig{},
+	&model.JwtSigningKey{},
+	// 动态数据管理平台相关表
+	&model.DynamicTable{},
+	&model.DynamicField{},
+	&model.TablePermission{},
+	&model.DataRule{},
+	&model.DynamicView{},
+	&model.DynamicImportExportLog{},
+}
+
 // Migrate 数据库迁移
 func Migrate() {
 	// 自动迁移模型
-	err := global.DB.AutoMigrate(
-		&model.User{},
-		&model.Role{},
-		&model.Menu{},
-		&model.Api{},
-		&model.Log{},
-		&model.Tenant{},
-		&model.UserRole{},
-		&model.RoleMenu{},
-		&model.RoleApi{},
-		// 动态数据管理平台相关表
-		&model.DynamicTable{},
-		&model.DynamicField{},
-		&model.TablePermission{},
-		&model.DynamicView{},
-		&model.DynamicImportExportLog{},
-	)
+	err := systemDB().AutoMigrate(migrateModels...)
 	if err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
 
+	// 角色名称改为在租户内唯一，删除旧的全局唯一索引
+	if systemDB().Migrator().HasIndex(&model.Role{}, "idx_roles_name") {
+		if err := systemDB().Migrator().DropIndex(&model.Role{}, "idx_roles_name"); err != nil {
+			log.Fatalf("删除角色名称唯一索引失败: %v", err)
+		}
+	}
+
 	log.Println("数据库迁移成功")
 	
 	// 初始化动态数据管理平台的默认数据
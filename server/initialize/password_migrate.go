@@ -0,0 +1,49 @@
+package initialize
+
+import (
+	"log"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+)
+
+// MigratePlaintextPasswords 将users表中遗留的明文密码转换为哈希
+// 明文密码可以直接哈希，无需等待用户登录；低强度的哈希只能在用户下次登录时升级
+func MigratePlaintextPasswords() (int, error) {
+	var users []model.User
+	if err := systemDB().Unscoped().Select("id", "username", "password").Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, user := range users {
+		if user.Password == "" || utils.IsPasswordHashed(user.Password) {
+			continue
+		}
+
+		hashed, err := utils.HashPassword(user.Password)
+		if err != nil {
+			return migrated, err
+		}
+
+		if err := systemDB().Unscoped().Model(&model.User{}).Where("id = ?", user.ID).
+			Update("password", hashed).Error; err != nil {
+			return migrated, err
+		}
+		migrated++
+		log.Printf("用户 %s 的明文密码已转换为哈希", user.Username)
+	}
+
+	return migrated, nil
+}
+
+// MigratePasswords 启动时执行明文密码迁移
+func MigratePasswords() {
+	count, err := MigratePlaintextPasswords()
+	if err != nil {
+		log.Fatalf("明文密码迁移失败: %v", err)
+	}
+	if count > 0 {
+		log.Printf("明文密码迁移完成，共处理 %d 个用户", count)
+	}
+}
@@ -0,0 +1,247 @@
+package initialize
+
+import (
+	"errors"
+	"reflect"
+
+	"go-react-admin/global"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrCrossTenantWrite 写入的数据属于其他租户
+	ErrCrossTenantWrite = errors.New("不能写入其他租户的数据")
+	// ErrTenantUnbound 访问租户数据的上下文既没有绑定租户也没有显式跳过租户隔离
+	ErrTenantUnbound = errors.New("访问租户数据前需要绑定租户")
+)
+
+// systemDB 返回跳过租户隔离的数据库会话，启动初始化和数据迁移跨租户读写数据时使用
+func systemDB() *gorm.DB {
+	return global.DB.WithContext(global.SystemContext())
+}
+
+// tenantSharedModel 租户ID为0的数据由各租户共用的模型，例如内置菜单
+type tenantSharedModel interface {
+	TenantShared() bool
+}
+
+// tenantScope 租户隔离回调
+// 上下文通过global.WithTenant绑定租户后，有tenant_id列的模型查询、更新和删除时自动限定tenant_id，
+// 创建时自动填写tenant_id；使用global.WithoutTenantScope的上下文不受影响，既未绑定也未跳过的上下文访问这些模型时返回ErrTenantUnbound。
+// Raw和Exec执行的SQL以及只指定Table的查询没有模型信息，不做隔离
+type tenantScope struct {
+	platformTenantID uint
+}
+
+// RegisterTenantScope 在数据库连接上注册租户隔离回调
+func RegisterTenantScope(db *gorm.DB, platformTenantID uint) error {
+	s := &tenantScope{platformTenantID: platformTenantID}
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("tenant:query", s.filter(false)); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tenant:row", s.filter(false)); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenant:update", s.filter(true)); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("tenant:delete", s.filter(true)); err != nil {
+		return err
+	}
+	return cb.Create().Before("gorm:create").Register("tenant:create", s.stamp)
+}
+
+// scoped 返回语句的租户字段和上下文绑定的租户，模型没有tenant_id列或上下文跳过了租户隔离时ok为false
+// 上下文未绑定租户时拒绝执行，避免遗漏租户条件的查询读写到其他租户的数据
+func (s *tenantScope) scoped(db *gorm.DB) (field *schema.Field, tenantID uint, ok bool) {
+	if db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	field = db.Statement.Schema.LookUpField("tenant_id")
+	if field == nil || field.DBName == "" {
+		return nil, 0, false
+	}
+	ctx := db.Statement.Context
+	if global.TenantScopeSkipped(ctx) {
+		return nil, 0, false
+	}
+	tenantID, ok = global.TenantFromContext(ctx)
+	if !ok {
+		db.AddError(ErrTenantUnbound)
+		return nil, 0, false
+	}
+	return field, tenantID, true
+}
+
+// shared 判断模型的租户ID为0的数据是否由各租户共用
+func shared(sch *schema.Schema) bool {
+	m, ok := reflect.New(sch.ModelType).Interface().(tenantSharedModel)
+	return ok && m.TenantShared()
+}
+
+// writable 判断当前租户能否写入属于tenantID的数据，共用数据只有平台租户可以修改
+func (s *tenantScope) writable(sch *schema.Schema, current, tenantID uint) bool {
+	return tenantID == current || (tenantID == 0 && current == s.platformTenantID && shared(sch))
+}
+
+// filter 查询、更新和删除时限定租户，读取共用模型时包含租户ID为0的数据
+func (s *tenantScope) filter(write bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		field, tenantID, ok := s.scoped(db)
+		if !ok {
+			return
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		// 同一语句先Count再Find时条件已经存在，不重复添加
+		if hasTenantCondition(db, column) {
+			return
+		}
+		// 没有任何条件时不添加租户条件，保留GORM对全表更新和删除的保护
+		if write && !hasConditions(db) {
+			return
+		}
+		if write {
+			s.checkUpdate(db, field, tenantID)
+		}
+
+		values := []interface{}{tenantID}
+		if shared(db.Statement.Schema) && (!write || tenantID == s.platformTenantID) {
+			values = append(values, uint(0))
+		}
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+	}
+}
+
+// hasTenantCondition 判断语句是否已经有租户条件
+func hasTenantCondition(db *gorm.DB, column clause.Column) bool {
+	where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return false
+	}
+	for _, expr := range where.Exprs {
+		if in, ok := expr.(clause.IN); ok && in.Column == column {
+			return true
+		}
+	}
+	return false
+}
+
+// hasConditions 判断更新或删除语句是否有条件
+func hasConditions(db *gorm.DB) bool {
+	if db.AllowGlobalUpdate {
+		return true
+	}
+	if _, ok := db.Statement.Clauses["WHERE"]; ok {
+		return true
+	}
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv.Len() > 0
+	case reflect.Struct:
+		if pk := db.Statement.Schema.PrioritizedPrimaryField; pk != nil {
+			_, zero := pk.ValueOf(db.Statement.Context, rv)
+			return !zero
+		}
+	}
+	return false
+}
+
+// checkUpdate 更新的值不能把数据改到其他租户，Save整行更新时补上当前租户
+func (s *tenantScope) checkUpdate(db *gorm.DB, field *schema.Field, tenantID uint) {
+	ctx := db.Statement.Context
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{field.Name, field.DBName} {
+			if v, ok := dest[key]; ok {
+				if id, ok := toUint(v); !ok || !s.writable(db.Statement.Schema, tenantID, id) {
+					db.AddError(ErrCrossTenantWrite)
+				}
+			}
+		}
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(dest))
+		if rv.Kind() != reflect.Struct || rv.Type() != db.Statement.Schema.ModelType {
+			return
+		}
+		v := field.ReflectValueOf(ctx, rv)
+		if v.IsZero() {
+			// 共用模型的租户ID为0表示共用数据，不能补上当前租户
+			if !shared(db.Statement.Schema) && rv.CanAddr() {
+				db.AddError(field.Set(ctx, rv, tenantID))
+			}
+			return
+		}
+		if v.CanUint() && !s.writable(db.Statement.Schema, tenantID, uint(v.Uint())) {
+			db.AddError(ErrCrossTenantWrite)
+		}
+	}
+}
+
+// stamp 创建时填写租户ID，已经填写其他租户时拒绝写入
+func (s *tenantScope) stamp(db *gorm.DB) {
+	field, tenantID, ok := s.scoped(db)
+	if !ok {
+		return
+	}
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			s.stampValue(db, field, reflect.Indirect(rv.Index(i)), tenantID)
+		}
+	case reflect.Struct:
+		s.stampValue(db, field, rv, tenantID)
+	}
+}
+
+// stampValue 填写单条记录的租户ID
+func (s *tenantScope) stampValue(db *gorm.DB, field *schema.Field, rv reflect.Value, tenantID uint) {
+	ctx := db.Statement.Context
+	v := field.ReflectValueOf(ctx, rv)
+	if v.IsZero() {
+		db.AddError(field.Set(ctx, rv, tenantID))
+	} else if v.CanUint() && uint(v.Uint()) != tenantID {
+		db.AddError(ErrCrossTenantWrite)
+		return
+	}
+
+	// Save在更新不到数据时改为ON CONFLICT插入，主键已存在时会更新已有的行，需要确认该行属于当前租户
+	if _, upsert := db.Statement.Clauses["ON CONFLICT"]; !upsert {
+		return
+	}
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return
+	}
+	id, zero := pk.ValueOf(ctx, rv)
+	if zero {
+		return
+	}
+	var count int64
+	err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).
+		Where(clause.Eq{Column: pk.DBName, Value: id}).
+		Where(clause.Neq{Column: field.DBName, Value: tenantID}).
+		Count(&count).Error
+	if err != nil {
+		db.AddError(err)
+	} else if count > 0 {
+		db.AddError(ErrCrossTenantWrite)
+	}
+}
+
+// toUint 把租户ID转换为uint
+func toUint(v interface{}) (uint, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch {
+	case rv.CanUint():
+		return uint(rv.Uint()), true
+	case rv.CanInt() && rv.Int() >= 0:
+		return uint(rv.Int()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,311 @@
+package initialize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	testPlatformTenant = 1
+	testTenantA        = 2
+	testTenantB        = 3
+)
+
+// newTenantTestDB 创建内存SQLite数据库，迁移全部模型并注册租户隔离回调，返回跳过租户隔离的会话用于准备数据
+func newTenantTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(migrateModels...); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTenantScope(db, testPlatformTenant); err != nil {
+		t.Fatal(err)
+	}
+	return db.WithContext(global.SystemContext())
+}
+
+// tenantModel 有tenant_id列的模型
+type tenantModel struct {
+	name   string
+	model  interface{}
+	schema *schema.Schema
+	tenant *schema.Field
+	pk     *schema.Field
+}
+
+// tenantModels 返回migrateModels中全部有tenant_id列的模型
+func tenantModels(t *testing.T, db *gorm.DB) []tenantModel {
+	t.Helper()
+	var models []tenantModel
+	for _, m := range migrateModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			t.Fatal(err)
+		}
+		field := stmt.Schema.LookUpField("tenant_id")
+		if field == nil {
+			continue
+		}
+		if stmt.Schema.PrioritizedPrimaryField == nil {
+			t.Fatalf("%s 有tenant_id列但没有主键，无法检查租户隔离", stmt.Schema.Name)
+		}
+		models = append(models, tenantModel{
+			name:   stmt.Schema.Name,
+			model:  m,
+			schema: stmt.Schema,
+			tenant: field,
+			pk:     stmt.Schema.PrioritizedPrimaryField,
+		})
+	}
+	if len(models) == 0 {
+		t.Fatal("没有找到有tenant_id列的模型")
+	}
+	return models
+}
+
+// insertRow 跳过租户隔离和模型钩子插入一条属于tenantID的记录，字符串字段填写不重复的值以满足唯一索引，返回主键
+func insertRow(t *testing.T, db *gorm.DB, m tenantModel, tenantID uint, seq int) interface{} {
+	t.Helper()
+	ctx := context.Background()
+	rv := reflect.New(m.schema.ModelType).Elem()
+	for _, field := range m.schema.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.FieldType.Kind() != reflect.String {
+			continue
+		}
+		// 使用JSON字符串，JSON类型的列也能接受
+		if err := field.Set(ctx, rv, fmt.Sprintf(`"t%d-%d-%s"`, tenantID, seq, field.DBName)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.tenant.Set(ctx, rv, tenantID); err != nil {
+		t.Fatal(err)
+	}
+	row := rv.Addr().Interface()
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Create(row).Error; err != nil {
+		t.Fatalf("%s 插入租户%d的数据失败: %v", m.name, tenantID, err)
+	}
+	id, zero := m.pk.ValueOf(ctx, rv)
+	if zero {
+		t.Fatalf("%s 插入后没有主键", m.name)
+	}
+	return id
+}
+
+// tenantIDs 查询模型的全部记录并返回各记录的租户ID
+func tenantIDs(t *testing.T, db *gorm.DB, m tenantModel) []uint {
+	t.Helper()
+	rows := reflect.New(reflect.SliceOf(m.schema.ModelType))
+	if err := db.Model(m.model).Find(rows.Interface()).Error; err != nil {
+		t.Fatalf("%s 查询失败: %v", m.name, err)
+	}
+	var ids []uint
+	for i := 0; i < rows.Elem().Len(); i++ {
+		id, _ := m.tenant.ValueOf(context.Background(), rows.Elem().Index(i))
+		ids = append(ids, id.(uint))
+	}
+	return ids
+}
+
+func TestTenantScopeIsolatesEveryModel(t *testing.T) {
+	db := newTenantTestDB(t)
+	ctxA := global.WithTenant(context.Background(), testTenantA)
+
+	for _, m := range tenantModels(t, db) {
+		t.Run(m.name, func(t *testing.T) {
+			insertRow(t, db, m, testTenantA, 1)
+			idB := insertRow(t, db, m, testTenantB, 2)
+			scoped := db.WithContext(ctxA)
+
+			// 列表只返回本租户的数据
+			ids := tenantIDs(t, scoped, m)
+			if len(ids) != 1 || ids[0] != testTenantA {
+				t.Fatalf("租户%d读取到的数据属于租户 %v", testTenantA, ids)
+			}
+
+			// 按主键读取其他租户的数据
+			row := reflect.New(m.schema.ModelType).Interface()
+			if err := scoped.First(row, idB).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Fatalf("租户%d按主键读取到了租户%d的数据: %v", testTenantA, testTenantB, err)
+			}
+
+			// Count也要限定租户
+			var count int64
+			if err := scoped.Model(m.model).Count(&count).Error; err != nil || count != 1 {
+				t.Fatalf("租户%d统计到%d条数据: %v", testTenantA, count, err)
+			}
+
+			// 更新和删除其他租户的数据不生效
+			pk := m.pk.DBName
+			result := scoped.Model(m.model).Where(pk+" = ?", idB).UpdateColumn(m.tenant.DBName, testTenantA)
+			if result.Error != nil || result.RowsAffected != 0 {
+				t.Fatalf("租户%d更新了租户%d的数据: %d行, %v", testTenantA, testTenantB, result.RowsAffected, result.Error)
+			}
+			result = scoped.Delete(reflect.New(m.schema.ModelType).Interface(), idB)
+			if result.Error != nil || result.RowsAffected != 0 {
+				t.Fatalf("租户%d删除了租户%d的数据: %d行, %v", testTenantA, testTenantB, result.RowsAffected, result.Error)
+			}
+
+			// 跳过租户隔离后可以读取全部租户的数据
+			all := tenantIDs(t, db.WithContext(global.WithoutTenantScope(ctxA)), m)
+			if len(all) != 2 {
+				t.Fatalf("跳过租户隔离后应读取到2条数据，实际为租户 %v", all)
+			}
+		})
+	}
+}
+
+func TestTenantScopeStampsCreate(t *testing.T) {
+	db := newTenantTestDB(t)
+	scoped := db.WithContext(global.WithTenant(context.Background(), testTenantA))
+
+	role := model.Role{Name: "审计员", Status: 1}
+	if err := scoped.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	if role.TenantID != testTenantA {
+		t.Fatalf("创建时应填写当前租户%d，实际为%d", testTenantA, role.TenantID)
+	}
+
+	roles := []model.Role{{Name: "a", Status: 1}, {Name: "b", Status: 1}}
+	if err := scoped.Create(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range roles {
+		if r.TenantID != testTenantA {
+			t.Fatalf("批量创建时应填写当前租户%d，实际为%d", testTenantA, r.TenantID)
+		}
+	}
+
+	other := model.Role{Name: "越权", Status: 1, TenantID: testTenantB}
+	if err := scoped.Create(&other).Error; !errors.Is(err, ErrCrossTenantWrite) {
+		t.Fatalf("不能创建其他租户的数据: %v", err)
+	}
+}
+
+func TestTenantScopeRejectsCrossTenantWrites(t *testing.T) {
+	db := newTenantTestDB(t)
+	scoped := db.WithContext(global.WithTenant(context.Background(), testTenantA))
+
+	own := model.Role{Name: "本租户", Status: 1, TenantID: testTenantA}
+	foreign := model.Role{Name: "其他租户", Status: 1, TenantID: testTenantB}
+	if err := db.Create(&own).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&foreign).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 不能把数据改到其他租户
+	err := scoped.Model(&model.Role{}).Where("id = ?", own.ID).Update("tenant_id", testTenantB).Error
+	if !errors.Is(err, ErrCrossTenantWrite) {
+		t.Fatalf("不能把数据改到其他租户: %v", err)
+	}
+
+	// Save其他租户的记录时不能通过ON CONFLICT覆盖
+	hijack := model.Role{ID: foreign.ID, Name: "覆盖", Status: 1}
+	if err := scoped.Save(&hijack).Error; !errors.Is(err, ErrCrossTenantWrite) {
+		t.Fatalf("Save不能覆盖其他租户的数据: %v", err)
+	}
+	var check model.Role
+	if err := db.First(&check, foreign.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if check.Name != foreign.Name || check.TenantID != testTenantB {
+		t.Fatalf("其他租户的数据被修改: %+v", check)
+	}
+
+	// Save本租户的记录正常更新
+	own.Description = "已更新"
+	if err := scoped.Save(&own).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有条件的更新和删除仍然被GORM拒绝
+	if err := scoped.Model(&model.Role{}).Update("status", 2).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("没有条件的更新应被拒绝: %v", err)
+	}
+	if err := scoped.Delete(&model.Role{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("没有条件的删除应被拒绝: %v", err)
+	}
+}
+
+func TestTenantScopeSharedMenus(t *testing.T) {
+	db := newTenantTestDB(t)
+	builtin := model.Menu{Name: "dashboard", Title: "仪表盘", Path: "/dashboard", Type: model.MenuTypeMenu, Status: 1}
+	custom := model.Menu{Name: "reports", Title: "报表", Path: "/reports", Type: model.MenuTypeMenu, Status: 1, TenantID: testTenantB}
+	if err := db.Create(&builtin).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&custom).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 内置菜单各租户都能读取，其他租户的菜单不能读取
+	var menus []model.Menu
+	tenantDB := db.WithContext(global.WithTenant(context.Background(), testTenantA))
+	if err := tenantDB.Find(&menus).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(menus) != 1 || menus[0].ID != builtin.ID {
+		t.Fatalf("租户%d应只读取到内置菜单: %+v", testTenantA, menus)
+	}
+
+	// 只有平台租户可以修改内置菜单
+	result := tenantDB.Model(&model.Menu{}).Where("id = ?", builtin.ID).Update("title", "改名")
+	if result.Error != nil || result.RowsAffected != 0 {
+		t.Fatalf("租户%d不能修改内置菜单: %d行, %v", testTenantA, result.RowsAffected, result.Error)
+	}
+	platformDB := db.WithContext(global.WithTenant(context.Background(), testPlatformTenant))
+	result = platformDB.Model(&model.Menu{}).Where("id = ?", builtin.ID).Update("title", "首页")
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Fatalf("平台租户应能修改内置菜单: %d行, %v", result.RowsAffected, result.Error)
+	}
+}
+
+func TestTenantScopeUnboundContext(t *testing.T) {
+	db := newTenantTestDB(t)
+	for _, tenantID := range []uint{testTenantA, testTenantB} {
+		if err := db.Create(&model.Role{Name: fmt.Sprintf("role-%d", tenantID), Status: 1, TenantID: tenantID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 既没有绑定租户也没有跳过租户隔离的上下文不能访问租户数据
+	unbound := db.WithContext(context.Background())
+	var count int64
+	if err := unbound.Model(&model.Role{}).Count(&count).Error; !errors.Is(err, ErrTenantUnbound) {
+		t.Fatalf("未绑定租户的查询: %v，期望 %v", err, ErrTenantUnbound)
+	}
+	if err := unbound.Create(&model.Role{Name: "unbound", Status: 1, TenantID: testTenantA}).Error; !errors.Is(err, ErrTenantUnbound) {
+		t.Fatalf("未绑定租户的创建: %v，期望 %v", err, ErrTenantUnbound)
+	}
+	if err := unbound.Model(&model.Role{}).Where("tenant_id = ?", testTenantB).Update("status", 2).Error; !errors.Is(err, ErrTenantUnbound) {
+		t.Fatalf("未绑定租户的更新: %v，期望 %v", err, ErrTenantUnbound)
+	}
+
+	// 没有tenant_id列的模型不受影响
+	if err := unbound.Model(&model.MenuApi{}).Count(&count).Error; err != nil {
+		t.Fatalf("没有tenant_id列的模型: %v", err)
+	}
+
+	// 启动初始化和后台任务显式跳过租户隔离后读取全部数据
+	if err := db.Model(&model.Role{}).Count(&count).Error; err != nil || count != 2 {
+		t.Fatalf("跳过租户隔离时应读取到全部数据: %d, %v", count, err)
+	}
+}
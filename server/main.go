@@ -23,6 +23,7 @@ import (
 	"go-react-admin/global"
 	"go-react-admin/initialize"
 	"go-react-admin/router"
+	"go-react-admin/service"
 
 	_ "go-react-admin/docs" // 引入生成的docs包
 
@@ -35,6 +36,7 @@ import (
 func main() {
 	// 加载配置
 	initialize.LoadConfig()
+	gin.SetMode(gin.ReleaseMode)
 
 	// 初始化数据库
 	initialize.InitDB()
@@ -42,6 +44,12 @@ func main() {
 	// 数据库迁移
 	initialize.Migrate()
 
+	// 迁移遗留的明文密码
+	initialize.MigratePasswords()
+
+	// 加载JWT签名密钥
+	initialize.InitKeyring()
+
 	// 初始化Redis
 	//initialize.InitRedis()
 
@@ -51,24 +59,44 @@ func main() {
 	// 初始化默认权限策略
 	initialize.InitDefaultPolicies()
 
+	// 按路由表同步API目录，需要在初始化管理员之前完成
+	initialize.InitApiData(router.ApiCatalogue())
+
+	// 初始化菜单数据，需要在初始化管理员之前完成，超级管理员按菜单表授权
+	initialize.InitMenuData()
+	initialize.InitActionMenus()
+
 	// 初始化管理员用户
 	initialize.InitAdminUser()
 
-	// 初始化菜单数据
-	initialize.InitMenuData()
+	// 迁移Casbin策略
+	if _, err := initialize.MigrateCasbinPolicies(); err != nil {
+		log.Printf("Casbin策略迁移失败: %v", err)
+	}
+
+	// 启动LDAP组定时同步
+	service.StartLdapGroupSync()
+
+	// 启动临时角色到期收回任务，需要在Casbin策略迁移之后
+	service.StartRoleGrantExpirer()
+	service.StartChangeRequestExpirer()
 
-	// 初始化API数据
-	initialize.InitApiData()
+	// 启动已删除租户的到期清除任务
+	service.StartTenantPurger()
+
+	// 启动内存验证码和单点登录state的过期清理任务
+	service.StartCaptchaSweeper()
+	service.StartOidcStateSweeper()
 
 	// 创建Gin路由器
-	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
 	// 添加CORS中间件
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Cache-Control", "Pragma", "Expires"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Cache-Control", "Pragma", "Expires", "If-None-Match"}
+	config.ExposeHeaders = []string{"ETag"}
 	r.Use(cors.New(config))
 
 	// 初始化API路由
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyAuth API Key认证，认证成功后写入与JWT认证相同的上下文信息
+func apiKeyAuth(c *gin.Context, rawKey string) bool {
+	key, err := apiKeyService.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrApiKeyInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, service.ErrApiKeyIPDenied):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "API Key检查失败",
+			})
+		}
+		c.Abort()
+		return false
+	}
+
+	// 检查路由和方法是否在授权范围内
+	if err := apiKeyService.Authorize(key, c.Request.Method, c.Request.URL.Path); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+		c.Abort()
+		return false
+	}
+
+	// API Key不受令牌生效时间影响，只检查所属用户和租户状态
+	if err := authStateService.CheckAccess(key.UserID, time.Now()); err != nil {
+		if errors.Is(err, service.ErrUserDisabled) || errors.Is(err, service.ErrTenantDisabled) ||
+			errors.Is(err, service.ErrTokenRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "用户状态检查失败",
+			})
+		}
+		c.Abort()
+		return false
+	}
+
+	// 需要两步验证的敏感API不允许使用API Key访问
+	if service.PathRequiresMFA(c.Request.URL.Path) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "该操作不允许使用API Key访问",
+		})
+		c.Abort()
+		return false
+	}
+
+	var user model.User
+	if err := global.DB.WithContext(global.WithTenant(c.Request.Context(), key.TenantID)).Select("id", "username").First(&user, key.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": service.ErrApiKeyInvalid.Error(),
+		})
+		c.Abort()
+		return false
+	}
+
+	// 将用户信息存储到上下文中，API Key请求没有claims
+	c.Set("username", user.Username)
+	c.Set("user_id", key.UserID)
+	c.Set("tenant_id", key.TenantID)
+	c.Set("api_key_id", key.ID)
+	bindTenant(c, key.TenantID)
+	return true
+}
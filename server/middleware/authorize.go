@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access 路由的授权方式
+type Access int
+
+const (
+	// AccessPolicy 需要登录并通过Casbin策略授权，统一授权前缀下未声明的路由默认使用
+	AccessPolicy Access = iota
+	// AccessAuthenticated 登录即可访问，用于只操作本人数据的接口
+	AccessAuthenticated
+	// AccessPublic 无需登录
+	AccessPublic
+)
+
+// RouteAccess 路由的授权声明
+// prefix下的路由经过Authorize统一授权，未声明的默认按Casbin策略授权；prefix外的路由只能声明为公开
+type RouteAccess struct {
+	prefix     string
+	routes     map[string]Access
+	registered gin.RoutesInfo // Validate时记录的已注册路由，用于把具体路径解析为路由
+}
+
+// NewRouteAccess 创建路由授权声明
+func NewRouteAccess(prefix string) *RouteAccess {
+	return &RouteAccess{prefix: prefix, routes: make(map[string]Access)}
+}
+
+// Public 声明无需登录的路由，path为Gin的路由模式
+func (a *RouteAccess) Public(method, path string) {
+	a.routes[routeKey(method, path)] = AccessPublic
+}
+
+// Authenticated 声明登录即可访问的路由
+func (a *RouteAccess) Authenticated(method, path string) {
+	a.routes[routeKey(method, path)] = AccessAuthenticated
+}
+
+// Lookup 返回路由的授权方式，ok为false表示路由不在统一授权范围内且未声明
+func (a *RouteAccess) Lookup(method, path string) (access Access, ok bool) {
+	if access, ok := a.routes[routeKey(method, path)]; ok {
+		return access, true
+	}
+	if path == a.prefix || strings.HasPrefix(path, a.prefix+"/") {
+		return AccessPolicy, true
+	}
+	return AccessPolicy, false
+}
+
+// Resolve 返回具体请求路径所属路由的授权方式，静态路由优先于带参数的路由，与Gin的匹配顺序一致
+// ok为false表示没有注册该路由
+func (a *RouteAccess) Resolve(method, path string) (access Access, ok bool) {
+	method = strings.ToUpper(method)
+	pattern := ""
+	for _, route := range a.registered {
+		if route.Method != method || !initialize.PathMatch(path, route.Path) {
+			continue
+		}
+		if route.Path == path {
+			pattern = path
+			break
+		}
+		if pattern == "" {
+			pattern = route.Path
+		}
+	}
+	if pattern == "" {
+		return AccessPolicy, false
+	}
+	return a.Lookup(method, pattern)
+}
+
+// Validate 检查每个已注册的路由都有授权决定，且声明中没有不存在的路由
+func (a *RouteAccess) Validate(routes gin.RoutesInfo) error {
+	a.registered = routes
+	var problems []string
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		registered[routeKey(route.Method, route.Path)] = true
+		access, ok := a.Lookup(route.Method, route.Path)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %s 没有授权声明", route.Method, route.Path))
+		} else if access != AccessPublic && !strings.HasPrefix(route.Path, a.prefix+"/") {
+			problems = append(problems, fmt.Sprintf("%s %s 不在%s下，无法统一授权", route.Method, route.Path, a.prefix))
+		}
+	}
+	for key := range a.routes {
+		if !registered[key] {
+			problems = append(problems, fmt.Sprintf("%s 已声明但未注册", key))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("路由授权声明错误:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Authorize 统一的认证授权中间件：公开路由直接放行，其余路由认证一次后按声明放行或使用Casbin授权
+func Authorize(access *RouteAccess) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision, _ := access.Lookup(c.Request.Method, c.FullPath())
+		if decision == AccessPublic {
+			c.Next()
+			return
+		}
+		if !authenticate(c) {
+			return
+		}
+		if decision == AccessPolicy && !enforceRequest(c, c.GetUint("user_id"), c.GetUint("tenant_id")) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// logDenied 记录被拒绝的访问
+func logDenied(c *gin.Context, userID, tenantID uint) {
+	entry := model.Log{
+		UserID:    userID,
+		Username:  c.GetString("username"),
+		IP:        c.ClientIP(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		UserAgent: c.Request.UserAgent(),
+		// 拒绝时响应尚未写入，直接记录403
+		StatusCode: http.StatusForbidden,
+		TenantID:   tenantID,
+		Type:       model.LogTypeDenied,
+		Detail:     "权限不足: " + c.FullPath(),
+	}
+	if global.DB == nil {
+		log.Printf("拒绝访问: 用户%d %s %s", userID, entry.Method, entry.Path)
+		return
+	}
+	if err := global.DB.WithContext(global.WithTenant(c.Request.Context(), tenantID)).Create(&entry).Error; err != nil {
+		log.Printf("记录拒绝访问日志失败: %v", err)
+	}
+}
+
+// routeKey 路由声明的键
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
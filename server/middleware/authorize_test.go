@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestEngine(access *RouteAccess) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/.well-known/jwks.json", ok)
+	v1 := r.Group("/api/v1")
+	v1.Use(Authorize(access))
+	v1.POST("/login", ok)
+	v1.GET("/user/info", ok)
+	v1.DELETE("/user/delete/:id", ok)
+	return r
+}
+
+func TestRouteAccessValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		declare func(a *RouteAccess)
+		wantErr string
+	}{
+		{"声明完整", func(a *RouteAccess) {
+			a.Public("GET", "/.well-known/jwks.json")
+			a.Public("POST", "/api/v1/login")
+			a.Authenticated("GET", "/api/v1/user/info")
+		}, ""},
+		{"前缀外的路由未声明", func(a *RouteAccess) {}, "GET /.well-known/jwks.json 没有授权声明"},
+		{"前缀外的路由不能只要求登录", func(a *RouteAccess) {
+			a.Authenticated("GET", "/.well-known/jwks.json")
+		}, "不在/api/v1下"},
+		{"声明了不存在的路由", func(a *RouteAccess) {
+			a.Public("GET", "/.well-known/jwks.json")
+			a.Public("POST", "/api/v1/logout")
+		}, "POST /api/v1/logout 已声明但未注册"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := NewRouteAccess("/api/v1")
+			tt.declare(access)
+			err := access.Validate(newTestEngine(access).Routes())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("期望通过，实际: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("期望错误包含%q，实际: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRouteAccessResolve(t *testing.T) {
+	access := NewRouteAccess("/api/v1")
+	access.Public("GET", "/.well-known/jwks.json")
+	access.Public("POST", "/api/v1/login")
+	access.Authenticated("GET", "/api/v1/user/info")
+	if err := access.Validate(newTestEngine(access).Routes()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         Access
+		ok           bool
+	}{
+		{"POST", "/api/v1/login", AccessPublic, true},
+		{"get", "/api/v1/user/info", AccessAuthenticated, true},
+		{"DELETE", "/api/v1/user/delete/5", AccessPolicy, true},
+		{"GET", "/api/v1/user/delete/5", AccessPolicy, false},
+		{"GET", "/api/v1/user/list", AccessPolicy, false},
+	}
+	for _, tt := range tests {
+		got, ok := access.Resolve(tt.method, tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%s, %s) = %v, %v，期望 %v, %v", tt.method, tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAuthorizeRequiresAuthentication(t *testing.T) {
+	access := NewRouteAccess("/api/v1")
+	access.Public("POST", "/api/v1/login")
+	access.Authenticated("GET", "/api/v1/user/info")
+	r := newTestEngine(access)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"POST", "/api/v1/login", http.StatusOK},
+		{"GET", "/api/v1/user/info", http.StatusUnauthorized},
+		{"DELETE", "/api/v1/user/delete/1", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Fatalf("状态码 %d，期望 %d", w.Code, tt.want)
+			}
+		})
+	}
+}
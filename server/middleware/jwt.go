@@ -1,52 +1,134 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"go-react-admin/global"
+	"go-react-admin/service"
 	"go-react-admin/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// JWTAuth JWT认证中间件
-func JWTAuth() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 获取Authorization header
-		authHeader := c.Request.Header.Get("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "请求头中缺少Authorization字段",
-			})
-			c.Abort()
-			return
-		}
+var (
+	tokenService     = &service.TokenService{}
+	sessionService   = &service.SessionService{}
+	authStateService = &service.AuthStateService{}
+	apiKeyService    = &service.ApiKeyService{}
+)
+
+// authenticate 校验访问令牌或API Key并将用户信息写入上下文，失败时写入响应并中止请求
+func authenticate(c *gin.Context) bool {
+	// 获取Authorization header
+	authHeader := c.Request.Header.Get("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "请求头中缺少Authorization字段",
+		})
+		c.Abort()
+		return false
+	}
+
+	// 机器客户端使用API Key认证
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) == 2 && parts[0] == service.ApiKeyScheme {
+		return apiKeyAuth(c, parts[1])
+	}
+
+	// 检查Bearer前缀
+	if !(len(parts) == 2 && parts[0] == "Bearer") {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "请求头中Authorization格式错误",
+		})
+		c.Abort()
+		return false
+	}
+
+	// 解析JWT token
+	claims, err := utils.ParseToken(parts[1])
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "无效的Token",
+		})
+		c.Abort()
+		return false
+	}
 
-		// 检查Bearer前缀
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
+	// 检查令牌是否已被吊销
+	revoked, err := tokenService.IsAccessTokenRevoked(claims.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "令牌状态检查失败",
+		})
+		c.Abort()
+		return false
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Token已失效",
+		})
+		c.Abort()
+		return false
+	}
+
+	// 检查登录会话是否仍然有效，会话被结束后令牌立即失效
+	session, err := sessionService.ValidateSession(claims.SessionID, claims.UserID)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) || errors.Is(err, service.ErrSessionInactive) {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "请求头中Authorization格式错误",
+				"error": "登录会话已失效",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "会话状态检查失败",
 			})
-			c.Abort()
-			return
 		}
+		c.Abort()
+		return false
+	}
 
-		// 解析JWT token
-		claims, err := utils.ParseToken(parts[1])
-		if err != nil {
+	// 检查用户状态、租户状态及令牌签发时间（带缓存）
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if err := authStateService.CheckAccess(claims.UserID, issuedAt); err != nil {
+		if errors.Is(err, service.ErrUserDisabled) || errors.Is(err, service.ErrTenantDisabled) ||
+			errors.Is(err, service.ErrTokenRevoked) {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "无效的Token",
+				"error": err.Error(),
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "用户状态检查失败",
 			})
-			c.Abort()
-			return
 		}
+		c.Abort()
+		return false
+	}
 
-		// 将用户信息存储到上下文中
-		c.Set("username", claims.Username)
-		c.Set("user_id", claims.UserID)
-		c.Set("tenant_id", claims.TenantID)
-
-		c.Next()
+	// 敏感API要求会话在登录时通过了两步验证
+	if !session.MfaVerified && service.PathRequiresMFA(c.Request.URL.Path) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "该操作需要开启两步验证后重新登录",
+		})
+		c.Abort()
+		return false
 	}
+
+	// 将用户信息存储到上下文中
+	c.Set("username", claims.Username)
+	c.Set("user_id", claims.UserID)
+	c.Set("tenant_id", claims.TenantID)
+	c.Set("claims", claims)
+	bindTenant(c, claims.TenantID)
+	return true
+}
+
+// bindTenant 把租户绑定到请求上下文，使用c.Request.Context()的数据库查询自动按租户隔离
+func bindTenant(c *gin.Context, tenantID uint) {
+	c.Request = c.Request.WithContext(global.WithTenant(c.Request.Context(), tenantID))
 }
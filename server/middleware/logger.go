@@ -68,10 +68,11 @@ func Logger() gin.HandlerFunc {
 			StatusCode:   statusCode,
 			ResponseTime: int(responseTime),
 			TenantID:     tenantID,
+			Type:         model.LogTypeOperation,
 		}
 		
 		// 保存到数据库
-		if err := global.DB.Create(&log).Error; err != nil {
+		if err := global.DB.WithContext(global.WithTenant(c.Request.Context(), tenantID)).Create(&log).Error; err != nil {
 			fmt.Printf("记录日志失败: %v\n", err)
 		}
 	}
@@ -107,8 +108,8 @@ func LoginLogger() gin.HandlerFunc {
 		// 获取状态码
 		statusCode := c.Writer.Status()
 		
-		// 如果是登录请求且成功，记录登录日志
-		if path == "/api/v1/user/login" && method == "POST" {
+		// 如果是登录请求（包括单点登录回调）且成功，记录登录日志
+		if (path == "/api/v1/login" || path == "/api/v1/oidc/callback") && method == "POST" {
 			var username string
 			var userID uint
 			var tenantID uint
@@ -148,10 +149,11 @@ func LoginLogger() gin.HandlerFunc {
 				StatusCode:   statusCode,
 				ResponseTime: int(responseTime),
 				TenantID:     tenantID,
+				Type:         model.LogTypeLogin,
 			}
 			
 			// 保存到数据库
-			if err := global.DB.Create(&log).Error; err != nil {
+			if err := global.DB.WithContext(global.WithTenant(c.Request.Context(), tenantID)).Create(&log).Error; err != nil {
 				fmt.Printf("记录登录日志失败: %v\n", err)
 			}
 		}
@@ -203,10 +205,11 @@ func LogoutLogger() gin.HandlerFunc {
 			StatusCode:   statusCode,
 			ResponseTime: 0, // 登出操作响应时间通常很短
 			TenantID:     tenantID,
+			Type:         model.LogTypeLogout,
 		}
 		
 		// 保存到数据库
-		if err := global.DB.Create(&log).Error; err != nil {
+		if err := global.DB.WithContext(global.WithTenant(c.Request.Context(), tenantID)).Create(&log).Error; err != nil {
 			fmt.Printf("记录登出日志失败: %v\n", err)
 		}
 		
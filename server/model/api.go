@@ -13,11 +13,12 @@ type Api struct {
 	UpdatedAt   time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" example:"null"`
 	Path        string         `gorm:"size:100" json:"path" validate:"required,max=100" example:"/api/users"`
-	Method      string         `gorm:"size:10" json:"method" validate:"required,oneof=GET POST PUT DELETE" example:"GET"` // GET, POST, PUT, DELETE
+	Method      string         `gorm:"size:10" json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE" example:"GET"` // GET, POST, PUT, PATCH, DELETE
 	Category    string         `gorm:"size:50" json:"category" validate:"required,max=50" example:"用户管理"`
 	Description string         `gorm:"size:255" json:"description" validate:"max=255" example:"获取用户列表接口"`
 	Status      int            `gorm:"default:1" json:"status" validate:"oneof=1 2" example:"1"` // 1:启用 2:禁用
 	TenantID    uint           `gorm:"index" json:"tenant_id" example:"1"` // 租户ID
+	Stale       bool           `gorm:"default:false" json:"stale" example:"false"` // 对应的路由已不存在
 }
 
 // TableName 自定义表名
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+)
+
+// 用户类型
+const (
+	UserTypeNormal  = "user"    // 普通用户
+	UserTypeService = "service" // 服务账号，只能通过API Key访问，不能登录
+)
+
+// ApiKey 供机器客户端使用的API Key，仅保存密钥哈希
+// 完整的Key格式为 gra_<KeyID>_<密钥>，KeyID用于查找记录
+type ApiKey struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Name           string     `gorm:"size:100" json:"name"`
+	KeyID          string     `gorm:"uniqueIndex;size:32" json:"key_id"`
+	SecretHash     string     `gorm:"size:64" json:"-"`
+	UserID         uint       `gorm:"index" json:"user_id"` // 所属用户或服务账号
+	TenantID       uint       `gorm:"index" json:"tenant_id"`
+	CreatedBy      uint       `json:"created_by"`
+	AllowedRoutes  []string   `gorm:"serializer:json;type:text" json:"allowed_routes"`  // 允许访问的路由，支持 * 和 :param，为空时不限制
+	AllowedMethods []string   `gorm:"serializer:json;type:text" json:"allowed_methods"` // 允许的HTTP方法，为空时不限制
+	AllowedIPs     []string   `gorm:"serializer:json;type:text" json:"allowed_ips"`     // IP或CIDR白名单，为空时不限制
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at"`                          // 为空时永不过期
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	LastUsedIP     string     `gorm:"size:50" json:"last_used_ip"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	RevokedBy      uint       `json:"revoked_by"`
+}
+
+// TableName 自定义表名
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
+
+// IsActive API Key是否仍然有效
+func (k *ApiKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 需要审批的权限变更类型
+const (
+	ChangeTypeRolePermissions = "role_permissions" // 角色的菜单和接口权限
+	ChangeTypeUserRoles       = "user_roles"       // 用户的角色
+	ChangeTypeRoleParents     = "role_parents"     // 角色的上级角色
+	ChangeTypeMenuApis        = "menu_apis"        // 操作关联的接口，拥有该操作的角色随之变化，按角色权限的审批配置审批
+)
+
+// 变更申请状态
+const (
+	ChangeStatusPending   = "pending"   // 待审批
+	ChangeStatusApplying  = "applying"  // 审批通过，正在写入
+	ChangeStatusApplied   = "applied"   // 审批通过并已生效
+	ChangeStatusRejected  = "rejected"  // 已拒绝
+	ChangeStatusExpired   = "expired"   // 超时未审批
+	ChangeStatusCancelled = "cancelled" // 申请人撤回
+	ChangeStatusFailed    = "failed"    // 审批通过但写入失败
+)
+
+// 变更申请的历史事件
+const (
+	ChangeEventCreated   = "created"
+	ChangeEventApproved  = "approved"
+	ChangeEventRejected  = "rejected"
+	ChangeEventApplied   = "applied"
+	ChangeEventExpired   = "expired"
+	ChangeEventCancelled = "cancelled"
+	ChangeEventFailed    = "failed"
+)
+
+// ApprovalPolicy 租户对某类权限变更的审批配置，未启用时变更立即生效
+type ApprovalPolicy struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID          uint   `gorm:"uniqueIndex:idx_approval_policy_type" json:"tenant_id"`
+	ChangeType        string `gorm:"size:30;uniqueIndex:idx_approval_policy_type" json:"change_type"`
+	Enabled           bool   `json:"enabled"`
+	ApproverUserIDs   []uint `gorm:"serializer:json;type:text" json:"approver_user_ids"` // 指定的审批人
+	ApproverRoleIDs   []uint `gorm:"serializer:json;type:text" json:"approver_role_ids"` // 拥有这些角色的用户都可以审批
+	RequiredApprovals int    `gorm:"default:1" json:"required_approvals"`                // 需要的同意人数，即N-of-M中的N
+	ExpireHours       int    `gorm:"default:72" json:"expire_hours"`                     // 超过该时长未审批则过期
+}
+
+// TableName 自定义表名
+func (ApprovalPolicy) TableName() string {
+	return "approval_policies"
+}
+
+// ChangeDiffItem 变更涉及的菜单、接口或角色
+type ChangeDiffItem struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// ChangeDiff 变更前后的差异
+type ChangeDiff struct {
+	AddedMenus   []ChangeDiffItem `json:"added_menus,omitempty"`
+	RemovedMenus []ChangeDiffItem `json:"removed_menus,omitempty"`
+	AddedApis    []ChangeDiffItem `json:"added_apis,omitempty"`
+	RemovedApis  []ChangeDiffItem `json:"removed_apis,omitempty"`
+	AddedRoles   []ChangeDiffItem `json:"added_roles,omitempty"`   // 新增的角色或上级角色
+	RemovedRoles []ChangeDiffItem `json:"removed_roles,omitempty"` // 移除的角色或上级角色
+	ChangedRoles []ChangeDiffItem `json:"changed_roles,omitempty"` // 调整了有效期的角色
+}
+
+// Empty 判断变更是否没有任何差异
+func (d *ChangeDiff) Empty() bool {
+	return len(d.AddedMenus)+len(d.RemovedMenus)+len(d.AddedApis)+len(d.RemovedApis)+len(d.AddedRoles)+len(d.RemovedRoles)+len(d.ChangedRoles) == 0
+}
+
+// ChangeRequest 待审批的权限变更，Payload为原始的变更请求，审批通过后按原请求写入
+type ChangeRequest struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID          uint            `gorm:"index" json:"tenant_id"`
+	ChangeType        string          `gorm:"size:30;index" json:"change_type"`
+	TargetID          uint            `gorm:"index" json:"target_id"` // 角色ID或用户ID
+	TargetName        string          `gorm:"size:50" json:"target_name"`
+	Payload           json.RawMessage `gorm:"type:json" json:"payload"`
+	Diff              ChangeDiff      `gorm:"serializer:json;type:text" json:"diff"`
+	Reason            string          `gorm:"size:255" json:"reason"`
+	Status            string          `gorm:"size:20;index;default:pending" json:"status"`
+	RequesterID       uint            `gorm:"index" json:"requester_id"`
+	Requester         string          `gorm:"size:50" json:"requester"`
+	RequiredApprovals int             `json:"required_approvals"`
+	Approvals         int             `json:"approvals"`
+	ExpiresAt         time.Time       `gorm:"index" json:"expires_at"`
+	ResolvedAt        *time.Time      `json:"resolved_at"`
+	Error             string          `gorm:"size:255" json:"error"` // 写入失败的原因
+
+	Events []ChangeRequestEvent `gorm:"foreignKey:RequestID" json:"events,omitempty"`
+}
+
+// TableName 自定义表名
+func (ChangeRequest) TableName() string {
+	return "change_requests"
+}
+
+// ChangeRequestEvent 变更申请的历史记录
+type ChangeRequestEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RequestID  uint   `gorm:"index;not null" json:"request_id"`
+	Action     string `gorm:"size:20" json:"action"`
+	OperatorID uint   `json:"operator_id"` // 系统操作（如过期）为0
+	Operator   string `gorm:"size:50" json:"operator"`
+	Comment    string `gorm:"size:255" json:"comment"`
+}
+
+// TableName 自定义表名
+func (ChangeRequestEvent) TableName() string {
+	return "change_request_events"
+}
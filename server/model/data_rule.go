@@ -0,0 +1,209 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 数据规则预设
+const (
+	DataRulePresetCustom   = "custom"    // 自定义条件
+	DataRulePresetOwn      = "own"       // 仅本人创建的数据
+	DataRulePresetDept     = "dept"      // 仅本部门的数据
+	DataRulePresetDeptTree = "dept_tree" // 本部门及下级部门的数据
+)
+
+// 动态数据表的系统列，创建数据时由系统写入
+const (
+	ColumnCreatedBy = "created_by"
+	ColumnDeptID    = "dept_id"
+	ColumnTenantID  = "tenant_id"
+)
+
+// 数据规则中可以使用的占位符
+const (
+	PlaceholderUserID   = "user.id"
+	PlaceholderUserDept = "user.dept_id"
+	PlaceholderDeptTree = "user.dept_tree" // 主部门及下级部门的ID列表，只能用于in和not_in
+	PlaceholderUsername = "user.username"
+	PlaceholderTenantID = "tenant.id"
+)
+
+var (
+	placeholderPattern = regexp.MustCompile(`^\{\{\s*([a-z_.]+)\s*\}\}$`)
+	columnPattern      = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// DataRule 角色对数据表的行级数据规则
+type DataRule struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	TableID   uint            `gorm:"index" json:"table_id"` // 0表示所有数据表
+	RoleID    uint            `gorm:"index;not null" json:"role_id" validate:"required"`
+	TenantID  uint            `gorm:"index" json:"tenant_id"`
+	Name      string          `gorm:"size:100" json:"name"`
+	Preset    string          `gorm:"size:20;default:custom" json:"preset"` // custom, own, dept, dept_tree
+	Condition json.RawMessage `gorm:"type:json" json:"condition"`           // 自定义条件树，Preset为custom时有效
+	Status    int             `gorm:"default:1" json:"status"`              // 1:启用 2:禁用
+}
+
+// TableName 自定义表名
+func (DataRule) TableName() string {
+	return "data_rules"
+}
+
+// DataRuleGroup 数据规则条件树，组内的条件和子组按Logic组合
+type DataRuleGroup struct {
+	Logic      string                  `json:"logic"` // AND, OR
+	Conditions []DynamicQueryCondition `json:"conditions"`
+	Groups     []DataRuleGroup         `json:"groups,omitempty"`
+}
+
+// GetCondition 获取规则的条件树，预设规则返回对应的固定条件
+func (r *DataRule) GetCondition() (*DataRuleGroup, error) {
+	switch r.Preset {
+	case DataRulePresetOwn:
+		return &DataRuleGroup{Logic: "AND", Conditions: []DynamicQueryCondition{
+			{Field: ColumnCreatedBy, Operator: "=", Value: "{{" + PlaceholderUserID + "}}"},
+		}}, nil
+	case DataRulePresetDept:
+		return &DataRuleGroup{Logic: "AND", Conditions: []DynamicQueryCondition{
+			{Field: ColumnDeptID, Operator: "=", Value: "{{" + PlaceholderUserDept + "}}"},
+		}}, nil
+	case DataRulePresetDeptTree:
+		return &DataRuleGroup{Logic: "AND", Conditions: []DynamicQueryCondition{
+			{Field: ColumnDeptID, Operator: "in", Values: []interface{}{"{{" + PlaceholderDeptTree + "}}"}},
+		}}, nil
+	case DataRulePresetCustom, "":
+		var group DataRuleGroup
+		if len(r.Condition) == 0 {
+			return nil, errors.New("自定义规则的条件不能为空")
+		}
+		if err := json.Unmarshal(r.Condition, &group); err != nil {
+			return nil, fmt.Errorf("解析规则条件失败: %v", err)
+		}
+		return &group, nil
+	default:
+		return nil, fmt.Errorf("不支持的规则预设: %s", r.Preset)
+	}
+}
+
+// Validate 校验条件树的逻辑运算符、字段名、操作符和占位符
+func (g *DataRuleGroup) Validate() error {
+	logic := strings.ToUpper(g.Logic)
+	if logic != "" && logic != "AND" && logic != "OR" {
+		return fmt.Errorf("不支持的逻辑运算符: %s", g.Logic)
+	}
+	if len(g.Conditions) == 0 && len(g.Groups) == 0 {
+		return errors.New("条件组不能为空")
+	}
+	for i := range g.Conditions {
+		c := &g.Conditions[i]
+		if !IsValidColumnName(c.Field) {
+			return fmt.Errorf("无效的字段名: %s", c.Field)
+		}
+		if err := c.ValidateCondition(); err != nil {
+			return err
+		}
+		if name, ok := placeholderName(c.Value); ok && name == PlaceholderDeptTree {
+			return fmt.Errorf("占位符 %v 只能用于in和not_in的values", c.Value)
+		}
+		values := append([]interface{}{c.Value}, c.Values...)
+		for _, v := range values {
+			if name, ok := placeholderName(v); ok && !isKnownPlaceholder(name) {
+				return fmt.Errorf("不支持的占位符: %v", v)
+			}
+		}
+	}
+	for i := range g.Groups {
+		if err := g.Groups[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildWhereClause 用占位符的取值构建WHERE子句，未知的占位符取NULL，不会匹配任何数据
+func (g *DataRuleGroup) BuildWhereClause(tableName string, vars map[string]interface{}) (string, []interface{}) {
+	joiner := " AND "
+	if strings.ToUpper(g.Logic) == "OR" {
+		joiner = " OR "
+	}
+
+	var clauses []string
+	var args []interface{}
+	for _, c := range g.Conditions {
+		resolved := c
+		resolved.Value = resolvePlaceholder(c.Value, vars)
+		if c.Values != nil {
+			// 列表占位符展开为多个值
+			resolved.Values = make([]interface{}, 0, len(c.Values))
+			for _, v := range c.Values {
+				if list, ok := resolvePlaceholder(v, vars).([]interface{}); ok {
+					resolved.Values = append(resolved.Values, list...)
+				} else {
+					resolved.Values = append(resolved.Values, resolvePlaceholder(v, vars))
+				}
+			}
+			if len(resolved.Values) == 0 {
+				resolved.Values = []interface{}{nil}
+			}
+		}
+		clause, clauseArgs := resolved.BuildWhereClause(tableName)
+		clauses = append(clauses, clause)
+		args = append(args, clauseArgs...)
+	}
+	for i := range g.Groups {
+		clause, clauseArgs := g.Groups[i].BuildWhereClause(tableName, vars)
+		if clause != "" {
+			clauses = append(clauses, clause)
+			args = append(args, clauseArgs...)
+		}
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(clauses, joiner) + ")", args
+}
+
+// placeholderName 返回形如{{user.id}}的占位符名称
+func placeholderName(value interface{}) (string, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return "", false
+	}
+	matches := placeholderPattern.FindStringSubmatch(s)
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+func isKnownPlaceholder(name string) bool {
+	switch name {
+	case PlaceholderUserID, PlaceholderUserDept, PlaceholderDeptTree, PlaceholderUsername, PlaceholderTenantID:
+		return true
+	}
+	return false
+}
+
+func resolvePlaceholder(value interface{}, vars map[string]interface{}) interface{} {
+	if name, ok := placeholderName(value); ok {
+		return vars[name]
+	}
+	return value
+}
+
+// IsValidColumnName 列名只能包含字母、数字和下划线，且不能以数字开头
+func IsValidColumnName(name string) bool {
+	return columnPattern.MatchString(name)
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDataRuleBuildWhereClause(t *testing.T) {
+	rule := DataRule{Preset: DataRulePresetCustom, Condition: json.RawMessage(`{
+		"logic": "OR",
+		"conditions": [{"field": "created_by", "operator": "=", "value": "{{user.id}}"}],
+		"groups": [{"logic": "AND", "conditions": [
+			{"field": "region", "operator": "in", "values": ["华东", "华南"]},
+			{"field": "tenant_id", "operator": "=", "value": "{{ tenant.id }}"}
+		]}]
+	}`)}
+	group, err := rule.GetCondition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]interface{}{PlaceholderUserID: uint(7), PlaceholderTenantID: uint(2)}
+	clause, args := group.BuildWhereClause("dyn_orders", vars)
+	wantClause := "(`dyn_orders`.`created_by` = ? OR (`dyn_orders`.`region` IN (?,?) AND `dyn_orders`.`tenant_id` = ?))"
+	if clause != wantClause {
+		t.Fatalf("clause = %s, 期望 %s", clause, wantClause)
+	}
+	if want := []interface{}{uint(7), "华东", "华南", uint(2)}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %v, 期望 %v", args, want)
+	}
+}
+
+func TestDataRulePresets(t *testing.T) {
+	for preset, column := range map[string]string{DataRulePresetOwn: ColumnCreatedBy, DataRulePresetDept: ColumnDeptID} {
+		group, err := (&DataRule{Preset: preset}).GetCondition()
+		if err != nil {
+			t.Fatal(err)
+		}
+		clause, args := group.BuildWhereClause("t", map[string]interface{}{PlaceholderUserID: uint(1), PlaceholderUserDept: uint(3)})
+		if clause != "(`t`.`"+column+"` = ?)" || len(args) != 1 {
+			t.Errorf("预设 %s 生成 %s %v", preset, clause, args)
+		}
+	}
+}
+
+func TestDataRuleValidate(t *testing.T) {
+	cases := map[string]string{
+		"非法字段名":  `{"conditions":[{"field":"id) OR (1","operator":"=","value":1}]}`,
+		"未知占位符":  `{"conditions":[{"field":"owner","operator":"=","value":"{{user.password}}"}]}`,
+		"非法逻辑":   `{"logic":"XOR","conditions":[{"field":"owner","operator":"=","value":1}]}`,
+		"空条件组":   `{"logic":"AND"}`,
+		"不支持的操作": `{"conditions":[{"field":"owner","operator":"~","value":1}]}`,
+		"列表占位符":  `{"conditions":[{"field":"dept_id","operator":"=","value":"{{user.dept_tree}}"}]}`,
+	}
+	for name, condition := range cases {
+		group, err := (&DataRule{Condition: json.RawMessage(condition)}).GetCondition()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if group.Validate() == nil {
+			t.Errorf("%s: 期望校验失败", name)
+		}
+	}
+}
+
+func TestDataRuleDeptTreePreset(t *testing.T) {
+	group, err := (&DataRule{Preset: DataRulePresetDeptTree}).GetCondition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{PlaceholderDeptTree: []interface{}{uint(4), uint(9)}}
+	clause, args := group.BuildWhereClause("t", vars)
+	if clause != "(`t`.`dept_id` IN (?,?))" || !reflect.DeepEqual(args, []interface{}{uint(4), uint(9)}) {
+		t.Fatalf("生成 %s %v", clause, args)
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Department 部门，Path为物化路径，如/1/4/9/，包含自身ID
+type Department struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	Name     string `gorm:"size:50;not null" json:"name" validate:"required,max=50"`
+	Code     string `gorm:"size:50" json:"code"`
+	ParentID uint   `gorm:"index;default:0" json:"parent_id"` // 0表示顶级部门
+	Path     string `gorm:"size:500;index" json:"path"`
+	Level    int    `gorm:"default:1" json:"level"` // 顶级部门为1
+	Sort     int    `gorm:"default:0" json:"sort"`
+	Status   int    `gorm:"default:1" json:"status"` // 1:启用 2:禁用
+	TenantID uint   `gorm:"index" json:"tenant_id"`
+
+	Children []*Department `gorm:"-" json:"children,omitempty"`
+}
+
+// TableName 自定义表名
+func (Department) TableName() string {
+	return "departments"
+}
+
+// UserDepartment 用户所属部门，每个用户最多一个主部门，可以同时属于多个兼职部门
+type UserDepartment struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	UserID    uint `gorm:"index;not null" json:"user_id"`
+	DeptID    uint `gorm:"index;not null" json:"dept_id"`
+	TenantID  uint `gorm:"index" json:"tenant_id"`
+	IsPrimary bool `gorm:"default:false" json:"is_primary"`
+	IsManager bool `gorm:"default:false" json:"is_manager"` // 部门负责人
+}
+
+// TableName 自定义表名
+func (UserDepartment) TableName() string {
+	return "user_departments"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 签名密钥状态
+const (
+	JwtKeyActive  = "active"
+	JwtKeyRetired = "retired"
+)
+
+// JwtSigningKey 访问令牌的非对称签名密钥，退役后在保留期内仍用于验证
+type JwtSigningKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Kid        string     `gorm:"uniqueIndex;size:32" json:"kid"`
+	Algorithm  string     `gorm:"size:10" json:"algorithm"` // RS256 或 EdDSA
+	PrivateKey string     `gorm:"type:text" json:"-"`       // PKCS#8 PEM
+	Status     string     `gorm:"size:10;index" json:"status"`
+	RetiredAt  *time.Time `json:"retired_at"`
+}
+
+// TableName 自定义表名
+func (JwtSigningKey) TableName() string {
+	return "jwt_signing_keys"
+}
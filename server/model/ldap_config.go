@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// 租户可选的身份验证方式
+const (
+	AuthProviderLocal = "local"
+	AuthProviderLDAP  = "ldap"
+)
+
+// 用户的身份来源
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+	AuthSourceOIDC  = "oidc"
+)
+
+// LdapConfig 租户的LDAP目录配置，租户的auth_provider为ldap时生效
+type LdapConfig struct {
+	ID                 uint              `gorm:"primaryKey" json:"id"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	TenantID           uint              `gorm:"uniqueIndex" json:"tenant_id"`
+	URL                string            `gorm:"size:255" json:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool              `gorm:"default:false" json:"start_tls"`
+	InsecureSkipVerify bool              `gorm:"default:false" json:"insecure_skip_verify"`
+	CACert             string            `gorm:"type:text" json:"ca_cert"` // PEM格式的CA证书，为空时使用系统证书
+	BindDN             string            `gorm:"size:255" json:"bind_dn"`  // 查询用户的服务账号，为空时匿名查询
+	BindPassword       string            `gorm:"size:255" json:"-"`
+	UserBaseDN         string            `gorm:"size:255" json:"user_base_dn"`
+	UserFilter         string            `gorm:"size:255" json:"user_filter"` // 如 (uid=%s)，%s替换为转义后的用户名
+	UsernameAttr       string            `gorm:"size:50" json:"username_attr"`
+	EmailAttr          string            `gorm:"size:50" json:"email_attr"`
+	NameAttr           string            `gorm:"size:50" json:"name_attr"`
+	GroupBaseDN        string            `gorm:"size:255" json:"group_base_dn"`
+	GroupFilter        string            `gorm:"size:255" json:"group_filter"`                        // 如 (member=%s)，%s替换为用户DN；为空时读取用户的memberOf属性
+	GroupRoleMapping   map[string]string `gorm:"serializer:json;type:text" json:"group_role_mapping"` // 组DN到本地角色名的映射
+	AutoProvision      bool              `gorm:"default:false" json:"auto_provision"`                 // 目录中存在但本地没有的用户首次登录时自动创建
+	SyncInterval       int               `gorm:"default:0" json:"sync_interval"`                      // 组同步间隔（分钟），0表示只在登录时同步
+	LastSyncAt         *time.Time        `json:"last_sync_at"`
+}
+
+// TableName 自定义表名
+func (LdapConfig) TableName() string {
+	return "ldap_configs"
+}
@@ -6,6 +6,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// 日志类型
+const (
+	LogTypeOperation  = "operation"   // 操作日志
+	LogTypeLogin      = "login"       // 登录日志
+	LogTypeLogout     = "logout"      // 登出日志
+	LogTypeLockout    = "lockout"     // 登录失败过多被锁定
+	LogTypeUnlock     = "unlock"      // 管理员解除锁定
+	LogTypeDenied     = "denied"      // 无权限的访问被拒绝
+	LogTypeRoleGrant  = "role_grant"  // 授予或调整角色有效期
+	LogTypeRoleExpire = "role_expire" // 临时角色到期收回
+	LogTypeElevation  = "elevation"   // 临时提权申请及审批
+)
+
 // Log 日志模型
 type Log struct {
 	ID           uint           `gorm:"primaryKey" json:"id" example:"1"`
@@ -19,8 +32,10 @@ type Log struct {
 	Path         string         `gorm:"size:100" json:"path" validate:"max=100" example:"/api/users"`
 	UserAgent    string         `gorm:"size:255" json:"user_agent" validate:"max=255" example:"Mozilla/5.0"`
 	StatusCode   int            `json:"status_code" example:"200"`
-	ResponseTime int            `json:"response_time" example:"150"` // 响应时间(毫秒)
-	TenantID     uint           `gorm:"index" json:"tenant_id" example:"1"` // 租户ID
+	ResponseTime int            `json:"response_time" example:"150"`                                     // 响应时间(毫秒)
+	TenantID     uint           `gorm:"index" json:"tenant_id" example:"1"`                              // 租户ID
+	Type         string         `gorm:"size:20;index;default:operation" json:"type" example:"operation"` // 日志类型
+	Detail       string         `gorm:"size:255" json:"detail" example:""`                               // 附加说明
 }
 
 // TableName 自定义表名
@@ -38,4 +53,4 @@ func (l *Log) BeforeCreate(tx *gorm.DB) error {
 func (l *Log) BeforeUpdate(tx *gorm.DB) error {
 	// 可以在这里添加更新前的逻辑
 	return nil
-}
\ No newline at end of file
+}
@@ -21,16 +21,32 @@ type Menu struct {
 	Sort        int            `gorm:"default:0" json:"sort" example:"1"`
 	Hidden      bool           `gorm:"default:false" json:"hidden" example:"false"`
 	Level       int            `gorm:"default:1" json:"level" example:"1"`
-	Type        string         `gorm:"size:20;default:menu" json:"type" validate:"oneof=menu group" example:"menu"` // menu:菜单项 group:菜单组
+	Type        string         `gorm:"size:20;default:menu" json:"type" validate:"oneof=menu group action" example:"menu"` // menu:菜单项 group:菜单组 action:按钮操作
+	Code        string         `gorm:"size:100;index" json:"code" example:"user:create"` // 操作权限码，action类型必填
 	Status      int            `gorm:"default:1" json:"status" validate:"oneof=1 2" example:"1"` // 1:启用 2:禁用
 	TenantID    uint           `gorm:"index" json:"tenant_id" example:"1"` // 租户ID
+
+	Children []*Menu `gorm:"-" json:"children,omitempty"` // 用户菜单树的子菜单
+	ApiIDs   []uint  `gorm:"-" json:"api_ids,omitempty"`  // 操作关联的API，授予操作时一并授予接口权限
 }
 
+// 菜单类型
+const (
+	MenuTypeMenu   = "menu"
+	MenuTypeGroup  = "group"
+	MenuTypeAction = "action"
+)
+
 // TableName 自定义表名
 func (Menu) TableName() string {
 	return "menus"
 }
 
+// TenantShared 租户ID为0的内置菜单由各租户共用，租户隔离时各租户都能读取
+func (Menu) TenantShared() bool {
+	return true
+}
+
 // BeforeCreate 创建前钩子
 func (m *Menu) BeforeCreate(tx *gorm.DB) error {
 	// 可以在这里添加创建前的逻辑
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// MenuApi 操作权限码与API的关联，角色被授予操作时同时获得关联API的策略
+type MenuApi struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	MenuID    uint      `gorm:"uniqueIndex:idx_menu_api" json:"menu_id"`
+	ApiID     uint      `gorm:"uniqueIndex:idx_menu_api;index" json:"api_id"`
+}
+
+// TableName 自定义表名
+func (MenuApi) TableName() string {
+	return "menu_apis"
+}
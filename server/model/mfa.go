@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// MfaRecoveryCode 两步验证恢复码，仅保存哈希，每个恢复码只能使用一次
+type MfaRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName 自定义表名
+func (MfaRecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OidcProvider 租户配置的OpenID Connect身份提供方
+type OidcProvider struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	TenantID      uint              `gorm:"index" json:"tenant_id"`
+	Name          string            `gorm:"size:50" json:"name"` // 登录页显示的名称
+	Issuer        string            `gorm:"size:255" json:"issuer"`
+	ClientID      string            `gorm:"size:255" json:"client_id"`
+	ClientSecret  string            `gorm:"size:255" json:"-"`
+	RedirectURL   string            `gorm:"size:255" json:"redirect_url"` // 前端回调页地址，需要在身份提供方登记
+	Scopes        []string          `gorm:"serializer:json;type:text" json:"scopes"`
+	AutoProvision bool              `gorm:"default:false" json:"auto_provision"`           // 没有关联账号时自动创建用户
+	LinkByEmail   bool              `gorm:"default:false" json:"link_by_email"`            // 按已验证的邮箱关联已有用户
+	RoleClaim     string            `gorm:"size:100" json:"role_claim"`                    // 角色声明路径，如 realm_access.roles
+	RoleMapping   map[string]string `gorm:"serializer:json;type:text" json:"role_mapping"` // 声明值到本地角色名的映射
+	Status        int               `gorm:"default:1" json:"status"`                       // 1:启用 2:禁用
+}
+
+// TableName 自定义表名
+func (OidcProvider) TableName() string {
+	return "oidc_providers"
+}
+
+// UserIdentity 用户与外部身份的关联
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	ProviderID  uint      `gorm:"uniqueIndex:idx_identity_provider_subject" json:"provider_id"`
+	Subject     string    `gorm:"uniqueIndex:idx_identity_provider_subject;size:255" json:"subject"` // 身份提供方中的sub
+	Email       string    `gorm:"size:100" json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// TableName 自定义表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
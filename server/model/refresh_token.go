@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken 刷新令牌（仅保存令牌哈希）
+// 同一次登录内轮换产生的刷新令牌共享同一个FamilyID
+type RefreshToken struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	TokenHash       string     `gorm:"uniqueIndex;size:64" json:"-"`
+	FamilyID        string     `gorm:"index;size:64" json:"family_id"`
+	UserID          uint       `gorm:"index" json:"user_id"`
+	TenantID        uint       `gorm:"index" json:"tenant_id"`
+	AccessJTI       string     `gorm:"size:64" json:"access_jti"` // 与该刷新令牌一同签发的访问令牌ID
+	AccessExpiresAt time.Time  `json:"access_expires_at"`         // 访问令牌过期时间
+	ExpiresAt       time.Time  `gorm:"index" json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at"`    // 已被轮换使用的时间
+	RevokedAt       *time.Time `json:"revoked_at"` // 被吊销的时间
+}
+
+// TableName 自定义表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// TokenDenylist 访问令牌黑名单
+type TokenDenylist struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	JTI       string    `gorm:"uniqueIndex;size:64" json:"jti"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"` // 原令牌过期后即可清理
+}
+
+// TableName 自定义表名
+func (TokenDenylist) TableName() string {
+	return "token_denylist"
+}
@@ -12,10 +12,11 @@ type Role struct {
 	CreatedAt   time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
 	UpdatedAt   time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" example:"null"`
-	Name        string         `gorm:"uniqueIndex;size:50" json:"name" validate:"required,min=2,max=50" example:"管理员"`
+	Name        string         `gorm:"uniqueIndex:idx_role_name_tenant;size:50" json:"name" validate:"required,min=2,max=50" example:"管理员"`
 	Description string         `gorm:"size:255" json:"description" validate:"max=255" example:"系统管理员角色"`
-	Status      int            `gorm:"default:1" json:"status" validate:"oneof=1 2" example:"1"` // 1:启用 2:禁用
-	TenantID    uint           `gorm:"index" json:"tenant_id" example:"1"` // 租户ID
+	Status      int            `gorm:"default:1" json:"status" validate:"oneof=1 2" example:"1"`            // 1:启用 2:禁用
+	TenantID    uint           `gorm:"index;uniqueIndex:idx_role_name_tenant" json:"tenant_id" example:"1"` // 租户ID，角色名称在租户内唯一
+	RequireMFA  bool           `gorm:"default:false" json:"require_mfa" example:"false"`                    // 拥有该角色的用户必须开启两步验证
 }
 
 // TableName 自定义表名
@@ -33,4 +34,4 @@ func (r *Role) BeforeCreate(tx *gorm.DB) error {
 func (r *Role) BeforeUpdate(tx *gorm.DB) error {
 	// 可以在这里添加更新前的逻辑
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 临时提权申请状态
+const (
+	ElevationPending   = "pending"   // 待审批
+	ElevationApproved  = "approved"  // 已批准，角色在有效期内
+	ElevationRejected  = "rejected"  // 已拒绝
+	ElevationCancelled = "cancelled" // 申请人撤回
+	ElevationExpired   = "expired"   // 已到期收回
+)
+
+// RoleElevation 临时提权申请，批准后用户在申请时长内拥有该角色，到期自动收回
+type RoleElevation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Username   string     `gorm:"size:50" json:"username"`
+	RoleID     uint       `gorm:"index;not null" json:"role_id"`
+	RoleName   string     `gorm:"size:50" json:"role_name"`
+	Hours      int        `gorm:"not null" json:"hours"`
+	Reason     string     `gorm:"size:255;not null" json:"reason"`
+	Status     string     `gorm:"size:20;index;default:pending" json:"status"`
+	ApproverID uint       `json:"approver_id"`
+	Approver   string     `gorm:"size:50" json:"approver"`
+	Comment    string     `gorm:"size:255" json:"comment"` // 审批意见
+	DecidedAt  *time.Time `json:"decided_at"`
+	ValidUntil *time.Time `json:"valid_until"` // 批准后角色的失效时间
+	TenantID   uint       `gorm:"index" json:"tenant_id"`
+}
+
+// TableName 自定义表名
+func (RoleElevation) TableName() string {
+	return "role_elevations"
+}
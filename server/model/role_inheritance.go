@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// RoleInheritance 角色继承关系，角色继承上级角色的菜单、接口和数据表权限
+type RoleInheritance struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	RoleID    uint      `gorm:"uniqueIndex:idx_role_parent_tenant" json:"role_id"`
+	ParentID  uint      `gorm:"uniqueIndex:idx_role_parent_tenant;index" json:"parent_id"`
+	TenantID  uint      `gorm:"uniqueIndex:idx_role_parent_tenant" json:"tenant_id"`
+}
+
+// TableName 自定义表名
+func (RoleInheritance) TableName() string {
+	return "role_inheritances"
+}
@@ -14,8 +14,9 @@ type TablePermission struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 	
-	TableID     uint           `gorm:"index" json:"table_id" validate:"required"`
+	TableID     uint           `gorm:"index" json:"table_id"` // 0表示租户内的所有数据表
 	RoleID      uint           `gorm:"index" json:"role_id" validate:"required"`
+	TenantID    uint           `gorm:"index" json:"tenant_id"`
 	CanView     bool           `gorm:"default:false" json:"can_view"`
 	CanCreate   bool           `gorm:"default:false" json:"can_create"`
 	CanUpdate   bool           `gorm:"default:false" json:"can_update"`
@@ -36,9 +37,9 @@ type FieldPermission struct {
 	CanEdit   bool   `json:"can_edit"`
 }
 
-// PermissionRequest 权限设置请求
+// PermissionRequest 权限设置请求，TableID为0表示所有数据表
 type PermissionRequest struct {
-	TableID          uint                        `json:"table_id" validate:"required"`
+	TableID          uint                        `json:"table_id"`
 	RoleID           uint                        `json:"role_id" validate:"required"`
 	CanView          bool                        `json:"can_view"`
 	CanCreate        bool                        `json:"can_create"`
@@ -64,6 +65,7 @@ type UserPermission struct {
 	CanDelete        bool                        `json:"can_delete"`
 	CanExport        bool                        `json:"can_export"`
 	FieldPermissions map[string]FieldPermission `json:"field_permissions"`
+	CreatableFields  map[string]bool             `json:"creatable_fields"` // 新增数据时可写的字段，FieldPermissions中的CanEdit为更新时可写
 }
 
 // GetFieldPermissions 获取字段权限
@@ -109,31 +111,26 @@ func (tp *TablePermission) HasPermission(action string) bool {
 	}
 }
 
-// HasFieldPermission 检查字段权限
+// HasFieldPermission 检查字段权限，action为view、create或update
+// 字段可写要求角色同时有表的新增或更新权限，未设置字段权限时继承表权限
 func (tp *TablePermission) HasFieldPermission(fieldName, action string) bool {
 	permissions, err := tp.GetFieldPermissions()
 	if err != nil {
 		return false
 	}
-	
+
 	fieldPerm, exists := permissions[fieldName]
-	if !exists {
-		// 如果没有设置字段权限，默认继承表权限
-		switch action {
-		case "view":
-			return tp.CanView
-		case "edit":
-			return tp.CanUpdate
-		default:
-			return false
-		}
-	}
-	
 	switch action {
 	case "view":
-		return fieldPerm.CanView
-	case "edit":
-		return fieldPerm.CanEdit
+		if exists {
+			return fieldPerm.CanView
+		}
+		return tp.CanView
+	case "create", "update":
+		if !tp.HasPermission(action) {
+			return false
+		}
+		return !exists || fieldPerm.CanEdit
 	default:
 		return false
 	}
@@ -194,6 +191,30 @@ func MergePermissions(permissions []TablePermission) *UserPermission {
 	return result
 }
 
+// CanViewField 字段是否可见，未设置字段权限时继承表的查看权限
+func (up *UserPermission) CanViewField(fieldName string) bool {
+	if fieldPerm, ok := up.FieldPermissions[fieldName]; ok {
+		return fieldPerm.CanView
+	}
+	return up.CanView
+}
+
+// CanEditField 更新数据时字段是否可写，未设置字段权限时继承表的更新权限
+func (up *UserPermission) CanEditField(fieldName string) bool {
+	if fieldPerm, ok := up.FieldPermissions[fieldName]; ok {
+		return fieldPerm.CanEdit
+	}
+	return up.CanUpdate
+}
+
+// CanCreateField 新增数据时字段是否可写，未设置字段权限时继承表的新增权限
+func (up *UserPermission) CanCreateField(fieldName string) bool {
+	if creatable, ok := up.CreatableFields[fieldName]; ok {
+		return creatable
+	}
+	return up.CanCreate
+}
+
 // DefaultPermissions 获取默认权限配置
 func DefaultPermissions() *PermissionRequest {
 	return &PermissionRequest{
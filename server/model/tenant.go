@@ -8,17 +8,25 @@ import (
 
 // Tenant 租户模型
 type Tenant struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"`
-	Name        string         `gorm:"uniqueIndex;size:100" json:"name"`
-	Code        string         `gorm:"uniqueIndex;size:50" json:"code"`
-	Description string         `gorm:"size:255" json:"description"`
-	Status      int            `gorm:"default:1" json:"status"` // 1:启用 2:禁用
-	AdminUserID uint           `json:"admin_user_id"` // 管理员用户ID
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	Name         string         `gorm:"uniqueIndex;size:100" json:"name"`
+	Code         string         `gorm:"uniqueIndex;size:50" json:"code"`
+	Description  string         `gorm:"size:255" json:"description"`
+	Status       int            `gorm:"default:1" json:"status"`                    // 1:启用 2:禁用
+	AdminUserID  uint           `json:"admin_user_id"`                              // 管理员用户ID
+	AuthProvider string         `gorm:"size:20;default:local" json:"auth_provider"` // 身份验证方式 local:本地账号 ldap:LDAP目录
+	PurgeAfter   *time.Time     `gorm:"index" json:"purge_after"`                   // 删除后在该时间之前可以恢复，之后彻底清除
 }
 
+// 租户状态
+const (
+	TenantStatusActive    = 1 // 启用
+	TenantStatusSuspended = 2 // 停用，租户用户无法登录和访问
+)
+
 // TableName 自定义表名
 func (Tenant) TableName() string {
 	return "tenants"
@@ -34,4 +42,4 @@ func (t *Tenant) BeforeCreate(tx *gorm.DB) error {
 func (t *Tenant) BeforeUpdate(tx *gorm.DB) error {
 	// 可以在这里添加更新前的逻辑
 	return nil
-}
\ No newline at end of file
+}
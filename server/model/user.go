@@ -8,20 +8,27 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id" example:"1"`
-	CreatedAt time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
-	UpdatedAt time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" example:"null"`
-	Username  string         `gorm:"uniqueIndex;size:50" json:"username" validate:"required,min=3,max=50" example:"admin"`
-	Password  string         `gorm:"size:100" json:"password" validate:"required,min=6,max=100" example:"123456"`
-	Nickname  string         `gorm:"size:50" json:"nickname" validate:"max=50" example:"管理员"`
-	RealName  string         `gorm:"size:50" json:"real_name" validate:"max=50" example:"张三"`
-	Email     string         `gorm:"size:100" json:"email" validate:"email,max=100" example:"admin@example.com"`
-	Phone     string         `gorm:"size:20" json:"phone" validate:"max=20" example:"13800138000"`
-	Bio       string         `gorm:"type:text" json:"bio" validate:"max=500" example:"这是一个用户简介"`
-	Status    int            `gorm:"default:1" json:"status" validate:"oneof=1 2" example:"1"` // 1:启用 2:禁用
-	Avatar    string         `gorm:"size:255" json:"avatar" validate:"max=255" example:"https://example.com/avatar.jpg"`
-	TenantID  uint           `gorm:"index" json:"tenant_id" example:"1"` // 租户ID
+	ID              uint           `gorm:"primaryKey" json:"id" example:"1"`
+	CreatedAt       time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt       time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" example:"null"`
+	Username        string         `gorm:"uniqueIndex;size:50" json:"username" validate:"required,min=3,max=50" example:"admin"`
+	Password        string         `gorm:"size:255" json:"-"`
+	Nickname        string         `gorm:"size:50" json:"nickname" validate:"max=50" example:"管理员"`
+	RealName        string         `gorm:"size:50" json:"real_name" validate:"max=50" example:"张三"`
+	Email           string         `gorm:"size:100" json:"email" validate:"email,max=100" example:"admin@example.com"`
+	Phone           string         `gorm:"size:20" json:"phone" validate:"max=20" example:"13800138000"`
+	Bio             string         `gorm:"type:text" json:"bio" validate:"max=500" example:"这是一个用户简介"`
+	Status          int            `gorm:"default:1" json:"status" validate:"oneof=1 2" example:"1"` // 1:启用 2:禁用
+	Avatar          string         `gorm:"size:255" json:"avatar" validate:"max=255" example:"https://example.com/avatar.jpg"`
+	TenantID        uint           `gorm:"index" json:"tenant_id" example:"1"`                       // 租户ID
+	DeptID          uint           `gorm:"index;default:0" json:"dept_id" example:"0"`               // 所属部门ID
+	Type            string         `gorm:"size:20;default:user" json:"type" example:"user"`          // user:普通用户 service:服务账号
+	AuthSource      string         `gorm:"size:20;default:local" json:"auth_source" example:"local"` // 身份来源 local:本地 ldap:LDAP目录 oidc:单点登录
+	TOTPSecret      string         `gorm:"size:64" json:"-"`                                         // 两步验证密钥
+	TOTPEnabled     bool           `gorm:"default:false" json:"totp_enabled" example:"false"`        // 是否已开启两步验证
+	TOTPLastStep    int64          `gorm:"default:0" json:"-"`                                       // 最近一次使用的时间步，防止验证码重放
+	TokenValidAfter *time.Time     `json:"-"`                                                        // 早于该时间签发的令牌全部失效
 }
 
 // TableName 自定义表名
@@ -39,4 +46,4 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	// 可以在这里添加更新前的逻辑
 	return nil
-}
\ No newline at end of file
+}
@@ -8,13 +8,27 @@ import (
 
 // UserRole 用户角色关联模型
 type UserRole struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
-	UserID    uint           `gorm:"index;uniqueIndex:idx_user_role_tenant" json:"user_id"`
-	RoleID    uint           `gorm:"index;uniqueIndex:idx_user_role_tenant" json:"role_id"`
-	TenantID  uint           `gorm:"index;uniqueIndex:idx_user_role_tenant" json:"tenant_id"` // 租户ID
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	UserID     uint           `gorm:"index;uniqueIndex:idx_user_role_tenant" json:"user_id"`
+	RoleID     uint           `gorm:"index;uniqueIndex:idx_user_role_tenant" json:"role_id"`
+	TenantID   uint           `gorm:"index;uniqueIndex:idx_user_role_tenant" json:"tenant_id"` // 租户ID
+	ValidFrom  *time.Time     `gorm:"index" json:"valid_from"`                                 // 生效时间，为空表示立即生效
+	ValidUntil *time.Time     `gorm:"index" json:"valid_until"`                                // 失效时间，为空表示长期有效
+}
+
+// Active 判断角色授权在指定时间是否有效
+func (ur *UserRole) Active(now time.Time) bool {
+	return (ur.ValidFrom == nil || !ur.ValidFrom.After(now)) && (ur.ValidUntil == nil || ur.ValidUntil.After(now))
+}
+
+// ActiveUserRoles 只查询在指定时间有效的用户角色
+func ActiveUserRoles(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(user_roles.valid_from IS NULL OR user_roles.valid_from <= ?) AND (user_roles.valid_until IS NULL OR user_roles.valid_until > ?)", now, now)
+	}
 }
 
 // TableName 自定义表名
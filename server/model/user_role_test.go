@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserRoleActive(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name  string
+		from  *time.Time
+		until *time.Time
+		want  bool
+	}{
+		{"长期有效", nil, nil, true},
+		{"已生效未到期", &before, &after, true},
+		{"尚未生效", &after, nil, false},
+		{"已到期", nil, &before, false},
+		{"到期时刻即失效", nil, &now, false},
+		{"生效时刻即生效", &now, nil, true},
+	}
+	for _, tt := range tests {
+		ur := UserRole{ValidFrom: tt.from, ValidUntil: tt.until}
+		if got := ur.Active(now); got != tt.want {
+			t.Errorf("%s: Active = %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
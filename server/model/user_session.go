@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// UserSession 用户登录会话
+// SessionID 同时也是该会话下刷新令牌的令牌族ID
+type UserSession struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	SessionID   string     `gorm:"uniqueIndex;size:64" json:"session_id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Username    string     `gorm:"size:50" json:"username"`
+	TenantID    uint       `gorm:"index" json:"tenant_id"`
+	IP          string     `gorm:"size:50" json:"ip"`
+	UserAgent   string     `gorm:"size:255" json:"user_agent"`
+	DeviceLabel string     `gorm:"size:100" json:"device_label"`
+	MfaVerified bool       `gorm:"default:false" json:"mfa_verified"` // 登录时是否通过了两步验证
+	IssuedAt    time.Time  `json:"issued_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ExpiresAt   time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	RevokedBy   uint       `json:"revoked_by"` // 执行强制下线的用户ID
+}
+
+// TableName 自定义表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive 会话是否仍然有效
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUserJSONOmitsSecrets(t *testing.T) {
+	user := User{Username: "admin", Password: "$2a$10$hash", TOTPSecret: "SECRET"}
+	data, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"password", "$2a$10$hash", "SECRET"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("用户JSON包含 %q: %s，期望不输出密码和密钥", secret, data)
+		}
+	}
+
+	// 请求体中的password不能写入模型
+	var bound User
+	if err := json.Unmarshal([]byte(`{"username":"u","password":"123456"}`), &bound); err != nil {
+		t.Fatal(err)
+	}
+	if bound.Password != "" {
+		t.Fatalf("Password = %q，期望不从JSON绑定", bound.Password)
+	}
+}
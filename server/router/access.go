@@ -0,0 +1,49 @@
+package router
+
+import (
+	"go-react-admin/middleware"
+)
+
+// newRouteAccess 路由授权声明：/api/v1下未在此声明的路由都需要按Casbin策略授权
+func newRouteAccess() *middleware.RouteAccess {
+	access := middleware.NewRouteAccess("/api/v1")
+
+	// 无需登录的接口
+	access.Public("GET", "/.well-known/jwks.json")
+	access.Public("POST", "/api/v1/login")
+	access.Public("POST", "/api/v1/register")
+	access.Public("POST", "/api/v1/token/refresh")
+	access.Public("GET", "/api/v1/captcha")
+	access.Public("POST", "/api/v1/mfa/verify")
+	access.Public("POST", "/api/v1/mfa/enroll/setup")
+	access.Public("POST", "/api/v1/mfa/enroll/confirm")
+	access.Public("GET", "/api/v1/oidc/providers")
+	access.Public("GET", "/api/v1/oidc/authorize/:id")
+	access.Public("POST", "/api/v1/oidc/callback")
+
+	// 登录用户均可访问、只操作本人数据的接口
+	access.Authenticated("GET", "/api/v1/user/info")
+	access.Authenticated("POST", "/api/v1/user/logout")
+	access.Authenticated("POST", "/api/v1/user/upload-avatar")
+	access.Authenticated("GET", "/api/v1/menus/user")
+	access.Authenticated("GET", "/api/v1/permissions/check")
+	access.Authenticated("GET", "/api/v1/session/list")
+	access.Authenticated("POST", "/api/v1/session/revoke")
+	access.Authenticated("POST", "/api/v1/session/revoke-others")
+	access.Authenticated("GET", "/api/v1/mfa/status")
+	access.Authenticated("POST", "/api/v1/mfa/setup")
+	access.Authenticated("POST", "/api/v1/mfa/enable")
+	access.Authenticated("POST", "/api/v1/mfa/disable")
+	access.Authenticated("POST", "/api/v1/mfa/recovery-codes")
+	access.Authenticated("GET", "/api/v1/apikey/list")
+	access.Authenticated("POST", "/api/v1/apikey/create")
+	access.Authenticated("POST", "/api/v1/apikey/revoke")
+	access.Authenticated("GET", "/api/v1/tablePermission/mine/:tableId")
+	access.Authenticated("POST", "/api/v1/elevation/request")
+	access.Authenticated("GET", "/api/v1/elevation/mine")
+	access.Authenticated("POST", "/api/v1/elevation/cancel/:id")
+	access.Authenticated("GET", "/api/v1/approval/mine")
+	access.Authenticated("POST", "/api/v1/approval/cancel/:id")
+
+	return access
+}
@@ -10,20 +10,39 @@ import (
 // InitApiRoutes 初始化API路由
 func InitApiRoutes(r *gin.Engine) {
 	// 初始化API实例
-	permissionApi := api.NewPermissionApi()
+	access := newRouteAccess()
+	permissionApi := api.NewPermissionApi(access)
+
+	// 访问令牌的公钥，其他服务据此验证令牌
+	r.GET("/.well-known/jwks.json", api.GetJWKS)
+
+	// 所有/api/v1路由统一认证授权，公开和登录即可访问的路由见newRouteAccess
+	v1 := r.Group("/api/v1")
+	v1.Use(middleware.Authorize(access))
 
 	// 公开路由
-	public := r.Group("/api/v1")
+	public := v1
 	{
 		// 用户相关路由
 		public.POST("/login", middleware.LoginLogger(), api.Login)
 		public.POST("/register", api.Register)
+		public.POST("/token/refresh", api.RefreshToken)
+		public.GET("/captcha", api.GetCaptcha)
+
+		// 两步验证登录流程
+		public.POST("/mfa/verify", api.VerifyMfa)
+		public.POST("/mfa/enroll/setup", api.SetupMfaEnrollment)
+		public.POST("/mfa/enroll/confirm", api.ConfirmMfaEnrollment)
+
+		// OpenID Connect单点登录
+		public.GET("/oidc/providers", api.GetOidcProviders)
+		public.GET("/oidc/authorize/:id", api.OidcAuthorize)
+		public.POST("/oidc/callback", middleware.LoginLogger(), api.OidcCallback)
 	}
 
 	// 受保护的路由
-	protected := r.Group("/api/v1")
+	protected := v1.Group("")
 	protected.Use(middleware.Logger()) // 添加操作日志记录
-	protected.Use(middleware.JWTAuth())
 	{
 		// 用户相关路由
 		protected.GET("/user/info", api.GetUserInfo)
@@ -34,11 +53,60 @@ func InitApiRoutes(r *gin.Engine) {
 		protected.DELETE("/user/delete/:id", api.DeleteUser)
 		protected.POST("/user/upload-avatar", api.UploadAvatar)
 
+		// 登录会话相关路由
+		protected.GET("/session/list", api.GetMySessions)
+		protected.POST("/session/revoke", api.RevokeMySession)
+		protected.POST("/session/revoke-others", api.RevokeMyOtherSessions)
+
+		// 两步验证相关路由
+		protected.GET("/mfa/status", api.GetMfaStatus)
+		protected.POST("/mfa/setup", api.SetupMfa)
+		protected.POST("/mfa/enable", api.EnableMfa)
+		protected.POST("/mfa/disable", api.DisableMfa)
+		protected.POST("/mfa/recovery-codes", api.RegenerateRecoveryCodes)
+
+		// 个人API Key相关路由
+		protected.GET("/apikey/list", api.GetMyApiKeys)
+		protected.POST("/apikey/create", api.CreateMyApiKey)
+		protected.POST("/apikey/revoke", api.RevokeMyApiKey)
+
 		// 角色相关路由
 		protected.GET("/role/list", api.GetRoleList)
 		protected.POST("/role/create", api.CreateRole)
 		protected.PUT("/role/update/:id", api.UpdateRole)
 		protected.DELETE("/role/delete/:id", api.DeleteRole)
+		protected.GET("/role/parents/:id", api.GetRoleParents)
+		protected.POST("/role/parents/:id", api.SetRoleParents)
+		protected.GET("/role/effective/:id", api.GetRoleEffectivePermissions)
+
+		// 临时提权
+		protected.POST("/elevation/request", api.RequestElevation)
+		protected.GET("/elevation/mine", api.GetMyElevations)
+		protected.POST("/elevation/cancel/:id", api.CancelElevation)
+		protected.GET("/elevation/list", api.GetElevationList)
+		protected.POST("/elevation/approve/:id", api.ApproveElevation)
+		protected.POST("/elevation/reject/:id", api.RejectElevation)
+
+		// 权限变更审批
+		protected.GET("/approval/list", api.GetChangeRequestList)
+		protected.GET("/approval/mine", api.GetMyChangeRequests)
+		protected.GET("/approval/get/:id", api.GetChangeRequest)
+		protected.POST("/approval/approve/:id", api.ApproveChangeRequest)
+		protected.POST("/approval/reject/:id", api.RejectChangeRequest)
+		protected.POST("/approval/cancel/:id", api.CancelChangeRequest)
+		protected.GET("/approval/policy", api.GetApprovalPolicies)
+		protected.POST("/approval/policy", api.SaveApprovalPolicy)
+
+		// 部门相关路由
+		protected.GET("/department/tree", api.GetDepartmentTree)
+		protected.POST("/department/create", api.CreateDepartment)
+		protected.PUT("/department/update/:id", api.UpdateDepartment)
+		protected.DELETE("/department/delete/:id", api.DeleteDepartment)
+		protected.POST("/department/move/:id", api.MoveDepartment)
+		protected.GET("/department/members/:id", api.GetDepartmentMembers)
+		protected.POST("/department/managers/:id", api.SetDepartmentManagers)
+		protected.GET("/user/departments/:id", api.GetUserDepartments)
+		protected.POST("/user/departments/:id", api.SetUserDepartments)
 
 		// 菜单相关路由
 		protected.GET("/menu/list", api.GetMenuList)
@@ -67,28 +135,51 @@ func InitApiRoutes(r *gin.Engine) {
 			
 			// 权限检查
 			permissions.GET("/check", permissionApi.CheckPermission)
+			permissions.POST("/explain", permissionApi.ExplainPermission)
 		}
 
 		// 日志相关路由
 		protected.GET("/log/list", api.GetLogList)
 
 		// 租户相关路由
-		protected.GET("/tenant/list", api.GetTenantList)
+		tenant := protected.Group("/tenant")
+		{
+			tenant.GET("/list", api.GetTenantList)
+			tenant.POST("/create", api.CreateTenant)
+			tenant.PUT("/update/:id", api.UpdateTenant)
+			tenant.POST("/suspend/:id", api.SuspendTenant)
+			tenant.POST("/resume/:id", api.ResumeTenant)
+			tenant.DELETE("/delete/:id", api.DeleteTenant)
+			tenant.POST("/restore/:id", api.RestoreTenant)
+		}
 
 		// 动态数据管理路由
 		InitDynamicRoutes(protected)
+
+		// 管理员路由
+		protected.GET("/admin/users", api.GetUserList)
+		protected.GET("/admin/roles", api.GetRoleList)
+		protected.GET("/admin/menus", api.GetMenuList)
+		protected.GET("/admin/apis", api.GetApiList)
+		protected.GET("/admin/sessions", api.GetTenantSessions)
+		protected.POST("/admin/sessions/logout", api.ForceLogout)
+		protected.POST("/admin/mfa/reset", api.ResetUserMfa)
+		protected.POST("/admin/login-guard/unlock", api.UnlockLogin)
+		protected.GET("/admin/apikeys", api.GetTenantApiKeys)
+		protected.POST("/admin/apikeys/revoke", api.RevokeTenantApiKey)
+		protected.GET("/admin/service-accounts", api.GetServiceAccounts)
+		protected.POST("/admin/service-accounts/create", api.CreateServiceAccount)
+		protected.POST("/admin/service-accounts/apikey", api.CreateServiceApiKey)
+		protected.GET("/admin/oidc/providers", api.GetTenantOidcProviders)
+		protected.POST("/admin/oidc/providers/save", api.SaveOidcProvider)
+		protected.POST("/admin/oidc/providers/delete", api.DeleteOidcProvider)
+		protected.GET("/admin/ldap/config", api.GetLdapConfig)
+		protected.POST("/admin/ldap/config/save", api.SaveLdapConfig)
+		protected.POST("/admin/ldap/sync", api.SyncLdapGroups)
 	}
 
-	// 需要权限验证的路由
-	authorized := r.Group("/api/v1")
-	authorized.Use(middleware.JWTAuth())
-	authorized.Use(middleware.CasbinMiddleware())
-	{
-		// 这里可以添加需要特定权限的路由
-		// 例如：只有管理员才能访问的路由
-		authorized.GET("/admin/users", api.GetUserList)
-		authorized.GET("/admin/roles", api.GetRoleList)
-		authorized.GET("/admin/menus", api.GetMenuList)
-		authorized.GET("/admin/apis", api.GetApiList)
+	// 每个路由都必须有授权决定，声明与注册不一致时拒绝启动
+	if err := access.Validate(r.Routes()); err != nil {
+		panic(err)
 	}
 }
@@ -0,0 +1,271 @@
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+
+	"go-react-admin/docs"
+	"go-react-admin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeMeta 路由在API目录中的分类和说明
+type routeMeta struct {
+	Category    string
+	Description string
+}
+
+// routeMetas 路由元数据，key为"方法 路由模式"；未登记的路由使用Swagger注释中的Tags和Summary
+var routeMetas = map[string]routeMeta{
+	// 认证
+	"POST /api/v1/login":                    {"认证管理", "用户登录"},
+	"POST /api/v1/register":                 {"认证管理", "用户注册"},
+	"POST /api/v1/token/refresh":            {"认证管理", "刷新访问令牌"},
+	"GET /api/v1/captcha":                   {"认证管理", "获取验证码"},
+	"POST /api/v1/admin/login-guard/unlock": {"认证管理", "解除登录锁定"},
+
+	// 用户
+	"GET /api/v1/user/info":           {"用户管理", "获取用户信息"},
+	"POST /api/v1/user/logout":        {"用户管理", "用户登出"},
+	"GET /api/v1/user/list":           {"用户管理", "获取用户列表"},
+	"POST /api/v1/user/create":        {"用户管理", "创建用户"},
+	"PUT /api/v1/user/update/:id":     {"用户管理", "更新用户"},
+	"DELETE /api/v1/user/delete/:id":  {"用户管理", "删除用户"},
+	"POST /api/v1/user/upload-avatar": {"用户管理", "上传头像"},
+	"GET /api/v1/admin/users":         {"用户管理", "管理员获取用户列表"},
+
+	// 角色
+	"GET /api/v1/role/list":          {"角色管理", "获取角色列表"},
+	"POST /api/v1/role/create":       {"角色管理", "创建角色"},
+	"PUT /api/v1/role/update/:id":    {"角色管理", "更新角色"},
+	"DELETE /api/v1/role/delete/:id": {"角色管理", "删除角色"},
+	"GET /api/v1/role/parents/:id":   {"角色管理", "获取上级角色"},
+	"POST /api/v1/role/parents/:id":  {"角色管理", "设置上级角色"},
+	"GET /api/v1/role/effective/:id": {"角色管理", "获取角色有效权限"},
+	"GET /api/v1/admin/roles":        {"角色管理", "管理员获取角色列表"},
+
+	// 临时提权
+	"POST /api/v1/elevation/request":     {"临时提权", "申请临时角色"},
+	"GET /api/v1/elevation/mine":         {"临时提权", "获取本人的提权申请"},
+	"POST /api/v1/elevation/cancel/:id":  {"临时提权", "撤回提权申请"},
+	"GET /api/v1/elevation/list":         {"临时提权", "获取提权申请列表"},
+	"POST /api/v1/elevation/approve/:id": {"临时提权", "批准提权申请"},
+	"POST /api/v1/elevation/reject/:id":  {"临时提权", "拒绝提权申请"},
+
+	// 权限审批
+	"GET /api/v1/approval/list":         {"权限审批", "获取变更申请列表"},
+	"GET /api/v1/approval/mine":         {"权限审批", "获取本人提交的变更申请"},
+	"GET /api/v1/approval/get/:id":      {"权限审批", "获取变更申请详情"},
+	"POST /api/v1/approval/approve/:id": {"权限审批", "同意变更申请"},
+	"POST /api/v1/approval/reject/:id":  {"权限审批", "拒绝变更申请"},
+	"POST /api/v1/approval/cancel/:id":  {"权限审批", "撤回变更申请"},
+	"GET /api/v1/approval/policy":       {"权限审批", "获取审批配置"},
+	"POST /api/v1/approval/policy":      {"权限审批", "保存审批配置"},
+
+	// 部门
+	"GET /api/v1/department/tree":          {"部门管理", "获取部门树"},
+	"POST /api/v1/department/create":       {"部门管理", "创建部门"},
+	"PUT /api/v1/department/update/:id":    {"部门管理", "更新部门"},
+	"DELETE /api/v1/department/delete/:id": {"部门管理", "删除部门"},
+	"POST /api/v1/department/move/:id":     {"部门管理", "移动部门"},
+	"GET /api/v1/department/members/:id":   {"部门管理", "获取部门成员"},
+	"POST /api/v1/department/managers/:id": {"部门管理", "设置部门负责人"},
+	"GET /api/v1/user/departments/:id":     {"部门管理", "获取用户所属部门"},
+	"POST /api/v1/user/departments/:id":    {"部门管理", "设置用户所属部门"},
+
+	// 菜单
+	"GET /api/v1/menu/list":          {"菜单管理", "获取菜单列表"},
+	"GET /api/v1/menus/user":         {"菜单管理", "获取用户菜单"},
+	"POST /api/v1/menu/create":       {"菜单管理", "创建菜单"},
+	"PUT /api/v1/menu/update/:id":    {"菜单管理", "更新菜单"},
+	"DELETE /api/v1/menu/delete/:id": {"菜单管理", "删除菜单"},
+	"GET /api/v1/admin/menus":        {"菜单管理", "管理员获取菜单列表"},
+
+	// API
+	"GET /api/v1/api/list":          {"API管理", "获取API列表"},
+	"POST /api/v1/api/create":       {"API管理", "创建API"},
+	"PUT /api/v1/api/update/:id":    {"API管理", "更新API"},
+	"DELETE /api/v1/api/delete/:id": {"API管理", "删除API"},
+	"GET /api/v1/admin/apis":        {"API管理", "管理员获取API列表"},
+
+	// 权限
+	"POST /api/v1/permissions/role":                {"权限管理", "分配角色权限"},
+	"GET /api/v1/permissions/role/:id":             {"权限管理", "获取角色权限"},
+	"POST /api/v1/permissions/user":                {"权限管理", "分配用户角色"},
+	"GET /api/v1/permissions/user/:id/roles":       {"权限管理", "获取用户角色"},
+	"GET /api/v1/permissions/user/:id/permissions": {"权限管理", "获取用户权限"},
+	"GET /api/v1/permissions/check":                {"权限管理", "检查权限"},
+	"POST /api/v1/permissions/explain":             {"权限管理", "解释权限"},
+
+	// 日志和租户
+	"GET /api/v1/log/list":             {"日志管理", "获取日志列表"},
+	"GET /api/v1/tenant/list":          {"租户管理", "获取租户列表"},
+	"POST /api/v1/tenant/create":       {"租户管理", "创建租户"},
+	"PUT /api/v1/tenant/update/:id":    {"租户管理", "更新租户"},
+	"POST /api/v1/tenant/suspend/:id":  {"租户管理", "停用租户"},
+	"POST /api/v1/tenant/resume/:id":   {"租户管理", "启用租户"},
+	"DELETE /api/v1/tenant/delete/:id": {"租户管理", "删除租户"},
+	"POST /api/v1/tenant/restore/:id":  {"租户管理", "恢复租户"},
+
+	// 会话
+	"GET /api/v1/session/list":           {"会话管理", "获取我的登录会话"},
+	"POST /api/v1/session/revoke":        {"会话管理", "结束我的登录会话"},
+	"POST /api/v1/session/revoke-others": {"会话管理", "结束其他登录会话"},
+	"GET /api/v1/admin/sessions":         {"会话管理", "获取租户登录会话"},
+	"POST /api/v1/admin/sessions/logout": {"会话管理", "强制用户下线"},
+
+	// 两步验证
+	"POST /api/v1/mfa/verify":         {"两步验证", "登录时验证两步验证码"},
+	"POST /api/v1/mfa/enroll/setup":   {"两步验证", "登录时生成两步验证密钥"},
+	"POST /api/v1/mfa/enroll/confirm": {"两步验证", "登录时启用两步验证"},
+	"GET /api/v1/mfa/status":          {"两步验证", "获取两步验证状态"},
+	"POST /api/v1/mfa/setup":          {"两步验证", "生成两步验证密钥"},
+	"POST /api/v1/mfa/enable":         {"两步验证", "启用两步验证"},
+	"POST /api/v1/mfa/disable":        {"两步验证", "关闭两步验证"},
+	"POST /api/v1/mfa/recovery-codes": {"两步验证", "重新生成恢复码"},
+	"POST /api/v1/admin/mfa/reset":    {"两步验证", "重置用户两步验证"},
+
+	// API Key
+	"GET /api/v1/apikey/list":                    {"API Key", "获取我的API Key"},
+	"POST /api/v1/apikey/create":                 {"API Key", "创建个人API Key"},
+	"POST /api/v1/apikey/revoke":                 {"API Key", "吊销个人API Key"},
+	"GET /api/v1/admin/apikeys":                  {"API Key", "获取租户API Key"},
+	"POST /api/v1/admin/apikeys/revoke":          {"API Key", "吊销租户API Key"},
+	"GET /api/v1/admin/service-accounts":         {"API Key", "获取服务账号列表"},
+	"POST /api/v1/admin/service-accounts/create": {"API Key", "创建服务账号"},
+	"POST /api/v1/admin/service-accounts/apikey": {"API Key", "创建服务账号API Key"},
+
+	// 单点登录和身份验证
+	"GET /api/v1/oidc/providers":               {"单点登录", "获取可用的身份提供方"},
+	"GET /api/v1/oidc/authorize/:id":           {"单点登录", "跳转到身份提供方登录"},
+	"POST /api/v1/oidc/callback":               {"单点登录", "单点登录回调"},
+	"GET /api/v1/admin/oidc/providers":         {"单点登录", "获取身份提供方配置"},
+	"POST /api/v1/admin/oidc/providers/save":   {"单点登录", "保存身份提供方配置"},
+	"POST /api/v1/admin/oidc/providers/delete": {"单点登录", "删除身份提供方"},
+	"GET /api/v1/admin/ldap/config":            {"身份验证", "获取LDAP配置"},
+	"POST /api/v1/admin/ldap/config/save":      {"身份验证", "保存LDAP配置"},
+	"POST /api/v1/admin/ldap/sync":             {"身份验证", "同步LDAP组"},
+
+	// 动态表
+	"POST /api/v1/dynamicTable/createTable":         {"动态表管理", "创建动态表"},
+	"GET /api/v1/dynamicTable/getTableList":         {"动态表管理", "获取动态表列表"},
+	"GET /api/v1/dynamicTable/getTable/:id":         {"动态表管理", "根据ID获取动态表"},
+	"PUT /api/v1/dynamicTable/updateTable/:id":      {"动态表管理", "更新动态表"},
+	"DELETE /api/v1/dynamicTable/deleteTable/:id":   {"动态表管理", "删除动态表"},
+	"PATCH /api/v1/dynamicTable/toggleStatus/:id":   {"动态表管理", "切换表状态"},
+	"GET /api/v1/dynamicTable/getSchema/:tableName": {"动态表管理", "获取表结构信息"},
+	"GET /api/v1/dynamicTable/validateTableName":    {"动态表管理", "验证表名"},
+
+	// 动态字段
+	"POST /api/v1/dynamicField/createField":           {"动态字段管理", "创建动态字段"},
+	"GET /api/v1/dynamicField/getFields/:tableId":     {"动态字段管理", "根据表ID获取字段列表"},
+	"GET /api/v1/dynamicField/getField/:id":           {"动态字段管理", "根据ID获取字段"},
+	"PUT /api/v1/dynamicField/updateField/:id":        {"动态字段管理", "更新字段"},
+	"DELETE /api/v1/dynamicField/deleteField/:id":     {"动态字段管理", "删除字段"},
+	"PATCH /api/v1/dynamicField/updateOrder/:tableId": {"动态字段管理", "更新字段排序"},
+	"PATCH /api/v1/dynamicField/toggleStatus/:id":     {"动态字段管理", "切换字段状态"},
+	"POST /api/v1/dynamicField/batchCreate":           {"动态字段管理", "批量创建字段"},
+	"GET /api/v1/dynamicField/getFieldTypes":          {"动态字段管理", "获取支持的字段类型"},
+
+	// 动态数据
+	"POST /api/v1/dynamicData/:tableName/create":        {"动态数据管理", "创建动态数据"},
+	"GET /api/v1/dynamicData/:tableName/list":           {"动态数据管理", "获取动态数据列表"},
+	"GET /api/v1/dynamicData/:tableName/get/:id":        {"动态数据管理", "根据ID获取动态数据"},
+	"PUT /api/v1/dynamicData/:tableName/update/:id":     {"动态数据管理", "更新动态数据"},
+	"DELETE /api/v1/dynamicData/:tableName/delete/:id":  {"动态数据管理", "删除动态数据"},
+	"DELETE /api/v1/dynamicData/:tableName/batchDelete": {"动态数据管理", "批量删除动态数据"},
+	"GET /api/v1/dynamicData/:tableName/statistics":     {"动态数据管理", "获取数据统计"},
+
+	// 动态视图
+	"POST /api/v1/dynamicView/create":        {"动态视图管理", "创建数据视图"},
+	"GET /api/v1/dynamicView/list/:tableId":  {"动态视图管理", "获取视图列表"},
+	"GET /api/v1/dynamicView/get/:id":        {"动态视图管理", "获取视图详情"},
+	"PUT /api/v1/dynamicView/update/:id":     {"动态视图管理", "更新视图"},
+	"DELETE /api/v1/dynamicView/delete/:id":  {"动态视图管理", "删除视图"},
+	"POST /api/v1/dynamicView/apply/:viewId": {"动态视图管理", "应用视图"},
+
+	// 数据表权限
+	"GET /api/v1/tablePermission/list/:tableId": {"数据表权限", "获取数据表的角色权限"},
+	"POST /api/v1/tablePermission/save":         {"数据表权限", "设置角色对数据表的权限"},
+	"DELETE /api/v1/tablePermission/delete/:id": {"数据表权限", "删除表权限"},
+	"GET /api/v1/tablePermission/mine/:tableId": {"数据表权限", "获取当前用户对数据表的权限"},
+
+	// 行级数据规则
+	"GET /api/v1/dataRule/list/:tableId": {"行级数据规则", "获取数据表的行级数据规则"},
+	"POST /api/v1/dataRule/save":         {"行级数据规则", "创建或更新数据规则"},
+	"DELETE /api/v1/dataRule/delete/:id": {"行级数据规则", "删除数据规则"},
+	"GET /api/v1/dataRule/presets":       {"行级数据规则", "获取数据规则预设"},
+}
+
+// uncategorized 既没有登记元数据也没有Swagger注释的路由分类
+const uncategorized = "未分类"
+
+// swaggerPathParam 匹配Swagger路径中的{id}参数
+var swaggerPathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// ApiCatalogue 按Gin路由表生成/api/v1下每个路由对应的API目录
+func ApiCatalogue() []model.Api {
+	r := gin.New()
+	InitApiRoutes(r)
+	return apiCatalogue(r.Routes())
+}
+
+// apiCatalogue 按路由生成API目录，分类和说明依次取自routeMetas、Swagger注释
+func apiCatalogue(routes gin.RoutesInfo) []model.Api {
+	swagger := swaggerMetas()
+	apis := make([]model.Api, 0, len(routes))
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/v1/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		meta, ok := routeMetas[key]
+		if !ok {
+			meta, ok = swagger[key]
+		}
+		if !ok {
+			log.Printf("路由 %s 没有登记分类和说明", key)
+			meta = routeMeta{Category: uncategorized, Description: route.Path}
+		}
+		apis = append(apis, model.Api{
+			Path:        route.Path,
+			Method:      route.Method,
+			Category:    meta.Category,
+			Description: meta.Description,
+			Status:      1,
+		})
+	}
+	return apis
+}
+
+// swaggerMetas 从生成的Swagger文档读取Tags和Summary，注释中的路径按/api/v1下的相对路径处理
+func swaggerMetas() map[string]routeMeta {
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Summary string   `json:"summary"`
+			Tags    []string `json:"tags"`
+		} `json:"paths"`
+	}
+	metas := make(map[string]routeMeta)
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &doc); err != nil {
+		log.Printf("解析Swagger文档失败: %v", err)
+		return metas
+	}
+	for p, operations := range doc.Paths {
+		p = swaggerPathParam.ReplaceAllString(p, ":$1")
+		if !strings.HasPrefix(p, "/api/v1/") {
+			p = path.Join("/api/v1", p)
+		}
+		for method, op := range operations {
+			if op.Summary == "" || len(op.Tags) == 0 {
+				continue
+			}
+			metas[strings.ToUpper(method)+" "+p] = routeMeta{Category: op.Tags[0], Description: op.Summary}
+		}
+	}
+	return metas
+}
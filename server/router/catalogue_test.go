@@ -0,0 +1,44 @@
+package router
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteMetasMatchRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	InitApiRoutes(r)
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, ok := routeMetas[key]; !ok {
+			t.Errorf("路由 %s 没有登记分类和说明", key)
+		}
+	}
+	for key := range routeMetas {
+		if !registered[key] {
+			t.Errorf("已登记的路由 %s 不存在", key)
+		}
+	}
+}
+
+func TestApiCatalogue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apis := ApiCatalogue()
+	if len(apis) != len(routeMetas) {
+		t.Fatalf("目录包含%d条，期望%d条", len(apis), len(routeMetas))
+	}
+	for _, api := range apis {
+		if api.Category == "" || api.Description == "" || api.Status != 1 {
+			t.Errorf("目录项不完整: %+v", api)
+		}
+	}
+}
@@ -11,6 +11,8 @@ func InitDynamicRoutes(Router *gin.RouterGroup) {
 	dynamicTableApi := v1.DynamicTableApi{}
 	dynamicFieldApi := v1.DynamicFieldApi{}
 	dynamicDataApi := v1.DynamicDataApi{}
+	tablePermissionApi := v1.TablePermissionApi{}
+	dataRuleApi := v1.DataRuleApi{}
 
 	// 动态表管理路由
 	dynamicTableRouter := Router.Group("dynamicTable")
@@ -61,4 +63,22 @@ func InitDynamicRoutes(Router *gin.RouterGroup) {
 		dynamicViewRouter.DELETE("delete/:id", dynamicDataApi.DeleteView)        // 删除视图
 		dynamicViewRouter.POST("apply/:viewId", dynamicDataApi.ApplyView)        // 应用视图
 	}
+
+	// 数据表权限管理路由
+	tablePermissionRouter := Router.Group("tablePermission")
+	{
+		tablePermissionRouter.GET("list/:tableId", tablePermissionApi.GetTablePermissions)   // 获取数据表的角色权限
+		tablePermissionRouter.POST("save", tablePermissionApi.SaveTablePermission)           // 设置角色对数据表的权限
+		tablePermissionRouter.DELETE("delete/:id", tablePermissionApi.DeleteTablePermission) // 删除表权限
+		tablePermissionRouter.GET("mine/:tableId", tablePermissionApi.GetMyTablePermission)  // 获取当前用户对数据表的权限
+	}
+
+	// 行级数据规则路由
+	dataRuleRouter := Router.Group("dataRule")
+	{
+		dataRuleRouter.GET("list/:tableId", dataRuleApi.GetDataRules)   // 获取数据表的行级数据规则
+		dataRuleRouter.POST("save", dataRuleApi.SaveDataRule)           // 创建或更新数据规则
+		dataRuleRouter.DELETE("delete/:id", dataRuleApi.DeleteDataRule) // 删除数据规则
+		dataRuleRouter.GET("presets", dataRuleApi.GetDataRulePresets)   // 获取数据规则预设
+	}
 }
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"github.com/casbin/casbin/v2/util"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrApiKeyInvalid API Key无效
+	ErrApiKeyInvalid = errors.New("无效的API Key")
+	// ErrApiKeyOptionInvalid API Key参数错误
+	ErrApiKeyOptionInvalid = errors.New("API Key参数错误")
+	// ErrApiKeyNotFound API Key不存在
+	ErrApiKeyNotFound = errors.New("API Key不存在")
+	// ErrApiKeyIPDenied 来源IP不在白名单内
+	ErrApiKeyIPDenied = errors.New("来源IP不允许使用该API Key")
+	// ErrApiKeyScopeDenied 接口不在API Key的授权范围内
+	ErrApiKeyScopeDenied = errors.New("API Key无权访问该接口")
+	// ErrServiceAccountLogin 服务账号不能登录
+	ErrServiceAccountLogin = errors.New("服务账号不能登录")
+)
+
+const (
+	// ApiKeyScheme Authorization请求头中API Key的前缀
+	ApiKeyScheme = "ApiKey"
+	apiKeyPrefix = "gra_"
+)
+
+type ApiKeyService struct{}
+
+// ApiKeyOptions 创建API Key的参数
+type ApiKeyOptions struct {
+	Name           string
+	AllowedRoutes  []string
+	AllowedMethods []string
+	AllowedIPs     []string
+	ExpiresAt      *time.Time
+}
+
+// hashApiKeySecret 计算API Key密钥的哈希，服务端只保存哈希
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKey 为用户或服务账号创建API Key，返回的明文Key只在创建时出现一次
+func (s *ApiKeyService) CreateKey(owner *model.User, createdBy uint, opts ApiKeyOptions) (*model.ApiKey, string, error) {
+	if strings.TrimSpace(opts.Name) == "" {
+		return nil, "", fmt.Errorf("%w: 名称不能为空", ErrApiKeyOptionInvalid)
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: 过期时间必须晚于当前时间", ErrApiKeyOptionInvalid)
+	}
+	for _, ip := range opts.AllowedIPs {
+		if !validIPRule(ip) {
+			return nil, "", fmt.Errorf("%w: IP白名单格式错误 %s", ErrApiKeyOptionInvalid, ip)
+		}
+	}
+	methods := make([]string, 0, len(opts.AllowedMethods))
+	for _, method := range opts.AllowedMethods {
+		methods = append(methods, strings.ToUpper(strings.TrimSpace(method)))
+	}
+
+	keyID, err := utils.RandomToken(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.RandomToken(24)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &model.ApiKey{
+		Name:           strings.TrimSpace(opts.Name),
+		KeyID:          keyID,
+		SecretHash:     hashApiKeySecret(secret),
+		UserID:         owner.ID,
+		TenantID:       owner.TenantID,
+		CreatedBy:      createdBy,
+		AllowedRoutes:  opts.AllowedRoutes,
+		AllowedMethods: methods,
+		AllowedIPs:     opts.AllowedIPs,
+		ExpiresAt:      opts.ExpiresAt,
+	}
+	if err := tenantDB(owner.TenantID).Create(key).Error; err != nil {
+		return nil, "", err
+	}
+	return key, apiKeyPrefix + keyID + "_" + secret, nil
+}
+
+// Authenticate 校验明文API Key及来源IP，并记录最近使用时间
+func (s *ApiKeyService) Authenticate(rawKey, ip string) (*model.ApiKey, error) {
+	parts := strings.SplitN(strings.TrimPrefix(rawKey, apiKeyPrefix), "_", 2)
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, ErrApiKeyInvalid
+	}
+
+	var key model.ApiKey
+	if err := systemDB().Where("key_id = ?", parts[0]).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApiKeyInvalid
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(hashApiKeySecret(parts[1]))) != 1 || !key.IsActive() {
+		return nil, ErrApiKeyInvalid
+	}
+	if !ipAllowed(key.AllowedIPs, ip) {
+		return nil, ErrApiKeyIPDenied
+	}
+
+	// 与会话一样限制最近使用时间的更新频率
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > sessionTouchInterval || key.LastUsedIP != ip {
+		systemDB().Model(&model.ApiKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": ip,
+		})
+	}
+	return &key, nil
+}
+
+// Authorize 检查请求是否在API Key的路由和方法范围内
+func (s *ApiKeyService) Authorize(key *model.ApiKey, method, path string) error {
+	if len(key.AllowedMethods) > 0 {
+		allowed := false
+		for _, m := range key.AllowedMethods {
+			if m == "*" || strings.EqualFold(m, method) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrApiKeyScopeDenied
+		}
+	}
+	if len(key.AllowedRoutes) > 0 {
+		for _, pattern := range key.AllowedRoutes {
+			if util.KeyMatch2(path, pattern) {
+				return nil
+			}
+		}
+		return ErrApiKeyScopeDenied
+	}
+	return nil
+}
+
+// ListKeys 获取租户内的API Key，userID为0时返回租户内全部
+func (s *ApiKeyService) ListKeys(tenantID, userID uint) ([]model.ApiKey, error) {
+	var keys []model.ApiKey
+	query := tenantDB(tenantID).Where("tenant_id = ?", tenantID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeKey 吊销API Key，ownerID不为0时只能吊销该用户自己的Key
+func (s *ApiKeyService) RevokeKey(id, tenantID, ownerID, revokedBy uint) error {
+	query := tenantDB(tenantID).Model(&model.ApiKey{}).Where("id = ? AND tenant_id = ?", id, tenantID)
+	if ownerID != 0 {
+		query = query.Where("user_id = ?", ownerID)
+	}
+	result := query.Where("revoked_at IS NULL").Updates(map[string]interface{}{
+		"revoked_at": time.Now(),
+		"revoked_by": revokedBy,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// RevokeUserKeys 吊销用户的全部API Key
+func (s *ApiKeyService) RevokeUserKeys(userID, revokedBy uint) error {
+	return systemDB().Model(&model.ApiKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"revoked_by": revokedBy,
+		}).Error
+}
+
+// CreateServiceAccount 创建服务账号，服务账号没有可用密码，只能通过API Key访问
+func (s *ApiKeyService) CreateServiceAccount(tenantID uint, username, nickname string) (*model.User, error) {
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &model.User{
+		Username: username,
+		Password: hashed,
+		Nickname: nickname,
+		Status:   1,
+		TenantID: tenantID,
+		Type:     model.UserTypeService,
+	}
+	if err := tenantDB(tenantID).Create(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// ListServiceAccounts 获取租户内的服务账号
+func (s *ApiKeyService) ListServiceAccounts(tenantID uint) ([]model.User, error) {
+	var accounts []model.User
+	err := tenantDB(tenantID).Where("tenant_id = ? AND type = ?", tenantID, model.UserTypeService).
+		Order("id DESC").Find(&accounts).Error
+	return accounts, err
+}
+
+// validIPRule IP白名单项可以是单个IP或CIDR
+func validIPRule(rule string) bool {
+	if strings.Contains(rule, "/") {
+		_, _, err := net.ParseCIDR(rule)
+		return err == nil
+	}
+	return net.ParseIP(rule) != nil
+}
+
+// ipAllowed 检查IP是否在白名单内，白名单为空时不限制
+func ipAllowed(rules []string, ip string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, rule := range rules {
+		if strings.Contains(rule, "/") {
+			if _, network, err := net.ParseCIDR(rule); err == nil && network.Contains(addr) {
+				return true
+			}
+		} else if other := net.ParseIP(rule); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
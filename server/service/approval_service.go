@@ -0,0 +1,775 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrChangeRequestNotFound 变更申请不存在
+	ErrChangeRequestNotFound = errors.New("变更申请不存在")
+	// ErrChangeRequestNotPending 变更申请已处理
+	ErrChangeRequestNotPending = errors.New("变更申请已处理")
+	// ErrChangeRequestExpired 变更申请已过期
+	ErrChangeRequestExpired = errors.New("变更申请已过期")
+	// ErrChangeRequestDuplicate 同一对象已有待审批的变更
+	ErrChangeRequestDuplicate = errors.New("该对象已有待审批的变更申请")
+	// ErrNotApprover 不是该类变更的审批人
+	ErrNotApprover = errors.New("不是该类变更的审批人")
+	// ErrSelfApproval 不能审批自己提交的变更
+	ErrSelfApproval = errors.New("不能审批自己提交的变更")
+	// ErrAlreadyApproved 已经同意过该变更
+	ErrAlreadyApproved = errors.New("已经同意过该变更申请")
+	// ErrApprovalPolicyInvalid 审批配置不合法
+	ErrApprovalPolicyInvalid = errors.New("审批配置不合法")
+)
+
+// changeTypes 支持审批的变更类型
+var changeTypes = []string{model.ChangeTypeRolePermissions, model.ChangeTypeUserRoles, model.ChangeTypeRoleParents}
+
+// ApprovalService 权限变更审批服务
+type ApprovalService struct{}
+
+// GetPolicies 获取租户的审批配置，未配置的变更类型返回未启用的默认配置
+func (s *ApprovalService) GetPolicies(tenantID uint) ([]model.ApprovalPolicy, error) {
+	policies := make([]model.ApprovalPolicy, 0, len(changeTypes))
+	for _, changeType := range changeTypes {
+		policy, err := s.policy(tenantID, changeType)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, nil
+}
+
+// policy 获取一类变更的审批配置
+func (s *ApprovalService) policy(tenantID uint, changeType string) (*model.ApprovalPolicy, error) {
+	var policy model.ApprovalPolicy
+	err := tenantDB(tenantID).Where("tenant_id = ? AND change_type = ?", tenantID, changeType).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.ApprovalPolicy{TenantID: tenantID, ChangeType: changeType, RequiredApprovals: 1, ExpireHours: 72}, nil
+	}
+	return &policy, err
+}
+
+// SavePolicy 保存审批配置，启用时审批人数必须不少于需要的同意人数
+func (s *ApprovalService) SavePolicy(tenantID uint, policy *model.ApprovalPolicy) (*model.ApprovalPolicy, error) {
+	known := false
+	for _, changeType := range changeTypes {
+		known = known || policy.ChangeType == changeType
+	}
+	if !known {
+		return nil, fmt.Errorf("%w：不支持的变更类型 %s", ErrApprovalPolicyInvalid, policy.ChangeType)
+	}
+	if policy.RequiredApprovals < 1 || policy.ExpireHours < 1 {
+		return nil, fmt.Errorf("%w：同意人数和过期时长至少为1", ErrApprovalPolicyInvalid)
+	}
+	policy.TenantID = tenantID
+	policy.ApproverUserIDs = uniqueIDs(policy.ApproverUserIDs)
+	policy.ApproverRoleIDs = uniqueIDs(policy.ApproverRoleIDs)
+	if policy.Enabled {
+		approvers, err := s.approvers(policy)
+		if err != nil {
+			return nil, err
+		}
+		if len(approvers) < policy.RequiredApprovals {
+			return nil, fmt.Errorf("%w：当前只有%d名审批人，少于需要的%d人", ErrApprovalPolicyInvalid, len(approvers), policy.RequiredApprovals)
+		}
+	}
+
+	existing, err := s.policy(tenantID, policy.ChangeType)
+	if err != nil {
+		return nil, err
+	}
+	policy.ID = existing.ID
+	if err := tenantDB(tenantID).Save(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// approvers 计算审批配置对应的全部审批人，即N-of-M中的M
+func (s *ApprovalService) approvers(policy *model.ApprovalPolicy) ([]uint, error) {
+	var ids []uint
+	if len(policy.ApproverUserIDs) > 0 {
+		if err := tenantDB(policy.TenantID).Model(&model.User{}).
+			Where("id IN ? AND tenant_id = ? AND status = 1", policy.ApproverUserIDs, policy.TenantID).
+			Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(policy.ApproverRoleIDs) > 0 {
+		var roleUsers []uint
+		if err := tenantDB(policy.TenantID).Model(&model.UserRole{}).Scopes(model.ActiveUserRoles(time.Now())).
+			Joins("JOIN users ON users.id = user_roles.user_id AND users.status = 1 AND users.deleted_at IS NULL").
+			Where("user_roles.role_id IN ? AND user_roles.tenant_id = ?", policy.ApproverRoleIDs, policy.TenantID).
+			Pluck("user_roles.user_id", &roleUsers).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, roleUsers...)
+	}
+	return uniqueIDs(ids), nil
+}
+
+// isApprover 判断用户是否为该类变更的审批人
+func (s *ApprovalService) isApprover(policy *model.ApprovalPolicy, userID uint) (bool, error) {
+	approvers, err := s.approvers(policy)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range approvers {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SubmitRolePermissions 提交角色权限变更，未启用审批或没有差异时立即生效并返回nil
+func (s *ApprovalService) SubmitRolePermissions(req *PermissionRequest, requesterID uint) (*model.ChangeRequest, error) {
+	policy, err := s.policy(req.TenantID, model.ChangeTypeRolePermissions)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, (&PermissionService{}).AssignRolePermissions(req)
+	}
+
+	var role model.Role
+	if err := tenantDB(req.TenantID).Where("id = ? AND tenant_id = ?", req.RoleID, req.TenantID).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	diff, err := rolePermissionDiff(req)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Empty() {
+		return nil, (&PermissionService{}).AssignRolePermissions(req)
+	}
+	return s.createRequest(policy, req.RoleID, role.Name, req, diff, requesterID, req.Reason)
+}
+
+// SubmitUserRoles 提交用户角色变更，未启用审批或没有差异时立即生效并返回nil
+func (s *ApprovalService) SubmitUserRoles(req *UserRoleRequest, requesterID uint) (*model.ChangeRequest, error) {
+	policy, err := s.policy(req.TenantID, model.ChangeTypeUserRoles)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, (&PermissionService{}).AssignUserRoles(req)
+	}
+	if err := checkValidity(req.Validity, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var user model.User
+	if err := tenantDB(req.TenantID).Where("id = ? AND tenant_id = ?", req.UserID, req.TenantID).First(&user).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if err := checkTenantRoles(tenantDB(req.TenantID), req.TenantID, req.RoleIDs); err != nil {
+		return nil, err
+	}
+	diff, err := userRoleDiff(req)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Empty() {
+		return nil, (&PermissionService{}).AssignUserRoles(req)
+	}
+	return s.createRequest(policy, req.UserID, user.Username, req, diff, requesterID, req.Reason)
+}
+
+// SubmitSyncedUserRoles 提交LDAP或OIDC同步的用户角色，移除的角色立即生效，启用审批时新增的角色提交审批
+// 用户已有待审批的变更时不重复提交，返回nil等待已有申请处理
+func (s *ApprovalService) SubmitSyncedUserRoles(req *UserRoleRequest) (*model.ChangeRequest, error) {
+	policy, err := s.policy(req.TenantID, model.ChangeTypeUserRoles)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, (&PermissionService{}).AssignUserRoles(req)
+	}
+
+	var current []uint
+	if err := tenantDB(req.TenantID).Model(&model.UserRole{}).Where("user_id = ? AND tenant_id = ?", req.UserID, req.TenantID).
+		Pluck("role_id", &current).Error; err != nil {
+		return nil, err
+	}
+	added, removed := diffIDs(current, req.RoleIDs)
+	if len(removed) > 0 {
+		var kept []uint
+		for _, id := range current {
+			if !containsID(removed, id) {
+				kept = append(kept, id)
+			}
+		}
+		if err := (&PermissionService{}).AssignUserRoles(&UserRoleRequest{UserID: req.UserID, RoleIDs: kept, TenantID: req.TenantID}); err != nil {
+			return nil, err
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	request, err := s.SubmitUserRoles(req, 0)
+	if errors.Is(err, ErrChangeRequestDuplicate) {
+		return nil, nil
+	}
+	return request, err
+}
+
+// RoleParentsRequest 上级角色变更
+type RoleParentsRequest struct {
+	RoleID    uint   `json:"role_id"`
+	ParentIDs []uint `json:"parent_ids"`
+	TenantID  uint   `json:"tenant_id"`
+	Reason    string `json:"reason"`
+}
+
+// SubmitRoleParents 提交上级角色变更，继承上级角色即获得其权限，未启用审批或没有差异时立即生效并返回nil
+func (s *ApprovalService) SubmitRoleParents(req *RoleParentsRequest, requesterID uint) (*model.ChangeRequest, error) {
+	policy, err := s.policy(req.TenantID, model.ChangeTypeRoleParents)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, (&RoleHierarchyService{}).SetParents(req.TenantID, req.RoleID, req.ParentIDs)
+	}
+
+	if req.ParentIDs, err = checkRoleParents(req.TenantID, req.RoleID, req.ParentIDs); err != nil {
+		return nil, err
+	}
+	var role model.Role
+	if err := tenantDB(req.TenantID).Where("id = ? AND tenant_id = ?", req.RoleID, req.TenantID).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	diff, err := roleParentsDiff(req)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Empty() {
+		return nil, nil
+	}
+	return s.createRequest(policy, req.RoleID, role.Name, req, diff, requesterID, req.Reason)
+}
+
+// SubmitMenuApis 提交操作关联API的变更，拥有该操作的角色的接口权限随之变化，按角色权限的审批配置审批
+// 未启用审批、没有角色拥有该操作或没有差异时立即生效并返回nil
+func (s *ApprovalService) SubmitMenuApis(req *MenuApisRequest, requesterID uint) (*model.ChangeRequest, error) {
+	policy, err := s.policy(req.TenantID, model.ChangeTypeRolePermissions)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, (&MenuService{}).SetMenuApis(req)
+	}
+
+	var menu model.Menu
+	if err := tenantDB(req.TenantID).First(&menu, req.MenuID).Error; err != nil {
+		return nil, ErrMenuNotFound
+	}
+	var grants int64
+	if err := systemDB().Model(&model.RoleMenu{}).Where("menu_id = ?", req.MenuID).Count(&grants).Error; err != nil {
+		return nil, err
+	}
+	diff, err := menuApisDiff(req)
+	if err != nil {
+		return nil, err
+	}
+	if grants == 0 || diff.Empty() {
+		return nil, (&MenuService{}).SetMenuApis(req)
+	}
+	menuPolicy := *policy
+	menuPolicy.ChangeType = model.ChangeTypeMenuApis
+	return s.createRequest(&menuPolicy, req.MenuID, menu.Title, req, diff, requesterID, req.Reason)
+}
+
+// policyChangeType 变更类型使用的审批配置，操作关联API的变更按角色权限的审批配置审批
+func policyChangeType(changeType string) string {
+	if changeType == model.ChangeTypeMenuApis {
+		return model.ChangeTypeRolePermissions
+	}
+	return changeType
+}
+
+// createRequest 创建待审批的变更申请
+func (s *ApprovalService) createRequest(policy *model.ApprovalPolicy, targetID uint, targetName string, payload interface{}, diff *model.ChangeDiff, requesterID uint, reason string) (*model.ChangeRequest, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	requester := approvalOperator(requesterID)
+	request := &model.ChangeRequest{
+		TenantID:          policy.TenantID,
+		ChangeType:        policy.ChangeType,
+		TargetID:          targetID,
+		TargetName:        targetName,
+		Payload:           data,
+		Diff:              *diff,
+		Reason:            strings.TrimSpace(reason),
+		Status:            model.ChangeStatusPending,
+		RequesterID:       requesterID,
+		Requester:         requester,
+		RequiredApprovals: policy.RequiredApprovals,
+		ExpiresAt:         time.Now().Add(time.Duration(policy.ExpireHours) * time.Hour),
+	}
+	err = tenantDB(policy.TenantID).Transaction(func(tx *gorm.DB) error {
+		// 同一对象的变更是整体替换，同时存在多个待审批申请时后生效的会覆盖先生效的
+		var count int64
+		if err := tx.Model(&model.ChangeRequest{}).
+			Where("tenant_id = ? AND change_type = ? AND target_id = ? AND status = ?", policy.TenantID, policy.ChangeType, targetID, model.ChangeStatusPending).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrChangeRequestDuplicate
+		}
+		if err := tx.Create(request).Error; err != nil {
+			return err
+		}
+		return addChangeEvent(tx, request.ID, model.ChangeEventCreated, requesterID, requester, request.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// rolePermissionDiff 计算角色权限变更前后的菜单和接口差异
+func rolePermissionDiff(req *PermissionRequest) (*model.ChangeDiff, error) {
+	var menuIDs, apiIDs []uint
+	if err := tenantDB(req.TenantID).Model(&model.RoleMenu{}).Where("role_id = ? AND tenant_id = ?", req.RoleID, req.TenantID).
+		Pluck("menu_id", &menuIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := tenantDB(req.TenantID).Model(&model.RoleApi{}).Where("role_id = ? AND tenant_id = ?", req.RoleID, req.TenantID).
+		Pluck("api_id", &apiIDs).Error; err != nil {
+		return nil, err
+	}
+
+	diff := &model.ChangeDiff{}
+	addedMenus, removedMenus := diffIDs(menuIDs, req.MenuIDs)
+	addedApis, removedApis := diffIDs(apiIDs, req.ApiIDs)
+	var menus []model.Menu
+	if err := tenantDB(req.TenantID).Where("id IN ?", append(addedMenus, removedMenus...)).Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	menuNames := make(map[uint]string, len(menus))
+	for _, menu := range menus {
+		menuNames[menu.ID] = menu.Title
+	}
+	var apis []model.Api
+	if err := tenantDB(req.TenantID).Where("id IN ?", append(addedApis, removedApis...)).Find(&apis).Error; err != nil {
+		return nil, err
+	}
+	apiNames := make(map[uint]string, len(apis))
+	for _, api := range apis {
+		apiNames[api.ID] = api.Method + " " + api.Path
+	}
+	diff.AddedMenus = diffItems(addedMenus, menuNames)
+	diff.RemovedMenus = diffItems(removedMenus, menuNames)
+	diff.AddedApis = diffItems(addedApis, apiNames)
+	diff.RemovedApis = diffItems(removedApis, apiNames)
+	return diff, nil
+}
+
+// menuApisDiff 计算操作关联API变更前后的接口差异
+func menuApisDiff(req *MenuApisRequest) (*model.ChangeDiff, error) {
+	var apiIDs []uint
+	if err := tenantDB(req.TenantID).Model(&model.MenuApi{}).Where("menu_id = ?", req.MenuID).Pluck("api_id", &apiIDs).Error; err != nil {
+		return nil, err
+	}
+	added, removed := diffIDs(apiIDs, req.ApiIDs)
+	var apis []model.Api
+	if err := tenantDB(req.TenantID).Where("id IN ?", append(added, removed...)).Find(&apis).Error; err != nil {
+		return nil, err
+	}
+	apiNames := make(map[uint]string, len(apis))
+	for _, api := range apis {
+		apiNames[api.ID] = api.Method + " " + api.Path
+	}
+	return &model.ChangeDiff{AddedApis: diffItems(added, apiNames), RemovedApis: diffItems(removed, apiNames)}, nil
+}
+
+// userRoleDiff 计算用户角色变更前后的差异，指定了有效期的已有角色记为有效期调整
+func userRoleDiff(req *UserRoleRequest) (*model.ChangeDiff, error) {
+	var current []uint
+	if err := tenantDB(req.TenantID).Model(&model.UserRole{}).Where("user_id = ? AND tenant_id = ?", req.UserID, req.TenantID).
+		Pluck("role_id", &current).Error; err != nil {
+		return nil, err
+	}
+	added, removed := diffIDs(current, req.RoleIDs)
+	var changed []uint
+	for _, id := range current {
+		if _, ok := req.Validity[id]; ok && containsID(req.RoleIDs, id) {
+			changed = append(changed, id)
+		}
+	}
+
+	var roles []model.Role
+	if err := tenantDB(req.TenantID).Where("id IN ? AND tenant_id = ?", append(append(append([]uint(nil), added...), removed...), changed...), req.TenantID).
+		Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(roles))
+	for _, role := range roles {
+		names[role.ID] = role.Name
+	}
+	return &model.ChangeDiff{
+		AddedRoles:   diffItems(added, names),
+		RemovedRoles: diffItems(removed, names),
+		ChangedRoles: diffItems(changed, names),
+	}, nil
+}
+
+// roleParentsDiff 计算上级角色变更前后的差异
+func roleParentsDiff(req *RoleParentsRequest) (*model.ChangeDiff, error) {
+	var current []uint
+	if err := tenantDB(req.TenantID).Model(&model.RoleInheritance{}).Where("role_id = ? AND tenant_id = ?", req.RoleID, req.TenantID).
+		Pluck("parent_id", &current).Error; err != nil {
+		return nil, err
+	}
+	added, removed := diffIDs(current, req.ParentIDs)
+	var roles []model.Role
+	if err := tenantDB(req.TenantID).Where("id IN ? AND tenant_id = ?", append(append([]uint(nil), added...), removed...), req.TenantID).
+		Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(roles))
+	for _, role := range roles {
+		names[role.ID] = role.Name
+	}
+	return &model.ChangeDiff{
+		AddedRoles:   diffItems(added, names),
+		RemovedRoles: diffItems(removed, names),
+	}, nil
+}
+
+// diffIDs 返回target相对current新增和删除的ID
+func diffIDs(current, target []uint) (added, removed []uint) {
+	for _, id := range uniqueIDs(target) {
+		if !containsID(current, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range uniqueIDs(current) {
+		if !containsID(target, id) {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// diffItems 按ID生成差异项，找不到名称时名称为空
+func diffItems(ids []uint, names map[uint]string) []model.ChangeDiffItem {
+	var items []model.ChangeDiffItem
+	for _, id := range ids {
+		items = append(items, model.ChangeDiffItem{ID: id, Name: names[id]})
+	}
+	return items
+}
+
+// ListRequests 分页查询变更申请，requesterID不为0时只查询该用户提交的申请
+func (s *ApprovalService) ListRequests(tenantID, requesterID uint, changeType, status string, page, pageSize int) ([]model.ChangeRequest, int64, error) {
+	db := tenantDB(tenantID).Model(&model.ChangeRequest{}).Where("tenant_id = ?", tenantID)
+	if requesterID != 0 {
+		db = db.Where("requester_id = ?", requesterID)
+	}
+	if changeType != "" {
+		db = db.Where("change_type = ?", changeType)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var requests []model.ChangeRequest
+	err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	return requests, total, err
+}
+
+// GetRequest 获取变更申请及其完整历史
+func (s *ApprovalService) GetRequest(tenantID, id uint) (*model.ChangeRequest, error) {
+	var request model.ChangeRequest
+	err := tenantDB(tenantID).Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("id = ? AND tenant_id = ?", id, tenantID).First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChangeRequestNotFound
+	}
+	return &request, err
+}
+
+// lockPendingRequest 锁定待审批的申请，已超时的申请在此标记为过期
+func lockPendingRequest(tx *gorm.DB, tenantID, id uint) (*model.ChangeRequest, error) {
+	var request model.ChangeRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChangeRequestNotFound
+		}
+		return nil, err
+	}
+	if request.Status != model.ChangeStatusPending {
+		return nil, ErrChangeRequestNotPending
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return &request, ErrChangeRequestExpired
+	}
+	return &request, nil
+}
+
+// Approve 同意变更申请，同意人数达到要求时写入变更
+func (s *ApprovalService) Approve(tenantID, id, approverID uint, comment string) (*model.ChangeRequest, error) {
+	approver := approvalOperator(approverID)
+	var request *model.ChangeRequest
+	reached := false
+	err := tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if request, err = s.checkApprover(tx, tenantID, id, approverID); err != nil {
+			return err
+		}
+		if request.RequesterID == approverID {
+			return ErrSelfApproval
+		}
+		var count int64
+		if err := tx.Model(&model.ChangeRequestEvent{}).
+			Where("request_id = ? AND operator_id = ? AND action = ?", id, approverID, model.ChangeEventApproved).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyApproved
+		}
+
+		request.Approvals++
+		reached = request.Approvals >= request.RequiredApprovals
+		updates := map[string]interface{}{"approvals": request.Approvals}
+		if reached {
+			// 在事务内结束待审批状态，并发的审批、拒绝、撤回和过期处理都不会再修改该申请
+			updates["status"] = model.ChangeStatusApplying
+		}
+		result := tx.Model(&model.ChangeRequest{}).Where("id = ? AND status = ?", id, model.ChangeStatusPending).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChangeRequestNotPending
+		}
+		if reached {
+			request.Status = model.ChangeStatusApplying
+		}
+		return addChangeEvent(tx, id, model.ChangeEventApproved, approverID, approver, comment)
+	})
+	if err != nil {
+		return nil, s.expireOnError(request, err)
+	}
+	if reached {
+		s.apply(request, approverID, approver)
+	}
+	return s.GetRequest(tenantID, id)
+}
+
+// apply 写入已通过审批的变更，失败时记录原因，调用前申请已在审批事务中标记为写入中
+func (s *ApprovalService) apply(request *model.ChangeRequest, operatorID uint, operator string) {
+	var err error
+	switch request.ChangeType {
+	case model.ChangeTypeRolePermissions:
+		var req PermissionRequest
+		if err = json.Unmarshal(request.Payload, &req); err == nil {
+			err = (&PermissionService{}).AssignRolePermissions(&req)
+		}
+	case model.ChangeTypeUserRoles:
+		var req UserRoleRequest
+		if err = json.Unmarshal(request.Payload, &req); err == nil {
+			err = (&PermissionService{}).AssignUserRoles(&req)
+		}
+	case model.ChangeTypeRoleParents:
+		var req RoleParentsRequest
+		if err = json.Unmarshal(request.Payload, &req); err == nil {
+			err = (&RoleHierarchyService{}).SetParents(req.TenantID, req.RoleID, req.ParentIDs)
+		}
+	case model.ChangeTypeMenuApis:
+		var req MenuApisRequest
+		if err = json.Unmarshal(request.Payload, &req); err == nil {
+			err = (&MenuService{}).SetMenuApis(&req)
+		}
+	default:
+		err = fmt.Errorf("不支持的变更类型 %s", request.ChangeType)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": model.ChangeStatusApplied, "resolved_at": now}
+	action, comment := model.ChangeEventApplied, ""
+	if err != nil {
+		comment = truncateRunes(err.Error(), 255)
+		updates["status"], updates["error"] = model.ChangeStatusFailed, comment
+		action = model.ChangeEventFailed
+	}
+	request.Status = updates["status"].(string)
+	if dbErr := tenantDB(request.TenantID).Model(&model.ChangeRequest{}).Where("id = ? AND status = ?", request.ID, model.ChangeStatusApplying).
+		Updates(updates).Error; dbErr != nil {
+		fmt.Printf("更新变更申请%d状态失败: %v\n", request.ID, dbErr)
+	}
+	if dbErr := addChangeEvent(tenantDB(request.TenantID), request.ID, action, operatorID, operator, comment); dbErr != nil {
+		fmt.Printf("记录变更申请%d历史失败: %v\n", request.ID, dbErr)
+	}
+}
+
+// Reject 拒绝变更申请，任一审批人拒绝即结束
+func (s *ApprovalService) Reject(tenantID, id, approverID uint, comment string) (*model.ChangeRequest, error) {
+	approver := approvalOperator(approverID)
+	var request *model.ChangeRequest
+	err := tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if request, err = s.checkApprover(tx, tenantID, id, approverID); err != nil {
+			return err
+		}
+		return resolveRequest(tx, request, model.ChangeStatusRejected, model.ChangeEventRejected, approverID, approver, comment)
+	})
+	if err != nil {
+		return nil, s.expireOnError(request, err)
+	}
+	return s.GetRequest(tenantID, id)
+}
+
+// Cancel 申请人撤回待审批的申请
+func (s *ApprovalService) Cancel(tenantID, id, requesterID uint) (*model.ChangeRequest, error) {
+	err := tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		request, err := lockPendingRequest(tx, tenantID, id)
+		if err != nil && !errors.Is(err, ErrChangeRequestExpired) {
+			return err
+		}
+		if request.RequesterID != requesterID {
+			return ErrChangeRequestNotFound
+		}
+		return resolveRequest(tx, request, model.ChangeStatusCancelled, model.ChangeEventCancelled, requesterID, request.Requester, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRequest(tenantID, id)
+}
+
+// checkApprover 锁定待审批的申请并检查审批人资格
+func (s *ApprovalService) checkApprover(tx *gorm.DB, tenantID, id, approverID uint) (*model.ChangeRequest, error) {
+	request, err := lockPendingRequest(tx, tenantID, id)
+	if err != nil {
+		return request, err
+	}
+	policy, err := s.policy(tenantID, policyChangeType(request.ChangeType))
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.isApprover(policy, approverID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotApprover
+	}
+	return request, nil
+}
+
+// expireOnError 审批时发现申请已超时，则把申请标记为过期
+func (s *ApprovalService) expireOnError(request *model.ChangeRequest, err error) error {
+	if errors.Is(err, ErrChangeRequestExpired) && request != nil {
+		if dbErr := tenantDB(request.TenantID).Transaction(func(tx *gorm.DB) error {
+			return resolveRequest(tx, request, model.ChangeStatusExpired, model.ChangeEventExpired, 0, "system", "")
+		}); dbErr != nil {
+			fmt.Printf("标记变更申请%d过期失败: %v\n", request.ID, dbErr)
+		}
+	}
+	return err
+}
+
+// ExpireRequests 把各租户超时未审批的申请标记为过期
+func (s *ApprovalService) ExpireRequests(now time.Time) error {
+	var requests []model.ChangeRequest
+	if err := systemDB().Where("status = ? AND expires_at <= ?", model.ChangeStatusPending, now).Find(&requests).Error; err != nil {
+		return err
+	}
+	for i := range requests {
+		if err := tenantDB(requests[i].TenantID).Transaction(func(tx *gorm.DB) error {
+			return resolveRequest(tx, &requests[i], model.ChangeStatusExpired, model.ChangeEventExpired, 0, "system", "")
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartChangeRequestExpirer 启动后台任务，每分钟把超时未审批的变更申请标记为过期
+func StartChangeRequestExpirer() {
+	s := &ApprovalService{}
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := s.ExpireRequests(now); err != nil {
+				fmt.Printf("处理变更申请过期失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// resolveRequest 结束待审批的申请并记录历史，状态已变化时不做修改
+func resolveRequest(tx *gorm.DB, request *model.ChangeRequest, status, action string, operatorID uint, operator, comment string) error {
+	now := time.Now()
+	result := tx.Model(&model.ChangeRequest{}).Where("id = ? AND status = ?", request.ID, model.ChangeStatusPending).
+		Updates(map[string]interface{}{"status": status, "resolved_at": now})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	request.Status, request.ResolvedAt = status, &now
+	return addChangeEvent(tx, request.ID, action, operatorID, operator, comment)
+}
+
+// addChangeEvent 记录变更申请的历史事件
+func addChangeEvent(tx *gorm.DB, requestID uint, action string, operatorID uint, operator, comment string) error {
+	return tx.Create(&model.ChangeRequestEvent{
+		RequestID:  requestID,
+		Action:     action,
+		OperatorID: operatorID,
+		Operator:   operator,
+		Comment:    truncateRunes(strings.TrimSpace(comment), 255),
+	}).Error
+}
+
+// approvalOperator 获取操作人的用户名，0为系统同步；操作人可能是管理其他租户的平台用户，查询时不限定租户
+func approvalOperator(userID uint) string {
+	if userID == 0 {
+		return "system"
+	}
+	var user model.User
+	if err := systemDB().Select("username").First(&user, userID).Error; err != nil {
+		return ""
+	}
+	return user.Username
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+)
+
+func TestDiffIDs(t *testing.T) {
+	tests := []struct {
+		name           string
+		current        []uint
+		target         []uint
+		added, removed []uint
+	}{
+		{"没有变化", []uint{1, 2}, []uint{2, 1}, nil, nil},
+		{"新增和删除", []uint{1, 2}, []uint{2, 3}, []uint{3}, []uint{1}},
+		{"清空", []uint{1, 2}, nil, nil, []uint{1, 2}},
+		{"忽略重复和0", nil, []uint{3, 0, 3}, []uint{3}, nil},
+	}
+	for _, tt := range tests {
+		added, removed := diffIDs(tt.current, tt.target)
+		if !reflect.DeepEqual(added, tt.added) || !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("%s: diffIDs = %v, %v，期望 %v, %v", tt.name, added, removed, tt.added, tt.removed)
+		}
+	}
+}
+
+func TestChangeDiffEmpty(t *testing.T) {
+	diff := model.ChangeDiff{}
+	if !diff.Empty() {
+		t.Error("没有差异时应为空")
+	}
+	diff.ChangedRoles = diffItems([]uint{2}, map[uint]string{2: "editor"})
+	if diff.Empty() {
+		t.Error("调整有效期也是差异")
+	}
+	if diff.ChangedRoles[0].Name != "editor" {
+		t.Errorf("差异项名称 = %q，期望 editor", diff.ChangedRoles[0].Name)
+	}
+}
+
+// seedApproval 准备租户1的用户和角色，启用指定变更类型的审批，审批人为用户2、3、4
+func seedApproval(t *testing.T, changeType string, required int) {
+	t.Helper()
+	db := useTestDB(t, &model.User{}, &model.Role{}, &model.UserRole{}, &model.RoleInheritance{},
+		&model.ApprovalPolicy{}, &model.ChangeRequest{}, &model.ChangeRequestEvent{})
+	useTestEnforcer(t)
+	for id := uint(1); id <= 4; id++ {
+		if err := db.Create(&model.User{ID: id, Username: fmt.Sprintf("user%d", id), TenantID: 1, Status: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := uint(1); id <= 3; id++ {
+		if err := db.Create(&model.Role{ID: id, Name: fmt.Sprintf("角色%d", id), TenantID: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := &model.ApprovalPolicy{ChangeType: changeType, Enabled: true, ApproverUserIDs: []uint{2, 3, 4}, RequiredApprovals: required, ExpireHours: 1}
+	if _, err := (&ApprovalService{}).SavePolicy(1, policy); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// submitUserRoles 由用户2提交为用户1分配角色的申请
+func submitUserRoles(t *testing.T, roleIDs ...uint) *model.ChangeRequest {
+	t.Helper()
+	request, err := (&ApprovalService{}).SubmitUserRoles(&UserRoleRequest{UserID: 1, RoleIDs: roleIDs, TenantID: 1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request == nil {
+		t.Fatal("启用审批时应提交变更申请")
+	}
+	return request
+}
+
+func TestApproveNOfM(t *testing.T) {
+	seedApproval(t, model.ChangeTypeUserRoles, 2)
+	s := &ApprovalService{}
+	request := submitUserRoles(t, 1)
+
+	got, err := s.Approve(1, request.ID, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.ChangeStatusPending || got.Approvals != 1 {
+		t.Fatalf("第一人同意后 status = %s，approvals = %d，期望 pending、1", got.Status, got.Approvals)
+	}
+	if roles, _ := userRoleIDs(1, 1); len(roles) != 0 {
+		t.Fatalf("同意人数不足时不应生效，角色 = %v", roles)
+	}
+	if _, err := s.Approve(1, request.ID, 3, ""); !errors.Is(err, ErrAlreadyApproved) {
+		t.Errorf("重复同意: err = %v，期望 %v", err, ErrAlreadyApproved)
+	}
+	if _, err := s.Approve(1, request.ID, 1, ""); !errors.Is(err, ErrNotApprover) {
+		t.Errorf("非审批人同意: err = %v，期望 %v", err, ErrNotApprover)
+	}
+
+	if got, err = s.Approve(1, request.ID, 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.ChangeStatusApplied || got.Approvals != 2 {
+		t.Fatalf("第二人同意后 status = %s，approvals = %d，期望 applied、2", got.Status, got.Approvals)
+	}
+	if roles, _ := userRoleIDs(1, 1); !reflect.DeepEqual(roles, []uint{1}) {
+		t.Errorf("审批通过后角色 = %v，期望 [1]", roles)
+	}
+	if allowed, _ := global.Enforcer.HasGroupingPolicy("1", "role_1", "1"); !allowed {
+		t.Error("审批通过后应写入Casbin分组策略")
+	}
+	if _, err := s.Approve(1, request.ID, 2, ""); !errors.Is(err, ErrChangeRequestNotPending) {
+		t.Errorf("已生效的申请: err = %v，期望 %v", err, ErrChangeRequestNotPending)
+	}
+}
+
+func TestApproveRejectsSelfApproval(t *testing.T) {
+	seedApproval(t, model.ChangeTypeUserRoles, 1)
+	request := submitUserRoles(t, 1)
+
+	if _, err := (&ApprovalService{}).Approve(1, request.ID, 2, ""); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("err = %v，期望 %v", err, ErrSelfApproval)
+	}
+	got, err := (&ApprovalService{}).GetRequest(1, request.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.ChangeStatusPending || got.Approvals != 0 {
+		t.Errorf("status = %s，approvals = %d，期望 pending、0", got.Status, got.Approvals)
+	}
+}
+
+// TestApproveSkipsApplyingRequest 达到同意人数的申请在审批事务内结束待审批状态，其他审批操作不能再修改
+func TestApproveSkipsApplyingRequest(t *testing.T) {
+	seedApproval(t, model.ChangeTypeUserRoles, 1)
+	s := &ApprovalService{}
+	request := submitUserRoles(t, 1)
+	if err := systemDB().Model(request).Update("status", model.ChangeStatusApplying).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Approve(1, request.ID, 3, ""); !errors.Is(err, ErrChangeRequestNotPending) {
+		t.Errorf("Approve: err = %v，期望 %v", err, ErrChangeRequestNotPending)
+	}
+	if _, err := s.Reject(1, request.ID, 3, ""); !errors.Is(err, ErrChangeRequestNotPending) {
+		t.Errorf("Reject: err = %v，期望 %v", err, ErrChangeRequestNotPending)
+	}
+	if roles, _ := userRoleIDs(1, 1); len(roles) != 0 {
+		t.Errorf("不应重复写入变更，角色 = %v", roles)
+	}
+}
+
+func TestChangeRequestExpiry(t *testing.T) {
+	seedApproval(t, model.ChangeTypeUserRoles, 1)
+	s := &ApprovalService{}
+	late := submitUserRoles(t, 1)
+	past := time.Now().Add(-time.Minute)
+	if err := systemDB().Model(late).Update("expires_at", past).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Approve(1, late.ID, 3, ""); !errors.Is(err, ErrChangeRequestExpired) {
+		t.Fatalf("err = %v，期望 %v", err, ErrChangeRequestExpired)
+	}
+	got, err := s.GetRequest(1, late.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.ChangeStatusExpired {
+		t.Errorf("超时后审批 status = %s，期望 expired", got.Status)
+	}
+	if roles, _ := userRoleIDs(1, 1); len(roles) != 0 {
+		t.Errorf("过期的申请不应生效，角色 = %v", roles)
+	}
+
+	pending := submitUserRoles(t, 2)
+	if err := s.ExpireRequests(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetRequest(1, pending.ID); got.Status != model.ChangeStatusPending {
+		t.Errorf("未超时的申请 status = %s，期望 pending", got.Status)
+	}
+	if err := s.ExpireRequests(pending.ExpiresAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetRequest(1, pending.ID); got.Status != model.ChangeStatusExpired {
+		t.Errorf("后台任务处理后 status = %s，期望 expired", got.Status)
+	}
+}
+
+// TestSubmitSyncedUserRoles 同步的角色移除立即生效，新增的角色需要审批
+func TestSubmitSyncedUserRoles(t *testing.T) {
+	seedApproval(t, model.ChangeTypeUserRoles, 1)
+	s := &ApprovalService{}
+	if err := (&PermissionService{}).AssignUserRoles(&UserRoleRequest{UserID: 1, RoleIDs: []uint{1, 2}, TenantID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := s.SubmitSyncedUserRoles(&UserRoleRequest{UserID: 1, RoleIDs: []uint{2, 3}, TenantID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request == nil || request.RequesterID != 0 || request.Requester != "system" {
+		t.Fatalf("request = %+v，期望由系统提交的变更申请", request)
+	}
+	if roles, _ := userRoleIDs(1, 1); !reflect.DeepEqual(roles, []uint{2}) {
+		t.Errorf("角色 = %v，期望移除的角色1立即生效、新增的角色3待审批", roles)
+	}
+
+	// 已有待审批申请时再次同步不重复提交
+	if request, err := s.SubmitSyncedUserRoles(&UserRoleRequest{UserID: 1, RoleIDs: []uint{2, 3}, TenantID: 1}); err != nil || request != nil {
+		t.Errorf("重复同步: request = %v，err = %v，期望都为nil", request, err)
+	}
+}
+
+// TestSubmitRoleParents 继承上级角色会获得其权限，启用审批时需要审批后生效
+func TestSubmitRoleParents(t *testing.T) {
+	seedApproval(t, model.ChangeTypeRoleParents, 1)
+	s := &ApprovalService{}
+
+	if _, err := s.SubmitRoleParents(&RoleParentsRequest{RoleID: 1, ParentIDs: []uint{1}, TenantID: 1}, 2); !errors.Is(err, ErrRoleCycle) {
+		t.Errorf("继承自身: err = %v，期望 %v", err, ErrRoleCycle)
+	}
+	request, err := s.SubmitRoleParents(&RoleParentsRequest{RoleID: 1, ParentIDs: []uint{2}, TenantID: 1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request == nil || len(request.Diff.AddedRoles) != 1 || request.Diff.AddedRoles[0].Name != "角色2" {
+		t.Fatalf("request = %+v，期望新增上级角色2的变更申请", request)
+	}
+	if parents, _ := roleParents(1); len(parents[1]) != 0 {
+		t.Fatalf("审批前不应生效，上级角色 = %v", parents[1])
+	}
+
+	if _, err := s.Approve(1, request.ID, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+	if parents, _ := roleParents(1); !reflect.DeepEqual(parents[1], []uint{2}) {
+		t.Errorf("审批后上级角色 = %v，期望 [2]", parents[1])
+	}
+}
+
+// TestSubmitMenuApis 修改操作关联的API会改变拥有该操作的角色的接口权限，启用角色权限审批时需要审批后生效
+func TestSubmitMenuApis(t *testing.T) {
+	seedApproval(t, model.ChangeTypeRolePermissions, 1)
+	db := systemDB()
+	if err := db.AutoMigrate(&model.Menu{}, &model.MenuApi{}, &model.Api{}, &model.RoleMenu{}, &model.RoleApi{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []interface{}{
+		&model.Menu{ID: 1, Name: "orders", Title: "订单", Type: model.MenuTypeMenu, Status: 1, TenantID: 1},
+		&model.Menu{ID: 2, Name: "export", Title: "导出订单", Type: model.MenuTypeAction, Code: "order:export", ParentID: 1, Status: 1, TenantID: 1},
+		&model.Api{ID: 1, Path: "/api/v1/orders/export", Method: "GET", Status: 1, TenantID: 1},
+		&model.Api{ID: 2, Path: "/api/v1/orders/export", Method: "GET", Status: 1, TenantID: 2},
+		&model.RoleMenu{RoleID: 1, MenuID: 2, TenantID: 1},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := global.WithTenant(context.Background(), 1)
+	menus, s := &MenuService{}, &ApprovalService{}
+
+	if _, err := menus.UpdateMenu(ctx, 2, &model.Menu{ApiIDs: []uint{2}}, 2); !errors.Is(err, ErrMenuApiInvalid) {
+		t.Errorf("关联其他租户的API: err = %v，期望 %v", err, ErrMenuApiInvalid)
+	}
+	request, err := menus.UpdateMenu(ctx, 2, &model.Menu{ApiIDs: []uint{1}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request == nil || request.ChangeType != model.ChangeTypeMenuApis || len(request.Diff.AddedApis) != 1 {
+		t.Fatalf("request = %+v，期望新增API的变更申请", request)
+	}
+	if allowed, _ := global.Enforcer.HasPolicy("role_1", "/api/v1/orders/export", "GET", "1"); allowed {
+		t.Fatal("审批前不应写入角色的接口策略")
+	}
+
+	if _, err := s.Approve(1, request.ID, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _ := global.Enforcer.HasPolicy("role_1", "/api/v1/orders/export", "GET", "1"); !allowed {
+		t.Error("审批后应写入角色的接口策略")
+	}
+}
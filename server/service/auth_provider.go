@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrAuthProviderUnknown 租户配置了未注册的身份验证方式
+	ErrAuthProviderUnknown = errors.New("未知的身份验证方式")
+)
+
+// AuthProvider 身份验证方式，验证通过时返回本地用户
+// 用户名或密码错误时返回ErrInvalidCredentials，其他错误表示验证服务不可用
+type AuthProvider interface {
+	Authenticate(ctx context.Context, tenantID uint, username, password string) (*model.User, error)
+}
+
+// authProviders 已注册的身份验证方式，键与Tenant.AuthProvider对应
+var authProviders = map[string]AuthProvider{
+	model.AuthProviderLocal: &LocalAuthProvider{},
+	model.AuthProviderLDAP:  &LdapAuthProvider{},
+}
+
+// RegisterAuthProvider 注册身份验证方式，需要在启动服务前调用
+func RegisterAuthProvider(name string, provider AuthProvider) {
+	authProviders[name] = provider
+}
+
+// AuthService 按租户选择身份验证方式
+type AuthService struct{}
+
+// Authenticate 验证用户名和密码
+// 已存在的用户使用其所属租户的验证方式；不存在的用户只能通过开启自动创建的LDAP租户登录，
+// tenantID不为0时只尝试该租户
+func (s *AuthService) Authenticate(ctx context.Context, tenantID uint, username, password string) (*model.User, error) {
+	var user model.User
+	err := systemDB().Where("username = ?", username).First(&user).Error
+	if err == nil {
+		if tenantID != 0 && user.TenantID != tenantID {
+			return nil, ErrInvalidCredentials
+		}
+		provider, err := s.providerFor(&user)
+		if err != nil {
+			return nil, err
+		}
+		return provider.Authenticate(ctx, user.TenantID, username, password)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if tenantID != 0 {
+		var tenant model.Tenant
+		if err := systemDB().Where("id = ?", tenantID).First(&tenant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrInvalidCredentials
+			}
+			return nil, err
+		}
+		if tenant.AuthProvider != model.AuthProviderLDAP {
+			return nil, ErrInvalidCredentials
+		}
+		return authProviders[model.AuthProviderLDAP].Authenticate(ctx, tenantID, username, password)
+	}
+
+	// 未指定租户时依次尝试开启自动创建的LDAP租户，单个目录不可用不影响其他租户
+	var tenantIDs []uint
+	if err := systemDB().Model(&model.LdapConfig{}).
+		Joins("JOIN tenants ON tenants.id = ldap_configs.tenant_id AND tenants.deleted_at IS NULL").
+		Where("tenants.auth_provider = ? AND ldap_configs.auto_provision = ?", model.AuthProviderLDAP, true).
+		Order("ldap_configs.tenant_id").Pluck("ldap_configs.tenant_id", &tenantIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range tenantIDs {
+		user, err := authProviders[model.AuthProviderLDAP].Authenticate(ctx, id, username, password)
+		if err == nil || errors.Is(err, ErrLdapUserNotProvisioned) {
+			return user, err
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			fmt.Printf("租户%d的LDAP验证失败: %v\n", id, err)
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// providerFor 返回用户所属租户的验证方式
+// 租户管理员始终可以使用本地密码登录，避免目录配置错误时无法进入系统修改配置
+func (s *AuthService) providerFor(user *model.User) (AuthProvider, error) {
+	var tenant model.Tenant
+	if err := systemDB().Where("id = ?", user.TenantID).First(&tenant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return authProviders[model.AuthProviderLocal], nil
+		}
+		return nil, err
+	}
+	name := tenant.AuthProvider
+	if name == "" || tenant.AdminUserID == user.ID {
+		name = model.AuthProviderLocal
+	}
+	provider, ok := authProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAuthProviderUnknown, name)
+	}
+	return provider, nil
+}
+
+// LocalAuthProvider 使用本地数据库中的密码哈希验证
+type LocalAuthProvider struct{}
+
+// Authenticate 校验本地密码，明文或弱哈希密码在验证通过后自动升级
+func (p *LocalAuthProvider) Authenticate(ctx context.Context, tenantID uint, username, password string) (*model.User, error) {
+	var user model.User
+	if err := tenantDB(tenantID).Where("username = ? AND tenant_id = ?", username, tenantID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	ok, needsRehash := utils.VerifyPassword(user.Password, password)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		if hashed, err := utils.HashPassword(password); err != nil {
+			fmt.Printf("密码重新哈希失败: %v\n", err)
+		} else if err := tenantDB(tenantID).Model(&model.User{}).Where("id = ?", user.ID).Update("password", hashed).Error; err != nil {
+			fmt.Printf("更新用户密码哈希失败: %v\n", err)
+		}
+	}
+	return &user, nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTenantDisabled 租户已被禁用
+	ErrTenantDisabled = errors.New("租户已被禁用")
+	// ErrTokenRevoked 令牌签发早于用户的令牌生效时间
+	ErrTokenRevoked = errors.New("登录状态已失效，请重新登录")
+)
+
+// AuthStateService 认证时的用户与租户状态检查
+// 状态会被缓存，修改用户或租户状态后需要调用对应的Invalidate方法
+type AuthStateService struct{}
+
+// userAuthState 缓存的用户认证状态
+type userAuthState struct {
+	Exists          bool  `json:"exists"`
+	Status          int   `json:"status"`
+	TenantID        uint  `json:"tenant_id"`
+	TokenValidAfter int64 `json:"token_valid_after"` // Unix秒，早于该时间签发的令牌无效
+}
+
+// tenantAuthState 缓存的租户认证状态
+type tenantAuthState struct {
+	Exists bool `json:"exists"`
+	Status int  `json:"status"`
+}
+
+const (
+	authStateUserPrefix   = "auth:state:user:"
+	authStateTenantPrefix = "auth:state:tenant:"
+)
+
+// authStateTTL 认证状态缓存时间
+func authStateTTL() time.Duration {
+	return time.Duration(global.GlobalConfig.Jwt.StateCacheTTL) * time.Second
+}
+
+// CheckLogin 登录时检查用户和租户状态
+func (s *AuthStateService) CheckLogin(user *model.User) error {
+	if user.Type == model.UserTypeService {
+		return ErrServiceAccountLogin
+	}
+	if user.Status == 2 {
+		return ErrUserDisabled
+	}
+	tenant, err := s.tenantState(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if tenant.Exists && tenant.Status == 2 {
+		return ErrTenantDisabled
+	}
+	return nil
+}
+
+// CheckAccess 每次请求时检查用户状态、租户状态以及令牌签发时间
+func (s *AuthStateService) CheckAccess(userID uint, issuedAt time.Time) error {
+	user, err := s.userState(userID)
+	if err != nil {
+		return err
+	}
+	if !user.Exists {
+		return ErrTokenRevoked
+	}
+	if user.Status == 2 {
+		return ErrUserDisabled
+	}
+	if user.TokenValidAfter > 0 && issuedAt.Unix() < user.TokenValidAfter {
+		return ErrTokenRevoked
+	}
+
+	tenant, err := s.tenantState(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if tenant.Exists && tenant.Status == 2 {
+		return ErrTenantDisabled
+	}
+	return nil
+}
+
+// RevokeUserTokens 使用户此前签发的所有令牌失效
+func (s *AuthStateService) RevokeUserTokens(userID uint) error {
+	// JWT的签发时间精确到秒，截断后保证之后重新登录签发的令牌有效
+	now := time.Now().Truncate(time.Second)
+	if err := systemDB().Model(&model.User{}).Where("id = ?", userID).Update("token_valid_after", now).Error; err != nil {
+		return err
+	}
+	return s.InvalidateUser(userID)
+}
+
+// InvalidateUser 清除用户认证状态缓存
+func (s *AuthStateService) InvalidateUser(userID uint) error {
+	return getAuthStateCache().del(authStateUserPrefix + strconv.FormatUint(uint64(userID), 10))
+}
+
+// InvalidateTenant 清除租户认证状态缓存
+func (s *AuthStateService) InvalidateTenant(tenantID uint) error {
+	return getAuthStateCache().del(authStateTenantPrefix + strconv.FormatUint(uint64(tenantID), 10))
+}
+
+// userState 获取用户认证状态，优先读取缓存
+func (s *AuthStateService) userState(userID uint) (*userAuthState, error) {
+	cache := getAuthStateCache()
+	key := authStateUserPrefix + strconv.FormatUint(uint64(userID), 10)
+
+	state := &userAuthState{}
+	if ok, err := cache.get(key, state); err != nil || ok {
+		return state, err
+	}
+
+	var user model.User
+	err := systemDB().Select("id", "status", "tenant_id", "token_valid_after").First(&user, userID).Error
+	switch {
+	case err == nil:
+		state.Exists = true
+		state.Status = user.Status
+		state.TenantID = user.TenantID
+		if user.TokenValidAfter != nil {
+			state.TokenValidAfter = user.TokenValidAfter.Unix()
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	return state, cache.set(key, state, authStateTTL())
+}
+
+// tenantState 获取租户认证状态，优先读取缓存
+func (s *AuthStateService) tenantState(tenantID uint) (*tenantAuthState, error) {
+	cache := getAuthStateCache()
+	key := authStateTenantPrefix + strconv.FormatUint(uint64(tenantID), 10)
+
+	state := &tenantAuthState{}
+	if ok, err := cache.get(key, state); err != nil || ok {
+		return state, err
+	}
+
+	// 已删除等待清除的租户视为停用
+	var tenant model.Tenant
+	err := systemDB().Unscoped().Select("id", "status", "deleted_at").First(&tenant, tenantID).Error
+	switch {
+	case err == nil:
+		state.Exists = true
+		state.Status = tenant.Status
+		if tenant.DeletedAt.Valid {
+			state.Status = model.TenantStatusSuspended
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	return state, cache.set(key, state, authStateTTL())
+}
+
+// authStateCache 认证状态缓存，配置了Redis时多实例共享，否则为进程内缓存
+type authStateCache interface {
+	get(key string, dst interface{}) (bool, error)
+	set(key string, value interface{}, ttl time.Duration) error
+	del(key string) error
+}
+
+var memoryAuthStateCache = &memoryStateCache{items: make(map[string]memoryStateItem)}
+
+func getAuthStateCache() authStateCache {
+	if global.RedisClient != nil {
+		return &redisStateCache{client: global.RedisClient}
+	}
+	return memoryAuthStateCache
+}
+
+type memoryStateItem struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// memoryStateCache 进程内缓存
+type memoryStateCache struct {
+	mu    sync.Mutex
+	items map[string]memoryStateItem
+}
+
+func (m *memoryStateCache) get(key string, dst interface{}) (bool, error) {
+	m.mu.Lock()
+	item, ok := m.items[key]
+	if ok && time.Now().After(item.expiresAt) {
+		delete(m.items, key)
+		ok = false
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(item.data, dst)
+}
+
+func (m *memoryStateCache) set(key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, item := range m.items {
+		if now.After(item.expiresAt) {
+			delete(m.items, k)
+		}
+	}
+	m.items[key] = memoryStateItem{data: data, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *memoryStateCache) del(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+// redisStateCache 基于Redis的缓存
+type redisStateCache struct {
+	client *redis.Client
+}
+
+func (r *redisStateCache) get(key string, dst interface{}) (bool, error) {
+	data, err := r.client.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, dst)
+}
+
+func (r *redisStateCache) set(key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), key, data, ttl).Err()
+}
+
+func (r *redisStateCache) del(key string) error {
+	return r.client.Del(context.Background(), key).Err()
+}
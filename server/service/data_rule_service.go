@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrDataRuleNotFound 数据规则不存在
+var ErrDataRuleNotFound = errors.New("数据规则不存在")
+
+// DataScope 当前用户对数据表的数据范围，Clause为空表示不限制
+type DataScope struct {
+	UserID   uint
+	DeptID   uint
+	TenantID uint
+	Clause   string
+	Args     []interface{}
+}
+
+// tenantContext 返回按数据范围的租户隔离的上下文，scope为nil时没有绑定租户，访问租户数据会被拒绝
+func (s *DataScope) tenantContext() context.Context {
+	if s == nil {
+		return context.Background()
+	}
+	return global.WithTenant(context.Background(), s.TenantID)
+}
+
+// apply 把数据范围附加到查询条件上
+func (s *DataScope) apply(db *gorm.DB) *gorm.DB {
+	if s == nil || s.Clause == "" {
+		return db
+	}
+	return db.Where(s.Clause, s.Args...)
+}
+
+// DataRuleService 行级数据规则服务
+type DataRuleService struct{}
+
+// ListRules 获取租户内数据表的数据规则，tableID为0时返回全部
+func (s *DataRuleService) ListRules(tenantID, tableID uint) ([]model.DataRule, error) {
+	query := tenantDB(tenantID).Where("tenant_id = ?", tenantID)
+	if tableID != 0 {
+		query = query.Where("table_id IN ?", []uint{0, tableID})
+	}
+	var rules []model.DataRule
+	err := query.Order("table_id, role_id, id").Find(&rules).Error
+	return rules, err
+}
+
+// SaveRule 创建或更新数据规则
+func (s *DataRuleService) SaveRule(tenantID uint, rule *model.DataRule) error {
+	var role model.Role
+	if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", rule.RoleID, tenantID).First(&role).Error; err != nil {
+		return errors.New("角色不存在")
+	}
+	if rule.TableID != 0 {
+		var table model.DynamicTable
+		if err := tenantDB(tenantID).First(&table, rule.TableID).Error; err != nil {
+			return errors.New("数据表不存在")
+		}
+	}
+	if rule.Preset == "" {
+		rule.Preset = model.DataRulePresetCustom
+	}
+	condition, err := rule.GetCondition()
+	if err != nil {
+		return err
+	}
+	if err := condition.Validate(); err != nil {
+		return err
+	}
+	if rule.Preset != model.DataRulePresetCustom {
+		rule.Condition = nil
+	}
+	if rule.Status == 0 {
+		rule.Status = 1
+	}
+
+	if rule.ID != 0 {
+		var existing model.DataRule
+		if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", rule.ID, tenantID).First(&existing).Error; err != nil {
+			return ErrDataRuleNotFound
+		}
+		rule.CreatedAt = existing.CreatedAt
+	}
+	rule.TenantID = tenantID
+	return tenantDB(tenantID).Save(rule).Error
+}
+
+// DeleteRule 删除数据规则
+func (s *DataRuleService) DeleteRule(tenantID, id uint) error {
+	result := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&model.DataRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDataRuleNotFound
+	}
+	return nil
+}
+
+// ResolveScope 计算用户对数据表的数据范围
+// 用户的每个直接角色连同其继承的上级角色视为一组：组内有数据表权限即授权，组内规则取交集；
+// 授权的各组之间取并集，任一授权组没有启用的规则时不限制
+func (s *DataRuleService) ResolveScope(userID, tenantID uint, table *model.DynamicTable) (*DataScope, error) {
+	var user model.User
+	if err := tenantDB(tenantID).Select("id, username, dept_id").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	scope := &DataScope{UserID: user.ID, DeptID: user.DeptID, TenantID: tenantID}
+
+	directIDs, err := userRoleIDs(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := roleParents(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[uint][]uint, len(directIDs))
+	for _, id := range directIDs {
+		groups[id] = expandRoles(parents, []uint{id})
+	}
+
+	permissions, err := roleTablePermissions(tenantID, table.ID, expandRoles(parents, directIDs))
+	if err != nil {
+		return nil, err
+	}
+	granted := make(map[uint]bool, len(permissions))
+	for _, permission := range permissions {
+		granted[permission.RoleID] = true
+	}
+	var roleIDs []uint
+	for _, id := range directIDs {
+		for _, member := range groups[id] {
+			if granted[member] {
+				roleIDs = append(roleIDs, id)
+				break
+			}
+		}
+	}
+	if len(roleIDs) == 0 {
+		// 没有任何授权角色，不返回数据
+		scope.Clause = "1 = 0"
+		return scope, nil
+	}
+
+	var rules []model.DataRule
+	err = tenantDB(tenantID).Where("tenant_id = ? AND status = 1 AND role_id IN ? AND table_id IN ?", tenantID, expandRoles(parents, roleIDs), []uint{0, table.ID}).
+		Order("id").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	rulesByRole := make(map[uint][]model.DataRule, len(roleIDs))
+	for _, rule := range rules {
+		rulesByRole[rule.RoleID] = append(rulesByRole[rule.RoleID], rule)
+	}
+
+	deptTree := []interface{}{user.DeptID}
+	if user.DeptID != 0 {
+		ids, err := (&DepartmentService{}).SubtreeIDs(tenantID, user.DeptID)
+		if err != nil && !errors.Is(err, ErrDepartmentNotFound) {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			deptTree = make([]interface{}, len(ids))
+			for i, id := range ids {
+				deptTree[i] = id
+			}
+		}
+	}
+	vars := map[string]interface{}{
+		model.PlaceholderUserID:   user.ID,
+		model.PlaceholderUserDept: user.DeptID,
+		model.PlaceholderDeptTree: deptTree,
+		model.PlaceholderUsername: user.Username,
+		model.PlaceholderTenantID: tenantID,
+	}
+	var roleClauses []string
+	var args []interface{}
+	for _, roleID := range roleIDs {
+		var groupRules []model.DataRule
+		for _, member := range groups[roleID] {
+			groupRules = append(groupRules, rulesByRole[member]...)
+		}
+		var clauses []string
+		for _, rule := range groupRules {
+			condition, err := rule.GetCondition()
+			if err != nil {
+				return nil, err
+			}
+			clause, clauseArgs := condition.BuildWhereClause(table.TableName, vars)
+			if clause != "" {
+				clauses = append(clauses, clause)
+				args = append(args, clauseArgs...)
+			}
+		}
+		if len(clauses) == 0 {
+			// 该角色不限制数据范围
+			return scope, nil
+		}
+		roleClauses = append(roleClauses, "("+strings.Join(clauses, " AND ")+")")
+	}
+	scope.Clause = "(" + strings.Join(roleClauses, " OR ") + ")"
+	scope.Args = args
+	return scope, nil
+}
+
+// DataRulePreset 数据规则预设说明
+type DataRulePreset struct {
+	Preset      string          `json:"preset"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Condition   json.RawMessage `json:"condition,omitempty"`
+}
+
+// GetPresets 获取可用的数据规则预设
+func (s *DataRuleService) GetPresets() []DataRulePreset {
+	return []DataRulePreset{
+		{Preset: model.DataRulePresetOwn, Name: "仅本人数据", Description: "只能访问自己创建的数据，按created_by列过滤"},
+		{Preset: model.DataRulePresetDept, Name: "仅本部门数据", Description: "只能访问本部门的数据，按dept_id列过滤"},
+		{Preset: model.DataRulePresetDeptTree, Name: "本部门及下级部门数据", Description: "可以访问本部门和全部下级部门的数据，按dept_id列过滤"},
+		{Preset: model.DataRulePresetCustom, Name: "自定义条件", Description: "按条件树过滤，值可以使用{{user.id}}、{{user.dept_id}}、{{user.dept_tree}}、{{user.username}}、{{tenant.id}}占位符",
+			Condition: json.RawMessage(`{"logic":"AND","conditions":[{"field":"region","operator":"=","value":"华东"}]}`)},
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+
+	"github.com/casbin/casbin/v2"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB 把global.DB替换为迁移了指定模型并注册了租户隔离的内存SQLite数据库，测试结束后恢复
+// 返回跳过租户隔离的会话，用于准备测试数据
+func useTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	if err := initialize.RegisterTenantScope(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := global.DB
+	global.DB = db
+	t.Cleanup(func() {
+		global.DB = saved
+		sqlDB.Close()
+	})
+	return db.WithContext(global.SystemContext())
+}
+
+// useTestEnforcer 把global.Enforcer替换为使用临时策略文件的Enforcer，测试结束后恢复
+func useTestEnforcer(t *testing.T) *casbin.Enforcer {
+	t.Helper()
+	policyPath := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	enforcer, err := initialize.NewEnforcer("../config/rbac_model.conf", policyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := global.Enforcer
+	global.Enforcer = enforcer
+	t.Cleanup(func() { global.Enforcer = saved })
+	return enforcer
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDepartmentNotFound 部门不存在
+	ErrDepartmentNotFound = errors.New("部门不存在")
+	// ErrDepartmentHasChildren 部门下还有子部门
+	ErrDepartmentHasChildren = errors.New("请先删除或移走子部门")
+	// ErrDepartmentHasMembers 部门下还有成员
+	ErrDepartmentHasMembers = errors.New("请先移除部门成员")
+	// ErrDepartmentCycle 不能把部门移动到自身或下级部门之下
+	ErrDepartmentCycle = errors.New("不能移动到自身或下级部门之下")
+)
+
+// DepartmentService 部门树服务
+type DepartmentService struct{}
+
+// GetDepartment 获取租户内的部门
+func (s *DepartmentService) GetDepartment(tenantID, id uint) (*model.Department, error) {
+	var dept model.Department
+	if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", id, tenantID).First(&dept).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+	return &dept, nil
+}
+
+// GetTree 获取租户的部门树
+func (s *DepartmentService) GetTree(tenantID uint) ([]*model.Department, error) {
+	var depts []*model.Department
+	if err := tenantDB(tenantID).Where("tenant_id = ?", tenantID).Order("level, sort, id").Find(&depts).Error; err != nil {
+		return nil, err
+	}
+	return BuildDepartmentTree(depts), nil
+}
+
+// BuildDepartmentTree 把按层级排序的部门列表组装成树，父部门不在列表中的部门作为根节点
+func BuildDepartmentTree(depts []*model.Department) []*model.Department {
+	byID := make(map[uint]*model.Department, len(depts))
+	for _, dept := range depts {
+		dept.Children = nil
+		byID[dept.ID] = dept
+	}
+	var roots []*model.Department
+	for _, dept := range depts {
+		if parent, ok := byID[dept.ParentID]; ok && dept.ParentID != dept.ID {
+			parent.Children = append(parent.Children, dept)
+		} else {
+			roots = append(roots, dept)
+		}
+	}
+	return roots
+}
+
+// CreateDepartment 创建部门并计算物化路径
+func (s *DepartmentService) CreateDepartment(tenantID uint, dept *model.Department) error {
+	if strings.TrimSpace(dept.Name) == "" {
+		return errors.New("部门名称不能为空")
+	}
+	dept.ID = 0
+	dept.TenantID = tenantID
+	if dept.Status == 0 {
+		dept.Status = 1
+	}
+	return tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		parentPath, level := "/", 1
+		if dept.ParentID != 0 {
+			var parent model.Department
+			if err := tx.Where("id = ? AND tenant_id = ?", dept.ParentID, tenantID).First(&parent).Error; err != nil {
+				return errors.New("上级部门不存在")
+			}
+			parentPath, level = parent.Path, parent.Level+1
+		}
+		dept.Level = level
+		if err := tx.Create(dept).Error; err != nil {
+			return err
+		}
+		dept.Path = fmt.Sprintf("%s%d/", parentPath, dept.ID)
+		return tx.Model(dept).Update("path", dept.Path).Error
+	})
+}
+
+// UpdateDepartment 更新部门的基本信息，调整上级部门使用MoveDepartment
+func (s *DepartmentService) UpdateDepartment(tenantID, id uint, dept *model.Department) (*model.Department, error) {
+	existing, err := s.GetDepartment(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(dept.Name) == "" {
+		return nil, errors.New("部门名称不能为空")
+	}
+	updates := map[string]interface{}{
+		"name": dept.Name,
+		"code": dept.Code,
+		"sort": dept.Sort,
+	}
+	if dept.Status != 0 {
+		updates["status"] = dept.Status
+	}
+	if err := tenantDB(tenantID).Model(existing).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetDepartment(tenantID, id)
+}
+
+// DeleteDepartment 删除没有子部门和成员的部门
+func (s *DepartmentService) DeleteDepartment(tenantID, id uint) error {
+	dept, err := s.GetDepartment(tenantID, id)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := tenantDB(tenantID).Model(&model.Department{}).Where("parent_id = ? AND tenant_id = ?", id, tenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDepartmentHasChildren
+	}
+	if err := tenantDB(tenantID).Model(&model.UserDepartment{}).Where("dept_id = ? AND tenant_id = ?", id, tenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDepartmentHasMembers
+	}
+	return tenantDB(tenantID).Delete(dept).Error
+}
+
+// MoveDepartment 把部门连同下级部门移动到新的上级部门之下，parentID为0时移动为顶级部门
+func (s *DepartmentService) MoveDepartment(tenantID, id, parentID uint) (*model.Department, error) {
+	dept, err := s.GetDepartment(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	parentPath, level := "/", 1
+	if parentID != 0 {
+		parent, err := s.GetDepartment(tenantID, parentID)
+		if err != nil {
+			return nil, errors.New("上级部门不存在")
+		}
+		if strings.HasPrefix(parent.Path, dept.Path) {
+			return nil, ErrDepartmentCycle
+		}
+		parentPath, level = parent.Path, parent.Level+1
+	}
+
+	oldPath := dept.Path
+	newPath := fmt.Sprintf("%s%d/", parentPath, dept.ID)
+	levelDelta := level - dept.Level
+	err = tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		var subtree []model.Department
+		if err := tx.Where("tenant_id = ? AND path LIKE ?", tenantID, oldPath+"%").Find(&subtree).Error; err != nil {
+			return err
+		}
+		for _, node := range subtree {
+			updates := map[string]interface{}{
+				"path":  newPath + strings.TrimPrefix(node.Path, oldPath),
+				"level": node.Level + levelDelta,
+			}
+			if node.ID == dept.ID {
+				updates["parent_id"] = parentID
+			}
+			if err := tx.Model(&model.Department{}).Where("id = ?", node.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDepartment(tenantID, id)
+}
+
+// SubtreeIDs 获取部门及其全部下级部门的ID，供数据范围、审批流转等按部门汇总的功能使用
+func (s *DepartmentService) SubtreeIDs(tenantID, deptID uint) ([]uint, error) {
+	dept, err := s.GetDepartment(tenantID, deptID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	err = tenantDB(tenantID).Model(&model.Department{}).
+		Where("tenant_id = ? AND path LIKE ?", tenantID, dept.Path+"%").
+		Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ListMembers 获取部门成员，includeChildren为true时包含下级部门的成员
+func (s *DepartmentService) ListMembers(tenantID, deptID uint, includeChildren bool) ([]model.UserDepartment, error) {
+	deptIDs := []uint{deptID}
+	if includeChildren {
+		ids, err := s.SubtreeIDs(tenantID, deptID)
+		if err != nil {
+			return nil, err
+		}
+		deptIDs = ids
+	} else if _, err := s.GetDepartment(tenantID, deptID); err != nil {
+		return nil, err
+	}
+	var members []model.UserDepartment
+	err := tenantDB(tenantID).Where("tenant_id = ? AND dept_id IN ?", tenantID, deptIDs).
+		Order("dept_id, is_manager DESC, user_id").Find(&members).Error
+	return members, err
+}
+
+// GetManagers 获取部门负责人
+func (s *DepartmentService) GetManagers(tenantID, deptID uint) ([]model.User, error) {
+	var users []model.User
+	err := tenantDB(tenantID).Joins("JOIN user_departments ON user_departments.user_id = users.id AND user_departments.deleted_at IS NULL").
+		Where("user_departments.tenant_id = ? AND user_departments.dept_id = ? AND user_departments.is_manager = ?", tenantID, deptID, true).
+		Order("users.id").Find(&users).Error
+	return users, err
+}
+
+// SetManagers 设置部门负责人，尚未加入部门的用户以兼职身份加入
+func (s *DepartmentService) SetManagers(tenantID, deptID uint, userIDs []uint) error {
+	if _, err := s.GetDepartment(tenantID, deptID); err != nil {
+		return err
+	}
+	if err := checkTenantUsers(tenantID, userIDs); err != nil {
+		return err
+	}
+	return tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.UserDepartment{}).Where("tenant_id = ? AND dept_id = ?", tenantID, deptID).
+			Update("is_manager", false).Error; err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			var member model.UserDepartment
+			err := tx.Where("tenant_id = ? AND dept_id = ? AND user_id = ?", tenantID, deptID, userID).
+				FirstOrInit(&member, model.UserDepartment{UserID: userID, DeptID: deptID, TenantID: tenantID}).Error
+			if err != nil {
+				return err
+			}
+			member.IsManager = true
+			if err := tx.Save(&member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetUserDepartments 设置用户的主部门和兼职部门，保留仍然所属部门的负责人身份
+func (s *DepartmentService) SetUserDepartments(tenantID, userID, primaryID uint, secondaryIDs []uint) error {
+	if err := checkTenantUsers(tenantID, []uint{userID}); err != nil {
+		return err
+	}
+	deptIDs := uniqueIDs(append([]uint{primaryID}, secondaryIDs...))
+	if primaryID == 0 {
+		deptIDs = uniqueIDs(secondaryIDs)
+	}
+	var count int64
+	if err := tenantDB(tenantID).Model(&model.Department{}).Where("tenant_id = ? AND id IN ?", tenantID, deptIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(deptIDs) {
+		return ErrDepartmentNotFound
+	}
+
+	return tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		var existing []model.UserDepartment
+		if err := tx.Where("tenant_id = ? AND user_id = ?", tenantID, userID).Find(&existing).Error; err != nil {
+			return err
+		}
+		managers := make(map[uint]bool, len(existing))
+		for _, member := range existing {
+			managers[member.DeptID] = member.IsManager
+		}
+		if err := tx.Unscoped().Where("tenant_id = ? AND user_id = ?", tenantID, userID).Delete(&model.UserDepartment{}).Error; err != nil {
+			return err
+		}
+		for _, deptID := range deptIDs {
+			member := model.UserDepartment{
+				UserID:    userID,
+				DeptID:    deptID,
+				TenantID:  tenantID,
+				IsPrimary: deptID == primaryID,
+				IsManager: managers[deptID],
+			}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		// 用户表保存主部门，供数据范围等按主部门计算的功能直接读取
+		return tx.Model(&model.User{}).Where("id = ?", userID).Update("dept_id", primaryID).Error
+	})
+}
+
+// GetUserDepartments 获取用户所属的部门
+func (s *DepartmentService) GetUserDepartments(tenantID, userID uint) ([]model.UserDepartment, error) {
+	var members []model.UserDepartment
+	err := tenantDB(tenantID).Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Order("is_primary DESC, dept_id").Find(&members).Error
+	return members, err
+}
+
+// checkTenantUsers 检查用户都属于租户
+func checkTenantUsers(tenantID uint, userIDs []uint) error {
+	userIDs = uniqueIDs(userIDs)
+	if len(userIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := tenantDB(tenantID).Model(&model.User{}).Where("tenant_id = ? AND id IN ?", tenantID, userIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(userIDs) {
+		return errors.New("用户不存在")
+	}
+	return nil
+}
+
+// uniqueIDs 去掉0和重复的ID并排序
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-react-admin/model"
+)
+
+func TestBuildDepartmentTree(t *testing.T) {
+	depts := []*model.Department{
+		{ID: 1, Name: "总部", Path: "/1/", Level: 1},
+		{ID: 2, Name: "华东区", ParentID: 1, Path: "/1/2/", Level: 2},
+		{ID: 3, Name: "华南区", ParentID: 1, Path: "/1/3/", Level: 2},
+		{ID: 4, Name: "上海", ParentID: 2, Path: "/1/2/4/", Level: 3},
+		{ID: 9, Name: "孤立部门", ParentID: 8, Path: "/8/9/", Level: 2},
+	}
+	roots := BuildDepartmentTree(depts)
+
+	if len(roots) != 2 || roots[0].ID != 1 || roots[1].ID != 9 {
+		t.Fatalf("根部门错误: %+v", roots)
+	}
+	if len(roots[0].Children) != 2 || roots[0].Children[0].ID != 2 {
+		t.Fatalf("总部的子部门错误: %+v", roots[0].Children)
+	}
+	if children := roots[0].Children[0].Children; len(children) != 1 || children[0].ID != 4 {
+		t.Fatalf("华东区的子部门错误: %+v", children)
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	if got := uniqueIDs([]uint{5, 0, 3, 5, 1}); !reflect.DeepEqual(got, []uint{1, 3, 5}) {
+		t.Fatalf("uniqueIDs = %v", got)
+	}
+}
+
+// seedDepartments 在租户1中创建部门树：总部 > 华东区 > 上海 > 浦东，总部 > 华南区；租户2中创建同名的总部
+func seedDepartments(t *testing.T) map[string]*model.Department {
+	t.Helper()
+	useTestDB(t, &model.Department{})
+	s := &DepartmentService{}
+	depts := make(map[string]*model.Department)
+	create := func(tenantID uint, name, parent string) {
+		dept := &model.Department{Name: name}
+		if parent != "" {
+			dept.ParentID = depts[parent].ID
+		}
+		if err := s.CreateDepartment(tenantID, dept); err != nil {
+			t.Fatal(err)
+		}
+		depts[name] = dept
+	}
+	create(1, "总部", "")
+	create(1, "华东区", "总部")
+	create(1, "上海", "华东区")
+	create(1, "浦东", "上海")
+	create(1, "华南区", "总部")
+	create(2, "其他租户", "")
+	return depts
+}
+
+func TestMoveDepartmentRewritesSubtree(t *testing.T) {
+	depts := seedDepartments(t)
+	s := &DepartmentService{}
+
+	// 把上海连同浦东移动到华南区之下
+	moved, err := s.MoveDepartment(1, depts["上海"].ID, depts["华南区"].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPath := depts["华南区"].Path + "3/"
+	if moved.ParentID != depts["华南区"].ID || moved.Path != wantPath || moved.Level != 3 {
+		t.Fatalf("上海移动后为 parent=%d path=%s level=%d，期望 parent=%d path=%s level=3",
+			moved.ParentID, moved.Path, moved.Level, depts["华南区"].ID, wantPath)
+	}
+	child, err := s.GetDepartment(1, depts["浦东"].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.Path != wantPath+"4/" || child.Level != 4 || child.ParentID != depts["上海"].ID {
+		t.Fatalf("浦东移动后为 parent=%d path=%s level=%d，期望路径 %s4/ 层级 4", child.ParentID, child.Path, child.Level, wantPath)
+	}
+
+	// 移动为顶级部门时层级减少
+	moved, err = s.MoveDepartment(1, depts["上海"].ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Path != "/3/" || moved.Level != 1 || moved.ParentID != 0 {
+		t.Fatalf("上海移动为顶级部门后为 parent=%d path=%s level=%d，期望 /3/ 层级 1", moved.ParentID, moved.Path, moved.Level)
+	}
+	if child, _ = s.GetDepartment(1, depts["浦东"].ID); child.Path != "/3/4/" || child.Level != 2 {
+		t.Fatalf("浦东路径为 %s 层级 %d，期望 /3/4/ 层级 2", child.Path, child.Level)
+	}
+
+	// 原来的上级部门不受影响
+	if east, _ := s.GetDepartment(1, depts["华东区"].ID); east.Path != depts["华东区"].Path || east.Level != 2 {
+		t.Fatalf("华东区路径被修改为 %s 层级 %d", east.Path, east.Level)
+	}
+}
+
+func TestMoveDepartmentRejectsCycle(t *testing.T) {
+	depts := seedDepartments(t)
+	s := &DepartmentService{}
+
+	for _, target := range []string{"华东区", "上海", "浦东"} {
+		if _, err := s.MoveDepartment(1, depts["华东区"].ID, depts[target].ID); !errors.Is(err, ErrDepartmentCycle) {
+			t.Fatalf("把华东区移动到%s之下返回 %v，期望 ErrDepartmentCycle", target, err)
+		}
+	}
+	// 拒绝后路径保持不变
+	var paths []string
+	if err := systemDB().Model(&model.Department{}).Where("tenant_id = ?", 1).Order("id").Pluck("path", &paths).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/1/", "/1/2/", "/1/2/3/", "/1/2/3/4/", "/1/5/"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("部门路径为 %v，期望 %v", paths, want)
+	}
+
+	// 不能移动到其他租户的部门之下
+	if _, err := s.MoveDepartment(1, depts["华南区"].ID, depts["其他租户"].ID); err == nil {
+		t.Fatal("移动到其他租户的部门之下未被拒绝")
+	}
+}
+
+func TestSubtreeIDs(t *testing.T) {
+	depts := seedDepartments(t)
+	s := &DepartmentService{}
+
+	ids, err := s.SubtreeIDs(1, depts["华东区"].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint{depts["华东区"].ID, depts["上海"].ID, depts["浦东"].ID}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("华东区的下级部门为 %v，期望 %v", ids, want)
+	}
+	if ids, _ = s.SubtreeIDs(1, depts["浦东"].ID); !reflect.DeepEqual(ids, []uint{depts["浦东"].ID}) {
+		t.Fatalf("浦东的下级部门为 %v，期望只有自身", ids)
+	}
+	if ids, _ = s.SubtreeIDs(1, depts["总部"].ID); len(ids) != 5 {
+		t.Fatalf("总部的下级部门为 %v，期望租户1的全部5个部门", ids)
+	}
+	if _, err := s.SubtreeIDs(1, depts["其他租户"].ID); !errors.Is(err, ErrDepartmentNotFound) {
+		t.Fatalf("查询其他租户的部门返回 %v，期望 ErrDepartmentNotFound", err)
+	}
+}
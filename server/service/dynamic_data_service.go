@@ -1,26 +1,87 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"go-react-admin/global"
 	"go-react-admin/model"
+
+	"gorm.io/gorm"
 )
 
 type DynamicDataService struct{}
 
-// CreateData 创建动态数据
-func (dds *DynamicDataService) CreateData(tableName string, data map[string]interface{}) (map[string]interface{}, error) {
+// orderByPattern 排序只能是单个列名加可选的排序方向
+var orderByPattern = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*( (?i:asc|desc))?)?$`)
+
+// UnknownColumnError 请求数据中包含数据表未定义的列
+type UnknownColumnError struct {
+	Columns []string
+}
+
+func (e *UnknownColumnError) Error() string {
+	return "数据表没有以下字段: " + strings.Join(e.Columns, ", ")
+}
+
+// isSystemColumn 判断是否为系统列，MySQL列名不区分大小写
+func isSystemColumn(name string) bool {
+	for _, column := range []string{model.ColumnCreatedBy, model.ColumnDeptID, model.ColumnTenantID} {
+		if strings.EqualFold(name, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeSystemColumns 删除请求中的系统列，系统列只能由服务端写入
+func removeSystemColumns(data map[string]interface{}) {
+	for key := range data {
+		if isSystemColumn(key) {
+			delete(data, key)
+		}
+	}
+}
+
+// checkColumns 请求数据只能写入数据表中已启用的字段，系统列和未定义的列返回UnknownColumnError
+func checkColumns(table *model.DynamicTable, data map[string]interface{}) error {
+	declared := make(map[string]bool, len(table.FieldDefinitions))
+	for _, field := range table.FieldDefinitions {
+		if field.Status == 1 {
+			declared[field.FieldName] = true
+		}
+	}
+	var unknown []string
+	for key := range data {
+		if !model.IsValidColumnName(key) || isSystemColumn(key) || !declared[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &UnknownColumnError{Columns: unknown}
+	}
+	return nil
+}
+
+// CreateData 创建动态数据，按数据范围写入创建人、部门和租户系统列
+func (dds *DynamicDataService) CreateData(tableName string, data map[string]interface{}, scope *DataScope) (map[string]interface{}, error) {
 	// 获取表定义
-	table, err := (&DynamicTableService{}).GetTableByName(tableName)
+	table, err := (&DynamicTableService{}).GetTableByName(scope.tenantContext(), tableName)
 	if err != nil {
 		return nil, fmt.Errorf("表不存在: %v", err)
 	}
+	// 插入的列名来自请求数据，只接受数据表中已定义的字段
+	if err := checkColumns(table, data); err != nil {
+		return nil, err
+	}
 
 	// 检查物理表是否存在，如果不存在则创建
 	if err := dds.ensurePhysicalTableExists(table); err != nil {
@@ -34,6 +95,12 @@ func (dds *DynamicDataService) CreateData(tableName string, data map[string]inte
 
 	// 处理数据
 	processedData := dds.processDataForInsert(table, data)
+	removeSystemColumns(processedData)
+	if scope != nil {
+		processedData[model.ColumnCreatedBy] = scope.UserID
+		processedData[model.ColumnDeptID] = scope.DeptID
+		processedData[model.ColumnTenantID] = scope.TenantID
+	}
 
 	// 构建插入SQL
 	columns := []string{"created_at", "updated_at"}
@@ -51,22 +118,35 @@ func (dds *DynamicDataService) CreateData(tableName string, data map[string]inte
 	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
 		tableName, strings.Join(columns, ","), placeholders)
 
-	// 执行插入
-	result := global.DB.Exec(sql, values...)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	// 获取插入的ID
+	// 执行插入，LAST_INSERT_ID按连接返回，插入和读取ID必须在同一个事务的连接上
 	var insertID int64
-	global.DB.Raw("SELECT LAST_INSERT_ID()").Scan(&insertID)
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(sql, values...).Error; err != nil {
+			return err
+		}
+		return tx.Raw("SELECT LAST_INSERT_ID()").Scan(&insertID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// 返回创建的数据
-	return dds.GetDataByID(tableName, uint(insertID))
+	return dds.GetDataByID(tableName, uint(insertID), scope)
 }
 
-// GetDataList 获取动态数据列表
-func (dds *DynamicDataService) GetDataList(tableName string, page, pageSize int, filters map[string]interface{}, orderBy string) ([]map[string]interface{}, int64, error) {
+// GetDataList 获取动态数据列表，只返回数据范围内的数据
+func (dds *DynamicDataService) GetDataList(tableName string, page, pageSize int, filters map[string]interface{}, orderBy string, scope *DataScope) ([]map[string]interface{}, int64, error) {
+	// 过滤条件和排序会拼接到SQL中，只接受合法的列名
+	for field := range filters {
+		if !model.IsValidColumnName(field) {
+			delete(filters, field)
+		}
+	}
+	orderBy = strings.TrimSpace(orderBy)
+	if !orderByPattern.MatchString(orderBy) {
+		orderBy = ""
+	}
+
 	// 构建查询
 	db := global.DB.Table(tableName)
 
@@ -79,6 +159,7 @@ func (dds *DynamicDataService) GetDataList(tableName string, page, pageSize int,
 			db = db.Where(fmt.Sprintf("%s = ?", field), value)
 		}
 	}
+	db = scope.apply(db)
 
 	// 获取总数
 	var total int64
@@ -111,6 +192,10 @@ func (dds *DynamicDataService) GetDataList(tableName string, page, pageSize int,
 			whereValues = append(whereValues, value)
 		}
 	}
+	if scope != nil && scope.Clause != "" {
+		whereClauses = append(whereClauses, scope.Clause)
+		whereValues = append(whereValues, scope.Args...)
+	}
 	
 	whereClause := strings.Join(whereClauses, " AND ")
 	
@@ -167,11 +252,17 @@ func (dds *DynamicDataService) GetDataList(tableName string, page, pageSize int,
 	return results, total, nil
 }
 
-// GetDataByID 根据ID获取动态数据
-func (dds *DynamicDataService) GetDataByID(tableName string, id uint) (map[string]interface{}, error) {
-	sql := fmt.Sprintf("SELECT * FROM %s WHERE id = ? AND deleted_at IS NULL LIMIT 1", tableName)
+// GetDataByID 根据ID获取数据范围内的动态数据
+func (dds *DynamicDataService) GetDataByID(tableName string, id uint, scope *DataScope) (map[string]interface{}, error) {
+	whereClause := "id = ? AND deleted_at IS NULL"
+	whereValues := []interface{}{id}
+	if scope != nil && scope.Clause != "" {
+		whereClause += " AND " + scope.Clause
+		whereValues = append(whereValues, scope.Args...)
+	}
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 1", tableName, whereClause)
 	
-	rows, err := global.DB.Raw(sql, id).Rows()
+	rows, err := global.DB.Raw(sql, whereValues...).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -209,11 +300,19 @@ func (dds *DynamicDataService) GetDataByID(tableName string, id uint) (map[strin
 	return result, nil
 }
 
-// UpdateData 更新动态数据
-func (dds *DynamicDataService) UpdateData(tableName string, id uint, data map[string]interface{}) (map[string]interface{}, error) {
+// UpdateData 更新数据范围内的动态数据，系统列不允许修改
+func (dds *DynamicDataService) UpdateData(tableName string, id uint, data map[string]interface{}, scope *DataScope) (map[string]interface{}, error) {
+	table, err := (&DynamicTableService{}).GetTableByName(scope.tenantContext(), tableName)
+	if err != nil {
+		return nil, fmt.Errorf("表不存在: %v", err)
+	}
+	if err := checkColumns(table, data); err != nil {
+		return nil, err
+	}
+
 	// 检查数据是否存在
 	var count int64
-	global.DB.Table(tableName).Where("id = ? AND deleted_at IS NULL", id).Count(&count)
+	scope.apply(global.DB.Table(tableName).Where("id = ? AND deleted_at IS NULL", id)).Count(&count)
 	if count == 0 {
 		return nil, errors.New("数据不存在")
 	}
@@ -222,62 +321,62 @@ func (dds *DynamicDataService) UpdateData(tableName string, id uint, data map[st
 	data["updated_at"] = time.Now()
 
 	// 执行更新
-	if err := global.DB.Table(tableName).Where("id = ?", id).Updates(data).Error; err != nil {
+	if err := scope.apply(global.DB.Table(tableName).Where("id = ?", id)).Updates(data).Error; err != nil {
 		return nil, err
 	}
 
 	// 返回更新后的数据
-	return dds.GetDataByID(tableName, id)
+	return dds.GetDataByID(tableName, id, scope)
 }
 
-// DeleteData 删除动态数据（软删除）
-func (dds *DynamicDataService) DeleteData(tableName string, id uint) error {
+// DeleteData 删除数据范围内的动态数据（软删除）
+func (dds *DynamicDataService) DeleteData(tableName string, id uint, scope *DataScope) error {
 	// 检查数据是否存在
 	var count int64
-	global.DB.Table(tableName).Where("id = ? AND deleted_at IS NULL", id).Count(&count)
+	scope.apply(global.DB.Table(tableName).Where("id = ? AND deleted_at IS NULL", id)).Count(&count)
 	if count == 0 {
 		return errors.New("数据不存在")
 	}
 
 	// 软删除
-	return global.DB.Table(tableName).Where("id = ?", id).Update("deleted_at", time.Now()).Error
+	return scope.apply(global.DB.Table(tableName).Where("id = ?", id)).Update("deleted_at", time.Now()).Error
 }
 
-// BatchDeleteData 批量删除动态数据
-func (dds *DynamicDataService) BatchDeleteData(tableName string, ids []uint) error {
+// BatchDeleteData 批量删除动态数据，数据范围外的数据不会被删除
+func (dds *DynamicDataService) BatchDeleteData(tableName string, ids []uint, scope *DataScope) error {
 	if len(ids) == 0 {
 		return nil
 	}
 
 	// 软删除
-	return global.DB.Table(tableName).Where("id IN ?", ids).Update("deleted_at", time.Now()).Error
+	return scope.apply(global.DB.Table(tableName).Where("id IN ?", ids)).Update("deleted_at", time.Now()).Error
 }
 
-// GetDataStatistics 获取动态数据统计信息
-func (dds *DynamicDataService) GetDataStatistics(tableName string) (map[string]interface{}, error) {
+// GetDataStatistics 获取数据范围内的动态数据统计信息
+func (dds *DynamicDataService) GetDataStatistics(tableName string, scope *DataScope) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
 	// 总记录数
 	var totalCount int64
-	global.DB.Table(tableName).Where("deleted_at IS NULL").Count(&totalCount)
+	scope.apply(global.DB.Table(tableName).Where("deleted_at IS NULL")).Count(&totalCount)
 	stats["total"] = totalCount
 
 	// 今日新增
 	var todayCount int64
 	today := time.Now().Format("2006-01-02")
-	global.DB.Table(tableName).Where("deleted_at IS NULL AND DATE(created_at) = ?", today).Count(&todayCount)
+	scope.apply(global.DB.Table(tableName).Where("deleted_at IS NULL AND DATE(created_at) = ?", today)).Count(&todayCount)
 	stats["today"] = todayCount
 
 	// 本周新增
 	var weekCount int64
 	weekStart := time.Now().AddDate(0, 0, -int(time.Now().Weekday())).Format("2006-01-02")
-	global.DB.Table(tableName).Where("deleted_at IS NULL AND DATE(created_at) >= ?", weekStart).Count(&weekCount)
+	scope.apply(global.DB.Table(tableName).Where("deleted_at IS NULL AND DATE(created_at) >= ?", weekStart)).Count(&weekCount)
 	stats["week"] = weekCount
 
 	// 本月新增
 	var monthCount int64
 	monthStart := time.Now().AddDate(0, 0, -time.Now().Day()+1).Format("2006-01-02")
-	global.DB.Table(tableName).Where("deleted_at IS NULL AND DATE(created_at) >= ?", monthStart).Count(&monthCount)
+	scope.apply(global.DB.Table(tableName).Where("deleted_at IS NULL AND DATE(created_at) >= ?", monthStart)).Count(&monthCount)
 	stats["month"] = monthCount
 
 	return stats, nil
@@ -489,6 +588,8 @@ func (dds *DynamicDataService) createPhysicalTable(table *model.DynamicTable) er
 	columns = append(columns, "updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP")
 	columns = append(columns, "deleted_at TIMESTAMP NULL")
 	columns = append(columns, "tenant_id INT DEFAULT 0")
+	columns = append(columns, "created_by INT DEFAULT 0")
+	columns = append(columns, "dept_id INT DEFAULT 0")
 
 	// 添加动态字段（如果有的话）
 	if table.FieldDefinitions != nil {
@@ -501,6 +602,8 @@ func (dds *DynamicDataService) createPhysicalTable(table *model.DynamicTable) er
 	// 添加索引
 	columns = append(columns, "INDEX idx_deleted_at (deleted_at)")
 	columns = append(columns, "INDEX idx_tenant_id (tenant_id)")
+	columns = append(columns, "INDEX idx_created_by (created_by)")
+	columns = append(columns, "INDEX idx_dept_id (dept_id)")
 
 	// 确保表名安全
 	safeTableName := strings.ReplaceAll(table.TableName, "`", "")
@@ -532,42 +635,75 @@ func (dds *DynamicDataService) buildColumnDefinition(field *model.DynamicField)
 	return definition
 }
 
-// CreateView 创建数据视图
-func (dds *DynamicDataService) CreateView(view *model.DynamicView) error {
-	return global.DB.Create(view).Error
+// CreateView 创建数据视图，视图所属的表必须属于ctx绑定的租户
+func (dds *DynamicDataService) CreateView(ctx context.Context, view *model.DynamicView) error {
+	if _, err := fieldTable(ctx, view.TableID); err != nil {
+		return err
+	}
+	return global.DB.WithContext(ctx).Create(view).Error
 }
 
 // GetViewList 获取视图列表
-func (dds *DynamicDataService) GetViewList(tableID uint) ([]model.DynamicView, error) {
+func (dds *DynamicDataService) GetViewList(ctx context.Context, tableID uint) ([]model.DynamicView, error) {
+	if _, err := fieldTable(ctx, tableID); err != nil {
+		return nil, err
+	}
 	var views []model.DynamicView
-	err := global.DB.Where("table_id = ?", tableID).Order("sort_order ASC").Find(&views).Error
+	err := global.DB.WithContext(ctx).Where("table_id = ?", tableID).Order("sort_order ASC").Find(&views).Error
 	return views, err
 }
 
-// GetViewByID 根据ID获取视图
-func (dds *DynamicDataService) GetViewByID(id uint) (*model.DynamicView, error) {
+// GetViewByID 根据ID获取视图，视图所属的表不属于ctx绑定的租户时返回错误
+func (dds *DynamicDataService) GetViewByID(ctx context.Context, id uint) (*model.DynamicView, error) {
 	var view model.DynamicView
-	err := global.DB.First(&view, id).Error
+	err := global.DB.WithContext(ctx).First(&view, id).Error
 	if err != nil {
 		return nil, err
 	}
+	if _, err := fieldTable(ctx, view.TableID); err != nil {
+		return nil, err
+	}
 	return &view, nil
 }
 
 // UpdateView 更新视图
-func (dds *DynamicDataService) UpdateView(view *model.DynamicView) error {
-	return global.DB.Save(view).Error
+func (dds *DynamicDataService) UpdateView(ctx context.Context, view *model.DynamicView) error {
+	if _, err := dds.GetViewByID(ctx, view.ID); err != nil {
+		return err
+	}
+	if _, err := fieldTable(ctx, view.TableID); err != nil {
+		return err
+	}
+	return global.DB.WithContext(ctx).Save(view).Error
 }
 
 // DeleteView 删除视图
-func (dds *DynamicDataService) DeleteView(id uint) error {
-	return global.DB.Delete(&model.DynamicView{}, id).Error
+func (dds *DynamicDataService) DeleteView(ctx context.Context, id uint) error {
+	if _, err := dds.GetViewByID(ctx, id); err != nil {
+		return err
+	}
+	return global.DB.WithContext(ctx).Delete(&model.DynamicView{}, id).Error
+}
+
+// maskViewConfig 去掉视图配置中不可见的字段，保存的视图不能按不可见的字段过滤和排序
+func maskViewConfig(permission *model.UserPermission, config *model.ViewConfig) {
+	MaskRecord(permission, config.Filters)
+	if config.Sort.Field != "" && !permission.CanViewField(config.Sort.Field) {
+		config.Sort = model.ViewSort{}
+	}
+	columns := config.Columns[:0]
+	for _, column := range config.Columns {
+		if permission.CanViewField(column) {
+			columns = append(columns, column)
+		}
+	}
+	config.Columns = columns
 }
 
-// ApplyView 应用视图
-func (dds *DynamicDataService) ApplyView(viewID uint, params map[string]interface{}) (interface{}, error) {
+// ApplyView 应用视图，只返回数据范围内的数据，视图配置中不可见的字段会被忽略
+func (dds *DynamicDataService) ApplyView(viewID uint, params map[string]interface{}, permission *model.UserPermission, scope *DataScope) (map[string]interface{}, error) {
 	// 获取视图配置
-	view, err := dds.GetViewByID(viewID)
+	view, err := dds.GetViewByID(scope.tenantContext(), viewID)
 	if err != nil {
 		return nil, fmt.Errorf("视图不存在: %v", err)
 	}
@@ -577,9 +713,10 @@ func (dds *DynamicDataService) ApplyView(viewID uint, params map[string]interfac
 	if err != nil {
 		return nil, fmt.Errorf("解析视图配置失败: %v", err)
 	}
+	maskViewConfig(permission, config)
 
 	// 获取表定义
-	table, err := (&DynamicTableService{}).GetTableByID(view.TableID)
+	table, err := (&DynamicTableService{}).GetTableByID(scope.tenantContext(), view.TableID)
 	if err != nil {
 		return nil, fmt.Errorf("获取表定义失败: %v", err)
 	}
@@ -617,7 +754,7 @@ func (dds *DynamicDataService) ApplyView(viewID uint, params map[string]interfac
 	}
 
 	// 执行查询
-	data, total, err := dds.GetDataList(table.TableName, page, pageSize, filters, orderBy)
+	data, total, err := dds.GetDataList(table.TableName, page, pageSize, filters, orderBy, scope)
 	if err != nil {
 		return nil, fmt.Errorf("查询数据失败: %v", err)
 	}
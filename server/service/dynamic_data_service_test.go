@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+)
+
+// seedOrdersTable 创建租户1的订单表，只定义title字段，并插入一条租户1的数据
+func seedOrdersTable(t *testing.T) *DataScope {
+	t.Helper()
+	db := useTestDB(t, &model.DynamicTable{}, &model.DynamicField{}, &model.DynamicView{})
+	table := &model.DynamicTable{ID: 1, Name: "orders", TableName: "dyn_orders", TenantID: 1, FieldDefinitions: []model.DynamicField{
+		{FieldName: "title", DisplayName: "标题", FieldType: "string", Status: 1},
+	}}
+	if err := db.Create(table).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`CREATE TABLE dyn_orders (id INTEGER PRIMARY KEY AUTOINCREMENT, created_at DATETIME, updated_at DATETIME,
+		deleted_at DATETIME, created_by INTEGER, dept_id INTEGER, tenant_id INTEGER, title TEXT)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO dyn_orders (title, created_by, dept_id, tenant_id) VALUES ('first', 1, 1, 1)").Error; err != nil {
+		t.Fatal(err)
+	}
+	return &DataScope{UserID: 1, DeptID: 1, TenantID: 1, Clause: "tenant_id = ?", Args: []interface{}{uint(1)}}
+}
+
+// TestUpdateDataRejectsUndeclaredColumns 更新时只能写入已定义的字段，大小写不同的系统列也不能写入
+func TestUpdateDataRejectsUndeclaredColumns(t *testing.T) {
+	scope := seedOrdersTable(t)
+	service := &DynamicDataService{}
+
+	for _, data := range []map[string]interface{}{
+		{"TENANT_ID": 2},
+		{"Dept_Id": 2},
+		{"Created_By": 2},
+		{"title": "x", "remark": "y"},
+	} {
+		_, err := service.UpdateData("dyn_orders", 1, data, scope)
+		var columnErr *UnknownColumnError
+		if !errors.As(err, &columnErr) {
+			t.Errorf("更新 %v: err = %v，期望UnknownColumnError", data, err)
+		}
+	}
+
+	record, err := service.UpdateData("dyn_orders", 1, map[string]interface{}{"title": "second"}, scope)
+	if err != nil {
+		t.Fatalf("更新已定义的字段: %v", err)
+	}
+	if record["title"] != "second" || record["tenant_id"] != int64(1) || record["dept_id"] != int64(1) {
+		t.Errorf("更新后的数据 = %v，期望title为second且系统列不变", record)
+	}
+}
+
+// TestCreateDataRejectsUndeclaredColumns 插入的列名必须是已定义的字段，非法列名不会拼接进SQL
+func TestCreateDataRejectsUndeclaredColumns(t *testing.T) {
+	scope := seedOrdersTable(t)
+
+	for _, data := range []map[string]interface{}{
+		{"title": "x", "remark": "y"},
+		{"title": "x", "title) VALUES (1); DROP TABLE dyn_orders; --": "y"},
+		{"title": "x", "Tenant_ID": 2},
+	} {
+		_, err := (&DynamicDataService{}).CreateData("dyn_orders", data, scope)
+		var columnErr *UnknownColumnError
+		if !errors.As(err, &columnErr) {
+			t.Errorf("插入 %v: err = %v，期望UnknownColumnError", data, err)
+		}
+	}
+
+	var count int64
+	if err := systemDB().Table("dyn_orders").Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("订单表有%d条数据，err = %v，期望1", count, err)
+	}
+}
+
+// TestApplyViewIgnoresHiddenFields 保存的视图按不可见字段过滤和排序时，这些条件不生效
+func TestApplyViewIgnoresHiddenFields(t *testing.T) {
+	scope := seedOrdersTable(t)
+	view := &model.DynamicView{TableID: 1, ViewName: "按创建人"}
+	if err := view.SetViewConfig(&model.ViewConfig{
+		Columns: []string{"title", "created_by"},
+		Filters: map[string]interface{}{"created_by": 2},
+		Sort:    model.ViewSort{Field: "created_by", Order: "desc"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	service := &DynamicDataService{}
+	if err := service.CreateView(global.WithTenant(context.Background(), 1), view); err != nil {
+		t.Fatal(err)
+	}
+	permission := &model.UserPermission{CanView: true, FieldPermissions: map[string]model.FieldPermission{
+		"created_by": {FieldName: "created_by", CanView: false},
+	}}
+
+	result, err := service.ApplyView(view.ID, map[string]interface{}{}, permission, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["total"] != int64(1) {
+		t.Errorf("total = %v，期望不可见字段的过滤条件被忽略", result["total"])
+	}
+	config := result["config"].(*model.ViewConfig)
+	if len(config.Filters) != 0 || config.Sort.Field != "" || len(config.Columns) != 1 {
+		t.Errorf("config = %+v，期望去掉不可见的字段", config)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -9,13 +10,29 @@ import (
 	"go-react-admin/model"
 )
 
+// DynamicFieldService 动态字段服务，ctx绑定租户时只能访问该租户动态表的字段
 type DynamicFieldService struct{}
 
+// fieldTable 获取字段所属的动态表，ctx绑定租户时其他租户的表视为不存在
+func fieldTable(ctx context.Context, tableID uint) (*model.DynamicTable, error) {
+	var table model.DynamicTable
+	if err := global.DB.WithContext(ctx).First(&table, tableID).Error; err != nil {
+		return nil, fmt.Errorf("获取表信息失败: %v", err)
+	}
+	return &table, nil
+}
+
 // CreateField 创建动态字段
-func (dfs *DynamicFieldService) CreateField(field *model.DynamicField) error {
+func (dfs *DynamicFieldService) CreateField(ctx context.Context, field *model.DynamicField) error {
+	// 获取表信息
+	table, err := fieldTable(ctx, field.TableID)
+	if err != nil {
+		return err
+	}
+
 	// 检查字段名是否已存在
 	var count int64
-	global.DB.Model(&model.DynamicField{}).Where("table_id = ? AND field_name = ?",
+	global.DB.WithContext(ctx).Model(&model.DynamicField{}).Where("table_id = ? AND field_name = ?",
 		field.TableID, field.FieldName).Count(&count)
 	if count > 0 {
 		return errors.New("字段名已存在")
@@ -26,14 +43,8 @@ func (dfs *DynamicFieldService) CreateField(field *model.DynamicField) error {
 		return err
 	}
 
-	// 获取表信息
-	var table model.DynamicTable
-	if err := global.DB.First(&table, field.TableID).Error; err != nil {
-		return fmt.Errorf("获取表信息失败: %v", err)
-	}
-
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -56,35 +67,41 @@ func (dfs *DynamicFieldService) CreateField(field *model.DynamicField) error {
 }
 
 // GetFieldsByTableID 根据表ID获取字段列表
-func (dfs *DynamicFieldService) GetFieldsByTableID(tableID uint) ([]model.DynamicField, error) {
+func (dfs *DynamicFieldService) GetFieldsByTableID(ctx context.Context, tableID uint) ([]model.DynamicField, error) {
+	if _, err := fieldTable(ctx, tableID); err != nil {
+		return nil, err
+	}
 	var fields []model.DynamicField
-	if err := global.DB.Where("table_id = ?", tableID).Order("sort_order ASC, id ASC").Find(&fields).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Where("table_id = ?", tableID).Order("sort_order ASC, id ASC").Find(&fields).Error; err != nil {
 		return nil, err
 	}
 	return fields, nil
 }
 
-// GetFieldByID 根据ID获取字段
-func (dfs *DynamicFieldService) GetFieldByID(id uint) (*model.DynamicField, error) {
+// GetFieldByID 根据ID获取字段，字段所属的表不属于ctx绑定的租户时返回错误
+func (dfs *DynamicFieldService) GetFieldByID(ctx context.Context, id uint) (*model.DynamicField, error) {
 	var field model.DynamicField
-	if err := global.DB.First(&field, id).Error; err != nil {
+	if err := global.DB.WithContext(ctx).First(&field, id).Error; err != nil {
+		return nil, err
+	}
+	if _, err := fieldTable(ctx, field.TableID); err != nil {
 		return nil, err
 	}
 	return &field, nil
 }
 
 // UpdateField 更新字段
-func (dfs *DynamicFieldService) UpdateField(field *model.DynamicField) error {
+func (dfs *DynamicFieldService) UpdateField(ctx context.Context, field *model.DynamicField) error {
 	// 检查字段是否存在
-	var existingField model.DynamicField
-	if err := global.DB.First(&existingField, field.ID).Error; err != nil {
+	existingField, err := dfs.GetFieldByID(ctx, field.ID)
+	if err != nil {
 		return err
 	}
 
 	// 如果修改了字段名，检查是否重复
 	if existingField.FieldName != field.FieldName {
 		var count int64
-		global.DB.Model(&model.DynamicField{}).Where("table_id = ? AND field_name = ? AND id != ?",
+		global.DB.WithContext(ctx).Model(&model.DynamicField{}).Where("table_id = ? AND field_name = ? AND id != ?",
 			field.TableID, field.FieldName, field.ID).Count(&count)
 		if count > 0 {
 			return errors.New("字段名已存在")
@@ -97,13 +114,13 @@ func (dfs *DynamicFieldService) UpdateField(field *model.DynamicField) error {
 	}
 
 	// 获取表信息
-	var table model.DynamicTable
-	if err := global.DB.First(&table, field.TableID).Error; err != nil {
-		return fmt.Errorf("获取表信息失败: %v", err)
+	table, err := fieldTable(ctx, field.TableID)
+	if err != nil {
+		return err
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -126,21 +143,21 @@ func (dfs *DynamicFieldService) UpdateField(field *model.DynamicField) error {
 }
 
 // DeleteField 删除字段
-func (dfs *DynamicFieldService) DeleteField(id uint) error {
+func (dfs *DynamicFieldService) DeleteField(ctx context.Context, id uint) error {
 	// 检查字段是否存在
-	field, err := dfs.GetFieldByID(id)
+	field, err := dfs.GetFieldByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	// 获取表信息
-	var table model.DynamicTable
-	if err := global.DB.First(&table, field.TableID).Error; err != nil {
-		return fmt.Errorf("获取表信息失败: %v", err)
+	table, err := fieldTable(ctx, field.TableID)
+	if err != nil {
+		return err
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -164,14 +181,17 @@ func (dfs *DynamicFieldService) DeleteField(id uint) error {
 }
 
 // UpdateFieldOrder 更新字段排序
-func (dfs *DynamicFieldService) UpdateFieldOrder(fieldIDs []uint) error {
+func (dfs *DynamicFieldService) UpdateFieldOrder(ctx context.Context, fieldIDs []uint) error {
 	if len(fieldIDs) == 0 {
 		return nil
 	}
 
 	// 更新每个字段的排序
 	for index, fieldID := range fieldIDs {
-		if err := global.DB.Model(&model.DynamicField{}).
+		if _, err := dfs.GetFieldByID(ctx, fieldID); err != nil {
+			return err
+		}
+		if err := global.DB.WithContext(ctx).Model(&model.DynamicField{}).
 			Where("id = ?", fieldID).
 			Update("sort_order", index+1).Error; err != nil {
 			return err
@@ -182,9 +202,9 @@ func (dfs *DynamicFieldService) UpdateFieldOrder(fieldIDs []uint) error {
 }
 
 // ToggleFieldStatus 切换字段状态
-func (dfs *DynamicFieldService) ToggleFieldStatus(id uint) error {
-	var field model.DynamicField
-	if err := global.DB.First(&field, id).Error; err != nil {
+func (dfs *DynamicFieldService) ToggleFieldStatus(ctx context.Context, id uint) error {
+	field, err := dfs.GetFieldByID(ctx, id)
+	if err != nil {
 		return err
 	}
 
@@ -195,13 +215,19 @@ func (dfs *DynamicFieldService) ToggleFieldStatus(id uint) error {
 		field.Status = 1
 	}
 
-	return global.DB.Save(&field).Error
+	return global.DB.WithContext(ctx).Save(field).Error
 }
 
 // BatchCreateFields 批量创建字段
-func (dfs *DynamicFieldService) BatchCreateFields(fields []model.DynamicField) error {
+func (dfs *DynamicFieldService) BatchCreateFields(ctx context.Context, fields []model.DynamicField) error {
+	for _, field := range fields {
+		if _, err := fieldTable(ctx, field.TableID); err != nil {
+			return err
+		}
+	}
+
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -585,12 +611,12 @@ func isValidFieldName(name string) bool {
 // isReservedFieldName 检查是否为保留字段名
 func isReservedFieldName(name string) bool {
 	reservedFields := []string{
-		"id", "created_at", "updated_at", "deleted_at", "tenant_id",
+		"id", "created_at", "updated_at", "deleted_at", "tenant_id", "created_by", "dept_id",
 		"password", "token", "session", "admin", "root", "system",
 	}
 
 	for _, reserved := range reservedFields {
-		if name == reserved {
+		if strings.EqualFold(name, reserved) {
 			return true
 		}
 	}
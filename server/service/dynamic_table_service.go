@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
@@ -13,10 +14,16 @@ import (
 	"gorm.io/gorm"
 )
 
+// DynamicTableService 动态表服务，ctx绑定租户时只能访问该租户的动态表
 type DynamicTableService struct{}
 
+// uniqueNameDB 动态表名称和物理表名全局唯一，检查重名时跳过租户隔离
+func uniqueNameDB(ctx context.Context) *gorm.DB {
+	return global.DB.WithContext(global.WithoutTenantScope(ctx))
+}
+
 // CreateTable 创建动态表
-func (dts *DynamicTableService) CreateTable(table *model.DynamicTable) error {
+func (dts *DynamicTableService) CreateTable(ctx context.Context, table *model.DynamicTable) error {
 	// 如果TableName为空，从Name生成
 	if table.TableName == "" {
 		table.TableName = "dyn_" + strings.ToLower(strings.ReplaceAll(table.Name, " ", "_"))
@@ -24,18 +31,18 @@ func (dts *DynamicTableService) CreateTable(table *model.DynamicTable) error {
 
 	// 检查表名是否已存在
 	var count int64
-	global.DB.Model(&model.DynamicTable{}).Where("table_name = ?", table.TableName).Count(&count)
+	uniqueNameDB(ctx).Model(&model.DynamicTable{}).Where("table_name = ?", table.TableName).Count(&count)
 	if count > 0 {
 		return errors.New("表名已存在")
 	}
 
 	// 验证表名
-	if valid, err := dts.ValidateTableName(table.TableName); !valid {
+	if valid, err := dts.ValidateTableName(ctx, table.TableName); !valid {
 		return err
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -123,11 +130,11 @@ func (dts *DynamicTableService) CreateTable(table *model.DynamicTable) error {
 }
 
 // GetTableList 获取动态表列表
-func (dts *DynamicTableService) GetTableList(page, pageSize int, search string) ([]model.DynamicTable, int64, error) {
+func (dts *DynamicTableService) GetTableList(ctx context.Context, page, pageSize int, search string) ([]model.DynamicTable, int64, error) {
 	var tables []model.DynamicTable
 	var total int64
 
-	db := global.DB.Model(&model.DynamicTable{})
+	db := global.DB.WithContext(ctx).Model(&model.DynamicTable{})
 
 	// 搜索条件
 	if search != "" {
@@ -150,34 +157,34 @@ func (dts *DynamicTableService) GetTableList(page, pageSize int, search string)
 }
 
 // GetTableByID 根据ID获取动态表
-func (dts *DynamicTableService) GetTableByID(id uint) (*model.DynamicTable, error) {
+func (dts *DynamicTableService) GetTableByID(ctx context.Context, id uint) (*model.DynamicTable, error) {
 	var table model.DynamicTable
-	if err := global.DB.Preload("FieldDefinitions").First(&table, id).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Preload("FieldDefinitions").First(&table, id).Error; err != nil {
 		return nil, err
 	}
 	return &table, nil
 }
 
 // GetTableByName 根据表名获取动态表
-func (dts *DynamicTableService) GetTableByName(tableName string) (*model.DynamicTable, error) {
+func (dts *DynamicTableService) GetTableByName(ctx context.Context, tableName string) (*model.DynamicTable, error) {
 	var table model.DynamicTable
-	if err := global.DB.Preload("FieldDefinitions").Where("table_name = ?", tableName).First(&table).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Preload("FieldDefinitions").Where("table_name = ?", tableName).First(&table).Error; err != nil {
 		return nil, err
 	}
 	return &table, nil
 }
 
 // UpdateTable 更新动态表
-func (dts *DynamicTableService) UpdateTable(table *model.DynamicTable) error {
+func (dts *DynamicTableService) UpdateTable(ctx context.Context, table *model.DynamicTable) error {
 	// 检查表是否存在
 	var existingTable model.DynamicTable
-	if err := global.DB.Preload("FieldDefinitions").First(&existingTable, table.ID).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Preload("FieldDefinitions").First(&existingTable, table.ID).Error; err != nil {
 		return err
 	}
 
 	// 检查名称是否已存在（排除当前记录）
 	var count int64
-	if err := global.DB.Model(&model.DynamicTable{}).
+	if err := uniqueNameDB(ctx).Model(&model.DynamicTable{}).
 		Where("name = ? AND id != ?", table.Name, table.ID).
 		Count(&count).Error; err != nil {
 		return err
@@ -187,7 +194,7 @@ func (dts *DynamicTableService) UpdateTable(table *model.DynamicTable) error {
 	}
 
 	// 检查表名是否已存在（排除当前记录）
-	if err := global.DB.Model(&model.DynamicTable{}).
+	if err := uniqueNameDB(ctx).Model(&model.DynamicTable{}).
 		Where("table_name = ? AND id != ?", table.TableName, table.ID).
 		Count(&count).Error; err != nil {
 		return err
@@ -197,7 +204,7 @@ func (dts *DynamicTableService) UpdateTable(table *model.DynamicTable) error {
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -227,16 +234,16 @@ func (dts *DynamicTableService) UpdateTable(table *model.DynamicTable) error {
 }
 
 // CreateDynamicTable 创建动态表
-func (dts *DynamicTableService) CreateDynamicTable(table *model.DynamicTable) (*model.DynamicTable, error) {
+func (dts *DynamicTableService) CreateDynamicTable(ctx context.Context, table *model.DynamicTable) (*model.DynamicTable, error) {
 	// 检查表名是否已存在
 	var count int64
-	global.DB.Model(&model.DynamicTable{}).Where("table_name = ?", table.TableName).Count(&count)
+	uniqueNameDB(ctx).Model(&model.DynamicTable{}).Where("table_name = ?", table.TableName).Count(&count)
 	if count > 0 {
 		return nil, errors.New("表名已存在")
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 
 	// 创建表记录
 	if err := tx.Create(table).Error; err != nil {
@@ -254,12 +261,12 @@ func (dts *DynamicTableService) CreateDynamicTable(table *model.DynamicTable) (*
 }
 
 // GetDynamicTableList 获取动态表列表
-func (dts *DynamicTableService) GetDynamicTableList(page, pageSize int, keyword string) ([]model.DynamicTable, int64, error) {
+func (dts *DynamicTableService) GetDynamicTableList(ctx context.Context, page, pageSize int, keyword string) ([]model.DynamicTable, int64, error) {
 	var tables []model.DynamicTable
 	var total int64
 
 	// 构建查询条件
-	db := global.DB.Model(&model.DynamicTable{})
+	db := global.DB.WithContext(ctx).Model(&model.DynamicTable{})
 
 	// 搜索条件
 	if keyword != "" {
@@ -282,33 +289,33 @@ func (dts *DynamicTableService) GetDynamicTableList(page, pageSize int, keyword
 }
 
 // GetDynamicTableByID 根据ID获取动态表
-func (dts *DynamicTableService) GetDynamicTableByID(id uint) (*model.DynamicTable, error) {
+func (dts *DynamicTableService) GetDynamicTableByID(ctx context.Context, id uint) (*model.DynamicTable, error) {
 	var table model.DynamicTable
-	if err := global.DB.Preload("FieldDefinitions").First(&table, id).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Preload("FieldDefinitions").First(&table, id).Error; err != nil {
 		return nil, err
 	}
 	return &table, nil
 }
 
 // GetDynamicTableByName 根据表名获取动态表
-func (dts *DynamicTableService) GetDynamicTableByName(tableName string) (*model.DynamicTable, error) {
+func (dts *DynamicTableService) GetDynamicTableByName(ctx context.Context, tableName string) (*model.DynamicTable, error) {
 	var table model.DynamicTable
-	if err := global.DB.Preload("FieldDefinitions").Where("table_name = ?", tableName).First(&table).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Preload("FieldDefinitions").Where("table_name = ?", tableName).First(&table).Error; err != nil {
 		return nil, err
 	}
 	return &table, nil
 }
 
 // UpdateDynamicTable 更新动态表
-func (dts *DynamicTableService) UpdateDynamicTable(table *model.DynamicTable) (*model.DynamicTable, error) {
+func (dts *DynamicTableService) UpdateDynamicTable(ctx context.Context, table *model.DynamicTable) (*model.DynamicTable, error) {
 	var existingTable model.DynamicTable
-	if err := global.DB.Preload("FieldDefinitions").First(&existingTable, table.ID).Error; err != nil {
+	if err := global.DB.WithContext(ctx).Preload("FieldDefinitions").First(&existingTable, table.ID).Error; err != nil {
 		return nil, err
 	}
 
 	// 检查名称是否已存在（排除当前记录）
 	var count int64
-	if err := global.DB.Model(&model.DynamicTable{}).
+	if err := uniqueNameDB(ctx).Model(&model.DynamicTable{}).
 		Where("name = ? AND id != ?", table.Name, table.ID).
 		Count(&count).Error; err != nil {
 		return nil, err
@@ -318,7 +325,7 @@ func (dts *DynamicTableService) UpdateDynamicTable(table *model.DynamicTable) (*
 	}
 
 	// 检查表名是否已存在（排除当前记录）
-	if err := global.DB.Model(&model.DynamicTable{}).
+	if err := uniqueNameDB(ctx).Model(&model.DynamicTable{}).
 		Where("table_name = ? AND id != ?", table.TableName, table.ID).
 		Count(&count).Error; err != nil {
 		return nil, err
@@ -328,7 +335,7 @@ func (dts *DynamicTableService) UpdateDynamicTable(table *model.DynamicTable) (*
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -358,15 +365,15 @@ func (dts *DynamicTableService) UpdateDynamicTable(table *model.DynamicTable) (*
 }
 
 // DeleteTable 删除动态表
-func (dts *DynamicTableService) DeleteTable(id uint) error {
+func (dts *DynamicTableService) DeleteTable(ctx context.Context, id uint) error {
 	// 获取表信息
-	table, err := dts.GetTableByID(id)
+	table, err := dts.GetTableByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("获取表信息失败: %v", err)
 	}
 
 	// 开启事务
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -430,9 +437,9 @@ func (dts *DynamicTableService) DeleteTable(id uint) error {
 }
 
 // ToggleTableStatus 切换表状态
-func (dts *DynamicTableService) ToggleTableStatus(id uint) error {
+func (dts *DynamicTableService) ToggleTableStatus(ctx context.Context, id uint) error {
 	var table model.DynamicTable
-	if err := global.DB.First(&table, id).Error; err != nil {
+	if err := global.DB.WithContext(ctx).First(&table, id).Error; err != nil {
 		return err
 	}
 
@@ -443,7 +450,7 @@ func (dts *DynamicTableService) ToggleTableStatus(id uint) error {
 		table.Status = 1
 	}
 
-	return global.DB.Save(&table).Error
+	return global.DB.WithContext(ctx).Save(&table).Error
 }
 
 // createPhysicalTable 创建物理表
@@ -457,6 +464,8 @@ func (dts *DynamicTableService) createPhysicalTable(tx *gorm.DB, table *model.Dy
 	columns = append(columns, "updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP")
 	columns = append(columns, "deleted_at TIMESTAMP NULL DEFAULT NULL")
 	columns = append(columns, "tenant_id BIGINT DEFAULT 0")
+	columns = append(columns, "created_by BIGINT DEFAULT 0")
+	columns = append(columns, "dept_id BIGINT DEFAULT 0")
 
 	// 添加动态字段（如果有的话）
 	if table.FieldDefinitions != nil {
@@ -472,6 +481,8 @@ func (dts *DynamicTableService) createPhysicalTable(tx *gorm.DB, table *model.Dy
 	columns = append(columns, "INDEX idx_deleted_at (deleted_at)")
 	columns = append(columns, "INDEX idx_tenant_id (tenant_id)")
 	columns = append(columns, "INDEX idx_created_at (created_at)")
+	columns = append(columns, "INDEX idx_created_by (created_by)")
+	columns = append(columns, "INDEX idx_dept_id (dept_id)")
 
 	// 确保表名安全
 	safeTableName := SanitizeTableName(table.TableName)
@@ -536,9 +547,9 @@ func (dts *DynamicTableService) buildColumnDefinition(field *model.DynamicField)
 }
 
 // GetTableSchema 获取表结构信息
-func (dts *DynamicTableService) GetTableSchema(tableName string) (map[string]interface{}, error) {
+func (dts *DynamicTableService) GetTableSchema(ctx context.Context, tableName string) (map[string]interface{}, error) {
 	// 获取表信息
-	table, err := dts.GetTableByName(tableName)
+	table, err := dts.GetTableByName(ctx, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -553,7 +564,7 @@ func (dts *DynamicTableService) GetTableSchema(tableName string) (map[string]int
 }
 
 // ValidateTableName 验证表名
-func (dts *DynamicTableService) ValidateTableName(tableName string) (bool, error) {
+func (dts *DynamicTableService) ValidateTableName(ctx context.Context, tableName string) (bool, error) {
 	// 检查表名格式
 	if tableName == "" {
 		return false, errors.New("表名不能为空")
@@ -586,7 +597,7 @@ func (dts *DynamicTableService) ValidateTableName(tableName string) (bool, error
 
 	// 检查表名是否已存在
 	var count int64
-	if err := global.DB.Model(&model.DynamicTable{}).Where("table_name = ?", tableName).Count(&count).Error; err != nil {
+	if err := uniqueNameDB(ctx).Model(&model.DynamicTable{}).Where("table_name = ?", tableName).Count(&count).Error; err != nil {
 		return false, err
 	}
 
@@ -640,12 +651,12 @@ func isReservedWord(word string) bool {
 }
 
 // UpdateTableStatus 更新表状态
-func (dts *DynamicTableService) UpdateTableStatus(id uint, status int) error {
+func (dts *DynamicTableService) UpdateTableStatus(ctx context.Context, id uint, status int) error {
 	var table model.DynamicTable
-	if err := global.DB.First(&table, id).Error; err != nil {
+	if err := global.DB.WithContext(ctx).First(&table, id).Error; err != nil {
 		return err
 	}
 
 	table.Status = status
-	return global.DB.Save(&table).Error
+	return global.DB.WithContext(ctx).Save(&table).Error
 }
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-react-admin/model"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const ldapTimeout = 10 * time.Second
+
+// ErrLdapConfig LDAP配置不完整或无效
+var ErrLdapConfig = errors.New("LDAP配置无效")
+
+// LdapEntry 目录中的用户
+type LdapEntry struct {
+	DN       string   `json:"dn"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Name     string   `json:"name"`
+	Groups   []string `json:"groups"` // 用户所属组的DN
+}
+
+// LdapDirectory 按租户配置访问LDAP目录，不依赖数据库
+type LdapDirectory struct {
+	Config *model.LdapConfig
+}
+
+// LdapConn 已完成服务账号绑定的目录连接
+type LdapConn struct {
+	conn   *ldap.Conn
+	config *model.LdapConfig
+}
+
+// tlsConfig 根据配置生成TLS参数
+func (d *LdapDirectory) tlsConfig(host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: d.Config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if d.Config.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(d.Config.CACert)) {
+			return nil, fmt.Errorf("%w: CA证书格式错误", ErrLdapConfig)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// Dial 连接目录并使用服务账号绑定，ldap://地址可以通过StartTLS升级为加密连接
+func (d *LdapDirectory) Dial() (*LdapConn, error) {
+	u, err := url.Parse(d.Config.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: 地址必须以ldap://或ldaps://开头", ErrLdapConfig)
+	}
+	if d.Config.StartTLS && u.Scheme == "ldaps" {
+		return nil, fmt.Errorf("%w: ldaps://地址不能同时使用StartTLS", ErrLdapConfig)
+	}
+	tlsCfg, err := d.tlsConfig(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ldap.DialURL(d.Config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(tlsCfg))
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP失败: %w", err)
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if d.Config.StartTLS {
+		if err := conn.StartTLS(tlsCfg); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS失败: %w", err)
+		}
+	}
+	if d.Config.BindDN != "" {
+		if err := conn.Bind(d.Config.BindDN, d.Config.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP服务账号绑定失败: %w", err)
+		}
+	}
+	return &LdapConn{conn: conn, config: d.Config}, nil
+}
+
+// Authenticate 查找用户后使用用户DN和密码绑定，密码为空时直接拒绝以免被当作匿名绑定
+func (d *LdapDirectory) Authenticate(username, password string) (*LdapEntry, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := d.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := conn.Find(username)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := conn.conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP用户绑定失败: %w", err)
+	}
+	return entry, nil
+}
+
+// Close 关闭连接
+func (c *LdapConn) Close() {
+	c.conn.Close()
+}
+
+// Find 按用户名查找用户及其所属组，用户不存在时返回nil
+func (c *LdapConn) Find(username string) (*LdapEntry, error) {
+	cfg := c.config
+	if cfg.UserBaseDN == "" || !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, fmt.Errorf("%w: 缺少用户搜索条件", ErrLdapConfig)
+	}
+	usernameAttr := ldapAttr(cfg.UsernameAttr, "uid")
+	emailAttr := ldapAttr(cfg.EmailAttr, "mail")
+	nameAttr := ldapAttr(cfg.NameAttr, "cn")
+
+	result, err := c.conn.Search(ldap.NewSearchRequest(
+		cfg.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false,
+		strings.ReplaceAll(cfg.UserFilter, "%s", ldap.EscapeFilter(username)),
+		[]string{usernameAttr, emailAttr, nameAttr, "memberOf"},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, nil
+		}
+		// 匹配多个用户时返回的是大小超限错误
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, fmt.Errorf("LDAP用户搜索条件匹配到多个用户: %s", username)
+		}
+		return nil, fmt.Errorf("LDAP查询用户失败: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("LDAP用户搜索条件匹配到多个用户: %s", username)
+	}
+
+	raw := result.Entries[0]
+	entry := &LdapEntry{
+		DN:       raw.DN,
+		Username: raw.GetAttributeValue(usernameAttr),
+		Email:    raw.GetAttributeValue(emailAttr),
+		Name:     raw.GetAttributeValue(nameAttr),
+	}
+	if entry.Username == "" {
+		entry.Username = username
+	}
+
+	if cfg.GroupFilter == "" {
+		entry.Groups = raw.GetAttributeValues("memberOf")
+		return entry, nil
+	}
+	groupBaseDN := cfg.GroupBaseDN
+	if groupBaseDN == "" {
+		groupBaseDN = cfg.UserBaseDN
+	}
+	groups, err := c.conn.Search(ldap.NewSearchRequest(
+		groupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout.Seconds()), false,
+		strings.ReplaceAll(cfg.GroupFilter, "%s", ldap.EscapeFilter(raw.DN)),
+		[]string{"dn"},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, fmt.Errorf("LDAP查询用户组失败: %w", err)
+	}
+	if groups != nil {
+		for _, group := range groups.Entries {
+			entry.Groups = append(entry.Groups, group.DN)
+		}
+	}
+	return entry, nil
+}
+
+// ldapAttr 属性名为空时使用默认值
+func ldapAttr(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// ldapGroupRoles 按组DN映射计算用户应有的角色名，DN比较不区分大小写和空格
+// managed为映射中出现的全部角色名，只有这些角色会被同步
+func ldapGroupRoles(mapping map[string]string, groups []string) (desired map[string]bool, managed []string) {
+	desired = make(map[string]bool)
+	seen := make(map[string]bool)
+	for groupDN, roleName := range mapping {
+		if !seen[roleName] {
+			seen[roleName] = true
+			managed = append(managed, roleName)
+		}
+		for _, group := range groups {
+			if ldapDNEqual(groupDN, group) {
+				desired[roleName] = true
+				break
+			}
+		}
+	}
+	return desired, managed
+}
+
+// ldapDNEqual 比较两个DN是否相同
+func ldapDNEqual(a, b string) bool {
+	dnA, errA := ldap.ParseDN(a)
+	dnB, errB := ldap.ParseDN(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return dnA.EqualFold(dnB)
+}
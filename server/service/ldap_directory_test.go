@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go-react-admin/model"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+)
+
+const (
+	testServiceDN = "cn=svc,ou=people,dc=example,dc=org"
+	testAdminsDN  = "cn=admins,ou=groups,dc=example,dc=org"
+	testDevsDN    = "cn=devs,ou=groups,dc=example,dc=org"
+)
+
+// startTestDirectory 启动进程内的LDAP测试目录，alice属于admins和devs组，bob不属于任何组
+func startTestDirectory(t *testing.T, opts ...testdirectory.Option) *testdirectory.Directory {
+	t.Helper()
+	users := testdirectory.NewUsers(t, []string{"alice"},
+		testdirectory.WithMembersOf(t, testdirectory.NewMemberOf(t, []string{"admins", "devs"})...))
+	users = append(users, testdirectory.NewUsers(t, []string{"bob", "svc"})...)
+	groups := []*gldap.Entry{
+		testdirectory.NewGroup(t, "admins", []string{"alice"}),
+		testdirectory.NewGroup(t, "devs", []string{"alice"}),
+	}
+
+	opts = append([]testdirectory.Option{
+		testdirectory.WithLogger(t, hclog.NewNullLogger()),
+		testdirectory.WithDefaults(t, &testdirectory.Defaults{Users: users, Groups: groups}),
+	}, opts...)
+	return testdirectory.Start(t, opts...)
+}
+
+// testLdapConfig 测试目录对应的配置，测试目录的条目使用name和email属性
+func testLdapConfig(d *testdirectory.Directory, scheme string) *model.LdapConfig {
+	return &model.LdapConfig{
+		URL:          fmt.Sprintf("%s://%s:%d", scheme, d.Host(), d.Port()),
+		CACert:       d.Cert(),
+		BindDN:       testServiceDN,
+		BindPassword: "password",
+		UserBaseDN:   testdirectory.DefaultUserDN,
+		UserFilter:   "(cn=%s)",
+		UsernameAttr: "name",
+		EmailAttr:    "email",
+		NameAttr:     "name",
+	}
+}
+
+func TestLdapAuthenticateOverTLS(t *testing.T) {
+	d := startTestDirectory(t)
+	dir := &LdapDirectory{Config: testLdapConfig(d, "ldaps")}
+
+	entry, err := dir.Authenticate("alice", "password")
+	if err != nil {
+		t.Fatalf("验证失败: %v", err)
+	}
+	if entry.DN != "cn=alice,ou=people,dc=example,dc=org" || entry.Username != "alice" || entry.Email != "alice@example.com" {
+		t.Fatalf("用户信息错误: %+v", entry)
+	}
+	if len(entry.Groups) != 2 || !ldapDNEqual(entry.Groups[0], testAdminsDN) || !ldapDNEqual(entry.Groups[1], testDevsDN) {
+		t.Fatalf("memberOf读取错误: %v", entry.Groups)
+	}
+}
+
+func TestLdapAuthenticateRejects(t *testing.T) {
+	d := startTestDirectory(t)
+	dir := &LdapDirectory{Config: testLdapConfig(d, "ldaps")}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"错误密码", "alice", "wrong"},
+		{"用户不存在", "mallory", "password"},
+		{"空密码", "alice", ""},
+		{"过滤器注入", "*", "password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := dir.Authenticate(tt.username, tt.password); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("期望ErrInvalidCredentials，实际: %v", err)
+			}
+		})
+	}
+}
+
+func TestLdapStartTLSWithGroupSearch(t *testing.T) {
+	d := startTestDirectory(t, testdirectory.WithNoTLS(t))
+	config := testLdapConfig(d, "ldap")
+	config.StartTLS = true
+	config.GroupBaseDN = testdirectory.DefaultGroupDN
+	config.GroupFilter = "(member=%s)"
+	dir := &LdapDirectory{Config: config}
+
+	entry, err := dir.Authenticate("alice", "password")
+	if err != nil {
+		t.Fatalf("验证失败: %v", err)
+	}
+	desired, _ := ldapGroupRoles(map[string]string{testAdminsDN: "admin", testDevsDN: "developer"}, entry.Groups)
+	if !desired["admin"] || !desired["developer"] {
+		t.Fatalf("组查询错误: %v", entry.Groups)
+	}
+
+	entry, err = dir.Authenticate("bob", "password")
+	if err != nil {
+		t.Fatalf("验证失败: %v", err)
+	}
+	if len(entry.Groups) != 0 {
+		t.Fatalf("bob不应属于任何组: %v", entry.Groups)
+	}
+}
+
+func TestLdapDialErrors(t *testing.T) {
+	d := startTestDirectory(t)
+
+	untrusted := testLdapConfig(d, "ldaps")
+	untrusted.CACert = ""
+	badBind := testLdapConfig(d, "ldaps")
+	badBind.BindPassword = "wrong"
+	conflict := testLdapConfig(d, "ldaps")
+	conflict.StartTLS = true
+
+	tests := []struct {
+		name   string
+		config *model.LdapConfig
+	}{
+		{"证书不受信任", untrusted},
+		{"服务账号密码错误", badBind},
+		{"ldaps同时使用StartTLS", conflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&LdapDirectory{Config: tt.config}).Authenticate("alice", "password")
+			if err == nil || errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("期望连接错误，实际: %v", err)
+			}
+		})
+	}
+}
+
+func TestLdapGroupRoles(t *testing.T) {
+	mapping := map[string]string{
+		testAdminsDN:                         "admin",
+		testDevsDN:                           "developer",
+		"cn=ops,ou=groups,dc=example,dc=org": "developer",
+	}
+	tests := []struct {
+		name   string
+		groups []string
+		want   []string
+	}{
+		{"无组", nil, nil},
+		{"大小写和空格不同", []string{"CN=Admins, OU=Groups, DC=example, DC=org"}, []string{"admin"}},
+		{"多个组映射到同一角色", []string{"cn=ops,ou=groups,dc=example,dc=org"}, []string{"developer"}},
+		{"未映射的组", []string{"cn=other,ou=groups,dc=example,dc=org"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired, managed := ldapGroupRoles(mapping, tt.groups)
+			if len(managed) != 2 {
+				t.Fatalf("managed错误: %v", managed)
+			}
+			if len(desired) != len(tt.want) {
+				t.Fatalf("期望%v，实际%v", tt.want, desired)
+			}
+			for _, role := range tt.want {
+				if !desired[role] {
+					t.Fatalf("期望%v，实际%v", tt.want, desired)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLdapNotConfigured 租户没有LDAP配置
+	ErrLdapNotConfigured = errors.New("租户未配置LDAP")
+	// ErrLdapUserNotProvisioned 目录验证通过但本地没有对应用户且未开启自动创建
+	ErrLdapUserNotProvisioned = errors.New("该账号未开通，请联系管理员")
+	// ErrLdapSyncRunning 组同步正在进行
+	ErrLdapSyncRunning = errors.New("LDAP组同步正在进行，请稍后再试")
+)
+
+type LdapService struct{}
+
+// LdapSyncResult 组同步结果
+type LdapSyncResult struct {
+	Users   int `json:"users"`   // 参与同步的LDAP用户数
+	Updated int `json:"updated"` // 角色发生变化的用户数
+	Missing int `json:"missing"` // 目录中已不存在的用户数
+}
+
+// ldapSyncLock 同一时间只进行一次组同步
+var ldapSyncLock sync.Mutex
+
+// GetConfig 获取租户的LDAP配置
+func (s *LdapService) GetConfig(tenantID uint) (*model.LdapConfig, error) {
+	var config model.LdapConfig
+	if err := tenantDB(tenantID).Where("tenant_id = ?", tenantID).First(&config).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLdapNotConfigured
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SaveConfig 保存租户的LDAP配置并切换租户的身份验证方式，BindPassword为空表示保持不变
+func (s *LdapService) SaveConfig(config *model.LdapConfig, enabled bool) error {
+	return tenantDB(config.TenantID).Transaction(func(tx *gorm.DB) error {
+		var existing model.LdapConfig
+		err := tx.Where("tenant_id = ?", config.TenantID).First(&existing).Error
+		switch {
+		case err == nil:
+			config.ID = existing.ID
+			config.CreatedAt = existing.CreatedAt
+			config.LastSyncAt = existing.LastSyncAt
+			if config.BindPassword == "" {
+				config.BindPassword = existing.BindPassword
+			}
+			if err := tx.Save(config).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(config).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		provider := model.AuthProviderLocal
+		if enabled {
+			provider = model.AuthProviderLDAP
+		}
+		return tx.Model(&model.Tenant{}).Where("id = ?", config.TenantID).Update("auth_provider", provider).Error
+	})
+}
+
+// SyncTenant 按目录中的组成员关系同步租户内所有LDAP用户的角色
+func (s *LdapService) SyncTenant(tenantID uint) (*LdapSyncResult, error) {
+	if !ldapSyncLock.TryLock() {
+		return nil, ErrLdapSyncRunning
+	}
+	defer ldapSyncLock.Unlock()
+	return s.syncTenant(tenantID)
+}
+
+func (s *LdapService) syncTenant(tenantID uint) (*LdapSyncResult, error) {
+	config, err := s.GetConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	var users []model.User
+	if err := tenantDB(tenantID).Where("tenant_id = ? AND auth_source = ? AND type = ?", tenantID, model.AuthSourceLDAP, model.UserTypeNormal).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	result := &LdapSyncResult{Users: len(users)}
+	if len(users) > 0 {
+		conn, err := (&LdapDirectory{Config: config}).Dial()
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		for i := range users {
+			entry, err := conn.Find(users[i].Username)
+			if err != nil {
+				return result, err
+			}
+			// 已从目录中删除的用户移除映射的角色，账号本身保留由管理员处理
+			var groups []string
+			if entry == nil {
+				result.Missing++
+			} else {
+				groups = entry.Groups
+			}
+			changed, err := s.syncUserRoles(config, &users[i], groups)
+			if err != nil {
+				return result, err
+			}
+			if changed {
+				result.Updated++
+			}
+		}
+	}
+
+	now := time.Now()
+	if err := tenantDB(tenantID).Model(config).Update("last_sync_at", &now).Error; err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// syncDue 同步已到同步间隔的租户
+func (s *LdapService) syncDue(now time.Time) {
+	if !ldapSyncLock.TryLock() {
+		return
+	}
+	defer ldapSyncLock.Unlock()
+
+	var configs []model.LdapConfig
+	if err := systemDB().Joins("JOIN tenants ON tenants.id = ldap_configs.tenant_id AND tenants.deleted_at IS NULL").
+		Where("tenants.auth_provider = ? AND ldap_configs.sync_interval > 0", model.AuthProviderLDAP).
+		Find(&configs).Error; err != nil {
+		fmt.Printf("查询LDAP配置失败: %v\n", err)
+		return
+	}
+	for _, config := range configs {
+		interval := time.Duration(config.SyncInterval) * time.Minute
+		if config.LastSyncAt != nil && now.Sub(*config.LastSyncAt) < interval {
+			continue
+		}
+		if result, err := s.syncTenant(config.TenantID); err != nil {
+			fmt.Printf("租户%d的LDAP组同步失败: %v\n", config.TenantID, err)
+		} else if result.Updated > 0 || result.Missing > 0 {
+			fmt.Printf("租户%d的LDAP组同步完成: 用户%d 更新%d 缺失%d\n", config.TenantID, result.Users, result.Updated, result.Missing)
+		}
+	}
+}
+
+// StartLdapGroupSync 启动后台组同步，每分钟检查一次各租户是否到达同步间隔
+func StartLdapGroupSync() {
+	s := &LdapService{}
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			s.syncDue(now)
+		}
+	}()
+}
+
+// syncUserRoles 按组映射同步用户角色，只增删映射中出现的角色，其他手动分配的角色保持不变
+// 角色有变化时通过ApprovalService.SubmitSyncedUserRoles写入，启用审批时新增的角色待审批后生效，返回是否有变化
+func (s *LdapService) syncUserRoles(config *model.LdapConfig, user *model.User, groups []string) (bool, error) {
+	if len(config.GroupRoleMapping) == 0 {
+		return false, nil
+	}
+	desired, managed := ldapGroupRoles(config.GroupRoleMapping, groups)
+
+	var roles []model.Role
+	if err := tenantDB(config.TenantID).Where("tenant_id = ? AND name IN ?", config.TenantID, managed).Find(&roles).Error; err != nil {
+		return false, err
+	}
+	managedIDs := make(map[uint]bool, len(roles))
+	for _, role := range roles {
+		managedIDs[role.ID] = true
+	}
+	var assigned []uint
+	if err := tenantDB(config.TenantID).Model(&model.UserRole{}).Where("user_id = ? AND tenant_id = ?", user.ID, config.TenantID).
+		Pluck("role_id", &assigned).Error; err != nil {
+		return false, err
+	}
+
+	var roleIDs []uint
+	for _, roleID := range assigned {
+		if !managedIDs[roleID] {
+			roleIDs = append(roleIDs, roleID)
+		}
+	}
+	for _, role := range roles {
+		if desired[role.Name] {
+			roleIDs = append(roleIDs, role.ID)
+		}
+	}
+	if sameRoleIDs(assigned, roleIDs) {
+		return false, nil
+	}
+
+	// 新增角色与手动分配一样需要经过审批
+	_, err := (&ApprovalService{}).SubmitSyncedUserRoles(&UserRoleRequest{
+		UserID:   user.ID,
+		RoleIDs:  roleIDs,
+		TenantID: config.TenantID,
+		Reason:   "LDAP组映射同步",
+	})
+	if err != nil {
+		return false, fmt.Errorf("同步角色失败: %w", err)
+	}
+	return true, nil
+}
+
+// sameRoleIDs 判断两组角色ID是否相同
+func sameRoleIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]uint(nil), a...)
+	y := append([]uint(nil), b...)
+	sort.Slice(x, func(i, j int) bool { return x[i] < x[j] })
+	sort.Slice(y, func(i, j int) bool { return y[i] < y[j] })
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LdapAuthProvider 使用租户配置的LDAP目录验证
+type LdapAuthProvider struct{}
+
+// Authenticate 在目录中验证密码，找到或创建本地用户后按组映射同步角色
+func (p *LdapAuthProvider) Authenticate(ctx context.Context, tenantID uint, username, password string) (*model.User, error) {
+	s := &LdapService{}
+	config, err := s.GetConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := (&LdapDirectory{Config: config}).Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.User
+	err = systemDB().Where("username = ?", entry.Username).First(&user).Error
+	switch {
+	case err == nil:
+		// 用户名全局唯一，其他租户的同名用户不能通过本租户的目录登录
+		if user.TenantID != tenantID {
+			return nil, ErrInvalidCredentials
+		}
+		if user.AuthSource != model.AuthSourceLDAP {
+			if err := tenantDB(tenantID).Model(&user).Update("auth_source", model.AuthSourceLDAP).Error; err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !config.AutoProvision {
+			return nil, ErrLdapUserNotProvisioned
+		}
+		created, err := p.provisionUser(tenantID, entry)
+		if err != nil {
+			return nil, err
+		}
+		user = *created
+	default:
+		return nil, err
+	}
+
+	if _, err := s.syncUserRoles(config, &user, entry.Groups); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// provisionUser 根据目录中的信息创建用户
+func (p *LdapAuthProvider) provisionUser(tenantID uint, entry *LdapEntry) (*model.User, error) {
+	if len(entry.Username) > 50 {
+		return nil, ErrLdapUserNotProvisioned
+	}
+	// 目录用户没有可用的本地密码
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	name, email := entry.Name, entry.Email
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	if len(email) > 100 {
+		email = ""
+	}
+
+	user := &model.User{
+		Username:   entry.Username,
+		Password:   hashed,
+		Nickname:   name,
+		RealName:   name,
+		Email:      email,
+		Status:     1,
+		TenantID:   tenantID,
+		Type:       model.UserTypeNormal,
+		AuthSource: model.AuthSourceLDAP,
+	}
+	if err := tenantDB(tenantID).Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-react-admin/global"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LoginAttempt 登录失败计数
+type LoginAttempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// LoginAttemptStore 登录失败计数存储
+type LoginAttemptStore interface {
+	// Get 获取计数，不存在时返回零值
+	Get(key string) (*LoginAttempt, error)
+	// RecordFailure 失败次数加一，计数在window内没有新的失败时过期
+	RecordFailure(key string, window time.Duration) (*LoginAttempt, error)
+	// Lock 锁定到指定时间
+	Lock(key string, until time.Time) error
+	// Reset 清除计数和锁定
+	Reset(key string) error
+}
+
+// memoryAttemptStore 进程内的登录失败计数，未配置Redis时使用
+var memoryAttemptStore = &memoryLoginAttemptStore{items: make(map[string]*memoryLoginAttempt)}
+
+// GetLoginAttemptStore 获取登录失败计数存储，配置了Redis时使用Redis，否则使用内存
+func GetLoginAttemptStore() LoginAttemptStore {
+	if global.RedisClient != nil {
+		return &redisLoginAttemptStore{client: global.RedisClient}
+	}
+	return memoryAttemptStore
+}
+
+type memoryLoginAttempt struct {
+	LoginAttempt
+	expiresAt time.Time
+}
+
+// memoryLoginAttemptStore 基于内存的登录失败计数
+type memoryLoginAttemptStore struct {
+	mu    sync.Mutex
+	items map[string]*memoryLoginAttempt
+}
+
+// get 获取未过期的计数，调用方需持有锁
+func (s *memoryLoginAttemptStore) get(key string, now time.Time) *memoryLoginAttempt {
+	item, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if now.After(item.expiresAt) && now.After(item.LockedUntil) {
+		delete(s.items, key)
+		return nil
+	}
+	return item
+}
+
+// purge 清理过期计数，调用方需持有锁
+func (s *memoryLoginAttemptStore) purge(now time.Time) {
+	for key, item := range s.items {
+		if now.After(item.expiresAt) && now.After(item.LockedUntil) {
+			delete(s.items, key)
+		}
+	}
+}
+
+func (s *memoryLoginAttemptStore) Get(key string) (*LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.get(key, time.Now())
+	if item == nil {
+		return &LoginAttempt{}, nil
+	}
+	attempt := item.LoginAttempt
+	return &attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) RecordFailure(key string, window time.Duration) (*LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+	item := s.get(key, now)
+	if item == nil {
+		item = &memoryLoginAttempt{}
+		s.items[key] = item
+	}
+	item.Failures++
+	item.LastFailureAt = now
+	item.expiresAt = now.Add(window)
+
+	attempt := item.LoginAttempt
+	return &attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.get(key, time.Now())
+	if item == nil {
+		item = &memoryLoginAttempt{expiresAt: until}
+		s.items[key] = item
+	}
+	item.LockedUntil = until
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+	return nil
+}
+
+// redisLoginAttemptStore 基于Redis的登录失败计数，多实例部署时共享
+type redisLoginAttemptStore struct {
+	client *redis.Client
+}
+
+const redisLoginAttemptPrefix = "auth:login_attempt:"
+
+func (s *redisLoginAttemptStore) Get(key string) (*LoginAttempt, error) {
+	values, err := s.client.HGetAll(context.Background(), redisLoginAttemptPrefix+key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseRedisLoginAttempt(values), nil
+}
+
+func (s *redisLoginAttemptStore) RecordFailure(key string, window time.Duration) (*LoginAttempt, error) {
+	ctx := context.Background()
+	redisKey := redisLoginAttemptPrefix + key
+	now := time.Now()
+
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, redisKey, "failures", 1)
+	pipe.HSet(ctx, redisKey, "last_failure", now.Unix())
+	ttl := pipe.TTL(ctx, redisKey)
+	all := pipe.HGetAll(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	// 锁定期间不能缩短过期时间
+	if ttl.Val() < window {
+		if err := s.client.Expire(ctx, redisKey, window).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return parseRedisLoginAttempt(all.Val()), nil
+}
+
+func (s *redisLoginAttemptStore) Lock(key string, until time.Time) error {
+	ctx := context.Background()
+	redisKey := redisLoginAttemptPrefix + key
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, redisKey, "locked_until", until.Unix())
+	pipe.ExpireAt(ctx, redisKey, until)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisLoginAttemptStore) Reset(key string) error {
+	return s.client.Del(context.Background(), redisLoginAttemptPrefix+key).Err()
+}
+
+// parseRedisLoginAttempt 解析Redis哈希中的计数
+func parseRedisLoginAttempt(values map[string]string) *LoginAttempt {
+	attempt := &LoginAttempt{}
+	if v, err := strconv.Atoi(values["failures"]); err == nil {
+		attempt.Failures = v
+	}
+	if v, err := strconv.ParseInt(values["last_failure"], 10, 64); err == nil {
+		attempt.LastFailureAt = time.Unix(v, 0)
+	}
+	if v, err := strconv.ParseInt(values["locked_until"], 10, 64); err == nil {
+		attempt.LockedUntil = time.Unix(v, 0)
+	}
+	return attempt
+}
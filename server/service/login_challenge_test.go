@@ -0,0 +1,75 @@
+package service
+
+import (
+	"container/list"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-react-admin/global"
+)
+
+func TestCaptchaStoreCapacity(t *testing.T) {
+	store := &captchaStore{items: make(map[string]*list.Element), order: list.New()}
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		store.save(fmt.Sprintf("id-%d", i), fmt.Sprint(i), now, 3)
+	}
+	if n := store.len(); n != 3 {
+		t.Fatalf("保存了%d个验证码，期望不超过容量3", n)
+	}
+	// 超过容量时淘汰最早生成的验证码
+	if answer := store.take("id-0", now); answer != "" {
+		t.Fatalf("id-0 = %q，期望已被淘汰", answer)
+	}
+	if answer := store.take("id-4", now); answer != "4" {
+		t.Fatalf("id-4 = %q，期望 4", answer)
+	}
+	// 每个验证码只能取出一次
+	if answer := store.take("id-4", now); answer != "" {
+		t.Fatalf("再次取出id-4 = %q，期望为空", answer)
+	}
+}
+
+func TestCaptchaStorePurge(t *testing.T) {
+	store := &captchaStore{items: make(map[string]*list.Element), order: list.New()}
+	now := time.Now()
+	store.save("old", "1", now, 0)
+	store.save("new", "2", now.Add(time.Minute), 0)
+
+	store.purge(now.Add(captchaTTL + time.Second))
+	if n := store.len(); n != 1 {
+		t.Fatalf("清理后剩余%d个验证码，期望 1", n)
+	}
+	if answer := store.take("old", now); answer != "" {
+		t.Fatalf("old = %q，期望已被清理", answer)
+	}
+	// 过期但尚未清理的验证码不能通过校验
+	if answer := store.take("new", now.Add(captchaTTL+2*time.Minute)); answer != "" {
+		t.Fatalf("new = %q，期望已过期", answer)
+	}
+}
+
+func TestAllowCaptcha(t *testing.T) {
+	saved := global.GlobalConfig
+	defer func() { global.GlobalConfig = saved }()
+	global.GlobalConfig = &global.Config{}
+	global.GlobalConfig.LoginGuard.CaptchaRate = 2
+
+	s := &LoginGuardService{}
+	ip := "203.0.113.9"
+	defer GetLoginAttemptStore().Reset(captchaIPKey(ip))
+	for i := 0; i < 2; i++ {
+		if wait, err := s.AllowCaptcha(ip); err != nil || wait != 0 {
+			t.Fatalf("第%d次获取验证码 wait = %v, err = %v，期望允许", i+1, wait, err)
+		}
+	}
+	if wait, err := s.AllowCaptcha(ip); err != nil || wait <= 0 {
+		t.Fatalf("超过上限后 wait = %v, err = %v，期望需要等待", wait, err)
+	}
+	// 其他IP不受影响
+	if wait, _ := s.AllowCaptcha("203.0.113.10"); wait != 0 {
+		t.Fatalf("其他IP wait = %v，期望允许", wait)
+	}
+	GetLoginAttemptStore().Reset(captchaIPKey("203.0.113.10"))
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+)
+
+// LoginGuardService 登录防暴力破解
+// 按用户名和IP分别统计失败次数，依次触发指数退避、图形验证码和临时锁定
+type LoginGuardService struct{}
+
+// LoginCheckResult 登录前检查结果
+type LoginCheckResult struct {
+	Locked          bool          // 已被临时锁定
+	RetryAfter      time.Duration // 需要等待的时间
+	CaptchaRequired bool          // 需要人机验证
+}
+
+// 锁定日志中记录的路径
+const (
+	loginGuardPath  = "/api/v1/login"
+	loginUnlockPath = "/api/v1/admin/login-guard/unlock"
+)
+
+func loginUserKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func captchaIPKey(ip string) string {
+	return "captcha:" + ip
+}
+
+func oidcIPKey(ip string) string {
+	return "oidc:" + ip
+}
+
+// backoffDelay 计算第failures次失败后需要等待的时间
+func backoffDelay(failures int) time.Duration {
+	cfg := global.GlobalConfig.LoginGuard
+	if failures <= 0 || cfg.BackoffBase <= 0 {
+		return 0
+	}
+
+	limit := time.Duration(cfg.BackoffMax) * time.Second
+	delay := time.Duration(cfg.BackoffBase) * time.Second
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if limit > 0 && delay >= limit {
+			return limit
+		}
+	}
+	if limit > 0 && delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// Check 登录前检查是否被锁定、处于退避期或需要验证码
+func (s *LoginGuardService) Check(username, ip string) (*LoginCheckResult, error) {
+	cfg := global.GlobalConfig.LoginGuard
+	store := GetLoginAttemptStore()
+	now := time.Now()
+
+	userAttempt, err := store.Get(loginUserKey(username))
+	if err != nil {
+		return nil, err
+	}
+	ipAttempt, err := store.Get(loginIPKey(ip))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LoginCheckResult{}
+	for _, attempt := range []*LoginAttempt{userAttempt, ipAttempt} {
+		if attempt.LockedUntil.After(now) {
+			result.Locked = true
+			if wait := attempt.LockedUntil.Sub(now); wait > result.RetryAfter {
+				result.RetryAfter = wait
+			}
+		}
+	}
+	if result.Locked {
+		return result, nil
+	}
+
+	if next := userAttempt.LastFailureAt.Add(backoffDelay(userAttempt.Failures)); next.After(now) {
+		result.RetryAfter = next.Sub(now)
+	}
+
+	// 同一IP的失败次数达到IP锁定阈值的一半时，即使更换用户名也需要验证码
+	if cfg.CaptchaAfter > 0 {
+		result.CaptchaRequired = userAttempt.Failures >= cfg.CaptchaAfter ||
+			(cfg.IPLockoutAfter > 0 && ipAttempt.Failures >= cfg.IPLockoutAfter/2)
+	}
+	return result, nil
+}
+
+// RecordFailure 记录一次登录失败，达到阈值时锁定并写入锁定日志
+func (s *LoginGuardService) RecordFailure(username, ip, userAgent string) (*LoginCheckResult, error) {
+	cfg := global.GlobalConfig.LoginGuard
+	store := GetLoginAttemptStore()
+	window := time.Duration(cfg.FailureWindow) * time.Minute
+	lockout := time.Duration(cfg.LockoutDuration) * time.Minute
+
+	userAttempt, err := store.RecordFailure(loginUserKey(username), window)
+	if err != nil {
+		return nil, err
+	}
+	ipAttempt, err := store.RecordFailure(loginIPKey(ip), window)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.LockoutAfter > 0 && userAttempt.Failures >= cfg.LockoutAfter {
+		if err := store.Lock(loginUserKey(username), time.Now().Add(lockout)); err != nil {
+			return nil, err
+		}
+		s.writeLog(model.LogTypeLockout, username, ip, userAgent, 0,
+			fmt.Sprintf("用户名连续登录失败%d次，锁定%d分钟", userAttempt.Failures, cfg.LockoutDuration))
+	}
+	if cfg.IPLockoutAfter > 0 && ipAttempt.Failures >= cfg.IPLockoutAfter {
+		if err := store.Lock(loginIPKey(ip), time.Now().Add(lockout)); err != nil {
+			return nil, err
+		}
+		s.writeLog(model.LogTypeLockout, username, ip, userAgent, 0,
+			fmt.Sprintf("IP %s 登录失败%d次，锁定%d分钟", ip, ipAttempt.Failures, cfg.LockoutDuration))
+	}
+
+	return s.Check(username, ip)
+}
+
+// RecordSuccess 登录成功后清除用户名的失败计数
+func (s *LoginGuardService) RecordSuccess(username string) error {
+	return GetLoginAttemptStore().Reset(loginUserKey(username))
+}
+
+// AllowCaptcha 限制同一IP获取验证码的频率，超过每分钟上限时返回需要等待的时间
+func (s *LoginGuardService) AllowCaptcha(ip string) (time.Duration, error) {
+	return allowPerMinute(captchaIPKey(ip), global.GlobalConfig.LoginGuard.CaptchaRate)
+}
+
+// AllowOidcAuthorize 限制同一IP发起单点登录的频率，超过每分钟上限时返回需要等待的时间
+func (s *LoginGuardService) AllowOidcAuthorize(ip string) (time.Duration, error) {
+	return allowPerMinute(oidcIPKey(ip), global.GlobalConfig.LoginGuard.OidcRate)
+}
+
+// allowPerMinute 按key统计一分钟内的请求次数，limit不大于0时不限制
+func allowPerMinute(key string, limit int) (time.Duration, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	attempt, err := GetLoginAttemptStore().RecordFailure(key, time.Minute)
+	if err != nil {
+		return 0, err
+	}
+	if attempt.Failures > limit {
+		return time.Minute, nil
+	}
+	return 0, nil
+}
+
+// Unlock 管理员解除用户名或IP的锁定
+func (s *LoginGuardService) Unlock(username, ip string, operator *model.User) error {
+	store := GetLoginAttemptStore()
+	if username != "" {
+		if err := store.Reset(loginUserKey(username)); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		if err := store.Reset(loginIPKey(ip)); err != nil {
+			return err
+		}
+	}
+
+	detail := fmt.Sprintf("管理员 %s 解除登录锁定", operator.Username)
+	if ip != "" {
+		detail += "，IP: " + ip
+	}
+	s.writeLog(model.LogTypeUnlock, username, ip, "", operator.TenantID, detail)
+	return nil
+}
+
+// writeLog 写入锁定相关日志
+func (s *LoginGuardService) writeLog(logType, username, ip, userAgent string, tenantID uint, detail string) {
+	var user model.User
+	if username != "" && systemDB().Where("username = ?", username).First(&user).Error == nil {
+		tenantID = user.TenantID
+	}
+
+	path, statusCode := loginGuardPath, 429
+	if logType == model.LogTypeUnlock {
+		path, statusCode = loginUnlockPath, 200
+	}
+	if len(username) > 50 {
+		username = username[:50]
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	entry := model.Log{
+		UserID:     user.ID,
+		Username:   username,
+		IP:         ip,
+		Method:     "POST",
+		Path:       path,
+		UserAgent:  userAgent,
+		StatusCode: statusCode,
+		TenantID:   tenantID,
+		Type:       logType,
+		Detail:     detail,
+	}
+	if err := systemDB().Create(&entry).Error; err != nil {
+		fmt.Printf("记录登录锁定日志失败: %v\n", err)
+	}
+}
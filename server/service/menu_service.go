@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMenuNotFound 菜单不存在
+	ErrMenuNotFound = errors.New("菜单不存在")
+	// ErrActionCodeInvalid 操作权限码不合法
+	ErrActionCodeInvalid = errors.New("操作权限码只能包含字母、数字、下划线、中划线、点和冒号")
+	// ErrActionCodeDuplicate 操作权限码重复
+	ErrActionCodeDuplicate = errors.New("操作权限码已存在")
+	// ErrActionParent 操作必须挂在菜单项下
+	ErrActionParent = errors.New("操作必须挂在菜单项下")
+	// ErrMenuShared 内置菜单只有平台租户可以修改
+	ErrMenuShared = errors.New("内置菜单只有平台租户可以修改")
+	// ErrMenuApiInvalid 操作关联的API不存在或不属于当前租户
+	ErrMenuApiInvalid = errors.New("关联的API不存在")
+)
+
+// actionCodePattern 操作权限码格式，如 user:create、dynamic:orders:export
+var actionCodePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(:[A-Za-z0-9_.-]+)*$`)
+
+// MenuService 菜单服务
+type MenuService struct{}
+
+// ListMenus 获取全部菜单，操作类型的菜单附带关联的API
+// ctx绑定租户时只返回该租户的菜单和内置菜单
+func (s *MenuService) ListMenus(ctx context.Context) ([]model.Menu, error) {
+	db := global.DB.WithContext(ctx)
+	var menus []model.Menu
+	if err := db.Order("sort ASC").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	var links []model.MenuApi
+	if err := db.Find(&links).Error; err != nil {
+		return nil, err
+	}
+	apiIDs := make(map[uint][]uint)
+	for _, link := range links {
+		apiIDs[link.MenuID] = append(apiIDs[link.MenuID], link.ApiID)
+	}
+	for i := range menus {
+		menus[i].ApiIDs = apiIDs[menus[i].ID]
+	}
+	return menus, nil
+}
+
+// CreateMenu 创建菜单，操作类型的菜单同时保存关联的API
+func (s *MenuService) CreateMenu(ctx context.Context, menu *model.Menu) error {
+	menu.ID = 0
+	if tenantID, ok := global.TenantFromContext(ctx); ok {
+		menu.TenantID = tenantID
+	}
+	db := global.DB.WithContext(ctx)
+	if err := validateMenu(db, menu.ID, menu.Type, &menu.Code, menu.ParentID, menu.TenantID); err != nil {
+		return err
+	}
+	if menu.Type == model.MenuTypeAction {
+		if err := checkMenuApis(db, menu.ApiIDs); err != nil {
+			return err
+		}
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(menu).Error; err != nil {
+			return err
+		}
+		if menu.Type != model.MenuTypeAction {
+			return nil
+		}
+		return setMenuApis(tx, menu.ID, menu.ApiIDs)
+	})
+}
+
+// UpdateMenu 更新菜单的非零字段，api_ids为null时不修改关联的API
+// 操作类型或状态变化后，重建拥有该菜单的角色的策略；关联API的变更通过SubmitMenuApis提交，
+// 启用角色权限审批时返回待审批的变更申请
+func (s *MenuService) UpdateMenu(ctx context.Context, id uint, menu *model.Menu, requesterID uint) (*model.ChangeRequest, error) {
+	db := global.DB.WithContext(ctx)
+	var existing model.Menu
+	if err := db.First(&existing, id).Error; err != nil {
+		return nil, ErrMenuNotFound
+	}
+	if !menuWritable(ctx, &existing) {
+		return nil, ErrMenuShared
+	}
+	menuType, parentID := existing.Type, existing.ParentID
+	if menu.Type != "" {
+		menuType = menu.Type
+	}
+	if menu.ParentID != 0 {
+		parentID = menu.ParentID
+	}
+	code := existing.Code
+	if menu.Code != "" {
+		code = menu.Code
+	}
+	if err := validateMenu(db, id, menuType, &code, parentID, existing.TenantID); err != nil {
+		return nil, err
+	}
+	menu.Code = code
+	apiIDs := menu.ApiIDs
+	if apiIDs != nil && menuType == model.MenuTypeAction {
+		if err := checkMenuApis(db, apiIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Menu{}).Where("id = ?", id).Updates(menu).Error; err != nil {
+			return err
+		}
+		if existing.Type == model.MenuTypeAction || menuType == model.MenuTypeAction {
+			return syncMenuRoles(tx, id)
+		}
+		return nil
+	})
+	if err != nil || apiIDs == nil || menuType != model.MenuTypeAction {
+		return nil, err
+	}
+	// 内置菜单由平台租户维护，按平台租户的审批配置审批
+	tenantID := existing.TenantID
+	if tenantID == 0 {
+		tenantID = global.GlobalConfig.MultiTenant.PlatformTenantID
+	}
+	return (&ApprovalService{}).SubmitMenuApis(&MenuApisRequest{MenuID: id, ApiIDs: apiIDs, TenantID: tenantID}, requesterID)
+}
+
+// MenuApisRequest 操作关联API的变更
+type MenuApisRequest struct {
+	MenuID   uint   `json:"menu_id"`
+	ApiIDs   []uint `json:"api_ids"`
+	TenantID uint   `json:"tenant_id"`
+	Reason   string `json:"reason"`
+}
+
+// SetMenuApis 替换操作关联的API并重建拥有该操作的角色的策略
+func (s *MenuService) SetMenuApis(req *MenuApisRequest) error {
+	return tenantDB(req.TenantID).Transaction(func(tx *gorm.DB) error {
+		if err := setMenuApis(tx, req.MenuID, req.ApiIDs); err != nil {
+			return err
+		}
+		return syncMenuRoles(tx, req.MenuID)
+	})
+}
+
+// DeleteMenu 删除菜单，删除操作时收回角色通过它获得的接口权限
+func (s *MenuService) DeleteMenu(ctx context.Context, id uint) error {
+	db := global.DB.WithContext(ctx)
+	var existing model.Menu
+	if err := db.First(&existing, id).Error; err != nil {
+		return ErrMenuNotFound
+	}
+	if !menuWritable(ctx, &existing) {
+		return ErrMenuShared
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.Menu{}, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("menu_id = ?", id).Delete(&model.MenuApi{}).Error; err != nil {
+			return err
+		}
+		if existing.Type == model.MenuTypeAction {
+			return syncMenuRoles(tx, id)
+		}
+		return nil
+	})
+}
+
+// menuWritable 判断ctx绑定的租户能否修改菜单，租户ID为0的内置菜单各租户都能读取，只有平台租户可以修改
+func menuWritable(ctx context.Context, menu *model.Menu) bool {
+	tenantID, ok := global.TenantFromContext(ctx)
+	return !ok || menu.TenantID != 0 || tenantID == global.GlobalConfig.MultiTenant.PlatformTenantID
+}
+
+// validateMenu 检查操作类型菜单的权限码和上级菜单，权限码会被去掉首尾空格
+func validateMenu(db *gorm.DB, id uint, menuType string, code *string, parentID, tenantID uint) error {
+	if menuType != model.MenuTypeAction {
+		return nil
+	}
+	*code = strings.TrimSpace(*code)
+	if !actionCodePattern.MatchString(*code) {
+		return ErrActionCodeInvalid
+	}
+	var parent model.Menu
+	if parentID == 0 || db.First(&parent, parentID).Error != nil || parent.Type == model.MenuTypeAction {
+		return ErrActionParent
+	}
+	var count int64
+	if err := db.Model(&model.Menu{}).
+		Where("code = ? AND type = ? AND id <> ? AND tenant_id IN ?", *code, model.MenuTypeAction, id, []uint{0, tenantID}).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrActionCodeDuplicate
+	}
+	return nil
+}
+
+// checkMenuApis 检查关联的API都存在，db绑定租户时只能关联该租户的API
+func checkMenuApis(db *gorm.DB, apiIDs []uint) error {
+	apiIDs = uniqueIDs(apiIDs)
+	if len(apiIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&model.Api{}).Where("id IN ?", apiIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(apiIDs)) {
+		return ErrMenuApiInvalid
+	}
+	return nil
+}
+
+// setMenuApis 替换操作关联的API
+func setMenuApis(tx *gorm.DB, menuID uint, apiIDs []uint) error {
+	if err := tx.Where("menu_id = ?", menuID).Delete(&model.MenuApi{}).Error; err != nil {
+		return err
+	}
+	for _, apiID := range uniqueIDs(apiIDs) {
+		if err := tx.Create(&model.MenuApi{MenuID: menuID, ApiID: apiID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMenuRoles 重建拥有该菜单的全部角色的Casbin策略
+// 内置菜单被各租户的角色共用，需要跳过租户隔离重建全部租户的角色
+func syncMenuRoles(tx *gorm.DB, menuID uint) error {
+	tx = tx.WithContext(global.WithoutTenantScope(tx.Statement.Context))
+	var grants []model.RoleMenu
+	if err := tx.Where("menu_id = ?", menuID).Find(&grants).Error; err != nil {
+		return err
+	}
+	if len(grants) == 0 || global.Enforcer == nil {
+		return nil
+	}
+	permissionService := &PermissionService{}
+	for _, grant := range grants {
+		if err := permissionService.syncRolePolicies(tx, grant.RoleID, grant.TenantID); err != nil {
+			return err
+		}
+	}
+	return global.Enforcer.SavePolicy()
+}
+
+// GetUserActionCodes 获取用户有效角色（含继承）被授予的全部操作权限码
+func (s *MenuService) GetUserActionCodes(userID, tenantID uint) ([]string, error) {
+	roleIDs, err := UserEffectiveRoleIDs(userID, tenantID)
+	if err != nil || len(roleIDs) == 0 {
+		return []string{}, err
+	}
+	codes := []string{}
+	err = tenantDB(tenantID).Model(&model.Menu{}).
+		Joins("JOIN role_menus ON role_menus.menu_id = menus.id AND role_menus.deleted_at IS NULL").
+		Joins("JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Where("role_menus.role_id IN ? AND role_menus.tenant_id = ?", roleIDs, tenantID).
+		Where("menus.type = ? AND menus.status = 1 AND menus.tenant_id IN ?", model.MenuTypeAction, []uint{0, tenantID}).
+		Distinct().Order("menus.code").Pluck("menus.code", &codes).Error
+	return codes, err
+}
+
+// GetUserMenuTree 获取用户在租户内可见的菜单树
+// 菜单来自用户有效角色（含继承）的菜单授权，授权菜单的上级菜单组会一并返回
+func (s *MenuService) GetUserMenuTree(userID, tenantID uint) ([]*model.Menu, error) {
+	roleIDs, err := UserEffectiveRoleIDs(userID, tenantID)
+	if err != nil || len(roleIDs) == 0 {
+		return []*model.Menu{}, err
+	}
+
+	var granted []uint
+	if err := tenantDB(tenantID).Model(&model.RoleMenu{}).
+		Joins("JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Where("role_menus.role_id IN ? AND role_menus.tenant_id = ?", roleIDs, tenantID).
+		Distinct().Pluck("role_menus.menu_id", &granted).Error; err != nil {
+		return nil, err
+	}
+	if len(granted) == 0 {
+		return []*model.Menu{}, nil
+	}
+
+	// 租户ID为0的菜单为各租户共用的内置菜单
+	var menus []*model.Menu
+	if err := tenantDB(tenantID).Where("tenant_id IN ?", []uint{0, tenantID}).Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	return BuildUserMenuTree(menus, granted), nil
+}
+
+// BuildUserMenuTree 用授权的菜单及其上级菜单组装成树，按Sort排序
+// 禁用或隐藏的菜单连同其下级菜单都不返回，没有可见子菜单的菜单组也不返回
+func BuildUserMenuTree(menus []*model.Menu, granted []uint) []*model.Menu {
+	byID := make(map[uint]*model.Menu, len(menus))
+	for _, menu := range menus {
+		menu.Children = nil
+		byID[menu.ID] = menu
+	}
+
+	// 从授权菜单向上收集上级，路径上有不可见的菜单时整条路径都不可见
+	included := make(map[uint]bool)
+	for _, id := range granted {
+		// 操作权限码不出现在菜单树中，也不会使其上级菜单可见
+		if menu, ok := byID[id]; ok && menu.Type == model.MenuTypeAction {
+			continue
+		}
+		var path []uint
+		visible := true
+		for cur, seen := id, map[uint]bool{}; cur != 0 && !seen[cur]; {
+			seen[cur] = true
+			menu, ok := byID[cur]
+			if !ok || menu.Status != 1 || menu.Hidden {
+				visible = false
+				break
+			}
+			path = append(path, cur)
+			cur = menu.ParentID
+		}
+		if visible {
+			for _, pid := range path {
+				included[pid] = true
+			}
+		}
+	}
+
+	nodes := make([]*model.Menu, 0, len(included))
+	for id := range included {
+		nodes = append(nodes, byID[id])
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Sort != nodes[j].Sort {
+			return nodes[i].Sort < nodes[j].Sort
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	var roots []*model.Menu
+	for _, menu := range nodes {
+		if included[menu.ParentID] && menu.ParentID != menu.ID {
+			byID[menu.ParentID].Children = append(byID[menu.ParentID].Children, menu)
+		} else {
+			roots = append(roots, menu)
+		}
+	}
+	return pruneEmptyGroups(roots)
+}
+
+// pruneEmptyGroups 去掉没有子菜单的菜单组
+func pruneEmptyGroups(menus []*model.Menu) []*model.Menu {
+	result := make([]*model.Menu, 0, len(menus))
+	for _, menu := range menus {
+		menu.Children = pruneEmptyGroups(menu.Children)
+		if menu.Type == "group" && len(menu.Children) == 0 {
+			continue
+		}
+		if len(menu.Children) == 0 {
+			menu.Children = nil
+		}
+		result = append(result, menu)
+	}
+	return result
+}
+
+// MenuTreeETag 根据菜单树内容计算ETag，角色授权或菜单变化后菜单树不同，ETag随之变化
+func MenuTreeETag(tree []*model.Menu) (string, error) {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// ETagMatch 判断If-None-Match请求头是否包含指定的ETag
+func ETagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
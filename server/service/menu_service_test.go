@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"go-react-admin/model"
+)
+
+func testMenus() []*model.Menu {
+	return []*model.Menu{
+		{ID: 1, Name: "dashboard", Type: "menu", Sort: 1, Status: 1},
+		{ID: 2, Name: "system", Type: "group", Sort: 2, Status: 1},
+		{ID: 3, Name: "user", Type: "menu", ParentID: 2, Sort: 2, Status: 1},
+		{ID: 4, Name: "role", Type: "menu", ParentID: 2, Sort: 1, Status: 1},
+		{ID: 5, Name: "hidden", Type: "menu", ParentID: 2, Sort: 3, Status: 1, Hidden: true},
+		{ID: 6, Name: "disabled", Type: "group", Sort: 3, Status: 2},
+		{ID: 7, Name: "log", Type: "menu", ParentID: 6, Status: 1},
+		{ID: 8, Name: "empty", Type: "group", Sort: 4, Status: 1},
+		{ID: 9, Name: "user:delete", Type: "action", Code: "user:delete", ParentID: 3, Status: 1},
+		{ID: 10, Name: "dashboard", Type: "menu", ParentID: 0, Sort: 5, Status: 1},
+		{ID: 11, Name: "export", Type: "action", Code: "report:export", ParentID: 10, Status: 1},
+	}
+}
+
+func menuNames(menus []*model.Menu) []string {
+	var names []string
+	for _, menu := range menus {
+		names = append(names, menu.Name)
+	}
+	return names
+}
+
+func TestBuildUserMenuTree(t *testing.T) {
+	tree := BuildUserMenuTree(testMenus(), []uint{3, 4, 5, 7, 8, 9, 11})
+	if got := menuNames(tree); len(got) != 1 || got[0] != "system" {
+		t.Fatalf("根菜单 = %v，期望只有包含授权菜单的 system", got)
+	}
+	if got := menuNames(tree[0].Children); len(got) != 2 || got[0] != "role" || got[1] != "user" {
+		t.Fatalf("子菜单 = %v，期望按sort排序的 [role user] 且不含隐藏菜单", got)
+	}
+	if tree[0].Children[1].Children != nil {
+		t.Fatalf("操作权限码不应出现在菜单树中: %v", menuNames(tree[0].Children[1].Children))
+	}
+
+	tree = BuildUserMenuTree(testMenus(), []uint{1})
+	if got := menuNames(tree); len(got) != 1 || got[0] != "dashboard" || tree[0].Children != nil {
+		t.Fatalf("菜单树 = %v，期望只有 dashboard", got)
+	}
+
+	if tree := BuildUserMenuTree(testMenus(), nil); len(tree) != 0 {
+		t.Fatalf("没有授权时菜单树应为空，实际 %v", menuNames(tree))
+	}
+}
+
+func TestMenuTreeETag(t *testing.T) {
+	a, err := MenuTreeETag(BuildUserMenuTree(testMenus(), []uint{3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := MenuTreeETag(BuildUserMenuTree(testMenus(), []uint{3}))
+	c, _ := MenuTreeETag(BuildUserMenuTree(testMenus(), []uint{3, 4}))
+	if a != b {
+		t.Errorf("相同菜单树的ETag不同: %s %s", a, b)
+	}
+	if a == c {
+		t.Error("授权变化后ETag应该变化")
+	}
+	if !ETagMatch(`W/"x", `+a, a) || ETagMatch(c, a) || ETagMatch("", a) {
+		t.Error("If-None-Match匹配结果错误")
+	}
+}
+
+func TestActionCodePattern(t *testing.T) {
+	for _, code := range []string{"user:create", "dynamic:orders:export", "report.view", "a_b-c"} {
+		if !actionCodePattern.MatchString(code) {
+			t.Errorf("%q 应为合法的操作权限码", code)
+		}
+	}
+	for _, code := range []string{"", "user:", ":create", "user create", "user::create"} {
+		if actionCodePattern.MatchString(code) {
+			t.Errorf("%q 不应为合法的操作权限码", code)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMfaNotEnrolled 用户尚未绑定两步验证
+	ErrMfaNotEnrolled = errors.New("尚未绑定两步验证")
+	// ErrMfaAlreadyEnabled 两步验证已开启
+	ErrMfaAlreadyEnabled = errors.New("两步验证已开启")
+	// ErrMfaRequired 角色要求开启两步验证
+	ErrMfaRequired = errors.New("所属角色要求开启两步验证，无法关闭")
+	// ErrMfaCodeInvalid 验证码错误
+	ErrMfaCodeInvalid = errors.New("验证码错误或已被使用")
+	// ErrMfaTokenInvalid 挑战令牌无效
+	ErrMfaTokenInvalid = errors.New("两步验证已过期，请重新登录")
+	// ErrMfaTooManyAttempts 验证次数过多
+	ErrMfaTooManyAttempts = errors.New("验证失败次数过多，请重新登录")
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+type MfaService struct{}
+
+// challengeAttempts 记录挑战令牌的失败次数
+var challengeAttempts = struct {
+	sync.Mutex
+	items map[string]*challengeAttempt
+}{items: make(map[string]*challengeAttempt)}
+
+type challengeAttempt struct {
+	count     int
+	expiresAt time.Time
+}
+
+// IsEnrolled 用户是否已开启两步验证
+func (s *MfaService) IsEnrolled(user *model.User) bool {
+	return user.TOTPEnabled && user.TOTPSecret != ""
+}
+
+// PathRequiresMFA 判断API路径是否属于需要两步验证的范围，规则以*结尾时按前缀匹配
+func PathRequiresMFA(path string) bool {
+	for _, pattern := range global.GlobalConfig.Mfa.RequiredPaths {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// UserRequiresMFA 判断用户的角色是否要求两步验证
+// 角色显式开启RequireMFA，或角色能访问配置中的敏感API时均视为要求
+func (s *MfaService) UserRequiresMFA(user *model.User) (bool, error) {
+	var roles []model.Role
+	err := tenantDB(user.TenantID).Joins("JOIN user_roles ON user_roles.role_id = roles.id AND user_roles.deleted_at IS NULL").
+		Where("user_roles.user_id = ? AND roles.status = 1", user.ID).
+		Find(&roles).Error
+	if err != nil {
+		return false, err
+	}
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	roleIDs := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		if role.RequireMFA {
+			return true, nil
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	if len(global.GlobalConfig.Mfa.RequiredPaths) == 0 {
+		return false, nil
+	}
+
+	var paths []string
+	err = tenantDB(user.TenantID).Model(&model.Api{}).
+		Joins("JOIN role_apis ON role_apis.api_id = apis.id AND role_apis.deleted_at IS NULL").
+		Where("role_apis.role_id IN ?", roleIDs).
+		Distinct().
+		Pluck("apis.path", &paths).Error
+	if err != nil {
+		return false, err
+	}
+	for _, path := range paths {
+		if PathRequiresMFA(path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BeginSetup 生成新的TOTP密钥，用户确认验证码后才会开启
+func (s *MfaService) BeginSetup(user *model.User) (string, string, error) {
+	if s.IsEnrolled(user) {
+		return "", "", ErrMfaAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := tenantDB(user.TenantID).Model(&model.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": false, "totp_last_step": 0}).Error; err != nil {
+		return "", "", err
+	}
+	user.TOTPSecret = secret
+	user.TOTPEnabled = false
+
+	uri := utils.TOTPProvisioningURI(global.GlobalConfig.Mfa.Issuer, user.Username, secret)
+	return secret, uri, nil
+}
+
+// Enable 校验首个验证码后开启两步验证，并返回新生成的恢复码
+func (s *MfaService) Enable(user *model.User, code string) ([]string, error) {
+	if s.IsEnrolled(user) {
+		return nil, ErrMfaAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMfaNotEnrolled
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	if err := tenantDB(user.TenantID).Model(&model.User{}).Where("id = ?", user.ID).Update("totp_enabled", true).Error; err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+
+	return s.RegenerateRecoveryCodes(user.ID)
+}
+
+// Disable 关闭两步验证，force为true时跳过角色要求检查（管理员重置）
+func (s *MfaService) Disable(user *model.User, force bool) error {
+	if !force {
+		required, err := s.UserRequiresMFA(user)
+		if err != nil {
+			return err
+		}
+		if required {
+			return ErrMfaRequired
+		}
+	}
+
+	return tenantDB(user.TenantID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&model.MfaRecoveryCode{}).Error
+	})
+}
+
+// Verify 校验TOTP验证码或恢复码，两者均只能使用一次
+func (s *MfaService) Verify(user *model.User, code string) error {
+	if !s.IsEnrolled(user) {
+		return ErrMfaNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		return s.verifyTOTP(user, code)
+	}
+	return s.useRecoveryCode(user.ID, code)
+}
+
+// verifyTOTP 校验TOTP验证码，同一时间步的验证码不能重复使用
+func (s *MfaService) verifyTOTP(user *model.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrMfaCodeInvalid
+	}
+
+	result := tenantDB(user.TenantID).Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMfaCodeInvalid
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+// useRecoveryCode 使用恢复码
+func (s *MfaService) useRecoveryCode(userID uint, code string) error {
+	result := systemDB().Model(&model.MfaRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMfaCodeInvalid
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的恢复码全部作废
+func (s *MfaService) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]model.MfaRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, model.MfaRecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	err := systemDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.MfaRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RemainingRecoveryCodes 获取未使用的恢复码数量
+func (s *MfaService) RemainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := systemDB().Model(&model.MfaRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// hashRecoveryCode 计算恢复码哈希，忽略大小写、空格和连字符
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckChallenge 解析挑战令牌，已完成或已作废的挑战令牌视为无效
+func (s *MfaService) CheckChallenge(tokenString, purpose string) (*utils.MfaClaims, error) {
+	claims, err := utils.ParseMfaToken(tokenString, purpose)
+	if err != nil {
+		return nil, ErrMfaTokenInvalid
+	}
+
+	denied, err := GetTokenStore().IsAccessTokenDenied(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, ErrMfaTokenInvalid
+	}
+	return claims, nil
+}
+
+// RecordChallengeFailure 记录一次验证失败，超过次数限制后作废挑战令牌
+func (s *MfaService) RecordChallengeFailure(claims *utils.MfaClaims) error {
+	now := time.Now()
+
+	challengeAttempts.Lock()
+	for jti, item := range challengeAttempts.items {
+		if now.After(item.expiresAt) {
+			delete(challengeAttempts.items, jti)
+		}
+	}
+	item, ok := challengeAttempts.items[claims.ID]
+	if !ok {
+		item = &challengeAttempt{expiresAt: claims.ExpiresAt.Time}
+		challengeAttempts.items[claims.ID] = item
+	}
+	item.count++
+	exceeded := item.count >= global.GlobalConfig.Mfa.MaxAttempts
+	challengeAttempts.Unlock()
+
+	if !exceeded {
+		return nil
+	}
+	if err := s.CompleteChallenge(claims); err != nil {
+		return err
+	}
+	return ErrMfaTooManyAttempts
+}
+
+// CompleteChallenge 作废挑战令牌，保证每个挑战令牌只能成功使用一次
+func (s *MfaService) CompleteChallenge(claims *utils.MfaClaims) error {
+	challengeAttempts.Lock()
+	delete(challengeAttempts.items, claims.ID)
+	challengeAttempts.Unlock()
+
+	return GetTokenStore().DenyAccessToken(claims.ID, claims.ExpiresAt.Time)
+}
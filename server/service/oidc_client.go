@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrOidcDiscovery 获取身份提供方配置失败
+	ErrOidcDiscovery = errors.New("获取身份提供方配置失败")
+	// ErrOidcExchange 授权码换取令牌失败
+	ErrOidcExchange = errors.New("授权码换取令牌失败")
+	// ErrOidcIDToken ID Token校验失败
+	ErrOidcIDToken = errors.New("ID Token校验失败")
+)
+
+// oidcMetadataTTL 发现文档和JWKS的缓存时间
+const oidcMetadataTTL = time.Hour
+
+// OidcDiscovery OpenID Connect发现文档中用到的字段
+type OidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// OidcTokenResponse 令牌端点响应
+type OidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// OidcClient OpenID Connect依赖方客户端，负责发现、授权码交换和ID Token校验
+type OidcClient struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+type oidcDiscoveryEntry struct {
+	doc       *OidcDiscovery
+	expiresAt time.Time
+}
+
+type oidcJwksEntry struct {
+	keys      map[string]interface{}
+	expiresAt time.Time
+}
+
+// oidcMetadataCache 按issuer缓存发现文档，按jwks_uri缓存公钥
+var oidcMetadataCache = struct {
+	sync.Mutex
+	discovery map[string]oidcDiscoveryEntry
+	jwks      map[string]oidcJwksEntry
+}{
+	discovery: make(map[string]oidcDiscoveryEntry),
+	jwks:      make(map[string]oidcJwksEntry),
+}
+
+func (o *OidcClient) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// Discover 获取并缓存发现文档
+func (o *OidcClient) Discover(ctx context.Context) (*OidcDiscovery, error) {
+	issuer := strings.TrimSuffix(o.Issuer, "/")
+
+	oidcMetadataCache.Lock()
+	entry, ok := oidcMetadataCache.discovery[issuer]
+	oidcMetadataCache.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.doc, nil
+	}
+
+	var doc OidcDiscovery
+	if err := o.getJSON(ctx, issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcDiscovery, err)
+	}
+	// 发现文档中的issuer必须与配置一致，防止被替换为其他身份提供方
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("%w: issuer不匹配 %s", ErrOidcDiscovery, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JwksURI == "" {
+		return nil, fmt.Errorf("%w: 缺少必要的端点", ErrOidcDiscovery)
+	}
+
+	oidcMetadataCache.Lock()
+	oidcMetadataCache.discovery[issuer] = oidcDiscoveryEntry{doc: &doc, expiresAt: time.Now().Add(oidcMetadataTTL)}
+	oidcMetadataCache.Unlock()
+	return &doc, nil
+}
+
+// AuthorizationURL 生成授权地址，使用S256方式的PKCE
+func (o *OidcClient) AuthorizationURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	doc, err := o.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", o.ClientID)
+	params.Set("redirect_uri", o.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", PKCEChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange 使用授权码和PKCE校验码换取令牌
+func (o *OidcClient) Exchange(ctx context.Context, code, codeVerifier string) (*OidcTokenResponse, error) {
+	doc, err := o.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.RedirectURL)
+	form.Set("client_id", o.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	}
+
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcExchange, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP %d %s", ErrOidcExchange, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token OidcTokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcExchange, err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: 响应中缺少id_token", ErrOidcExchange)
+	}
+	return &token, nil
+}
+
+// VerifyIDToken 校验ID Token的签名、issuer、audience、有效期和nonce，返回全部声明
+func (o *OidcClient) VerifyIDToken(ctx context.Context, rawToken, nonce string) (jwt.MapClaims, error) {
+	doc, err := o.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.publicKey(ctx, doc.JwksURI, kid)
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcIDToken, err)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(o.Issuer, "/") {
+		return nil, fmt.Errorf("%w: issuer不匹配", ErrOidcIDToken)
+	}
+	if !claims.VerifyAudience(o.ClientID, true) {
+		return nil, fmt.Errorf("%w: audience不匹配", ErrOidcIDToken)
+	}
+	// 存在多个audience时azp必须为本客户端
+	if aud, ok := claims["aud"].([]interface{}); ok && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != o.ClientID {
+			return nil, fmt.Errorf("%w: azp不匹配", ErrOidcIDToken)
+		}
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: 缺少exp", ErrOidcIDToken)
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce不匹配", ErrOidcIDToken)
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("%w: 缺少sub", ErrOidcIDToken)
+	}
+	return claims, nil
+}
+
+// publicKey 按kid查找JWKS中的公钥，找不到时刷新一次以支持密钥轮换
+func (o *OidcClient) publicKey(ctx context.Context, jwksURI, kid string) (interface{}, error) {
+	oidcMetadataCache.Lock()
+	entry, ok := oidcMetadataCache.jwks[jwksURI]
+	oidcMetadataCache.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) || lookupJwk(entry.keys, kid) == nil {
+		keys, err := o.fetchJwks(ctx, jwksURI)
+		if err != nil {
+			return nil, err
+		}
+		entry = oidcJwksEntry{keys: keys, expiresAt: time.Now().Add(oidcMetadataTTL)}
+		oidcMetadataCache.Lock()
+		oidcMetadataCache.jwks[jwksURI] = entry
+		oidcMetadataCache.Unlock()
+	}
+
+	if key := lookupJwk(entry.keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未找到签名公钥 kid=%s", kid)
+}
+
+// lookupJwk 按kid查找公钥，令牌未指定kid且只有一个公钥时使用该公钥
+func lookupJwk(keys map[string]interface{}, kid string) interface{} {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// jsonWebKey JWKS中的单个公钥
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchJwks 获取并解析JWKS，只保留用于签名的RSA和EC公钥
+func (o *OidcClient) fetchJwks(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJwk(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// parseJwk 将JWK转换为公钥
+func parseJwk(jwk jsonWebKey) (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线: %s", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("不支持的密钥类型: %s", jwk.Kty)
+}
+
+// getJSON 请求JSON资源
+func (o *OidcClient) getJSON(ctx context.Context, endpoint string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
+
+// NewPKCEVerifier 生成PKCE校验码
+func NewPKCEVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// PKCEChallenge 计算S256方式的PKCE挑战码
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ClaimValues 按点分隔的路径读取声明，如 realm_access.roles，返回字符串列表
+func ClaimValues(claims map[string]interface{}, path string) []string {
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+
+	switch v := current.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
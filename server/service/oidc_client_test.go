@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockIdP 本地模拟的OpenID Connect身份提供方
+type mockIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	clientID  string
+	challenge string // 授权请求中的code_challenge
+	claims    jwt.MapClaims
+	signKey   *rsa.PrivateKey // 为空时使用key签名
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, clientID: "go-react-admin"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/auth",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test-key",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		user, _, _ := r.BasicAuth()
+		if r.Form.Get("code") != "valid-code" || user != idp.clientID ||
+			PKCEChallenge(r.Form.Get("code_verifier")) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		signKey := idp.key
+		if idp.signKey != nil {
+			signKey = idp.signKey
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+		token.Header["kid"] = "test-key"
+		signed, err := token.SignedString(signKey)
+		if err != nil {
+			t.Error(err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"id_token":     signed,
+			"token_type":   "Bearer",
+			"expires_in":   300,
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (m *mockIdP) client() *OidcClient {
+	return &OidcClient{
+		Issuer:       m.server.URL,
+		ClientID:     m.clientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/oidc/callback",
+	}
+}
+
+func (m *mockIdP) defaultClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                m.server.URL,
+		"sub":                "user-1",
+		"aud":                m.clientID,
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []interface{}{"admin", "offline_access"}},
+	}
+}
+
+// login 模拟一次完整的授权码流程，返回ID Token校验结果
+func (m *mockIdP) login(t *testing.T, mutate func(claims jwt.MapClaims)) (jwt.MapClaims, error) {
+	t.Helper()
+	ctx := context.Background()
+	client := m.client()
+
+	verifier, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := client.AuthorizationURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("state") != "state-1" || query.Get("nonce") != "nonce-1" {
+		t.Fatalf("授权地址参数错误: %s", authURL)
+	}
+	m.challenge = query.Get("code_challenge")
+
+	m.claims = m.defaultClaims("nonce-1")
+	if mutate != nil {
+		mutate(m.claims)
+	}
+	token, err := client.Exchange(ctx, "valid-code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+}
+
+func TestOidcAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+
+	claims, err := idp.login(t, nil)
+	if err != nil {
+		t.Fatalf("校验ID Token失败: %v", err)
+	}
+	if claims["sub"] != "user-1" {
+		t.Errorf("sub = %v", claims["sub"])
+	}
+	roles := ClaimValues(claims, "realm_access.roles")
+	if len(roles) != 2 || roles[0] != "admin" {
+		t.Errorf("roles = %v", roles)
+	}
+}
+
+func TestOidcExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	client := idp.client()
+	ctx := context.Background()
+
+	verifier, _ := NewPKCEVerifier()
+	if _, err := client.AuthorizationURL(ctx, "state", "nonce", verifier); err != nil {
+		t.Fatal(err)
+	}
+	idp.challenge = PKCEChallenge(verifier)
+	idp.claims = idp.defaultClaims("nonce")
+
+	other, _ := NewPKCEVerifier()
+	if _, err := client.Exchange(ctx, "valid-code", other); err == nil {
+		t.Fatal("错误的PKCE校验码应当被拒绝")
+	}
+}
+
+func TestOidcVerifyIDTokenRejects(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(claims jwt.MapClaims)
+		signKey *rsa.PrivateKey
+	}{
+		{name: "nonce不匹配", mutate: func(c jwt.MapClaims) { c["nonce"] = "other" }},
+		{name: "audience不匹配", mutate: func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{name: "issuer不匹配", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "已过期", mutate: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "多个audience缺少azp", mutate: func(c jwt.MapClaims) { c["aud"] = []interface{}{"go-react-admin", "other"} }},
+		{name: "签名密钥不匹配", signKey: otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.signKey = tt.signKey
+			if _, err := idp.login(t, tt.mutate); err == nil {
+				t.Fatal("ID Token应当校验失败")
+			}
+		})
+	}
+}
+
+func TestClaimValues(t *testing.T) {
+	claims := map[string]interface{}{
+		"groups":       []interface{}{"dev", 1, "ops"},
+		"role":         "admin",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"a"}},
+	}
+	if got := ClaimValues(claims, "groups"); len(got) != 2 || got[1] != "ops" {
+		t.Errorf("groups = %v", got)
+	}
+	if got := ClaimValues(claims, "role"); len(got) != 1 || got[0] != "admin" {
+		t.Errorf("role = %v", got)
+	}
+	if got := ClaimValues(claims, "realm_access.roles"); len(got) != 1 {
+		t.Errorf("realm_access.roles = %v", got)
+	}
+	if got := ClaimValues(claims, "missing.path"); got != nil {
+		t.Errorf("missing.path = %v", got)
+	}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOidcProviderNotFound 身份提供方不存在或未启用
+	ErrOidcProviderNotFound = errors.New("身份提供方不存在或未启用")
+	// ErrOidcStateInvalid state无效或已过期
+	ErrOidcStateInvalid = errors.New("登录请求已过期，请重新登录")
+	// ErrOidcUserNotLinked 外部身份没有关联的本地用户
+	ErrOidcUserNotLinked = errors.New("该账号未关联系统用户，请联系管理员")
+)
+
+type OidcService struct{}
+
+// usernameSanitizer 自动创建用户时清理用户名中的特殊字符
+var usernameSanitizer = regexp.MustCompile(`[^A-Za-z0-9_.@-]`)
+
+// Client 根据身份提供方配置创建客户端
+func (s *OidcService) Client(provider *model.OidcProvider) *OidcClient {
+	return &OidcClient{
+		Issuer:       provider.Issuer,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  provider.RedirectURL,
+		Scopes:       provider.Scopes,
+	}
+}
+
+// ListEnabledProviders 获取已启用的身份提供方，tenantID为0时返回全部租户
+func (s *OidcService) ListEnabledProviders(tenantID uint) ([]model.OidcProvider, error) {
+	var providers []model.OidcProvider
+	query := systemDB().Where("status = 1")
+	if tenantID != 0 {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	err := query.Order("id").Find(&providers).Error
+	return providers, err
+}
+
+// ListProviders 获取租户内的全部身份提供方
+func (s *OidcService) ListProviders(tenantID uint) ([]model.OidcProvider, error) {
+	var providers []model.OidcProvider
+	err := tenantDB(tenantID).Where("tenant_id = ?", tenantID).Order("id").Find(&providers).Error
+	return providers, err
+}
+
+// GetProvider 获取已启用的身份提供方
+func (s *OidcService) GetProvider(id uint) (*model.OidcProvider, error) {
+	var provider model.OidcProvider
+	if err := systemDB().Where("id = ? AND status = 1", id).First(&provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOidcProviderNotFound
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// SaveProvider 创建或更新身份提供方，更新时ClientSecret为空表示保持不变
+func (s *OidcService) SaveProvider(provider *model.OidcProvider) error {
+	if provider.ID == 0 {
+		return tenantDB(provider.TenantID).Create(provider).Error
+	}
+
+	var existing model.OidcProvider
+	if err := tenantDB(provider.TenantID).Where("id = ? AND tenant_id = ?", provider.ID, provider.TenantID).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOidcProviderNotFound
+		}
+		return err
+	}
+	if provider.ClientSecret == "" {
+		provider.ClientSecret = existing.ClientSecret
+	}
+	provider.CreatedAt = existing.CreatedAt
+	return tenantDB(provider.TenantID).Save(provider).Error
+}
+
+// DeleteProvider 删除身份提供方及其身份关联
+func (s *OidcService) DeleteProvider(id, tenantID uint) error {
+	return tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&model.OidcProvider{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOidcProviderNotFound
+		}
+		return tx.Where("provider_id = ?", id).Delete(&model.UserIdentity{}).Error
+	})
+}
+
+// BeginLogin 生成state、nonce和PKCE校验码，返回身份提供方的授权地址
+func (s *OidcService) BeginLogin(ctx context.Context, providerID uint) (string, error) {
+	provider, err := s.GetProvider(providerID)
+	if err != nil {
+		return "", err
+	}
+
+	stateKey, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := s.Client(provider).AuthorizationURL(ctx, stateKey, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+	if err := saveOidcState(stateKey, &OidcLoginState{ProviderID: provider.ID, Nonce: nonce, CodeVerifier: verifier}); err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// CompleteLogin 校验回调的state和授权码，返回外部身份对应的本地用户
+func (s *OidcService) CompleteLogin(ctx context.Context, stateKey, code string) (*model.User, error) {
+	state, err := takeOidcState(stateKey)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrOidcStateInvalid
+	}
+	provider, err := s.GetProvider(state.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client(provider)
+	token, err := client.Exchange(ctx, code, state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := client.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(provider, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncRoles(provider, user, claims); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// resolveUser 按已有关联、已验证邮箱、自动创建的顺序确定本地用户
+func (s *OidcService) resolveUser(provider *model.OidcProvider, claims map[string]interface{}) (*model.User, error) {
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+
+	var identity model.UserIdentity
+	err := tenantDB(provider.TenantID).Where("provider_id = ? AND subject = ?", provider.ID, subject).First(&identity).Error
+	if err == nil {
+		var user model.User
+		if err := tenantDB(provider.TenantID).Where("id = ? AND tenant_id = ?", identity.UserID, provider.TenantID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrOidcUserNotLinked
+			}
+			return nil, err
+		}
+		tenantDB(provider.TenantID).Model(&identity).Updates(map[string]interface{}{"email": email, "last_login_at": time.Now()})
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var user model.User
+	switch {
+	case provider.LinkByEmail && email != "" && emailVerified &&
+		tenantDB(provider.TenantID).Where("email = ? AND tenant_id = ? AND type = ?", email, provider.TenantID, model.UserTypeNormal).First(&user).Error == nil:
+		// 按邮箱关联已有用户
+	case provider.AutoProvision:
+		created, err := s.provisionUser(provider, claims)
+		if err != nil {
+			return nil, err
+		}
+		user = *created
+	default:
+		return nil, ErrOidcUserNotLinked
+	}
+
+	identity = model.UserIdentity{
+		UserID:      user.ID,
+		ProviderID:  provider.ID,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: time.Now(),
+	}
+	if err := tenantDB(provider.TenantID).Create(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// provisionUser 根据ID Token中的信息自动创建用户，用户名冲突时追加随机后缀
+func (s *OidcService) provisionUser(provider *model.OidcProvider, claims map[string]interface{}) (*model.User, error) {
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	username, _ := claims["preferred_username"].(string)
+	if username == "" {
+		username = strings.Split(email, "@")[0]
+	}
+	username = usernameSanitizer.ReplaceAllString(username, "")
+	if len(username) < 3 {
+		username = "sso_" + username
+	}
+	if len(username) > 40 {
+		username = username[:40]
+	}
+
+	var count int64
+	systemDB().Unscoped().Model(&model.User{}).Where("username = ?", username).Count(&count)
+	if count > 0 {
+		suffix, err := utils.RandomToken(3)
+		if err != nil {
+			return nil, err
+		}
+		username = username + "_" + suffix
+	}
+
+	// 外部身份登录的用户没有可用的本地密码
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	if len(email) > 100 {
+		email = ""
+	}
+
+	user := &model.User{
+		Username:   username,
+		Password:   hashed,
+		Nickname:   name,
+		RealName:   name,
+		Email:      email,
+		Status:     1,
+		TenantID:   provider.TenantID,
+		Type:       model.UserTypeNormal,
+		AuthSource: model.AuthSourceOIDC,
+	}
+	if err := tenantDB(provider.TenantID).Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// syncRoles 按角色映射同步用户角色，只增删映射中出现的角色，其他手动分配的角色保持不变
+// 启用审批时新增的角色提交审批，移除的角色立即生效
+func (s *OidcService) syncRoles(provider *model.OidcProvider, user *model.User, claims map[string]interface{}) error {
+	if provider.RoleClaim == "" || len(provider.RoleMapping) == 0 {
+		return nil
+	}
+
+	desired := make(map[string]bool)
+	for _, value := range ClaimValues(claims, provider.RoleClaim) {
+		if roleName, ok := provider.RoleMapping[value]; ok {
+			desired[roleName] = true
+		}
+	}
+	managed := make([]string, 0, len(provider.RoleMapping))
+	for _, roleName := range provider.RoleMapping {
+		managed = append(managed, roleName)
+	}
+
+	var roles []model.Role
+	if err := tenantDB(provider.TenantID).Where("tenant_id = ? AND name IN ?", provider.TenantID, managed).Find(&roles).Error; err != nil {
+		return err
+	}
+	var assigned []uint
+	if err := tenantDB(provider.TenantID).Model(&model.UserRole{}).Where("user_id = ? AND tenant_id = ?", user.ID, provider.TenantID).
+		Pluck("role_id", &assigned).Error; err != nil {
+		return err
+	}
+	managedIDs := make(map[uint]bool, len(roles))
+	for _, role := range roles {
+		managedIDs[role.ID] = true
+	}
+	var roleIDs []uint
+	for _, roleID := range assigned {
+		if !managedIDs[roleID] {
+			roleIDs = append(roleIDs, roleID)
+		}
+	}
+	for _, role := range roles {
+		if desired[role.Name] {
+			roleIDs = append(roleIDs, role.ID)
+		}
+	}
+	if sameRoleIDs(assigned, roleIDs) {
+		return nil
+	}
+
+	// 新增角色与手动分配一样需要经过审批
+	if _, err := (&ApprovalService{}).SubmitSyncedUserRoles(&UserRoleRequest{
+		UserID:   user.ID,
+		RoleIDs:  roleIDs,
+		TenantID: provider.TenantID,
+		Reason:   "OIDC角色映射同步",
+	}); err != nil {
+		return fmt.Errorf("同步角色失败: %w", err)
+	}
+	return nil
+}
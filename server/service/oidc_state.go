@@ -0,0 +1,148 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go-react-admin/global"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	oidcStateTTL         = 10 * time.Minute
+	redisOidcStatePrefix = "auth:oidc:state:"
+)
+
+// OidcLoginState 授权请求发起时保存的state，回调时一次性取出
+type OidcLoginState struct {
+	ProviderID   uint   `json:"provider_id"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// oidcStates 未配置Redis时在内存中保存state
+// 按生成顺序排列，有效期相同，队首最先过期；超过容量时淘汰最早生成的state
+var oidcStates = &oidcStateStore{items: make(map[string]*list.Element), order: list.New()}
+
+type oidcStateItem struct {
+	key       string
+	state     OidcLoginState
+	expiresAt time.Time
+}
+
+// oidcStateStore 有容量上限的内存state存储
+type oidcStateStore struct {
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+// save 保存state，超过容量时淘汰最早生成的state
+func (s *oidcStateStore) save(key string, state *OidcLoginState, now time.Time, capacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.remove(elem)
+	}
+	for capacity > 0 && s.order.Len() >= capacity {
+		s.remove(s.order.Front())
+	}
+	s.items[key] = s.order.PushBack(&oidcStateItem{key: key, state: *state, expiresAt: now.Add(oidcStateTTL)})
+}
+
+// take 取出并删除state，不存在或已过期时返回nil
+func (s *oidcStateStore) take(key string, now time.Time) *OidcLoginState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	item := s.remove(elem)
+	if now.After(item.expiresAt) {
+		return nil
+	}
+	return &item.state
+}
+
+// purge 从队首清理已过期的state
+func (s *oidcStateStore) purge(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for elem := s.order.Front(); elem != nil; elem = s.order.Front() {
+		if !now.After(elem.Value.(*oidcStateItem).expiresAt) {
+			return
+		}
+		s.remove(elem)
+	}
+}
+
+// len 返回保存的state数量
+func (s *oidcStateStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// remove 删除一条state，调用方需持有锁
+func (s *oidcStateStore) remove(elem *list.Element) *oidcStateItem {
+	item := s.order.Remove(elem).(*oidcStateItem)
+	delete(s.items, item.key)
+	return item
+}
+
+// StartOidcStateSweeper 启动后台任务，定时清理内存中过期的state，配置了Redis时由Redis过期
+func StartOidcStateSweeper() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			oidcStates.purge(now)
+		}
+	}()
+}
+
+// saveOidcState 保存授权请求的state
+func saveOidcState(key string, state *OidcLoginState) error {
+	if global.RedisClient != nil {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return global.RedisClient.Set(context.Background(), redisOidcStatePrefix+key, data, oidcStateTTL).Err()
+	}
+
+	oidcStates.save(key, state, time.Now(), global.GlobalConfig.LoginGuard.OidcStateMax)
+	return nil
+}
+
+// takeOidcState 取出并删除state，保证每个state只能使用一次，不存在时返回nil
+func takeOidcState(key string) (*OidcLoginState, error) {
+	if global.RedisClient != nil {
+		ctx := context.Background()
+		pipe := global.RedisClient.TxPipeline()
+		get := pipe.Get(ctx, redisOidcStatePrefix+key)
+		pipe.Del(ctx, redisOidcStatePrefix+key)
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if get.Val() == "" {
+			return nil, nil
+		}
+		var state OidcLoginState
+		if err := json.Unmarshal([]byte(get.Val()), &state); err != nil {
+			return nil, err
+		}
+		return &state, nil
+	}
+
+	return oidcStates.take(key, time.Now()), nil
+}
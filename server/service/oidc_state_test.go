@@ -0,0 +1,72 @@
+package service
+
+import (
+	"container/list"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-react-admin/global"
+)
+
+func TestOidcStateStoreCapacity(t *testing.T) {
+	store := &oidcStateStore{items: make(map[string]*list.Element), order: list.New()}
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		store.save(fmt.Sprintf("state-%d", i), &OidcLoginState{ProviderID: uint(i)}, now, 3)
+	}
+	if n := store.len(); n != 3 {
+		t.Fatalf("保存了%d个state，期望不超过容量3", n)
+	}
+	// 超过容量时淘汰最早生成的state
+	if state := store.take("state-0", now); state != nil {
+		t.Fatalf("state-0 = %+v，期望已被淘汰", state)
+	}
+	if state := store.take("state-4", now); state == nil || state.ProviderID != 4 {
+		t.Fatalf("state-4 = %+v，期望ProviderID为4", state)
+	}
+	// 每个state只能取出一次
+	if state := store.take("state-4", now); state != nil {
+		t.Fatalf("再次取出state-4 = %+v，期望为nil", state)
+	}
+}
+
+func TestOidcStateStorePurge(t *testing.T) {
+	store := &oidcStateStore{items: make(map[string]*list.Element), order: list.New()}
+	now := time.Now()
+	store.save("old", &OidcLoginState{}, now, 0)
+	store.save("new", &OidcLoginState{}, now.Add(time.Minute), 0)
+
+	store.purge(now.Add(oidcStateTTL + time.Second))
+	if n := store.len(); n != 1 {
+		t.Fatalf("清理后剩余%d个state，期望 1", n)
+	}
+	// 过期但尚未清理的state不能使用
+	if state := store.take("new", now.Add(oidcStateTTL+2*time.Minute)); state != nil {
+		t.Fatalf("new = %+v，期望已过期", state)
+	}
+}
+
+func TestAllowOidcAuthorize(t *testing.T) {
+	saved := global.GlobalConfig
+	defer func() { global.GlobalConfig = saved }()
+	global.GlobalConfig = &global.Config{}
+	global.GlobalConfig.LoginGuard.OidcRate = 2
+
+	s := &LoginGuardService{}
+	ip := "203.0.113.20"
+	defer GetLoginAttemptStore().Reset(oidcIPKey(ip))
+	for i := 0; i < 2; i++ {
+		if wait, err := s.AllowOidcAuthorize(ip); err != nil || wait != 0 {
+			t.Fatalf("第%d次发起单点登录 wait = %v, err = %v，期望允许", i+1, wait, err)
+		}
+	}
+	if wait, err := s.AllowOidcAuthorize(ip); err != nil || wait <= 0 {
+		t.Fatalf("超过上限后 wait = %v, err = %v，期望需要等待", wait, err)
+	}
+	// 获取验证码的次数单独统计
+	if wait, _ := s.AllowCaptcha(ip); wait != 0 {
+		t.Fatalf("获取验证码 wait = %v，期望允许", wait)
+	}
+	GetLoginAttemptStore().Reset(captchaIPKey(ip))
+}
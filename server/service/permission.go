@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"go-react-admin/global"
 	"go-react-admin/initialize"
@@ -20,13 +21,35 @@ type PermissionRequest struct {
 	MenuIDs  []uint `json:"menu_ids"`
 	ApiIDs   []uint `json:"api_ids"`
 	TenantID uint   `json:"tenant_id"`
+	Reason   string `json:"reason"` // 变更理由，需要审批时记录在变更申请中
 }
 
 // UserRoleRequest 用户角色请求结构
 type UserRoleRequest struct {
-	UserID   uint   `json:"user_id" binding:"required"`
-	RoleIDs  []uint `json:"role_ids"`
-	TenantID uint   `json:"tenant_id"`
+	UserID   uint                  `json:"user_id" binding:"required"`
+	RoleIDs  []uint                `json:"role_ids"`
+	TenantID uint                  `json:"tenant_id"`
+	Validity map[uint]RoleValidity `json:"validity"` // 按角色ID指定有效期，未指定的角色保留原有效期，新增角色长期有效
+	Reason   string                `json:"reason"`   // 变更理由，需要审批时记录在变更申请中
+}
+
+// RoleValidity 角色授权的有效期，字段为空表示不限制
+type RoleValidity struct {
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+}
+
+// ErrInvalidValidity 角色有效期不合法
+var ErrInvalidValidity = errors.New("角色失效时间必须晚于生效时间和当前时间")
+
+// checkValidity 检查角色有效期，失效时间必须晚于生效时间和当前时间
+func checkValidity(validity map[uint]RoleValidity, now time.Time) error {
+	for _, v := range validity {
+		if v.ValidUntil != nil && (!v.ValidUntil.After(now) || (v.ValidFrom != nil && !v.ValidUntil.After(*v.ValidFrom))) {
+			return ErrInvalidValidity
+		}
+	}
+	return nil
 }
 
 // RolePermissionResponse 角色权限响应结构
@@ -39,14 +62,31 @@ type RolePermissionResponse struct {
 
 // UserRoleResponse 用户角色响应结构
 type UserRoleResponse struct {
-	UserID uint         `json:"user_id"`
-	User   *model.User  `json:"user"`
-	Roles  []model.Role `json:"roles"`
+	UserID uint             `json:"user_id"`
+	User   *model.User      `json:"user"`
+	Roles  []model.Role     `json:"roles"`
+	Grants []model.UserRole `json:"grants"` // 角色授权记录，包含有效期
 }
 
-// AssignRolePermissions 分配角色权限
+// applyPolicies 在数据库事务提交后修改并保存Casbin策略，失败时从存储重新加载，内存中不残留部分修改
+func applyPolicies(apply func() error) error {
+	if err := apply(); err != nil {
+		if global.Enforcer != nil {
+			if loadErr := global.Enforcer.LoadPolicy(); loadErr != nil {
+				fmt.Printf("重新加载Casbin策略失败: %v\n", loadErr)
+			}
+		}
+		return err
+	}
+	if global.Enforcer != nil {
+		return global.Enforcer.SavePolicy()
+	}
+	return nil
+}
+
+// AssignRolePermissions 分配角色权限，事务提交后按数据库中的授权重建角色的Casbin策略
 func (s *PermissionService) AssignRolePermissions(req *PermissionRequest) error {
-	return global.DB.Transaction(func(tx *gorm.DB) error {
+	err := tenantDB(req.TenantID).Transaction(func(tx *gorm.DB) error {
 		// 验证角色是否存在
 		var role model.Role
 		if err := tx.Where("id = ? AND tenant_id = ?", req.RoleID, req.TenantID).First(&role).Error; err != nil {
@@ -93,47 +133,77 @@ func (s *PermissionService) AssignRolePermissions(req *PermissionRequest) error
 			}
 		}
 
-		// 更新Casbin策略
-		if err := s.updateCasbinPoliciesForRole(req.RoleID, req.ApiIDs, req.TenantID); err != nil {
-			return err
-		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		// 保存Casbin策略
-		if global.Enforcer != nil {
-			if err := global.Enforcer.SavePolicy(); err != nil {
-				return err
-			}
-		}
+	// 事务回滚时Casbin不能保留授权，提交后再更新策略
+	return applyPolicies(func() error {
+		return s.syncRolePolicies(tenantDB(req.TenantID), req.RoleID, req.TenantID)
+	})
+}
 
+// checkTenantRoles 检查角色都属于该租户，不能把其他租户的角色分配给用户
+func checkTenantRoles(db *gorm.DB, tenantID uint, roleIDs []uint) error {
+	ids := uniqueIDs(roleIDs)
+	if len(ids) == 0 {
 		return nil
-	})
+	}
+	var count int64
+	if err := db.Model(&model.Role{}).Where("id IN ? AND tenant_id = ?", ids, tenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(ids)) {
+		return ErrRoleNotFound
+	}
+	return nil
 }
 
-// AssignUserRoles 分配用户角色
+// AssignUserRoles 分配用户角色，事务提交后更新Casbin中用户的角色关联
 func (s *PermissionService) AssignUserRoles(req *UserRoleRequest) error {
-	return global.DB.Transaction(func(tx *gorm.DB) error {
+	var active []uint
+	err := tenantDB(req.TenantID).Transaction(func(tx *gorm.DB) error {
 		// 验证用户是否存在
 		var user model.User
 		if err := tx.Where("id = ? AND tenant_id = ?", req.UserID, req.TenantID).First(&user).Error; err != nil {
 			return errors.New("用户不存在")
 		}
+		if err := checkTenantRoles(tx, req.TenantID, req.RoleIDs); err != nil {
+			return err
+		}
 
-		// 删除现有的用户角色关联
-		if err := tx.Where("user_id = ? AND tenant_id = ?", req.UserID, req.TenantID).Delete(&model.UserRole{}).Error; err != nil {
+		// 记录现有角色的有效期，未指定有效期的角色沿用
+		var existing []model.UserRole
+		if err := tx.Where("user_id = ? AND tenant_id = ?", req.UserID, req.TenantID).Find(&existing).Error; err != nil {
+			return err
+		}
+		validity := make(map[uint]RoleValidity, len(existing))
+		for _, ur := range existing {
+			validity[ur.RoleID] = RoleValidity{ValidFrom: ur.ValidFrom, ValidUntil: ur.ValidUntil}
+		}
+		now := time.Now()
+		if err := checkValidity(req.Validity, now); err != nil {
 			return err
 		}
+		for roleID, v := range req.Validity {
+			validity[roleID] = v
+		}
 
-		// 删除用户在Casbin中的所有角色关联
-		if err := s.removeAllUserRoles(req.UserID, req.TenantID); err != nil {
+		// 删除现有的用户角色关联
+		if err := tx.Where("user_id = ? AND tenant_id = ?", req.UserID, req.TenantID).Delete(&model.UserRole{}).Error; err != nil {
 			return err
 		}
 
 		// 添加新的用户角色关联
 		for _, roleID := range req.RoleIDs {
 			userRole := model.UserRole{
-				UserID:   req.UserID,
-				RoleID:   roleID,
-				TenantID: req.TenantID,
+				UserID:     req.UserID,
+				RoleID:     roleID,
+				TenantID:   req.TenantID,
+				ValidFrom:  validity[roleID].ValidFrom,
+				ValidUntil: validity[roleID].ValidUntil,
 			}
 			// 使用OnConflict实现存在则更新，不存在则插入
 			if err := tx.Clauses(clause.OnConflict{
@@ -143,19 +213,28 @@ func (s *PermissionService) AssignUserRoles(req *UserRoleRequest) error {
 				return err
 			}
 
-			// 更新Casbin用户角色关联
-			if err := initialize.AddUserRole(req.UserID, roleID, req.TenantID); err != nil {
-				return err
+			// 尚未生效的角色由后台任务到时添加到Casbin
+			if userRole.Active(now) {
+				active = append(active, roleID)
 			}
 		}
 
-		// 保存Casbin策略
-		if global.Enforcer != nil {
-			if err := global.Enforcer.SavePolicy(); err != nil {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 事务回滚时Casbin不能保留角色关联，提交后再替换
+	return applyPolicies(func() error {
+		if err := s.removeAllUserRoles(req.UserID, req.TenantID); err != nil {
+			return err
+		}
+		for _, roleID := range active {
+			if err := initialize.AddUserRole(req.UserID, roleID, req.TenantID); err != nil {
 				return err
 			}
 		}
-
 		return nil
 	})
 }
@@ -163,13 +242,13 @@ func (s *PermissionService) AssignUserRoles(req *UserRoleRequest) error {
 // GetRolePermissions 获取角色权限
 func (s *PermissionService) GetRolePermissions(roleID, tenantID uint) (*RolePermissionResponse, error) {
 	var role model.Role
-	if err := global.DB.Where("id = ? AND tenant_id = ?", roleID, tenantID).First(&role).Error; err != nil {
+	if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", roleID, tenantID).First(&role).Error; err != nil {
 		return nil, errors.New("角色不存在")
 	}
 
 	// 获取角色关联的菜单
 	var menus []model.Menu
-	if err := global.DB.Table("menus").
+	if err := tenantDB(tenantID).Table("menus").
 		Joins("JOIN role_menus ON menus.id = role_menus.menu_id").
 		Where("role_menus.role_id = ? AND role_menus.tenant_id = ?", roleID, tenantID).
 		Find(&menus).Error; err != nil {
@@ -178,7 +257,7 @@ func (s *PermissionService) GetRolePermissions(roleID, tenantID uint) (*RolePerm
 
 	// 获取角色关联的API
 	var apis []model.Api
-	if err := global.DB.Table("apis").
+	if err := tenantDB(tenantID).Table("apis").
 		Joins("JOIN role_apis ON apis.id = role_apis.api_id").
 		Where("role_apis.role_id = ? AND role_apis.tenant_id = ?", roleID, tenantID).
 		Find(&apis).Error; err != nil {
@@ -196,33 +275,37 @@ func (s *PermissionService) GetRolePermissions(roleID, tenantID uint) (*RolePerm
 // GetUserRoles 获取用户角色
 func (s *PermissionService) GetUserRoles(userID, tenantID uint) (*UserRoleResponse, error) {
 	var user model.User
-	if err := global.DB.Where("id = ? AND tenant_id = ?", userID, tenantID).First(&user).Error; err != nil {
+	if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", userID, tenantID).First(&user).Error; err != nil {
 		return nil, errors.New("用户不存在")
 	}
 
 	// 获取用户关联的角色
 	var roles []model.Role
-	if err := global.DB.Table("roles").
-		Joins("JOIN user_roles ON roles.id = user_roles.role_id").
+	if err := tenantDB(tenantID).Table("roles").
+		Joins("JOIN user_roles ON roles.id = user_roles.role_id AND user_roles.deleted_at IS NULL").
 		Where("user_roles.user_id = ? AND user_roles.tenant_id = ?", userID, tenantID).
 		Find(&roles).Error; err != nil {
 		return nil, err
 	}
 
+	var grants []model.UserRole
+	if err := tenantDB(tenantID).Where("user_id = ? AND tenant_id = ?", userID, tenantID).Find(&grants).Error; err != nil {
+		return nil, err
+	}
+
 	return &UserRoleResponse{
 		UserID: userID,
 		User:   &user,
 		Roles:  roles,
+		Grants: grants,
 	}, nil
 }
 
-// GetUserPermissions 获取用户权限（通过角色）
+// GetUserPermissions 获取用户权限（通过角色及其继承的上级角色）
 func (s *PermissionService) GetUserPermissions(userID, tenantID uint) ([]model.Menu, []model.Api, error) {
-	// 获取用户角色
-	var roleIDs []uint
-	if err := global.DB.Model(&model.UserRole{}).
-		Where("user_id = ? AND tenant_id = ?", userID, tenantID).
-		Pluck("role_id", &roleIDs).Error; err != nil {
+	// 获取用户当前有效的角色
+	roleIDs, err := userRoleIDs(userID, tenantID)
+	if err != nil {
 		return nil, nil, err
 	}
 
@@ -230,9 +313,15 @@ func (s *PermissionService) GetUserPermissions(userID, tenantID uint) ([]model.M
 		return []model.Menu{}, []model.Api{}, nil
 	}
 
+	// 包含继承的上级角色
+	roleIDs, err = ExpandRoleIDs(tenantID, roleIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// 获取角色关联的菜单
 	var menus []model.Menu
-	if err := global.DB.Table("menus").
+	if err := tenantDB(tenantID).Table("menus").
 		Joins("JOIN role_menus ON menus.id = role_menus.menu_id").
 		Where("role_menus.role_id IN ? AND role_menus.tenant_id = ?", roleIDs, tenantID).
 		Group("menus.id").
@@ -242,7 +331,7 @@ func (s *PermissionService) GetUserPermissions(userID, tenantID uint) ([]model.M
 
 	// 获取角色关联的API
 	var apis []model.Api
-	if err := global.DB.Table("apis").
+	if err := tenantDB(tenantID).Table("apis").
 		Joins("JOIN role_apis ON apis.id = role_apis.api_id").
 		Where("role_apis.role_id IN ? AND role_apis.tenant_id = ?", roleIDs, tenantID).
 		Group("apis.id").
@@ -253,8 +342,8 @@ func (s *PermissionService) GetUserPermissions(userID, tenantID uint) ([]model.M
 	return menus, apis, nil
 }
 
-// updateCasbinPoliciesForRole 更新角色的Casbin策略
-func (s *PermissionService) updateCasbinPoliciesForRole(roleID uint, apiIDs []uint, tenantID uint) error {
+// updateCasbinPoliciesForRole 更新角色的Casbin策略，授予的操作权限码关联的API也会生成策略
+func (s *PermissionService) updateCasbinPoliciesForRole(db *gorm.DB, roleID uint, menuIDs, apiIDs []uint, tenantID uint) error {
 	if global.Enforcer == nil {
 		return errors.New("casbin enforcer not initialized")
 	}
@@ -271,33 +360,67 @@ func (s *PermissionService) updateCasbinPoliciesForRole(roleID uint, apiIDs []ui
 	// 根据API权限添加新策略
 	var apis []model.Api
 	if len(apiIDs) > 0 {
-		if err := global.DB.Where("id IN ? AND tenant_id = ?", apiIDs, tenantID).Find(&apis).Error; err != nil {
+		if err := db.Where("id IN ? AND tenant_id = ?", apiIDs, tenantID).Find(&apis).Error; err != nil {
 			return err
 		}
 
 		for _, api := range apis {
-			_, err := global.Enforcer.AddPolicy(roleIDStr, api.Path, api.Method, tenantIDStr)
-			if err != nil {
+			rule := initialize.NormalizePolicy([]string{roleIDStr, api.Path, api.Method, tenantIDStr})
+			if _, err := global.Enforcer.AddPolicy(rule); err != nil {
 				return err
 			}
 		}
 	}
 
+	linked, err := actionApis(db, menuIDs)
+	if err != nil {
+		return err
+	}
+	for _, api := range linked {
+		rule := initialize.NormalizePolicy([]string{roleIDStr, api.Path, api.Method, tenantIDStr})
+		if _, err := global.Enforcer.AddPolicy(rule); err != nil {
+			return err
+		}
+	}
+
 	// 不在这里保存策略，由调用方统一保存
 	return nil
 }
 
-// CheckUserPermission 检查用户是否有特定权限
+// actionApis 获取菜单中启用的操作权限码所关联的API
+func actionApis(db *gorm.DB, menuIDs []uint) ([]model.Api, error) {
+	var apis []model.Api
+	if len(menuIDs) == 0 {
+		return apis, nil
+	}
+	err := db.Model(&model.Api{}).
+		Joins("JOIN menu_apis ON menu_apis.api_id = apis.id").
+		Joins("JOIN menus ON menus.id = menu_apis.menu_id AND menus.type = ? AND menus.status = 1 AND menus.deleted_at IS NULL", model.MenuTypeAction).
+		Where("menu_apis.menu_id IN ?", menuIDs).
+		Find(&apis).Error
+	return apis, err
+}
+
+// syncRolePolicies 按数据库中角色的菜单和API授权重建角色的Casbin策略
+func (s *PermissionService) syncRolePolicies(db *gorm.DB, roleID, tenantID uint) error {
+	var menuIDs, apiIDs []uint
+	if err := db.Model(&model.RoleMenu{}).Where("role_id = ? AND tenant_id = ?", roleID, tenantID).
+		Pluck("menu_id", &menuIDs).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&model.RoleApi{}).Where("role_id = ? AND tenant_id = ?", roleID, tenantID).
+		Pluck("api_id", &apiIDs).Error; err != nil {
+		return err
+	}
+	return s.updateCasbinPoliciesForRole(db, roleID, menuIDs, apiIDs, tenantID)
+}
+
+// CheckUserPermission 检查用户是否有特定权限，超级管理员通过角色上的通配符策略获得权限
 func (s *PermissionService) CheckUserPermission(userID uint, resource, action string, tenantID uint) (bool, error) {
 	if global.Enforcer == nil {
 		return false, errors.New("casbin enforcer not initialized")
 	}
 
-	// 特殊处理：如果是admin用户（ID为1），直接返回true
-	if userID == 1 {
-		return true, nil
-	}
-
 	// 资源名称到API路径的映射
 	resourceToPath := map[string]string{
 		"dashboard":  "/api/v1/dashboard",
@@ -336,22 +459,35 @@ func (s *PermissionService) CheckUserPermission(userID uint, resource, action st
 	// 在RBAC模型中，权限是赋予角色的，用户通过分组策略关联到角色
 	allowed, err := global.Enforcer.Enforce(userIDStr, apiPath, httpMethod, tenantIDStr)
 	if err != nil {
-		fmt.Printf("权限检查错误: userID=%d, path=%s, method=%s, tenantID=%d, error: %v", userID, apiPath, httpMethod, tenantID, err)
 		return false, err
 	}
 
 	// 如果映射路径检查失败，尝试直接使用原始资源名称检查
 	if !allowed {
-		fmt.Printf("权限检查失败，尝试原始资源: userID=%d, resource=%s, action=%s, tenantID=%d", userID, resource, action, tenantID)
-		allowed, err = global.Enforcer.Enforce(userIDStr, resource, action, tenantIDStr)
-		if err != nil {
-			fmt.Printf("原始资源权限检查错误: userID=%d, resource=%s, action=%s, tenantID=%d, error: %v", userID, resource, action, tenantID, err)
-			return false, err
-		}
+		return global.Enforcer.Enforce(userIDStr, resource, action, tenantIDStr)
+	}
+	return true, nil
+}
+
+// platformAdminPath 有权创建租户的平台租户用户是平台管理员
+const platformAdminPath = "/api/v1/tenant/create"
+
+// IsPlatformAdmin 判断用户是否为平台管理员，平台管理员可以跨租户管理用户和日志
+// 只属于平台租户而没有创建租户权限的用户仍然按租户隔离
+func (s *PermissionService) IsPlatformAdmin(userID, tenantID uint) bool {
+	if tenantID != global.GlobalConfig.MultiTenant.PlatformTenantID {
+		return false
 	}
+	allowed, err := s.Enforce(userID, platformAdminPath, "POST", tenantID)
+	return err == nil && allowed
+}
 
-	fmt.Printf("权限检查结果: userID=%d, resource=%s, action=%s, tenantID=%d, allowed=%v", userID, resource, action, tenantID, allowed)
-	return allowed, nil
+// Enforce 与Casbin中间件相同的授权检查，path为请求的实际路径
+func (s *PermissionService) Enforce(userID uint, path, method string, tenantID uint) (bool, error) {
+	if global.Enforcer == nil {
+		return false, errors.New("casbin enforcer not initialized")
+	}
+	return global.Enforcer.Enforce(fmt.Sprintf("%d", userID), path, method, fmt.Sprintf("%d", tenantID))
 }
 
 // removeAllUserRoles 删除用户的所有角色关联
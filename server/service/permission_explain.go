@@ -0,0 +1,497 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+
+	"github.com/casbin/casbin/v2"
+	"gorm.io/gorm"
+)
+
+// ErrExplainSubject 权限解释需要且只能指定用户或角色之一
+var ErrExplainSubject = errors.New("请指定用户或角色之一")
+
+// ExplainAccess 被解释路由在统一授权中间件中的授权方式，由接口层按路由声明填写
+type ExplainAccess int
+
+const (
+	// ExplainAccessPolicy 需要登录并通过Casbin策略授权
+	ExplainAccessPolicy ExplainAccess = iota
+	// ExplainAccessAuthenticated 登录即可访问
+	ExplainAccessAuthenticated
+	// ExplainAccessPublic 无需登录
+	ExplainAccessPublic
+	// ExplainAccessNoRoute 没有注册该路由
+	ExplainAccessNoRoute
+)
+
+// explainAccessNames 授权方式在解释结果中的名称
+var explainAccessNames = map[ExplainAccess]string{
+	ExplainAccessPolicy:        "policy",
+	ExplainAccessAuthenticated: "authenticated",
+	ExplainAccessPublic:        "public",
+	ExplainAccessNoRoute:       "no_route",
+}
+
+// ExplainRequest 权限解释请求，UserID与RoleID二选一
+type ExplainRequest struct {
+	UserID   uint          `json:"user_id"`
+	RoleID   uint          `json:"role_id"`
+	ApiKeyID uint          `json:"api_key_id"` // 按用户的API Key解释，检查Key的访问范围
+	Path     string        `json:"path" binding:"required"`
+	Method   string        `json:"method" binding:"required"`
+	TenantID uint          `json:"tenant_id"` // 为0时使用当前租户
+	WhatIf   *WhatIfChange `json:"what_if"`
+	Access   ExplainAccess `json:"-"`
+}
+
+// WhatIfChange 假设的角色变更，只用于评估，不会保存
+type WhatIfChange struct {
+	AddRoles    []uint          `json:"add_roles"`    // 假设为用户增加的角色
+	RemoveRoles []uint          `json:"remove_roles"` // 假设移除用户的角色
+	SetParents  map[uint][]uint `json:"set_parents"`  // 假设替换角色的上级角色
+}
+
+// MatchedPolicy 匹配请求的Casbin策略，Chain为从主体到策略所属角色的继承路径
+type MatchedPolicy struct {
+	Subject string    `json:"subject"`
+	Path    string    `json:"path"`
+	Method  string    `json:"method"`
+	Tenant  string    `json:"tenant"`
+	Chain   []RoleRef `json:"chain"`
+}
+
+// SubjectRole 主体在租户内拥有的角色及获得该角色的继承路径
+type SubjectRole struct {
+	RoleRef
+	Chain []RoleRef `json:"chain"`
+}
+
+// TableExplain 请求涉及的数据表权限
+type TableExplain struct {
+	TableID    uint                       `json:"table_id"`
+	TableName  string                     `json:"table_name"`
+	Action     string                     `json:"action"`
+	Allowed    bool                       `json:"allowed"`
+	Permission *model.UserPermission      `json:"permission"`
+	Grants     []InheritedTablePermission `json:"grants"`
+}
+
+// ExplainDecision 一次评估的结果
+type ExplainDecision struct {
+	Allowed     bool            `json:"allowed"`
+	Reason      string          `json:"reason"`
+	Access      string          `json:"access"`       // 路由的授权方式
+	MfaRequired bool            `json:"mfa_required"` // 登录会话需通过两步验证
+	Roles       []SubjectRole   `json:"roles"`
+	Matched     []MatchedPolicy `json:"matched"`
+	Table       *TableExplain   `json:"table,omitempty"`
+}
+
+// ExplainResult 权限解释结果，what-if模式下Baseline为变更前的结果
+type ExplainResult struct {
+	Subject string `json:"subject"`
+	Path    string `json:"path"`
+	Method  string `json:"method"`
+	Tenant  string `json:"tenant"`
+	ExplainDecision
+	WhatIf   bool             `json:"what_if"`
+	Baseline *ExplainDecision `json:"baseline,omitempty"`
+}
+
+// dynamicDataRoute 匹配动态数据接口，提取表名和操作
+var dynamicDataRoute = regexp.MustCompile(`^/api/v1/dynamicData/([^/]+)/(create|list|get|update|delete|batchDelete|statistics)(/[^/]+)?$`)
+
+// dynamicDataActions 动态数据接口对应的数据表操作
+var dynamicDataActions = map[string]string{
+	"create":      TableActionCreate,
+	"list":        TableActionView,
+	"get":         TableActionView,
+	"statistics":  TableActionView,
+	"update":      TableActionUpdate,
+	"delete":      TableActionDelete,
+	"batchDelete": TableActionDelete,
+}
+
+// Explain 解释主体对请求的授权结果，依次执行与Authorize中间件相同的路由声明、认证和Casbin检查
+func (s *PermissionService) Explain(req *ExplainRequest) (*ExplainResult, error) {
+	if global.Enforcer == nil {
+		return nil, errors.New("casbin enforcer not initialized")
+	}
+	if (req.UserID == 0) == (req.RoleID == 0) || (req.ApiKeyID != 0 && req.UserID == 0) {
+		return nil, ErrExplainSubject
+	}
+	if req.RoleID != 0 {
+		var count int64
+		if err := tenantDB(req.TenantID).Model(&model.Role{}).Where("id = ? AND tenant_id = ?", req.RoleID, req.TenantID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrRoleNotFound
+		}
+	}
+	var key *model.ApiKey
+	if req.ApiKeyID != 0 {
+		key = &model.ApiKey{}
+		if err := tenantDB(req.TenantID).Where("id = ? AND user_id = ? AND tenant_id = ?", req.ApiKeyID, req.UserID, req.TenantID).First(key).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrApiKeyNotFound
+			}
+			return nil, err
+		}
+	}
+
+	result := &ExplainResult{
+		Subject: fmt.Sprintf("%d", req.UserID),
+		Path:    req.Path,
+		Method:  strings.ToUpper(req.Method),
+		Tenant:  fmt.Sprintf("%d", req.TenantID),
+		WhatIf:  req.WhatIf != nil,
+	}
+	if req.RoleID != 0 {
+		result.Subject = fmt.Sprintf("role_%d", req.RoleID)
+	}
+
+	// 认证阶段的检查与角色无关，what-if前后结果相同
+	denied, err := authenticateDenied(req, result, key)
+	if err != nil {
+		return nil, err
+	}
+
+	groupings, err := global.Enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := tenantRoleRefs(req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := roleParents(req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	var directIDs []uint
+	if req.UserID != 0 {
+		if directIDs, err = userRoleIDs(req.UserID, req.TenantID); err != nil {
+			return nil, err
+		}
+	} else {
+		directIDs = []uint{req.RoleID}
+	}
+
+	baseline, err := s.decide(req, result, denied, global.Enforcer, groupings, refs, parents, directIDs)
+	if err != nil {
+		return nil, err
+	}
+	if req.WhatIf == nil {
+		result.ExplainDecision = *baseline
+		return result, nil
+	}
+
+	groupings = applyWhatIf(groupings, req.WhatIf, result.Subject, result.Tenant, req.UserID != 0)
+	for roleID, ids := range req.WhatIf.SetParents {
+		parents[roleID] = ids
+	}
+	if req.UserID != 0 {
+		directIDs = applyRoleChange(directIDs, req.WhatIf.AddRoles, req.WhatIf.RemoveRoles)
+	}
+	enforcer, err := whatIfEnforcer(groupings)
+	if err != nil {
+		return nil, err
+	}
+	decision, err := s.decide(req, result, denied, enforcer, groupings, refs, parents, directIDs)
+	if err != nil {
+		return nil, err
+	}
+	result.ExplainDecision = *decision
+	result.Baseline = baseline
+	return result, nil
+}
+
+// authenticateDenied 按认证中间件的顺序检查用户状态和API Key范围，返回拒绝原因，通过时返回空字符串
+func authenticateDenied(req *ExplainRequest, result *ExplainResult, key *model.ApiKey) (string, error) {
+	if req.Access == ExplainAccessNoRoute || req.Access == ExplainAccessPublic {
+		return "", nil
+	}
+	if key != nil {
+		if !key.IsActive() {
+			return ErrApiKeyInvalid.Error(), nil
+		}
+		if err := (&ApiKeyService{}).Authorize(key, result.Method, result.Path); err != nil {
+			return err.Error(), nil
+		}
+	}
+	if req.UserID != 0 {
+		if err := (&AuthStateService{}).CheckAccess(req.UserID, time.Now()); err != nil {
+			if errors.Is(err, ErrUserDisabled) || errors.Is(err, ErrTenantDisabled) || errors.Is(err, ErrTokenRevoked) {
+				return err.Error(), nil
+			}
+			return "", err
+		}
+	}
+	if key != nil && PathRequiresMFA(result.Path) {
+		return "该操作不允许使用API Key访问", nil
+	}
+	return "", nil
+}
+
+// whatIfEnforcer 使用线上模型和策略创建独立的Enforcer，分组策略替换为假设变更后的结果
+func whatIfEnforcer(groupings [][]string) (*casbin.Enforcer, error) {
+	policies, err := global.Enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	m := global.Enforcer.GetModel().Copy()
+	m.ClearPolicy()
+	enforcer, err := initialize.NewEnforcer(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) > 0 {
+		if _, err := enforcer.AddPolicies(policies); err != nil {
+			return nil, err
+		}
+	}
+	if len(groupings) > 0 {
+		if _, err := enforcer.AddGroupingPolicies(groupings); err != nil {
+			return nil, err
+		}
+	}
+	return enforcer, nil
+}
+
+// decide 按给定的Enforcer评估一次请求，denied为认证阶段的拒绝原因，parents和directIDs用于计算数据表权限
+func (s *PermissionService) decide(req *ExplainRequest, result *ExplainResult, denied string, enforcer *casbin.Enforcer, groupings [][]string, refs map[string]RoleRef, parents map[uint][]uint, directIDs []uint) (*ExplainDecision, error) {
+	chains := subjectRoleChains(groupings, result.Subject, result.Tenant)
+	decision := &ExplainDecision{Access: explainAccessNames[req.Access]}
+	for _, role := range sortedChainKeys(chains) {
+		chain := roleRefs(chains[role], refs)
+		decision.Roles = append(decision.Roles, SubjectRole{RoleRef: chain[len(chain)-1], Chain: chain})
+	}
+
+	switch req.Access {
+	case ExplainAccessNoRoute:
+		decision.Reason = "没有注册该路由"
+		return decision, nil
+	case ExplainAccessPublic:
+		decision.Allowed = true
+		decision.Reason = "公开接口，无需登录"
+		return decision, nil
+	}
+	if denied != "" {
+		decision.Reason = denied
+		return decision, nil
+	}
+	// 使用JWT访问敏感API时，登录会话需要通过两步验证
+	decision.MfaRequired = req.ApiKeyID == 0 && PathRequiresMFA(result.Path)
+	if req.Access == ExplainAccessAuthenticated {
+		decision.Allowed = true
+		decision.Reason = "登录即可访问，不检查策略"
+		return decision, nil
+	}
+
+	allowed, matched, err := enforcer.EnforceEx(result.Subject, result.Path, result.Method, result.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	if allowed && len(matched) >= 4 {
+		decision.Matched = append(decision.Matched, MatchedPolicy{
+			Subject: matched[0],
+			Path:    matched[1],
+			Method:  matched[2],
+			Tenant:  matched[3],
+			Chain:   roleRefs(chains[matched[0]], refs),
+		})
+	}
+
+	switch {
+	case allowed:
+		decision.Allowed = true
+		decision.Reason = "由策略 " + describePolicy(decision.Matched[0]) + " 授权"
+	case len(decision.Roles) == 0 && req.UserID != 0:
+		decision.Reason = "主体在该租户没有任何有效角色"
+	default:
+		decision.Reason = "主体及其继承的角色都没有匹配该路径和方法的策略"
+	}
+
+	table, err := explainTable(result.Path, req.TenantID, parents, directIDs, refs)
+	if err != nil {
+		return nil, err
+	}
+	if table != nil {
+		decision.Table = table
+		if decision.Allowed && !table.Allowed {
+			decision.Allowed = false
+			decision.Reason = fmt.Sprintf("接口策略允许，但没有数据表 %s 的%s权限", table.TableName, table.Action)
+		}
+	}
+	return decision, nil
+}
+
+// explainTable 请求为动态数据接口时计算涉及的数据表及字段权限，其他请求或表不存在时返回nil
+func explainTable(path string, tenantID uint, parents map[uint][]uint, directIDs []uint, refs map[string]RoleRef) (*TableExplain, error) {
+	match := dynamicDataRoute.FindStringSubmatch(path)
+	if match == nil {
+		return nil, nil
+	}
+	var table model.DynamicTable
+	if err := tenantDB(tenantID).Where("table_name = ? AND tenant_id = ?", match[1], tenantID).First(&table).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	permission, grants, err := mergeRolePermissions(tenantID, table.ID, expandRoles(parents, directIDs))
+	if err != nil {
+		return nil, err
+	}
+	action := dynamicDataActions[match[2]]
+	explain := &TableExplain{
+		TableID:    table.ID,
+		TableName:  table.TableName,
+		Action:     action,
+		Allowed:    tableActionAllowed(permission, action),
+		Permission: permission,
+	}
+	direct := make(map[uint]bool, len(directIDs))
+	for _, id := range directIDs {
+		direct[id] = true
+	}
+	for _, grant := range grants {
+		source := refs[fmt.Sprintf("role_%d", grant.RoleID)]
+		explain.Grants = append(explain.Grants, InheritedTablePermission{TablePermission: grant, Source: source, Inherited: !direct[grant.RoleID]})
+	}
+	return explain, nil
+}
+
+// tenantRoleRefs 以Casbin中的角色名为键返回租户内的角色
+func tenantRoleRefs(tenantID uint) (map[string]RoleRef, error) {
+	var roles []model.Role
+	if err := tenantDB(tenantID).Where("tenant_id = ?", tenantID).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	refs := make(map[string]RoleRef, len(roles))
+	for _, role := range roles {
+		refs[fmt.Sprintf("role_%d", role.ID)] = RoleRef{ID: role.ID, Name: role.Name}
+	}
+	return refs, nil
+}
+
+// roleRefs 把Casbin角色名转换为角色引用，未知的角色只保留名称
+func roleRefs(names []string, refs map[string]RoleRef) []RoleRef {
+	result := make([]RoleRef, 0, len(names))
+	for _, name := range names {
+		ref, ok := refs[name]
+		if !ok {
+			ref = RoleRef{Name: name}
+		}
+		result = append(result, ref)
+	}
+	return result
+}
+
+// describePolicy 以 角色 方法 路径 的形式描述策略
+func describePolicy(policy MatchedPolicy) string {
+	name := policy.Subject
+	if len(policy.Chain) > 0 {
+		name = policy.Chain[len(policy.Chain)-1].Name
+	}
+	return fmt.Sprintf("%s %s %s", name, policy.Method, policy.Path)
+}
+
+// subjectRoleChains 按广度优先展开主体在租户内的角色，返回每个角色到主体的最短继承路径（不含主体）
+func subjectRoleChains(groupings [][]string, subject, tenant string) map[string][]string {
+	edges := make(map[string][]string)
+	for _, g := range groupings {
+		if len(g) >= 3 && g[2] == tenant {
+			edges[g[0]] = append(edges[g[0]], g[1])
+		}
+	}
+	chains := make(map[string][]string)
+	queue := []string{subject}
+	paths := map[string][]string{subject: nil}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[node] {
+			if _, seen := paths[next]; seen {
+				continue
+			}
+			path := append(append([]string(nil), paths[node]...), next)
+			paths[next] = path
+			chains[next] = path
+			queue = append(queue, next)
+		}
+	}
+	return chains
+}
+
+// sortedChainKeys 按继承距离和名称排序角色，保证输出稳定
+func sortedChainKeys(chains map[string][]string) []string {
+	keys := make([]string, 0, len(chains))
+	for key := range chains {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(chains[keys[i]]) != len(chains[keys[j]]) {
+			return len(chains[keys[i]]) < len(chains[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// applyWhatIf 在分组策略副本上应用假设的角色变更
+func applyWhatIf(groupings [][]string, change *WhatIfChange, subject, tenant string, isUser bool) [][]string {
+	removed := make(map[string]bool)
+	var added [][]string
+	if isUser {
+		for _, id := range change.RemoveRoles {
+			removed[subject+"\x00"+fmt.Sprintf("role_%d", id)] = true
+		}
+		for _, id := range change.AddRoles {
+			added = append(added, []string{subject, fmt.Sprintf("role_%d", id), tenant})
+		}
+	}
+	replaced := make(map[string]bool, len(change.SetParents))
+	for roleID, parentIDs := range change.SetParents {
+		role := fmt.Sprintf("role_%d", roleID)
+		replaced[role] = true
+		for _, parentID := range parentIDs {
+			added = append(added, []string{role, fmt.Sprintf("role_%d", parentID), tenant})
+		}
+	}
+
+	result := make([][]string, 0, len(groupings)+len(added))
+	for _, g := range groupings {
+		if len(g) >= 3 && g[2] == tenant && (removed[g[0]+"\x00"+g[1]] || replaced[g[0]]) {
+			continue
+		}
+		result = append(result, g)
+	}
+	return append(result, added...)
+}
+
+// applyRoleChange 在角色列表上应用假设的增删
+func applyRoleChange(roleIDs, add, remove []uint) []uint {
+	removed := make(map[uint]bool, len(remove))
+	for _, id := range remove {
+		removed[id] = true
+	}
+	var result []uint
+	for _, id := range append(append([]uint(nil), roleIDs...), add...) {
+		if !removed[id] {
+			result = append(result, id)
+		}
+	}
+	return uniqueIDs(result)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+)
+
+var (
+	explainPolicies = [][]string{
+		{"role_1", "/api/v1/logs", "GET", "1"},
+		{"role_2", "/api/v1/user/update/:id", "PUT", "1"},
+		{"role_3", "/api/v1/role/*", "*", "1"},
+		{"role_4", "/api/v1/profile", "GET", "*"},
+		{"9", "/api/v1/menu/list", "GET", "1"},
+	}
+	explainGroupings = [][]string{
+		{"7", "role_3", "1"},
+		{"role_3", "role_2", "1"},
+		{"role_2", "role_1", "1"},
+		{"8", "role_4", "2"},
+		{"8", "role_1", "2"},
+	}
+)
+
+func TestSubjectRoleChains(t *testing.T) {
+	chains := subjectRoleChains(explainGroupings, "7", "1")
+	want := map[string][]string{
+		"role_3": {"role_3"},
+		"role_2": {"role_3", "role_2"},
+		"role_1": {"role_3", "role_2", "role_1"},
+	}
+	if !reflect.DeepEqual(chains, want) {
+		t.Fatalf("chains = %v，期望 %v", chains, want)
+	}
+	if got := sortedChainKeys(chains); !reflect.DeepEqual(got, []string{"role_3", "role_2", "role_1"}) {
+		t.Errorf("sortedChainKeys = %v", got)
+	}
+	if chains := subjectRoleChains(explainGroupings, "7", "2"); len(chains) != 0 {
+		t.Errorf("其他租户不应有角色: %v", chains)
+	}
+}
+
+// useExplainEnforcer 把global.Enforcer替换为加载了测试策略的Enforcer，并准备解释所需的数据
+func useExplainEnforcer(t *testing.T) {
+	t.Helper()
+	enforcer, err := initialize.NewEnforcer("../config/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.AddPolicies(explainPolicies); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.AddGroupingPolicies(explainGroupings); err != nil {
+		t.Fatal(err)
+	}
+	savedEnforcer, savedConfig := global.Enforcer, global.GlobalConfig
+	global.Enforcer = enforcer
+	global.GlobalConfig = &global.Config{Mfa: global.MfaConfig{RequiredPaths: []string{"/api/v1/role/*"}}}
+	t.Cleanup(func() {
+		global.Enforcer, global.GlobalConfig = savedEnforcer, savedConfig
+	})
+
+	db := useTestDB(t, &model.User{}, &model.Tenant{}, &model.Role{}, &model.RoleInheritance{}, &model.UserRole{}, &model.ApiKey{})
+	for id := uint(1); id <= 3; id++ {
+		if err := db.Create(&model.Role{ID: id, Name: fmt.Sprintf("角色%d", id), TenantID: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&model.User{ID: 7, Username: "u7", TenantID: 1, Status: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.UserRole{UserID: 7, RoleID: 3, TenantID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := (&AuthStateService{}).InvalidateUser(7); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestExplainAgreesWithCasbin 策略授权的路由，解释结果必须与Casbin中间件的判断一致
+func TestExplainAgreesWithCasbin(t *testing.T) {
+	useExplainEnforcer(t)
+	service := &PermissionService{}
+
+	tests := []struct {
+		userID, roleID uint
+		path, method   string
+	}{
+		{7, 0, "/api/v1/logs", "GET"},
+		{7, 0, "/api/v1/logs", "POST"},
+		{7, 0, "/api/v1/user/update/5", "PUT"},
+		{7, 0, "/api/v1/role/list", "DELETE"},
+		{7, 0, "/api/v1/profile", "GET"},
+		{7, 0, "/api/v1/menu/list", "GET"},
+		{0, 2, "/api/v1/logs", "GET"},
+		{0, 2, "/api/v1/role/list", "GET"},
+	}
+	for _, tt := range tests {
+		result, err := service.Explain(&ExplainRequest{UserID: tt.userID, RoleID: tt.roleID, Path: tt.path, Method: tt.method, TenantID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := global.Enforcer.Enforce(result.Subject, tt.path, tt.method, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != want {
+			t.Errorf("%s %s %s: 解释结果 %v，Casbin %v（%s）", result.Subject, tt.method, tt.path, result.Allowed, want, result.Reason)
+		}
+		if result.Allowed && len(result.Matched) != 1 {
+			t.Errorf("%s %s %s: 匹配策略 %v，期望 1 条", result.Subject, tt.method, tt.path, result.Matched)
+		}
+	}
+
+	result, err := service.Explain(&ExplainRequest{UserID: 7, Path: "/api/v1/logs", Method: "GET", TenantID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain := result.Matched[0].Chain; len(chain) != 3 || chain[2].ID != 1 {
+		t.Errorf("继承路径 = %v，期望经由角色3、2到达角色1", chain)
+	}
+}
+
+// TestExplainRouteAccess 公开和登录即可访问的路由不检查策略，与Authorize中间件一致
+func TestExplainRouteAccess(t *testing.T) {
+	useExplainEnforcer(t)
+	service := &PermissionService{}
+
+	tests := []struct {
+		access  ExplainAccess
+		path    string
+		allowed bool
+	}{
+		{ExplainAccessAuthenticated, "/api/v1/tablePermission/mine/5", true},
+		{ExplainAccessPolicy, "/api/v1/tablePermission/mine/5", false},
+		{ExplainAccessPublic, "/api/v1/captcha", true},
+		{ExplainAccessNoRoute, "/api/v1/logs", false},
+	}
+	for _, tt := range tests {
+		result, err := service.Explain(&ExplainRequest{UserID: 7, Path: tt.path, Method: "GET", TenantID: 1, Access: tt.access})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != tt.allowed {
+			t.Errorf("%s %s: allowed = %v，期望 %v（%s）", result.Access, tt.path, result.Allowed, tt.allowed, result.Reason)
+		}
+	}
+
+	if err := systemDB().Model(&model.User{}).Where("id = ?", 7).Update("status", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := (&AuthStateService{}).InvalidateUser(7); err != nil {
+		t.Fatal(err)
+	}
+	result, err := service.Explain(&ExplainRequest{UserID: 7, Path: "/api/v1/tablePermission/mine/5", Method: "GET", TenantID: 1, Access: ExplainAccessAuthenticated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Error("禁用的用户不应通过认证")
+	}
+}
+
+// TestExplainMfaAndApiKey 敏感API要求两步验证，API Key受访问范围限制且不能访问敏感API
+func TestExplainMfaAndApiKey(t *testing.T) {
+	useExplainEnforcer(t)
+	service := &PermissionService{}
+
+	result, err := service.Explain(&ExplainRequest{UserID: 7, Path: "/api/v1/role/list", Method: "DELETE", TenantID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed || !result.MfaRequired {
+		t.Errorf("allowed = %v，mfa_required = %v，期望都为true", result.Allowed, result.MfaRequired)
+	}
+
+	scoped := model.ApiKey{KeyID: "scoped", UserID: 7, TenantID: 1, AllowedRoutes: []string{"/api/v1/logs"}}
+	open := model.ApiKey{KeyID: "open", UserID: 7, TenantID: 1}
+	if err := systemDB().Create(&scoped).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := systemDB().Create(&open).Error; err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		keyID        uint
+		path, method string
+		allowed      bool
+	}{
+		{scoped.ID, "/api/v1/logs", "GET", true},
+		{scoped.ID, "/api/v1/user/update/5", "PUT", false},
+		{open.ID, "/api/v1/user/update/5", "PUT", true},
+		{open.ID, "/api/v1/role/list", "DELETE", false},
+	}
+	for _, tt := range tests {
+		result, err := service.Explain(&ExplainRequest{UserID: 7, ApiKeyID: tt.keyID, Path: tt.path, Method: tt.method, TenantID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != tt.allowed {
+			t.Errorf("Key %d %s %s: allowed = %v，期望 %v（%s）", tt.keyID, tt.method, tt.path, result.Allowed, tt.allowed, result.Reason)
+		}
+	}
+
+	if _, err := service.Explain(&ExplainRequest{UserID: 8, ApiKeyID: open.ID, Path: "/api/v1/logs", Method: "GET", TenantID: 1}); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Errorf("其他用户的API Key: err = %v，期望 %v", err, ErrApiKeyNotFound)
+	}
+}
+
+// TestExplainWhatIfLeavesEnforcer what-if使用独立的Enforcer评估，不影响线上策略
+func TestExplainWhatIfLeavesEnforcer(t *testing.T) {
+	useExplainEnforcer(t)
+
+	result, err := (&PermissionService{}).Explain(&ExplainRequest{
+		UserID: 7, Path: "/api/v1/logs", Method: "GET", TenantID: 1,
+		WhatIf: &WhatIfChange{RemoveRoles: []uint{3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.Baseline == nil || !result.Baseline.Allowed {
+		t.Errorf("allowed = %v，baseline = %+v，期望移除角色后被拒绝、变更前允许", result.Allowed, result.Baseline)
+	}
+	if allowed, _ := global.Enforcer.Enforce("7", "/api/v1/logs", "GET", "1"); !allowed {
+		t.Error("what-if不应修改线上的分组策略")
+	}
+}
+
+func TestApplyWhatIf(t *testing.T) {
+	change := &WhatIfChange{AddRoles: []uint{4}, RemoveRoles: []uint{3}, SetParents: map[uint][]uint{2: nil}}
+	groupings := applyWhatIf(explainGroupings, change, "7", "1", true)
+	want := [][]string{
+		{"role_3", "role_2", "1"},
+		{"8", "role_4", "2"},
+		{"8", "role_1", "2"},
+		{"7", "role_4", "1"},
+	}
+	if !reflect.DeepEqual(groupings, want) {
+		t.Fatalf("groupings = %v，期望 %v", groupings, want)
+	}
+	if len(explainGroupings) != 5 {
+		t.Error("不应修改原始分组策略")
+	}
+
+	if got := applyRoleChange([]uint{1, 3}, []uint{4, 1}, []uint{3}); !reflect.DeepEqual(got, []uint{1, 4}) {
+		t.Errorf("applyRoleChange = %v", got)
+	}
+}
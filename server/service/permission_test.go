@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+// TestAssignUserRolesRollback 写入用户角色的事务回滚时，Casbin中的角色关联保持不变
+func TestAssignUserRolesRollback(t *testing.T) {
+	db := useTestDB(t, &model.User{}, &model.Role{}, &model.UserRole{})
+	useTestEnforcer(t)
+	if err := db.Create(&model.User{ID: 1, Username: "user1", TenantID: 1, Status: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for id := uint(1); id <= 2; id++ {
+		if err := db.Create(&model.Role{ID: id, Name: fmt.Sprintf("角色%d", id), TenantID: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &PermissionService{}
+	if err := s.AssignUserRoles(&UserRoleRequest{UserID: 1, RoleIDs: []uint{1}, TenantID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	errWrite := errors.New("写入失败")
+	if err := global.DB.Callback().Create().Before("gorm:create").Register("test:fail_user_role", func(tx *gorm.DB) {
+		if userRole, ok := tx.Statement.Dest.(*model.UserRole); ok && userRole.RoleID == 2 {
+			tx.AddError(errWrite)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignUserRoles(&UserRoleRequest{UserID: 1, RoleIDs: []uint{2}, TenantID: 1}); !errors.Is(err, errWrite) {
+		t.Fatalf("err = %v，期望 %v", err, errWrite)
+	}
+
+	if roles, _ := userRoleIDs(1, 1); !reflect.DeepEqual(roles, []uint{1}) {
+		t.Errorf("回滚后角色 = %v，期望 [1]", roles)
+	}
+	if allowed, _ := global.Enforcer.HasGroupingPolicy("1", "role_1", "1"); !allowed {
+		t.Error("回滚后Casbin应保留原有的角色关联")
+	}
+	if allowed, _ := global.Enforcer.HasGroupingPolicy("1", "role_2", "1"); allowed {
+		t.Error("回滚后Casbin不应有新的角色关联")
+	}
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrElevationNotFound 提权申请不存在
+	ErrElevationNotFound = errors.New("提权申请不存在")
+	// ErrElevationNotPending 提权申请已处理
+	ErrElevationNotPending = errors.New("提权申请已处理")
+	// ErrElevationSelfApproval 不能审批自己的申请
+	ErrElevationSelfApproval = errors.New("不能审批自己的提权申请")
+	// ErrElevationDuplicate 同一角色已有待审批的申请
+	ErrElevationDuplicate = errors.New("该角色已有待审批的申请")
+	// ErrElevationHasRole 用户已长期拥有该角色
+	ErrElevationHasRole = errors.New("已长期拥有该角色，无需临时提权")
+	// ErrElevationInvalid 申请时长或理由不合法
+	ErrElevationInvalid = errors.New("提权申请参数不合法")
+)
+
+const (
+	elevationRequestPath = "/api/v1/elevation/request"
+	elevationApprovePath = "/api/v1/elevation/approve"
+	elevationRejectPath  = "/api/v1/elevation/reject"
+	elevationCancelPath  = "/api/v1/elevation/cancel"
+	// roleExpirerPath 后台任务写入的审计日志没有请求路径，以任务名代替
+	roleExpirerPath = "role_expirer"
+)
+
+// RoleElevationService 临时提权服务
+type RoleElevationService struct{}
+
+// RequestElevation 申请在指定时长内临时拥有角色
+func (s *RoleElevationService) RequestElevation(user *model.User, roleID uint, hours int, reason string) (*model.RoleElevation, error) {
+	reason = strings.TrimSpace(reason)
+	maxHours := global.GlobalConfig.Elevation.MaxHours
+	if hours < 1 || hours > maxHours {
+		return nil, fmt.Errorf("%w：申请时长须为1至%d小时", ErrElevationInvalid, maxHours)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("%w：请填写申请理由", ErrElevationInvalid)
+	}
+
+	var role model.Role
+	if err := tenantDB(user.TenantID).Where("id = ? AND tenant_id = ? AND status = 1", roleID, user.TenantID).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	var count int64
+	if err := tenantDB(user.TenantID).Model(&model.UserRole{}).
+		Where("user_id = ? AND role_id = ? AND tenant_id = ? AND valid_until IS NULL", user.ID, roleID, user.TenantID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrElevationHasRole
+	}
+	if err := tenantDB(user.TenantID).Model(&model.RoleElevation{}).
+		Where("user_id = ? AND role_id = ? AND tenant_id = ? AND status = ?", user.ID, roleID, user.TenantID, model.ElevationPending).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrElevationDuplicate
+	}
+
+	elevation := &model.RoleElevation{
+		UserID:   user.ID,
+		Username: user.Username,
+		RoleID:   role.ID,
+		RoleName: role.Name,
+		Hours:    hours,
+		Reason:   reason,
+		Status:   model.ElevationPending,
+		TenantID: user.TenantID,
+	}
+	if err := tenantDB(user.TenantID).Create(elevation).Error; err != nil {
+		return nil, err
+	}
+	writeRoleAudit(model.LogTypeElevation, elevation.UserID, elevation.Username, elevation.TenantID, "POST", elevationRequestPath,
+		fmt.Sprintf("申请临时角色 %s %d小时，理由：%s", role.Name, hours, reason))
+	return elevation, nil
+}
+
+// ListElevations 分页查询租户内的提权申请，userID不为0时只查询该用户的申请
+func (s *RoleElevationService) ListElevations(tenantID, userID uint, status string, page, pageSize int) ([]model.RoleElevation, int64, error) {
+	db := tenantDB(tenantID).Model(&model.RoleElevation{}).Where("tenant_id = ?", tenantID)
+	if userID != 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var elevations []model.RoleElevation
+	err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&elevations).Error
+	return elevations, total, err
+}
+
+// pendingElevation 获取租户内待审批的申请
+func pendingElevation(tx *gorm.DB, tenantID, id uint) (*model.RoleElevation, error) {
+	var elevation model.RoleElevation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).First(&elevation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrElevationNotFound
+		}
+		return nil, err
+	}
+	if elevation.Status != model.ElevationPending {
+		return nil, ErrElevationNotPending
+	}
+	return &elevation, nil
+}
+
+// Approve 批准提权申请，用户立即获得角色，到期后由后台任务收回
+// 用户已有同一角色的临时授权时取两者中较晚的失效时间
+func (s *RoleElevationService) Approve(id uint, approver *model.User, comment string) (*model.RoleElevation, error) {
+	var elevation *model.RoleElevation
+	err := tenantDB(approver.TenantID).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if elevation, err = pendingElevation(tx, approver.TenantID, id); err != nil {
+			return err
+		}
+		if elevation.UserID == approver.ID {
+			return ErrElevationSelfApproval
+		}
+
+		now := time.Now()
+		until := now.Add(time.Duration(elevation.Hours) * time.Hour)
+		var existing model.UserRole
+		err = tx.Where("user_id = ? AND role_id = ? AND tenant_id = ?", elevation.UserID, elevation.RoleID, elevation.TenantID).
+			First(&existing).Error
+		switch {
+		case err == nil && existing.ValidUntil == nil:
+			return ErrElevationHasRole
+		case err == nil && existing.ValidUntil.After(until):
+			until = *existing.ValidUntil
+		case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		userRole := model.UserRole{
+			UserID:     elevation.UserID,
+			RoleID:     elevation.RoleID,
+			TenantID:   elevation.TenantID,
+			ValidUntil: &until,
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}, {Name: "tenant_id"}},
+			UpdateAll: true,
+		}).Create(&userRole).Error; err != nil {
+			return err
+		}
+
+		elevation.Status = model.ElevationApproved
+		elevation.ApproverID = approver.ID
+		elevation.Approver = approver.Username
+		elevation.Comment = strings.TrimSpace(comment)
+		elevation.DecidedAt = &now
+		elevation.ValidUntil = &until
+		if err := tx.Save(elevation).Error; err != nil {
+			return err
+		}
+		return initialize.AddUserRole(elevation.UserID, elevation.RoleID, elevation.TenantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	writeRoleAudit(model.LogTypeElevation, elevation.UserID, elevation.Username, elevation.TenantID, "POST", elevationApprovePath,
+		fmt.Sprintf("%s 批准临时角色 %s，有效期至 %s", approver.Username, elevation.RoleName, elevation.ValidUntil.Format("2006-01-02 15:04")))
+	return elevation, nil
+}
+
+// Reject 拒绝提权申请
+func (s *RoleElevationService) Reject(id uint, approver *model.User, comment string) (*model.RoleElevation, error) {
+	elevation, err := s.decide(approver.TenantID, id, 0, func(elevation *model.RoleElevation, now time.Time) {
+		elevation.Status = model.ElevationRejected
+		elevation.ApproverID = approver.ID
+		elevation.Approver = approver.Username
+		elevation.Comment = strings.TrimSpace(comment)
+		elevation.DecidedAt = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeRoleAudit(model.LogTypeElevation, elevation.UserID, elevation.Username, elevation.TenantID, "POST", elevationRejectPath,
+		fmt.Sprintf("%s 拒绝临时角色 %s：%s", approver.Username, elevation.RoleName, elevation.Comment))
+	return elevation, nil
+}
+
+// Cancel 申请人撤回待审批的申请
+func (s *RoleElevationService) Cancel(id uint, user *model.User) (*model.RoleElevation, error) {
+	elevation, err := s.decide(user.TenantID, id, user.ID, func(elevation *model.RoleElevation, now time.Time) {
+		elevation.Status = model.ElevationCancelled
+		elevation.DecidedAt = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeRoleAudit(model.LogTypeElevation, elevation.UserID, elevation.Username, elevation.TenantID, "POST", elevationCancelPath,
+		fmt.Sprintf("撤回临时角色 %s 的申请", elevation.RoleName))
+	return elevation, nil
+}
+
+// decide 在事务中修改待审批的申请，ownerID不为0时只能处理该用户的申请
+func (s *RoleElevationService) decide(tenantID, id, ownerID uint, apply func(*model.RoleElevation, time.Time)) (*model.RoleElevation, error) {
+	var elevation *model.RoleElevation
+	err := tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if elevation, err = pendingElevation(tx, tenantID, id); err != nil {
+			return err
+		}
+		if ownerID != 0 && elevation.UserID != ownerID {
+			return ErrElevationNotFound
+		}
+		apply(elevation, time.Now())
+		return tx.Save(elevation).Error
+	})
+	return elevation, err
+}
+
+// ExpireGrants 收回各租户已到期的临时角色，并为到达生效时间的角色添加Casbin关联
+func (s *RoleElevationService) ExpireGrants(now time.Time) error {
+	if global.Enforcer == nil {
+		return errors.New("casbin enforcer not initialized")
+	}
+	var lapsed []model.UserRole
+	if err := systemDB().Where("valid_until IS NOT NULL AND valid_until <= ?", now).Find(&lapsed).Error; err != nil {
+		return err
+	}
+	for _, ur := range lapsed {
+		if err := systemDB().Delete(&model.UserRole{}, ur.ID).Error; err != nil {
+			return err
+		}
+		if err := initialize.RemoveUserRole(ur.UserID, ur.RoleID, ur.TenantID); err != nil {
+			return err
+		}
+		writeRoleAudit(model.LogTypeRoleExpire, ur.UserID, "", ur.TenantID, "SYSTEM", roleExpirerPath,
+			fmt.Sprintf("角色%d已于 %s 到期收回", ur.RoleID, ur.ValidUntil.Format("2006-01-02 15:04")))
+	}
+	if err := systemDB().Model(&model.RoleElevation{}).
+		Where("status = ? AND valid_until <= ?", model.ElevationApproved, now).
+		Update("status", model.ElevationExpired).Error; err != nil {
+		return err
+	}
+
+	var started []model.UserRole
+	if err := systemDB().Scopes(model.ActiveUserRoles(now)).Where("valid_from IS NOT NULL").Find(&started).Error; err != nil {
+		return err
+	}
+	for _, ur := range started {
+		grouping := []string{fmt.Sprintf("%d", ur.UserID), fmt.Sprintf("role_%d", ur.RoleID), fmt.Sprintf("%d", ur.TenantID)}
+		if ok, err := global.Enforcer.HasGroupingPolicy(grouping); err != nil || ok {
+			continue
+		}
+		if err := initialize.AddUserRole(ur.UserID, ur.RoleID, ur.TenantID); err != nil {
+			return err
+		}
+		writeRoleAudit(model.LogTypeRoleGrant, ur.UserID, "", ur.TenantID, "SYSTEM", roleExpirerPath,
+			fmt.Sprintf("角色%d于 %s 开始生效", ur.RoleID, ur.ValidFrom.Format("2006-01-02 15:04")))
+	}
+	return nil
+}
+
+// StartRoleGrantExpirer 启动后台任务，启动时立即检查一次，之后每分钟检查临时角色的生效和到期
+func StartRoleGrantExpirer() {
+	s := &RoleElevationService{}
+	run := func(now time.Time) {
+		if err := s.ExpireGrants(now); err != nil {
+			fmt.Printf("处理临时角色到期失败: %v\n", err)
+		}
+	}
+	run(time.Now())
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			run(now)
+		}
+	}()
+}
+
+// writeRoleAudit 写入角色授权审计日志，UserID和Username为角色发生变化的用户
+func writeRoleAudit(logType string, userID uint, username string, tenantID uint, method, path, detail string) {
+	if username == "" {
+		var user model.User
+		if tenantDB(tenantID).Select("username").First(&user, userID).Error == nil {
+			username = user.Username
+		}
+	}
+	if runes := []rune(detail); len(runes) > 255 {
+		detail = string(runes[:255])
+	}
+	entry := model.Log{
+		UserID:     userID,
+		Username:   username,
+		Method:     method,
+		Path:       path,
+		StatusCode: 200,
+		TenantID:   tenantID,
+		Type:       logType,
+		Detail:     detail,
+	}
+	if err := tenantDB(tenantID).Create(&entry).Error; err != nil {
+		fmt.Printf("记录角色授权审计日志失败: %v\n", err)
+	}
+}
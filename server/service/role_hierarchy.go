@@ -0,0 +1,295 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+	// ErrRoleCycle 角色继承出现环
+	ErrRoleCycle = errors.New("角色继承不能形成循环")
+)
+
+// RoleHierarchyService 角色继承服务
+type RoleHierarchyService struct{}
+
+// RoleRef 权限来源角色
+type RoleRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// InheritedMenu 带来源角色的菜单权限
+type InheritedMenu struct {
+	model.Menu
+	Source    RoleRef `json:"source"`
+	Inherited bool    `json:"inherited"`
+}
+
+// InheritedApi 带来源角色的接口权限
+type InheritedApi struct {
+	model.Api
+	Source    RoleRef `json:"source"`
+	Inherited bool    `json:"inherited"`
+}
+
+// InheritedPolicy 带来源角色的Casbin策略
+type InheritedPolicy struct {
+	Path      string  `json:"path"`
+	Method    string  `json:"method"`
+	Source    RoleRef `json:"source"`
+	Inherited bool    `json:"inherited"`
+}
+
+// InheritedTablePermission 带来源角色的数据表权限
+type InheritedTablePermission struct {
+	model.TablePermission
+	Source    RoleRef `json:"source"`
+	Inherited bool    `json:"inherited"`
+}
+
+// EffectivePermissions 角色展开继承后的有效权限，同一菜单或接口由多个角色授予时来源取继承链上最近的角色
+type EffectivePermissions struct {
+	Role             *model.Role                `json:"role"`
+	Ancestors        []RoleRef                  `json:"ancestors"` // 按继承距离由近到远排列
+	Menus            []InheritedMenu            `json:"menus"`
+	Apis             []InheritedApi             `json:"apis"`
+	Policies         []InheritedPolicy          `json:"policies"`
+	TablePermissions []InheritedTablePermission `json:"table_permissions"`
+}
+
+// GetParents 获取角色的直接上级角色
+func (s *RoleHierarchyService) GetParents(tenantID, roleID uint) ([]model.Role, error) {
+	var roles []model.Role
+	err := tenantDB(tenantID).Joins("JOIN role_inheritances ON role_inheritances.parent_id = roles.id").
+		Where("role_inheritances.role_id = ? AND role_inheritances.tenant_id = ?", roleID, tenantID).
+		Order("roles.id").Find(&roles).Error
+	return roles, err
+}
+
+// SetParents 替换角色的上级角色，拒绝会形成循环的继承关系
+func (s *RoleHierarchyService) SetParents(tenantID, roleID uint, parentIDs []uint) error {
+	parentIDs, err := checkRoleParents(tenantID, roleID, parentIDs)
+	if err != nil {
+		return err
+	}
+
+	return tenantDB(tenantID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ? AND tenant_id = ?", roleID, tenantID).Delete(&model.RoleInheritance{}).Error; err != nil {
+			return err
+		}
+		for _, parentID := range parentIDs {
+			if err := tx.Create(&model.RoleInheritance{RoleID: roleID, ParentID: parentID, TenantID: tenantID}).Error; err != nil {
+				return err
+			}
+		}
+		return initialize.SetRoleParents(roleID, parentIDs, tenantID)
+	})
+}
+
+// checkRoleParents 检查角色和上级角色都属于租户且不会形成循环，返回去重后的上级角色
+func checkRoleParents(tenantID, roleID uint, parentIDs []uint) ([]uint, error) {
+	parentIDs = uniqueIDs(parentIDs)
+	var count int64
+	if err := tenantDB(tenantID).Model(&model.Role{}).Where("tenant_id = ? AND id IN ?", tenantID, append([]uint{roleID}, parentIDs...)).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(parentIDs)+1 {
+		for _, id := range parentIDs {
+			if id == roleID {
+				return nil, ErrRoleCycle
+			}
+		}
+		return nil, ErrRoleNotFound
+	}
+
+	// 上级角色及其祖先中出现当前角色时会形成环
+	ancestors, err := ExpandRoleIDs(tenantID, parentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ancestors {
+		if id == roleID {
+			return nil, ErrRoleCycle
+		}
+	}
+	return parentIDs, nil
+}
+
+// RemoveRole 删除租户内角色的继承关系，包括以该角色为上级的关系
+func (s *RoleHierarchyService) RemoveRole(tenantID, roleID uint) error {
+	if err := tenantDB(tenantID).Where("role_id = ? OR parent_id = ?", roleID, roleID).Delete(&model.RoleInheritance{}).Error; err != nil {
+		return err
+	}
+	if global.Enforcer == nil {
+		return nil
+	}
+	roleIDStr := fmt.Sprintf("role_%d", roleID)
+	if _, err := global.Enforcer.RemoveFilteredGroupingPolicy(0, roleIDStr); err != nil {
+		return err
+	}
+	_, err := global.Enforcer.RemoveFilteredGroupingPolicy(1, roleIDStr)
+	return err
+}
+
+// ExpandRoleIDs 返回角色及其全部祖先角色的ID，按继承距离由近到远排列
+func ExpandRoleIDs(tenantID uint, roleIDs []uint) ([]uint, error) {
+	parents, err := roleParents(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return expandRoles(parents, roleIDs), nil
+}
+
+// roleParents 加载租户内的全部继承关系，键为角色ID，值为其直接上级角色ID
+func roleParents(tenantID uint) (map[uint][]uint, error) {
+	var links []model.RoleInheritance
+	if err := tenantDB(tenantID).Where("tenant_id = ?", tenantID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint][]uint, len(links))
+	for _, link := range links {
+		parents[link.RoleID] = append(parents[link.RoleID], link.ParentID)
+	}
+	return parents, nil
+}
+
+// expandRoles 按广度优先展开角色的祖先，已访问的角色不会重复出现
+func expandRoles(parents map[uint][]uint, roleIDs []uint) []uint {
+	visited := make(map[uint]bool, len(roleIDs))
+	var result []uint
+	queue := append([]uint(nil), roleIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		result = append(result, id)
+		queue = append(queue, parents[id]...)
+	}
+	return result
+}
+
+// userRoleIDs 获取用户在租户内直接拥有且当前有效的角色
+func userRoleIDs(userID, tenantID uint) ([]uint, error) {
+	var roleIDs []uint
+	err := tenantDB(tenantID).Model(&model.UserRole{}).Scopes(model.ActiveUserRoles(time.Now())).
+		Where("user_id = ? AND tenant_id = ?", userID, tenantID).
+		Pluck("role_id", &roleIDs).Error
+	return roleIDs, err
+}
+
+// UserEffectiveRoleIDs 获取用户直接拥有的角色及其继承的全部角色
+func UserEffectiveRoleIDs(userID, tenantID uint) ([]uint, error) {
+	roleIDs, err := userRoleIDs(userID, tenantID)
+	if err != nil || len(roleIDs) == 0 {
+		return roleIDs, err
+	}
+	return ExpandRoleIDs(tenantID, roleIDs)
+}
+
+// GetEffectivePermissions 展开角色继承链，返回角色的全部有效权限及每项权限的来源角色
+func (s *RoleHierarchyService) GetEffectivePermissions(tenantID, roleID uint) (*EffectivePermissions, error) {
+	var role model.Role
+	if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", roleID, tenantID).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	chain, err := ExpandRoleIDs(tenantID, []uint{roleID})
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []model.Role
+	if err := tenantDB(tenantID).Where("id IN ? AND tenant_id = ?", chain, tenantID).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	refs := make(map[uint]RoleRef, len(roles))
+	for _, r := range roles {
+		refs[r.ID] = RoleRef{ID: r.ID, Name: r.Name}
+	}
+
+	result := &EffectivePermissions{Role: &role}
+	for _, id := range chain[1:] {
+		if ref, ok := refs[id]; ok {
+			result.Ancestors = append(result.Ancestors, ref)
+		}
+	}
+
+	// 按继承链由近到远收集，先出现的来源角色优先
+	seenMenus := make(map[uint]bool)
+	seenApis := make(map[uint]bool)
+	seenPolicies := make(map[string]bool)
+	for _, id := range chain {
+		source, ok := refs[id]
+		if !ok {
+			continue
+		}
+		inherited := id != roleID
+
+		var menus []model.Menu
+		if err := tenantDB(tenantID).Joins("JOIN role_menus ON menus.id = role_menus.menu_id AND role_menus.deleted_at IS NULL").
+			Where("role_menus.role_id = ? AND role_menus.tenant_id = ?", id, tenantID).
+			Order("menus.sort, menus.id").Find(&menus).Error; err != nil {
+			return nil, err
+		}
+		for _, menu := range menus {
+			if !seenMenus[menu.ID] {
+				seenMenus[menu.ID] = true
+				result.Menus = append(result.Menus, InheritedMenu{Menu: menu, Source: source, Inherited: inherited})
+			}
+		}
+
+		var apis []model.Api
+		if err := tenantDB(tenantID).Joins("JOIN role_apis ON apis.id = role_apis.api_id AND role_apis.deleted_at IS NULL").
+			Where("role_apis.role_id = ? AND role_apis.tenant_id = ?", id, tenantID).
+			Order("apis.path, apis.method").Find(&apis).Error; err != nil {
+			return nil, err
+		}
+		for _, api := range apis {
+			if !seenApis[api.ID] {
+				seenApis[api.ID] = true
+				result.Apis = append(result.Apis, InheritedApi{Api: api, Source: source, Inherited: inherited})
+			}
+		}
+
+		if global.Enforcer != nil {
+			policies, err := global.Enforcer.GetFilteredPolicy(0, fmt.Sprintf("role_%d", id))
+			if err != nil {
+				return nil, err
+			}
+			for _, policy := range policies {
+				if len(policy) < 4 || (policy[3] != "*" && policy[3] != fmt.Sprintf("%d", tenantID)) {
+					continue
+				}
+				key := policy[1] + " " + policy[2]
+				if !seenPolicies[key] {
+					seenPolicies[key] = true
+					result.Policies = append(result.Policies, InheritedPolicy{Path: policy[1], Method: policy[2], Source: source, Inherited: inherited})
+				}
+			}
+		}
+
+		var tablePermissions []model.TablePermission
+		if err := tenantDB(tenantID).Where("role_id = ? AND tenant_id = ?", id, tenantID).
+			Order("table_id").Find(&tablePermissions).Error; err != nil {
+			return nil, err
+		}
+		// 数据表权限按角色合并，每个角色的授权都会生效，因此全部列出
+		for _, permission := range tablePermissions {
+			result.TablePermissions = append(result.TablePermissions, InheritedTablePermission{TablePermission: permission, Source: source, Inherited: inherited})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		parents map[uint][]uint
+		roles   []uint
+		want    []uint
+	}{
+		{"无继承", map[uint][]uint{}, []uint{3}, []uint{3}},
+		{"继承链", map[uint][]uint{3: {2}, 2: {1}}, []uint{3}, []uint{3, 2, 1}},
+		{"菱形继承", map[uint][]uint{4: {2, 3}, 2: {1}, 3: {1}}, []uint{4}, []uint{4, 2, 3, 1}},
+		{"多个角色共享祖先", map[uint][]uint{2: {1}, 3: {1}}, []uint{2, 3}, []uint{2, 3, 1}},
+		{"存在环时不死循环", map[uint][]uint{1: {2}, 2: {1}}, []uint{1}, []uint{1, 2}},
+	}
+	for _, tt := range tests {
+		if got := expandRoles(tt.parents, tt.roles); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expandRoles = %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSessionNotFound 会话不存在
+	ErrSessionNotFound = errors.New("会话不存在")
+	// ErrSessionInactive 会话已失效
+	ErrSessionInactive = errors.New("会话已失效")
+)
+
+// sessionTouchInterval 最近活跃时间的最小更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+type SessionService struct{}
+
+// SessionInfo 会话创建参数
+type SessionInfo struct {
+	IP          string
+	UserAgent   string
+	DeviceLabel string
+	MfaVerified bool
+}
+
+// CreateSession 为用户登记新的登录会话
+func (s *SessionService) CreateSession(user *model.User, info SessionInfo) (*model.UserSession, error) {
+	sessionID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceLabel := strings.TrimSpace(info.DeviceLabel)
+	if deviceLabel == "" {
+		deviceLabel = DeviceLabelFromUserAgent(info.UserAgent)
+	}
+	userAgent := info.UserAgent
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	now := time.Now()
+	session := &model.UserSession{
+		SessionID:   sessionID,
+		UserID:      user.ID,
+		Username:    user.Username,
+		TenantID:    user.TenantID,
+		IP:          info.IP,
+		UserAgent:   userAgent,
+		DeviceLabel: deviceLabel,
+		MfaVerified: info.MfaVerified,
+		IssuedAt:    now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(utils.RefreshTokenTTL()),
+	}
+	if err := tenantDB(user.TenantID).Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSession 根据会话ID获取会话
+func (s *SessionService) GetSession(sessionID string) (*model.UserSession, error) {
+	var session model.UserSession
+	if err := systemDB().Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ValidateSession 校验会话仍然有效并记录最近活跃时间
+func (s *SessionService) ValidateSession(sessionID string, userID uint) (*model.UserSession, error) {
+	if sessionID == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID || !session.IsActive() {
+		return nil, ErrSessionInactive
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		systemDB().Model(&model.UserSession{}).Where("id = ?", session.ID).Update("last_seen_at", time.Now())
+	}
+	return session, nil
+}
+
+// MarkMfaVerified 标记会话已通过两步验证
+func (s *SessionService) MarkMfaVerified(sessionID string) error {
+	return systemDB().Model(&model.UserSession{}).Where("session_id = ?", sessionID).Update("mfa_verified", true).Error
+}
+
+// ExtendSession 刷新令牌轮换后延长会话有效期
+func (s *SessionService) ExtendSession(sessionID string) error {
+	now := time.Now()
+	return systemDB().Model(&model.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"expires_at":   now.Add(utils.RefreshTokenTTL()),
+		}).Error
+}
+
+// ListUserSessions 获取用户在租户内的会话，activeOnly为true时仅返回有效会话
+func (s *SessionService) ListUserSessions(userID, tenantID uint, activeOnly bool) ([]model.UserSession, error) {
+	var sessions []model.UserSession
+	db := tenantDB(tenantID).Where("user_id = ? AND tenant_id = ?", userID, tenantID)
+	if activeOnly {
+		db = db.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+	err := db.Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// ListTenantSessions 获取租户内所有有效会话
+func (s *SessionService) ListTenantSessions(tenantID uint) ([]model.UserSession, error) {
+	var sessions []model.UserSession
+	err := tenantDB(tenantID).Where("tenant_id = ? AND revoked_at IS NULL AND expires_at > ?", tenantID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession 结束会话并吊销其令牌
+func (s *SessionService) RevokeSession(session *model.UserSession, revokedBy uint) error {
+	if session.RevokedAt == nil {
+		now := time.Now()
+		if err := tenantDB(session.TenantID).Model(&model.UserSession{}).Where("id = ?", session.ID).
+			Updates(map[string]interface{}{"revoked_at": now, "revoked_by": revokedBy}).Error; err != nil {
+			return err
+		}
+		session.RevokedAt = &now
+		session.RevokedBy = revokedBy
+	}
+	return (&TokenService{}).RevokeFamily(session.SessionID)
+}
+
+// RevokeSessionByID 结束指定会话，ownerID不为0时要求会话属于该用户
+func (s *SessionService) RevokeSessionByID(sessionID string, tenantID, ownerID, revokedBy uint) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.TenantID != tenantID || (ownerID != 0 && session.UserID != ownerID) {
+		return ErrSessionNotFound
+	}
+	return s.RevokeSession(session, revokedBy)
+}
+
+// RevokeUserSessions 结束用户的全部会话，exceptSessionID用于保留当前会话
+func (s *SessionService) RevokeUserSessions(userID, tenantID, revokedBy uint, exceptSessionID string) (int, error) {
+	sessions, err := s.ListUserSessions(userID, tenantID, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range sessions {
+		if sessions[i].SessionID == exceptSessionID {
+			continue
+		}
+		if err := s.RevokeSession(&sessions[i], revokedBy); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DeviceLabelFromUserAgent 根据User-Agent生成简单的设备描述
+func DeviceLabelFromUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "未知设备"
+	}
+
+	browser := "未知浏览器"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"), strings.Contains(ua, "go-http-client"), strings.Contains(ua, "python"):
+		browser = "API客户端"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " / " + os
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+// 数据表操作，对应TablePermission中的权限标志
+const (
+	TableActionView   = "view"
+	TableActionCreate = "create"
+	TableActionUpdate = "update"
+	TableActionDelete = "delete"
+	TableActionExport = "export"
+)
+
+var (
+	// ErrTablePermissionDenied 没有数据表的操作权限
+	ErrTablePermissionDenied = errors.New("没有该数据表的操作权限")
+	// ErrTablePermissionNotFound 表权限不存在
+	ErrTablePermissionNotFound = errors.New("表权限不存在")
+)
+
+// FieldPermissionError 写入了没有编辑权限的字段
+type FieldPermissionError struct {
+	Fields []string
+}
+
+func (e *FieldPermissionError) Error() string {
+	return "没有以下字段的编辑权限: " + strings.Join(e.Fields, ", ")
+}
+
+// TablePermissionService 动态数据表权限服务
+type TablePermissionService struct{}
+
+// ListPermissions 获取租户内数据表的角色权限，tableID为0时返回全部
+func (s *TablePermissionService) ListPermissions(tenantID, tableID uint) ([]model.TablePermission, error) {
+	query := tenantDB(tenantID).Where("tenant_id = ?", tenantID)
+	if tableID != 0 {
+		query = query.Where("table_id IN ?", []uint{0, tableID})
+	}
+	var permissions []model.TablePermission
+	err := query.Order("table_id, role_id").Find(&permissions).Error
+	return permissions, err
+}
+
+// SavePermission 设置角色对数据表的权限，已存在时覆盖
+func (s *TablePermissionService) SavePermission(tenantID uint, req *model.PermissionRequest) (*model.TablePermission, error) {
+	var role model.Role
+	if err := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", req.RoleID, tenantID).First(&role).Error; err != nil {
+		return nil, errors.New("角色不存在")
+	}
+	if req.TableID != 0 {
+		var table model.DynamicTable
+		if err := tenantDB(tenantID).First(&table, req.TableID).Error; err != nil {
+			return nil, errors.New("数据表不存在")
+		}
+	}
+
+	var permission model.TablePermission
+	err := tenantDB(tenantID).Where("table_id = ? AND role_id = ? AND tenant_id = ?", req.TableID, req.RoleID, tenantID).
+		First(&permission).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	permission.TableID = req.TableID
+	permission.RoleID = req.RoleID
+	permission.TenantID = tenantID
+	permission.CanView = req.CanView
+	permission.CanCreate = req.CanCreate
+	permission.CanUpdate = req.CanUpdate
+	permission.CanDelete = req.CanDelete
+	permission.CanExport = req.CanExport
+	if err := permission.SetFieldPermissions(req.FieldPermissions); err != nil {
+		return nil, err
+	}
+	if err := tenantDB(tenantID).Save(&permission).Error; err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// DeletePermission 删除表权限
+func (s *TablePermissionService) DeletePermission(tenantID, id uint) error {
+	result := tenantDB(tenantID).Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&model.TablePermission{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTablePermissionNotFound
+	}
+	return nil
+}
+
+// GetUserPermission 合并用户在租户内所有启用角色（含继承的上级角色）对数据表的权限
+func (s *TablePermissionService) GetUserPermission(userID, tenantID, tableID uint) (*model.UserPermission, error) {
+	roleIDs, err := UserEffectiveRoleIDs(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	merged, _, err := mergeRolePermissions(tenantID, tableID, roleIDs)
+	return merged, err
+}
+
+// mergeRolePermissions 合并一组角色对数据表的权限，同时返回参与合并的授权记录
+func mergeRolePermissions(tenantID, tableID uint, roleIDs []uint) (*model.UserPermission, []model.TablePermission, error) {
+	permissions, err := roleTablePermissions(tenantID, tableID, roleIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	merged := model.MergePermissions(permissions)
+	merged.TableID = tableID
+
+	// 逐个角色计算字段权限后合并，避免某个角色的字段限制覆盖其他角色继承自表的权限
+	var fieldNames []string
+	if tableID != 0 {
+		if err := tenantDB(tenantID).Model(&model.DynamicField{}).Where("table_id = ?", tableID).
+			Pluck("field_name", &fieldNames).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	// 新增和更新分别计算，只有新增权限的角色也能写入字段
+	merged.FieldPermissions = make(map[string]model.FieldPermission, len(fieldNames))
+	merged.CreatableFields = make(map[string]bool, len(fieldNames))
+	for _, name := range fieldNames {
+		result := model.FieldPermission{FieldName: name}
+		creatable := false
+		for i := range permissions {
+			result.CanView = result.CanView || permissions[i].HasFieldPermission(name, TableActionView)
+			result.CanEdit = result.CanEdit || permissions[i].HasFieldPermission(name, TableActionUpdate)
+			creatable = creatable || permissions[i].HasFieldPermission(name, TableActionCreate)
+		}
+		merged.FieldPermissions[name] = result
+		merged.CreatableFields[name] = creatable
+	}
+	return merged, permissions, nil
+}
+
+// roleTablePermissions 获取角色中启用的角色对数据表的权限，包括对所有数据表的授权
+func roleTablePermissions(tenantID, tableID uint, roleIDs []uint) ([]model.TablePermission, error) {
+	var permissions []model.TablePermission
+	if len(roleIDs) == 0 {
+		return permissions, nil
+	}
+	err := tenantDB(tenantID).Model(&model.TablePermission{}).
+		Joins("JOIN roles ON roles.id = table_permissions.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Where("table_permissions.role_id IN ? AND table_permissions.tenant_id = ? AND table_permissions.table_id IN ?", roleIDs, tenantID, []uint{0, tableID}).
+		Find(&permissions).Error
+	return permissions, err
+}
+
+// CheckPermission 检查用户能否对数据表执行操作，没有权限时返回ErrTablePermissionDenied
+func (s *TablePermissionService) CheckPermission(userID, tenantID, tableID uint, action string) (*model.UserPermission, error) {
+	permission, err := s.GetUserPermission(userID, tenantID, tableID)
+	if err != nil {
+		return nil, err
+	}
+	if !tableActionAllowed(permission, action) {
+		return permission, ErrTablePermissionDenied
+	}
+	return permission, nil
+}
+
+// tableActionAllowed 判断合并后的权限是否允许对数据表执行操作
+func tableActionAllowed(permission *model.UserPermission, action string) bool {
+	switch action {
+	case TableActionView:
+		return permission.CanView
+	case TableActionCreate:
+		return permission.CanCreate
+	case TableActionUpdate:
+		return permission.CanUpdate
+	case TableActionDelete:
+		return permission.CanDelete
+	case TableActionExport:
+		return permission.CanExport
+	}
+	return false
+}
+
+// MaskRecord 删除记录中用户无权查看的字段
+func MaskRecord(permission *model.UserPermission, record map[string]interface{}) {
+	for field := range record {
+		if !permission.CanViewField(field) {
+			delete(record, field)
+		}
+	}
+}
+
+// MaskRecords 删除每条记录中用户无权查看的字段
+func MaskRecords(permission *model.UserPermission, records []map[string]interface{}) {
+	for _, record := range records {
+		MaskRecord(permission, record)
+	}
+}
+
+// CheckWritableFields 检查新增或更新时写入的字段是否都可编辑，返回列出全部不可编辑字段的FieldPermissionError
+func CheckWritableFields(permission *model.UserPermission, action string, data map[string]interface{}) error {
+	writable := permission.CanEditField
+	if action == TableActionCreate {
+		writable = permission.CanCreateField
+	}
+	var denied []string
+	for field := range data {
+		if !writable(field) {
+			denied = append(denied, field)
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return &FieldPermissionError{Fields: denied}
+	}
+	return nil
+}
+
+// FieldAccess 带有当前用户访问权限的字段定义
+type FieldAccess struct {
+	model.DynamicField
+	CanView   bool `json:"can_view"`
+	CanCreate bool `json:"can_create"` // 新增数据时可写
+	CanEdit   bool `json:"can_edit"`   // 更新数据时可写
+}
+
+// AnnotateFields 为字段定义标注用户的查看和编辑权限
+func AnnotateFields(permission *model.UserPermission, fields []model.DynamicField) []FieldAccess {
+	result := make([]FieldAccess, len(fields))
+	for i, field := range fields {
+		result[i] = FieldAccess{
+			DynamicField: field,
+			CanView:      permission.CanViewField(field.FieldName),
+			CanCreate:    permission.CanCreateField(field.FieldName),
+			CanEdit:      permission.CanEditField(field.FieldName),
+		}
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-react-admin/model"
+)
+
+func testFieldPermission() *model.UserPermission {
+	return &model.UserPermission{
+		CanView:   true,
+		CanUpdate: true,
+		FieldPermissions: map[string]model.FieldPermission{
+			"salary": {FieldName: "salary", CanView: false, CanEdit: false},
+			"level":  {FieldName: "level", CanView: true, CanEdit: false},
+		},
+	}
+}
+
+func TestMaskRecords(t *testing.T) {
+	records := []map[string]interface{}{
+		{"id": 1, "name": "alice", "salary": 100, "level": 3},
+		{"id": 2, "name": "bob", "salary": 200},
+	}
+	MaskRecords(testFieldPermission(), records)
+
+	for _, record := range records {
+		if _, ok := record["salary"]; ok {
+			t.Fatalf("不可见字段未被删除: %v", record)
+		}
+		if _, ok := record["name"]; !ok {
+			t.Fatalf("未设置字段权限的字段应继承表的查看权限: %v", record)
+		}
+	}
+	if records[0]["level"] != 3 {
+		t.Fatalf("只读字段应保留: %v", records[0])
+	}
+}
+
+func TestCheckWritableFields(t *testing.T) {
+	permission := testFieldPermission()
+
+	if err := CheckWritableFields(permission, TableActionUpdate, map[string]interface{}{"name": "alice"}); err != nil {
+		t.Fatalf("可编辑字段被拒绝: %v", err)
+	}
+
+	err := CheckWritableFields(permission, TableActionUpdate, map[string]interface{}{"name": "alice", "salary": 1, "level": 2})
+	var fieldErr *FieldPermissionError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("期望FieldPermissionError, 实际为 %v", err)
+	}
+	if want := []string{"level", "salary"}; !reflect.DeepEqual(fieldErr.Fields, want) {
+		t.Fatalf("不可编辑字段为 %v, 期望 %v", fieldErr.Fields, want)
+	}
+}
+
+func TestAnnotateFields(t *testing.T) {
+	fields := []model.DynamicField{{FieldName: "name"}, {FieldName: "salary"}, {FieldName: "level"}}
+	got := AnnotateFields(testFieldPermission(), fields)
+
+	want := map[string][2]bool{"name": {true, true}, "salary": {false, false}, "level": {true, false}}
+	for _, field := range got {
+		access := want[field.FieldName]
+		if field.CanView != access[0] || field.CanEdit != access[1] {
+			t.Errorf("字段 %s 权限为 view=%v edit=%v, 期望 %v", field.FieldName, field.CanView, field.CanEdit, access)
+		}
+		if field.CanCreate {
+			t.Errorf("字段 %s 没有新增权限时 create=true, 期望 false", field.FieldName)
+		}
+	}
+}
+
+// seedTablePermissions 创建数据表字段和角色，按角色名称返回角色ID
+func seedTablePermissions(t *testing.T, tableID uint, fields []string, permissions map[string]model.TablePermission) map[string]uint {
+	t.Helper()
+	for _, name := range fields {
+		if err := systemDB().Create(&model.DynamicField{TableID: tableID, FieldName: name, DisplayName: name}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := make(map[string]uint, len(permissions))
+	for name, permission := range permissions {
+		role := model.Role{Name: name, Status: 1, TenantID: 1}
+		if err := systemDB().Create(&role).Error; err != nil {
+			t.Fatal(err)
+		}
+		permission.RoleID, permission.TenantID = role.ID, 1
+		if permission.TableID == 0 {
+			permission.TableID = tableID
+		}
+		if err := systemDB().Create(&permission).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = role.ID
+	}
+	return ids
+}
+
+// fieldPermissions 序列化字段权限配置
+func fieldPermissions(t *testing.T, perms map[string]model.FieldPermission) json.RawMessage {
+	t.Helper()
+	var tp model.TablePermission
+	if err := tp.SetFieldPermissions(perms); err != nil {
+		t.Fatal(err)
+	}
+	return tp.FieldPermissions
+}
+
+func TestMergeRolePermissionsCreateOnly(t *testing.T) {
+	useTestDB(t, &model.Role{}, &model.DynamicField{}, &model.TablePermission{})
+	const tableID = 7
+	ids := seedTablePermissions(t, tableID, []string{"name", "salary"}, map[string]model.TablePermission{
+		// 只能新增不能更新，salary不可编辑
+		"录入员": {CanView: true, CanCreate: true, FieldPermissions: fieldPermissions(t, map[string]model.FieldPermission{
+			"salary": {FieldName: "salary", CanView: true, CanEdit: false},
+		})},
+	})
+
+	merged, _, err := mergeRolePermissions(1, tableID, []uint{ids["录入员"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckWritableFields(merged, TableActionCreate, map[string]interface{}{"name": "alice"}); err != nil {
+		t.Fatalf("只有新增权限的角色新增数据被拒绝: %v", err)
+	}
+	err = CheckWritableFields(merged, TableActionCreate, map[string]interface{}{"name": "alice", "salary": 1})
+	var fieldErr *FieldPermissionError
+	if !errors.As(err, &fieldErr) || !reflect.DeepEqual(fieldErr.Fields, []string{"salary"}) {
+		t.Fatalf("新增时写入不可编辑字段返回 %v, 期望拒绝salary", err)
+	}
+	if err := CheckWritableFields(merged, TableActionUpdate, map[string]interface{}{"name": "alice"}); err == nil {
+		t.Fatal("没有更新权限的角色更新字段未被拒绝")
+	}
+}
+
+func TestMergeRolePermissionsPerAction(t *testing.T) {
+	useTestDB(t, &model.Role{}, &model.DynamicField{}, &model.TablePermission{})
+	const tableID = 7
+	ids := seedTablePermissions(t, tableID, []string{"name", "salary"}, map[string]model.TablePermission{
+		// 能新增全部字段
+		"录入员": {CanView: true, CanCreate: true},
+		// 能更新但salary不可编辑
+		"编辑": {CanView: true, CanUpdate: true, FieldPermissions: fieldPermissions(t, map[string]model.FieldPermission{
+			"salary": {FieldName: "salary", CanView: true, CanEdit: false},
+		})},
+		// 只读角色的字段编辑配置不能让字段可写
+		"访客": {TableID: 0, CanView: true, FieldPermissions: fieldPermissions(t, map[string]model.FieldPermission{
+			"salary": {FieldName: "salary", CanView: true, CanEdit: true},
+		})},
+	})
+
+	merged, _, err := mergeRolePermissions(1, tableID, []uint{ids["录入员"], ids["编辑"], ids["访客"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckWritableFields(merged, TableActionCreate, map[string]interface{}{"name": "a", "salary": 1}); err != nil {
+		t.Fatalf("新增时salary应继承录入员的新增权限: %v", err)
+	}
+	if err := CheckWritableFields(merged, TableActionUpdate, map[string]interface{}{"salary": 1}); err == nil {
+		t.Fatal("更新时salary应不可编辑")
+	}
+	if err := CheckWritableFields(merged, TableActionUpdate, map[string]interface{}{"name": "b"}); err != nil {
+		t.Fatalf("更新时name应可编辑: %v", err)
+	}
+
+	// 禁用的角色不参与合并
+	if err := systemDB().Model(&model.Role{}).Where("id = ?", ids["录入员"]).Update("status", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	merged, _, err = mergeRolePermissions(1, tableID, []uint{ids["录入员"], ids["编辑"], ids["访客"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.CanCreate || merged.CanCreateField("name") {
+		t.Fatalf("录入员禁用后仍可新增: %+v", merged)
+	}
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"go-react-admin/global"
+
+	"gorm.io/gorm"
+)
+
+// tenantDB 返回绑定租户的数据库会话，查询和写入只能访问该租户的数据
+func tenantDB(tenantID uint) *gorm.DB {
+	return global.DB.WithContext(global.WithTenant(context.Background(), tenantID))
+}
+
+// systemDB 返回跳过租户隔离的数据库会话，只用于登录认证、后台任务等不属于单个租户的代码路径
+func systemDB() *gorm.DB {
+	return global.DB.WithContext(global.SystemContext())
+}
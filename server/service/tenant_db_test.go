@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+)
+
+// seedTwoTenants 在租户1和租户2中各创建一个角色和一张动态表，返回租户2的动态表
+func seedTwoTenants(t *testing.T, models ...interface{}) *model.DynamicTable {
+	t.Helper()
+	db := useTestDB(t, append([]interface{}{&model.Role{}, &model.DynamicTable{}, &model.DynamicField{}}, models...)...)
+	for id := uint(1); id <= 2; id++ {
+		if err := db.Create(&model.Role{ID: id, Name: "管理员", Status: 1, TenantID: id}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&model.DynamicTable{ID: 1, Name: "orders", TableName: "dyn_orders", TenantID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	other := &model.DynamicTable{ID: 2, Name: "secrets", TableName: "dyn_secrets", TenantID: 2}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	return other
+}
+
+func TestTenantDBRejectsUnboundQueries(t *testing.T) {
+	seedTwoTenants(t)
+
+	var roles []model.Role
+	if err := global.DB.Find(&roles).Error; !errors.Is(err, initialize.ErrTenantUnbound) {
+		t.Errorf("未绑定租户的查询: err = %v，期望 %v", err, initialize.ErrTenantUnbound)
+	}
+	if err := tenantDB(2).Find(&roles).Error; err != nil || len(roles) != 1 || roles[0].TenantID != 2 {
+		t.Errorf("租户2的查询结果 = %+v，err = %v，期望只有租户2的角色", roles, err)
+	}
+	if err := systemDB().Find(&roles).Error; err != nil || len(roles) != 2 {
+		t.Errorf("跳过租户隔离的查询结果 = %+v，err = %v，期望两个租户的角色", roles, err)
+	}
+}
+
+// TestSaveRejectsOtherTenantTable 表权限和数据规则不能引用其他租户的数据表
+func TestSaveRejectsOtherTenantTable(t *testing.T) {
+	other := seedTwoTenants(t, &model.TablePermission{}, &model.DataRule{})
+
+	_, err := (&TablePermissionService{}).SavePermission(1, &model.PermissionRequest{RoleID: 1, TableID: other.ID, CanView: true})
+	if err == nil {
+		t.Error("为其他租户的数据表授权应失败")
+	}
+	err = (&DataRuleService{}).SaveRule(1, &model.DataRule{RoleID: 1, TableID: other.ID, Preset: model.DataRulePresetOwn})
+	if err == nil {
+		t.Error("为其他租户的数据表添加数据规则应失败")
+	}
+
+	var count int64
+	systemDB().Model(&model.TablePermission{}).Count(&count)
+	if count != 0 {
+		t.Errorf("写入了%d条表权限，期望0", count)
+	}
+	systemDB().Model(&model.DataRule{}).Count(&count)
+	if count != 0 {
+		t.Errorf("写入了%d条数据规则，期望0", count)
+	}
+
+	if _, err := (&TablePermissionService{}).SavePermission(1, &model.PermissionRequest{RoleID: 1, TableID: 1, CanView: true}); err != nil {
+		t.Errorf("为本租户的数据表授权: %v", err)
+	}
+}
+
+// TestCrossTenantLookups 按ID访问其他租户的部门、变更申请和提权申请时视为不存在
+func TestCrossTenantLookups(t *testing.T) {
+	seedTwoTenants(t, &model.User{}, &model.Department{}, &model.UserDepartment{},
+		&model.ChangeRequest{}, &model.ChangeRequestEvent{}, &model.RoleElevation{})
+	db := systemDB()
+
+	dept := &model.Department{Name: "总部"}
+	if err := (&DepartmentService{}).CreateDepartment(2, dept); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&DepartmentService{}).GetDepartment(1, dept.ID); !errors.Is(err, ErrDepartmentNotFound) {
+		t.Errorf("读取其他租户的部门: err = %v，期望 %v", err, ErrDepartmentNotFound)
+	}
+	if err := (&DepartmentService{}).DeleteDepartment(1, dept.ID); !errors.Is(err, ErrDepartmentNotFound) {
+		t.Errorf("删除其他租户的部门: err = %v，期望 %v", err, ErrDepartmentNotFound)
+	}
+
+	request := &model.ChangeRequest{TenantID: 2, ChangeType: model.ChangeTypeUserRoles, Status: model.ChangeStatusPending, RequesterID: 2}
+	if err := db.Create(request).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&ApprovalService{}).GetRequest(1, request.ID); !errors.Is(err, ErrChangeRequestNotFound) {
+		t.Errorf("读取其他租户的变更申请: err = %v，期望 %v", err, ErrChangeRequestNotFound)
+	}
+
+	elevation := &model.RoleElevation{UserID: 2, RoleID: 2, Hours: 1, Reason: "排查", Status: model.ElevationPending, TenantID: 2}
+	if err := db.Create(elevation).Error; err != nil {
+		t.Fatal(err)
+	}
+	approver := &model.User{ID: 1, Username: "admin", TenantID: 1}
+	if _, err := (&RoleElevationService{}).Reject(elevation.ID, approver, ""); !errors.Is(err, ErrElevationNotFound) {
+		t.Errorf("拒绝其他租户的提权申请: err = %v，期望 %v", err, ErrElevationNotFound)
+	}
+	if err := db.First(elevation, elevation.ID).Error; err != nil || elevation.Status != model.ElevationPending {
+		t.Errorf("其他租户的提权申请 status = %s，err = %v，期望保持pending", elevation.Status, err)
+	}
+
+	if _, err := (&PermissionService{}).GetRolePermissions(2, 1); err == nil {
+		t.Error("读取其他租户角色的权限应失败")
+	}
+}
+
+// TestDynamicFieldTenant 字段和视图通过所属数据表按租户隔离
+func TestDynamicFieldTenant(t *testing.T) {
+	other := seedTwoTenants(t, &model.DynamicView{})
+	field := &model.DynamicField{TableID: other.ID, FieldName: "salary", DisplayName: "薪资", Status: 1}
+	if err := systemDB().Create(field).Error; err != nil {
+		t.Fatal(err)
+	}
+	view := &model.DynamicView{TableID: other.ID, ViewName: "全部"}
+	if err := systemDB().Create(view).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := global.WithTenant(context.Background(), 1)
+	fields, views := &DynamicFieldService{}, &DynamicDataService{}
+
+	if _, err := fields.GetFieldByID(ctx, field.ID); err == nil {
+		t.Error("读取其他租户的字段应失败")
+	}
+	if _, err := fields.GetFieldsByTableID(ctx, other.ID); err == nil {
+		t.Error("读取其他租户数据表的字段列表应失败")
+	}
+	if err := fields.ToggleFieldStatus(ctx, field.ID); err == nil {
+		t.Error("修改其他租户的字段应失败")
+	}
+	if err := fields.UpdateFieldOrder(ctx, []uint{field.ID}); err == nil {
+		t.Error("调整其他租户的字段排序应失败")
+	}
+	if err := fields.DeleteField(ctx, field.ID); err == nil {
+		t.Error("删除其他租户的字段应失败")
+	}
+	if _, err := views.GetViewByID(ctx, view.ID); err == nil {
+		t.Error("读取其他租户的视图应失败")
+	}
+	if err := views.DeleteView(ctx, view.ID); err == nil {
+		t.Error("删除其他租户的视图应失败")
+	}
+
+	var got model.DynamicField
+	if err := systemDB().First(&got, field.ID).Error; err != nil || got.Status != 1 || got.SortOrder != 0 {
+		t.Errorf("其他租户的字段 = %+v，err = %v，期望保持不变", got, err)
+	}
+	if _, err := fields.GetFieldByID(global.WithTenant(context.Background(), 2), field.ID); err != nil {
+		t.Errorf("读取本租户的字段: %v", err)
+	}
+}
+
+// TestIsPlatformAdmin 只有平台租户中有权管理租户的用户可以跨租户管理，普通的平台租户用户仍然按租户隔离
+func TestIsPlatformAdmin(t *testing.T) {
+	saved := global.GlobalConfig
+	defer func() { global.GlobalConfig = saved }()
+	global.GlobalConfig = &global.Config{}
+	global.GlobalConfig.MultiTenant.PlatformTenantID = 1
+	enforcer := useTestEnforcer(t)
+	enforcer.AddPolicy("role_1", "/api/v1/*", "*", "1")
+	enforcer.AddPolicy("role_2", "/api/v1/users", "GET", "1")
+	enforcer.AddPolicy("role_3", "/api/v1/*", "*", "2")
+	enforcer.AddGroupingPolicy("1", "role_1", "1")
+	enforcer.AddGroupingPolicy("2", "role_2", "1")
+	enforcer.AddGroupingPolicy("3", "role_3", "2")
+
+	s := &PermissionService{}
+	for _, tt := range []struct {
+		userID, tenantID uint
+		want             bool
+	}{
+		{1, 1, true},
+		{2, 1, false},
+		{3, 2, false},
+	} {
+		if got := s.IsPlatformAdmin(tt.userID, tt.tenantID); got != tt.want {
+			t.Errorf("IsPlatformAdmin(%d, %d) = %v，期望 %v", tt.userID, tt.tenantID, got, tt.want)
+		}
+	}
+}
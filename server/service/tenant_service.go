@@ -0,0 +1,628 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/initialize"
+	"go-react-admin/model"
+	"go-react-admin/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTenantNotFound 租户不存在
+	ErrTenantNotFound = errors.New("租户不存在")
+	// ErrTenantInvalid 租户信息不合法
+	ErrTenantInvalid = errors.New("租户信息不合法")
+	// ErrTenantExists 租户名称或编码已被使用
+	ErrTenantExists = errors.New("租户名称或编码已存在")
+	// ErrTenantProtected 平台租户和模板租户不能停用或删除
+	ErrTenantProtected = errors.New("不能停用或删除平台租户和模板租户")
+	// ErrTenantNotDeleted 租户未被删除
+	ErrTenantNotDeleted = errors.New("租户未被删除")
+	// ErrTenantGraceExpired 已超过恢复期限
+	ErrTenantGraceExpired = errors.New("租户已超过恢复期限")
+)
+
+// tenantCodePattern 租户编码格式
+var tenantCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// tenantAdminRoleName 新租户管理员的默认角色
+const tenantAdminRoleName = "租户管理员"
+
+// TenantService 租户生命周期服务
+type TenantService struct{}
+
+// TenantCreateRequest 新建租户请求
+type TenantCreateRequest struct {
+	Name             string `json:"name" binding:"required"`
+	Code             string `json:"code" binding:"required"`
+	Description      string `json:"description"`
+	AuthProvider     string `json:"auth_provider"`
+	TemplateTenantID uint   `json:"template_tenant_id"` // 为0时使用配置的模板租户
+	AdminRoleID      uint   `json:"admin_role_id"`      // 模板租户中的角色，管理员获得其副本；为0时创建拥有全部菜单、API和数据表权限的租户管理员角色
+	AdminUsername    string `json:"admin_username" binding:"required"`
+	AdminPassword    string `json:"admin_password" binding:"required"`
+	AdminEmail       string `json:"admin_email"`
+}
+
+// TenantUpdateRequest 更新租户请求，空字段不修改
+type TenantUpdateRequest struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	AuthProvider string `json:"auth_provider"`
+}
+
+// ProtectedTenant 判断租户是否为平台租户或默认模板租户
+func ProtectedTenant(tenantID uint) bool {
+	cfg := global.GlobalConfig.MultiTenant
+	return tenantID == cfg.PlatformTenantID || tenantID == cfg.TemplateTenantID
+}
+
+// ListTenants 分页查询租户，deleted为true时只查询已删除、等待清除的租户
+func (s *TenantService) ListTenants(keyword string, deleted bool, page, pageSize int) ([]model.Tenant, int64, error) {
+	db := systemDB().Model(&model.Tenant{})
+	if deleted {
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if keyword != "" {
+		db = db.Where("name LIKE ? OR code LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var tenants []model.Tenant
+	err := db.Order("id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&tenants).Error
+	return tenants, total, err
+}
+
+// GetTenant 获取租户
+func (s *TenantService) GetTenant(id uint) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := systemDB().First(&tenant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// CreateTenant 在一个事务中创建租户、复制模板租户的角色菜单和API、创建租户管理员并写入Casbin策略
+func (s *TenantService) CreateTenant(req *TenantCreateRequest) (*model.Tenant, error) {
+	req.Name, req.Code = strings.TrimSpace(req.Name), strings.TrimSpace(req.Code)
+	req.AdminUsername = strings.TrimSpace(req.AdminUsername)
+	if req.Name == "" || !tenantCodePattern.MatchString(req.Code) {
+		return nil, fmt.Errorf("%w：名称不能为空，编码只能包含小写字母、数字、下划线和中划线并以字母开头", ErrTenantInvalid)
+	}
+	if req.AuthProvider == "" {
+		req.AuthProvider = "local"
+	}
+	templateID := req.TemplateTenantID
+	if templateID == 0 {
+		templateID = global.GlobalConfig.MultiTenant.TemplateTenantID
+	}
+	if _, err := s.GetTenant(templateID); err != nil {
+		return nil, fmt.Errorf("%w：模板租户%d不存在", ErrTenantInvalid, templateID)
+	}
+	hashed, err := utils.HashPassword(req.AdminPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := &model.Tenant{
+		Name:         req.Name,
+		Code:         req.Code,
+		Description:  req.Description,
+		Status:       model.TenantStatusActive,
+		AuthProvider: req.AuthProvider,
+	}
+	err = systemDB().Transaction(func(tx *gorm.DB) error {
+		// 已删除但未清除的租户仍占用名称和编码
+		var count int64
+		if err := tx.Unscoped().Model(&model.Tenant{}).Where("name = ? OR code = ?", req.Name, req.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTenantExists
+		}
+		if err := tx.Unscoped().Model(&model.User{}).Where("username = ?", req.AdminUsername).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w：用户名%s已存在", ErrTenantInvalid, req.AdminUsername)
+		}
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+
+		clone, err := cloneTenantBaseline(tx, templateID, tenant.ID)
+		if err != nil {
+			return err
+		}
+		adminRoleID, err := tenantAdminRole(tx, clone, req.AdminRoleID, tenant.ID)
+		if err != nil {
+			return err
+		}
+
+		admin := &model.User{
+			Username: req.AdminUsername,
+			Email:    req.AdminEmail,
+			Password: hashed,
+			Status:   1,
+			TenantID: tenant.ID,
+		}
+		if err := tx.Create(admin).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&model.UserRole{UserID: admin.ID, RoleID: adminRoleID, TenantID: tenant.ID}).Error; err != nil {
+			return err
+		}
+		tenant.AdminUserID = admin.ID
+		if err := tx.Model(tenant).Update("admin_user_id", admin.ID).Error; err != nil {
+			return err
+		}
+
+		// Casbin策略不在数据库事务内，写入失败时清理已写入的策略并回滚事务
+		if err := seedTenantPolicies(tx, tenant.ID, clone, admin.ID, adminRoleID); err != nil {
+			if cleanupErr := removeTenantPolicies(tenant.ID); cleanupErr != nil {
+				fmt.Printf("清理租户%d的Casbin策略失败: %v\n", tenant.ID, cleanupErr)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// tenantClone 复制模板租户时旧ID到新ID的映射
+type tenantClone struct {
+	Roles   map[uint]uint
+	Menus   map[uint]uint
+	Apis    map[uint]uint
+	Parents map[uint][]uint // 新角色ID到新上级角色ID
+}
+
+// cloneTenantBaseline 把模板租户的API、租户菜单、角色及其授权和继承关系复制到新租户
+// 租户ID为0的内置菜单各租户共用，不复制；数据表属于各租户，只复制对所有数据表生效的表权限和数据规则
+func cloneTenantBaseline(tx *gorm.DB, fromID, toID uint) (*tenantClone, error) {
+	clone := &tenantClone{Roles: map[uint]uint{}, Menus: map[uint]uint{}, Apis: map[uint]uint{}, Parents: map[uint][]uint{}}
+
+	var apis []model.Api
+	if err := tx.Where("tenant_id = ?", fromID).Find(&apis).Error; err != nil {
+		return nil, err
+	}
+	for _, api := range apis {
+		oldID := api.ID
+		api.ID, api.TenantID = 0, toID
+		if err := tx.Create(&api).Error; err != nil {
+			return nil, err
+		}
+		clone.Apis[oldID] = api.ID
+	}
+
+	var menus []model.Menu
+	if err := tx.Where("tenant_id = ?", fromID).Order("level, id").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	for _, menu := range menus {
+		oldID := menu.ID
+		menu.ID, menu.TenantID = 0, toID
+		if newParent, ok := clone.Menus[menu.ParentID]; ok {
+			menu.ParentID = newParent
+		}
+		if err := tx.Create(&menu).Error; err != nil {
+			return nil, err
+		}
+		clone.Menus[oldID] = menu.ID
+	}
+	if len(menus) > 0 {
+		var links []model.MenuApi
+		if err := tx.Where("menu_id IN ?", mapKeys(clone.Menus)).Find(&links).Error; err != nil {
+			return nil, err
+		}
+		for _, link := range links {
+			if err := tx.Create(&model.MenuApi{MenuID: clone.Menus[link.MenuID], ApiID: mappedID(clone.Apis, link.ApiID)}).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var roles []model.Role
+	if err := tx.Where("tenant_id = ?", fromID).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		oldID := role.ID
+		role.ID, role.TenantID = 0, toID
+		if err := tx.Create(&role).Error; err != nil {
+			return nil, err
+		}
+		clone.Roles[oldID] = role.ID
+	}
+	if len(roles) == 0 {
+		return clone, nil
+	}
+	oldRoleIDs := mapKeys(clone.Roles)
+
+	var roleMenus []model.RoleMenu
+	if err := tx.Where("tenant_id = ? AND role_id IN ?", fromID, oldRoleIDs).Find(&roleMenus).Error; err != nil {
+		return nil, err
+	}
+	for _, rm := range roleMenus {
+		if err := tx.Create(&model.RoleMenu{RoleID: clone.Roles[rm.RoleID], MenuID: mappedID(clone.Menus, rm.MenuID), TenantID: toID}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var roleApis []model.RoleApi
+	if err := tx.Where("tenant_id = ? AND role_id IN ?", fromID, oldRoleIDs).Find(&roleApis).Error; err != nil {
+		return nil, err
+	}
+	for _, ra := range roleApis {
+		apiID, ok := clone.Apis[ra.ApiID]
+		if !ok {
+			continue
+		}
+		if err := tx.Create(&model.RoleApi{RoleID: clone.Roles[ra.RoleID], ApiID: apiID, TenantID: toID}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var links []model.RoleInheritance
+	if err := tx.Where("tenant_id = ?", fromID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		roleID, ok1 := clone.Roles[link.RoleID]
+		parentID, ok2 := clone.Roles[link.ParentID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if err := tx.Create(&model.RoleInheritance{RoleID: roleID, ParentID: parentID, TenantID: toID}).Error; err != nil {
+			return nil, err
+		}
+		clone.Parents[roleID] = append(clone.Parents[roleID], parentID)
+	}
+
+	var tablePermissions []model.TablePermission
+	if err := tx.Where("tenant_id = ? AND table_id = 0 AND role_id IN ?", fromID, oldRoleIDs).Find(&tablePermissions).Error; err != nil {
+		return nil, err
+	}
+	for _, tp := range tablePermissions {
+		tp.ID, tp.RoleID, tp.TenantID = 0, clone.Roles[tp.RoleID], toID
+		if err := tx.Create(&tp).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var dataRules []model.DataRule
+	if err := tx.Where("tenant_id = ? AND table_id = 0 AND role_id IN ?", fromID, oldRoleIDs).Find(&dataRules).Error; err != nil {
+		return nil, err
+	}
+	for _, rule := range dataRules {
+		rule.ID, rule.RoleID, rule.TenantID = 0, clone.Roles[rule.RoleID], toID
+		if err := tx.Create(&rule).Error; err != nil {
+			return nil, err
+		}
+	}
+	return clone, nil
+}
+
+// tenantAdminRole 确定新租户管理员的角色，未指定模板角色时创建拥有全部菜单、API和数据表权限的租户管理员角色
+func tenantAdminRole(tx *gorm.DB, clone *tenantClone, templateRoleID, tenantID uint) (uint, error) {
+	if templateRoleID != 0 {
+		roleID, ok := clone.Roles[templateRoleID]
+		if !ok {
+			return 0, fmt.Errorf("%w：模板租户中不存在角色%d", ErrTenantInvalid, templateRoleID)
+		}
+		return roleID, nil
+	}
+
+	role := model.Role{Name: tenantAdminRoleName, Description: "新建租户时创建的管理员角色", Status: 1, TenantID: tenantID}
+	if err := tx.Where("name = ? AND tenant_id = ?", role.Name, tenantID).FirstOrCreate(&role).Error; err != nil {
+		return 0, err
+	}
+
+	var menuIDs []uint
+	if err := tx.Model(&model.Menu{}).Where("tenant_id IN ?", []uint{0, tenantID}).Pluck("id", &menuIDs).Error; err != nil {
+		return 0, err
+	}
+	for _, menuID := range menuIDs {
+		if err := tx.Where(model.RoleMenu{RoleID: role.ID, MenuID: menuID, TenantID: tenantID}).
+			FirstOrCreate(&model.RoleMenu{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	for _, apiID := range clone.Apis {
+		if err := tx.Where(model.RoleApi{RoleID: role.ID, ApiID: apiID, TenantID: tenantID}).
+			FirstOrCreate(&model.RoleApi{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	full := model.TablePermission{CanView: true, CanCreate: true, CanUpdate: true, CanDelete: true, CanExport: true}
+	if err := tx.Where("table_id = 0 AND role_id = ? AND tenant_id = ?", role.ID, tenantID).
+		Attrs(full).FirstOrCreate(&model.TablePermission{RoleID: role.ID, TenantID: tenantID}).Error; err != nil {
+		return 0, err
+	}
+	return role.ID, nil
+}
+
+// seedTenantPolicies 按复制后的授权写入新租户的Casbin策略
+func seedTenantPolicies(tx *gorm.DB, tenantID uint, clone *tenantClone, adminID, adminRoleID uint) error {
+	if global.Enforcer == nil {
+		return errors.New("casbin enforcer not initialized")
+	}
+	permissionService := &PermissionService{}
+	roleIDs := append(mapValues(clone.Roles), adminRoleID)
+	for _, roleID := range uniqueIDs(roleIDs) {
+		if err := permissionService.syncRolePolicies(tx, roleID, tenantID); err != nil {
+			return err
+		}
+	}
+	for roleID, parentIDs := range clone.Parents {
+		if err := initialize.SetRoleParents(roleID, parentIDs, tenantID); err != nil {
+			return err
+		}
+	}
+	if _, err := global.Enforcer.AddGroupingPolicy(fmt.Sprintf("%d", adminID), fmt.Sprintf("role_%d", adminRoleID), fmt.Sprintf("%d", tenantID)); err != nil {
+		return err
+	}
+	return global.Enforcer.SavePolicy()
+}
+
+// removeTenantPolicies 删除租户的全部Casbin策略和角色关联
+func removeTenantPolicies(tenantID uint) error {
+	if global.Enforcer == nil {
+		return nil
+	}
+	tenantIDStr := fmt.Sprintf("%d", tenantID)
+	if _, err := global.Enforcer.RemoveFilteredPolicy(3, tenantIDStr); err != nil {
+		return err
+	}
+	if _, err := global.Enforcer.RemoveFilteredGroupingPolicy(2, tenantIDStr); err != nil {
+		return err
+	}
+	return global.Enforcer.SavePolicy()
+}
+
+// UpdateTenant 更新租户的名称、描述和身份验证方式
+func (s *TenantService) UpdateTenant(id uint, req *TenantUpdateRequest) (*model.Tenant, error) {
+	tenant, err := s.GetTenant(id)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{}
+	if name := strings.TrimSpace(req.Name); name != "" && name != tenant.Name {
+		var count int64
+		if err := systemDB().Unscoped().Model(&model.Tenant{}).Where("name = ? AND id <> ?", name, id).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrTenantExists
+		}
+		updates["name"] = name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.AuthProvider != "" {
+		updates["auth_provider"] = req.AuthProvider
+	}
+	if len(updates) > 0 {
+		if err := systemDB().Model(tenant).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.GetTenant(id)
+}
+
+// SetStatus 停用或启用租户，停用后租户用户的请求立即被拒绝
+func (s *TenantService) SetStatus(id uint, status int) (*model.Tenant, error) {
+	if status == model.TenantStatusSuspended && ProtectedTenant(id) {
+		return nil, ErrTenantProtected
+	}
+	tenant, err := s.GetTenant(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := systemDB().Model(tenant).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	if err := (&AuthStateService{}).InvalidateTenant(id); err != nil {
+		fmt.Printf("清除租户%d认证状态缓存失败: %v\n", id, err)
+	}
+	return tenant, nil
+}
+
+// DeleteTenant 软删除租户，租户立即停用，宽限期内可以恢复，之后由后台任务彻底清除
+func (s *TenantService) DeleteTenant(id uint) (*model.Tenant, error) {
+	if ProtectedTenant(id) {
+		return nil, ErrTenantProtected
+	}
+	tenant, err := s.GetTenant(id)
+	if err != nil {
+		return nil, err
+	}
+	purgeAfter := time.Now().AddDate(0, 0, global.GlobalConfig.MultiTenant.DeleteGraceDays)
+	err = systemDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(tenant).Updates(map[string]interface{}{"status": model.TenantStatusSuspended, "purge_after": purgeAfter}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tenant).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := (&AuthStateService{}).InvalidateTenant(id); err != nil {
+		fmt.Printf("清除租户%d认证状态缓存失败: %v\n", id, err)
+	}
+	tenant.Status, tenant.PurgeAfter = model.TenantStatusSuspended, &purgeAfter
+	return tenant, nil
+}
+
+// RestoreTenant 在宽限期内恢复已删除的租户，恢复后仍为停用状态，需要再启用
+func (s *TenantService) RestoreTenant(id uint) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := systemDB().Unscoped().First(&tenant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	if !tenant.DeletedAt.Valid {
+		return nil, ErrTenantNotDeleted
+	}
+	if tenant.PurgeAfter != nil && !tenant.PurgeAfter.After(time.Now()) {
+		return nil, ErrTenantGraceExpired
+	}
+	if err := systemDB().Unscoped().Model(&tenant).Updates(map[string]interface{}{"deleted_at": nil, "purge_after": nil}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetTenant(id)
+}
+
+// PurgeTenants 彻底清除超过宽限期的已删除租户
+func (s *TenantService) PurgeTenants(now time.Time) error {
+	var tenants []model.Tenant
+	if err := systemDB().Unscoped().Where("deleted_at IS NOT NULL AND purge_after <= ?", now).Find(&tenants).Error; err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if err := s.purgeTenant(tenant.ID); err != nil {
+			return fmt.Errorf("清除租户%d失败: %w", tenant.ID, err)
+		}
+		fmt.Printf("已清除租户%d（%s）的全部数据\n", tenant.ID, tenant.Code)
+	}
+	return nil
+}
+
+// purgeTenant 删除租户的全部数据和Casbin策略，操作日志保留用于审计
+func (s *TenantService) purgeTenant(tenantID uint) error {
+	// 动态表需要删除物理表，逐个删除
+	var tableIDs []uint
+	if err := systemDB().Model(&model.DynamicTable{}).Where("tenant_id = ?", tenantID).Pluck("id", &tableIDs).Error; err != nil {
+		return err
+	}
+	tableService := &DynamicTableService{}
+	for _, id := range tableIDs {
+		if err := tableService.DeleteTable(global.WithTenant(context.Background(), tenantID), id); err != nil {
+			return err
+		}
+	}
+
+	err := systemDB().Transaction(func(tx *gorm.DB) error {
+		// 使用新会话，后续每个查询的条件互不累积
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		var userIDs, menuIDs, apiIDs, requestIDs []uint
+		if err := tx.Model(&model.User{}).Where("tenant_id = ?", tenantID).Pluck("id", &userIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Menu{}).Where("tenant_id = ?", tenantID).Pluck("id", &menuIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Api{}).Where("tenant_id = ?", tenantID).Pluck("id", &apiIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ChangeRequest{}).Where("tenant_id = ?", tenantID).Pluck("id", &requestIDs).Error; err != nil {
+			return err
+		}
+
+		if len(userIDs) > 0 {
+			for _, m := range []interface{}{&model.MfaRecoveryCode{}, &model.UserIdentity{}} {
+				if err := tx.Where("user_id IN ?", userIDs).Delete(m).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if len(menuIDs) > 0 {
+			if err := tx.Where("menu_id IN ?", menuIDs).Delete(&model.MenuApi{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(apiIDs) > 0 {
+			if err := tx.Where("api_id IN ?", apiIDs).Delete(&model.MenuApi{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(requestIDs) > 0 {
+			if err := tx.Where("request_id IN ?", requestIDs).Delete(&model.ChangeRequestEvent{}).Error; err != nil {
+				return err
+			}
+		}
+
+		tenantModels := []interface{}{
+			&model.UserRole{}, &model.UserDepartment{}, &model.Department{},
+			&model.RoleMenu{}, &model.RoleApi{}, &model.RoleInheritance{}, &model.RoleElevation{},
+			&model.TablePermission{}, &model.DataRule{}, &model.DynamicTable{},
+			&model.ApprovalPolicy{}, &model.ChangeRequest{},
+			&model.UserSession{}, &model.RefreshToken{}, &model.ApiKey{},
+			&model.OidcProvider{}, &model.LdapConfig{},
+			&model.Role{}, &model.Menu{}, &model.Api{}, &model.User{},
+		}
+		for _, m := range tenantModels {
+			if err := tx.Where("tenant_id = ?", tenantID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.Tenant{}, tenantID).Error
+	})
+	if err != nil {
+		return err
+	}
+	if err := removeTenantPolicies(tenantID); err != nil {
+		return err
+	}
+	return (&AuthStateService{}).InvalidateTenant(tenantID)
+}
+
+// StartTenantPurger 启动后台任务，每分钟清除超过宽限期的已删除租户
+func StartTenantPurger() {
+	s := &TenantService{}
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := s.PurgeTenants(now); err != nil {
+				fmt.Printf("清除已删除租户失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// mapKeys 返回映射的全部键
+func mapKeys(m map[uint]uint) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// mapValues 返回映射的全部值
+func mapValues(m map[uint]uint) []uint {
+	values := make([]uint, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// mappedID 返回复制后的新ID，不在映射中时返回原ID
+func mappedID(m map[uint]uint, id uint) uint {
+	if newID, ok := m[id]; ok {
+		return newID
+	}
+	return id
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"gorm.io/gorm"
+)
+
+func TestTenantCodePattern(t *testing.T) {
+	valid := []string{"acme", "acme-corp", "a1_b2"}
+	invalid := []string{"", "a", "Acme", "1acme", "acme corp", "acme.corp"}
+	for _, code := range valid {
+		if !tenantCodePattern.MatchString(code) {
+			t.Errorf("%q 应为合法的租户编码", code)
+		}
+	}
+	for _, code := range invalid {
+		if tenantCodePattern.MatchString(code) {
+			t.Errorf("%q 应为不合法的租户编码", code)
+		}
+	}
+}
+
+func TestProtectedTenant(t *testing.T) {
+	saved := global.GlobalConfig
+	defer func() { global.GlobalConfig = saved }()
+	global.GlobalConfig = &global.Config{}
+	global.GlobalConfig.MultiTenant.PlatformTenantID = 1
+	global.GlobalConfig.MultiTenant.TemplateTenantID = 2
+
+	for id, want := range map[uint]bool{1: true, 2: true, 3: false} {
+		if got := ProtectedTenant(id); got != want {
+			t.Errorf("ProtectedTenant(%d) = %v，期望 %v", id, got, want)
+		}
+	}
+}
+
+func TestMappedID(t *testing.T) {
+	m := map[uint]uint{3: 30}
+	if got := mappedID(m, 3); got != 30 {
+		t.Errorf("mappedID(3) = %d，期望 30", got)
+	}
+	if got := mappedID(m, 0); got != 0 {
+		t.Errorf("mappedID(0) = %d，期望 0", got)
+	}
+}
+
+// tenantModels 租户创建和清除涉及的全部模型
+var tenantModels = []interface{}{
+	&model.Tenant{}, &model.User{}, &model.Role{}, &model.Menu{}, &model.Api{}, &model.MenuApi{},
+	&model.RoleMenu{}, &model.RoleApi{}, &model.RoleInheritance{}, &model.UserRole{}, &model.RoleElevation{},
+	&model.UserDepartment{}, &model.Department{}, &model.TablePermission{}, &model.DataRule{},
+	&model.DynamicTable{}, &model.ApprovalPolicy{}, &model.ChangeRequest{}, &model.ChangeRequestEvent{},
+	&model.UserSession{}, &model.RefreshToken{}, &model.ApiKey{}, &model.OidcProvider{}, &model.LdapConfig{},
+	&model.MfaRecoveryCode{}, &model.UserIdentity{},
+}
+
+// seedTemplateTenant 准备模板租户2：角色10拥有菜单、API、对所有数据表的查看权限和数据规则，角色11继承角色10
+func seedTemplateTenant(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := useTestDB(t, tenantModels...)
+	useTestEnforcer(t)
+	saved := global.GlobalConfig
+	global.GlobalConfig = &global.Config{}
+	global.GlobalConfig.MultiTenant.TemplateTenantID = 2
+	t.Cleanup(func() { global.GlobalConfig = saved })
+
+	records := []interface{}{
+		&model.Tenant{ID: 2, Name: "模板", Code: "template", Status: model.TenantStatusActive},
+		&model.Api{ID: 5, Path: "/api/v1/logs", Method: "GET", TenantID: 2},
+		&model.Menu{ID: 6, Title: "日志", Name: "logs", Path: "/logs", TenantID: 2},
+		&model.MenuApi{MenuID: 6, ApiID: 5},
+		&model.Role{ID: 10, Name: "审计员", Status: 1, TenantID: 2},
+		&model.Role{ID: 11, Name: "高级审计员", Status: 1, TenantID: 2},
+		&model.RoleInheritance{RoleID: 11, ParentID: 10, TenantID: 2},
+		&model.RoleMenu{RoleID: 10, MenuID: 6, TenantID: 2},
+		&model.RoleApi{RoleID: 10, ApiID: 5, TenantID: 2},
+		&model.TablePermission{RoleID: 10, TenantID: 2, CanView: true},
+		&model.TablePermission{TableID: 99, RoleID: 10, TenantID: 2, CanView: true},
+		&model.DataRule{RoleID: 10, TenantID: 2, Name: "本人数据", Preset: "own", Status: 1},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// createTestTenant 以模板租户创建租户acme
+func createTestTenant(t *testing.T, adminRoleID uint) *model.Tenant {
+	t.Helper()
+	tenant, err := (&TenantService{}).CreateTenant(&TenantCreateRequest{
+		Name: "Acme", Code: "acme", AdminRoleID: adminRoleID,
+		AdminUsername: "acme-admin", AdminPassword: "secret123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tenant
+}
+
+func TestCreateTenantProvisioning(t *testing.T) {
+	db := seedTemplateTenant(t)
+	tenant := createTestTenant(t, 0)
+
+	var roles []model.Role
+	if err := db.Where("tenant_id = ?", tenant.ID).Order("id").Find(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	roleIDs := map[string]uint{}
+	for _, role := range roles {
+		roleIDs[role.Name] = role.ID
+	}
+	auditor, senior, admin := roleIDs["审计员"], roleIDs["高级审计员"], roleIDs[tenantAdminRoleName]
+	if len(roles) != 3 || auditor == 0 || senior == 0 || admin == 0 {
+		t.Fatalf("新租户角色 = %v，期望复制的两个角色和租户管理员", roleIDs)
+	}
+
+	var parents []uint
+	db.Model(&model.RoleInheritance{}).Where("role_id = ? AND tenant_id = ?", senior, tenant.ID).Pluck("parent_id", &parents)
+	if len(parents) != 1 || parents[0] != auditor {
+		t.Errorf("高级审计员的上级角色 = %v，期望 [%d]", parents, auditor)
+	}
+
+	var tablePermissions []model.TablePermission
+	db.Where("tenant_id = ?", tenant.ID).Order("role_id").Find(&tablePermissions)
+	if len(tablePermissions) != 2 {
+		t.Fatalf("新租户表权限 %d 条，期望复制的默认权限和租户管理员的全部权限共 2 条", len(tablePermissions))
+	}
+	for _, tp := range tablePermissions {
+		if tp.TableID != 0 {
+			t.Errorf("不应复制指定数据表的权限: %+v", tp)
+		}
+		switch tp.RoleID {
+		case auditor:
+			if !tp.CanView || tp.CanUpdate {
+				t.Errorf("审计员表权限 = %+v，期望只能查看", tp)
+			}
+		case admin:
+			if !tp.CanView || !tp.CanCreate || !tp.CanUpdate || !tp.CanDelete || !tp.CanExport {
+				t.Errorf("租户管理员表权限 = %+v，期望拥有全部权限", tp)
+			}
+		default:
+			t.Errorf("表权限属于未知角色%d", tp.RoleID)
+		}
+	}
+
+	var rules []model.DataRule
+	db.Where("tenant_id = ?", tenant.ID).Find(&rules)
+	if len(rules) != 1 || rules[0].RoleID != auditor || rules[0].Preset != "own" {
+		t.Errorf("新租户数据规则 = %+v，期望复制审计员的本人数据规则", rules)
+	}
+
+	var adminUser model.User
+	if err := db.First(&adminUser, tenant.AdminUserID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if adminUser.TenantID != tenant.ID || adminUser.Password == "secret123" {
+		t.Errorf("租户管理员 = %+v，期望属于新租户且密码已哈希", adminUser)
+	}
+	tenantStr := fmt.Sprintf("%d", tenant.ID)
+	if ok, _ := global.Enforcer.Enforce(fmt.Sprintf("%d", adminUser.ID), "/api/v1/logs", "GET", tenantStr); !ok {
+		t.Error("租户管理员应能访问复制的API")
+	}
+	if ok, _ := global.Enforcer.Enforce(fmt.Sprintf("role_%d", senior), "/api/v1/logs", "GET", tenantStr); !ok {
+		t.Error("高级审计员应通过继承获得审计员的API权限")
+	}
+}
+
+func TestCreateTenantWithTemplateRole(t *testing.T) {
+	db := seedTemplateTenant(t)
+	if _, err := (&TenantService{}).CreateTenant(&TenantCreateRequest{
+		Name: "Acme", Code: "acme", AdminRoleID: 12, AdminUsername: "acme-admin", AdminPassword: "secret123",
+	}); err == nil {
+		t.Fatal("模板租户中不存在的角色应返回错误")
+	}
+	var count int64
+	db.Model(&model.Tenant{}).Where("code = ?", "acme").Count(&count)
+	if count != 0 {
+		t.Error("创建失败时应回滚租户")
+	}
+
+	tenant := createTestTenant(t, 10)
+	var roleIDs []uint
+	db.Model(&model.UserRole{}).Where("user_id = ? AND tenant_id = ?", tenant.AdminUserID, tenant.ID).Pluck("role_id", &roleIDs)
+	var role model.Role
+	if len(roleIDs) != 1 || db.First(&role, roleIDs[0]).Error != nil || role.Name != "审计员" || role.TenantID != tenant.ID {
+		t.Errorf("租户管理员角色 = %v，期望新租户中审计员角色的副本", roleIDs)
+	}
+	db.Model(&model.Role{}).Where("tenant_id = ? AND name = ?", tenant.ID, tenantAdminRoleName).Count(&count)
+	if count != 0 {
+		t.Error("指定模板角色时不应创建租户管理员角色")
+	}
+}
+
+func TestPurgeTenant(t *testing.T) {
+	db := seedTemplateTenant(t)
+	tenant := createTestTenant(t, 0)
+	records := []interface{}{
+		&model.ApiKey{KeyID: "acme", UserID: tenant.AdminUserID, TenantID: tenant.ID},
+		&model.UserSession{SessionID: "acme-session", UserID: tenant.AdminUserID, TenantID: tenant.ID},
+		&model.Department{Name: "研发部", TenantID: tenant.ID},
+		&model.ApprovalPolicy{TenantID: tenant.ID, ChangeType: model.ChangeTypeUserRoles},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (&TenantService{}).purgeTenant(tenant.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range tenantModels {
+		if _, ok := m.(*model.Tenant); ok {
+			continue
+		}
+		if !db.Migrator().HasColumn(m, "tenant_id") {
+			continue
+		}
+		var count int64
+		if err := db.Unscoped().Model(m).Where("tenant_id = ?", tenant.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%T 仍有 %d 条租户数据", m, count)
+		}
+	}
+	var count int64
+	db.Unscoped().Model(&model.Tenant{}).Where("id = ?", tenant.ID).Count(&count)
+	if count != 0 {
+		t.Error("应删除租户记录")
+	}
+	tenantStr := fmt.Sprintf("%d", tenant.ID)
+	if policies, _ := global.Enforcer.GetFilteredPolicy(3, tenantStr); len(policies) != 0 {
+		t.Errorf("租户的Casbin策略未清除: %v", policies)
+	}
+	if groupings, _ := global.Enforcer.GetFilteredGroupingPolicy(2, tenantStr); len(groupings) != 0 {
+		t.Errorf("租户的Casbin角色关联未清除: %v", groupings)
+	}
+
+	var roles int64
+	db.Model(&model.Role{}).Where("tenant_id = ?", 2).Count(&roles)
+	if roles != 2 {
+		t.Errorf("模板租户角色 %d 个，期望 2 个保持不变", roles)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go-react-admin/model"
+	"go-react-admin/utils"
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新令牌无效
+	ErrRefreshTokenInvalid = errors.New("无效的刷新令牌")
+	// ErrRefreshTokenReused 刷新令牌被重复使用
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，该登录会话已被吊销")
+	// ErrUserDisabled 用户已被禁用
+	ErrUserDisabled = errors.New("用户已被禁用")
+)
+
+type TokenService struct{}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken  string              `json:"token"`
+	RefreshToken string              `json:"refresh_token"`
+	ExpiresIn    int64               `json:"expires_in"` // 访问令牌有效期（秒）
+	Claims       *utils.CustomClaims `json:"-"`
+}
+
+// hashRefreshToken 计算刷新令牌的哈希，服务端只保存哈希
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueTokenPair 为用户的登录会话签发新的令牌族，令牌族ID即会话ID
+func (s *TokenService) IssueTokenPair(user *model.User, sessionID string) (*TokenPair, error) {
+	return s.issue(user.ID, user.Username, user.TenantID, sessionID)
+}
+
+// issue 在指定令牌族内签发访问令牌和刷新令牌
+func (s *TokenService) issue(userID uint, username string, tenantID uint, familyID string) (*TokenPair, error) {
+	accessToken, claims, err := utils.GenerateToken(userID, username, tenantID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &model.RefreshToken{
+		TokenHash:       hashRefreshToken(refreshToken),
+		FamilyID:        familyID,
+		UserID:          userID,
+		TenantID:        tenantID,
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := GetTokenStore().SaveRefreshToken(record); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+		Claims:       claims,
+	}, nil
+}
+
+// Refresh 使用刷新令牌轮换出新的令牌对
+// 已被使用过的刷新令牌再次出现时视为令牌泄露，吊销整个令牌族
+func (s *TokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	store := GetTokenStore()
+	tokenHash := hashRefreshToken(refreshToken)
+
+	record, err := store.GetRefreshToken(tokenHash)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if record.RevokedAt != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if record.UsedAt != nil {
+		if err := s.RevokeFamily(record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	marked, err := store.MarkRefreshTokenUsed(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// 并发请求中另一方已完成轮换
+		if err := s.RevokeFamily(record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	var user model.User
+	if err := systemDB().First(&user, record.UserID).Error; err != nil {
+		_ = s.RevokeFamily(record.FamilyID)
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err := (&AuthStateService{}).CheckLogin(&user); err != nil {
+		_ = s.RevokeFamily(record.FamilyID)
+		return nil, err
+	}
+
+	// 会话已被结束，或会话建立早于用户的令牌生效时间时不再续签
+	sessionService := &SessionService{}
+	session, err := sessionService.GetSession(record.FamilyID)
+	if err != nil || !session.IsActive() ||
+		(user.TokenValidAfter != nil && session.IssuedAt.Before(*user.TokenValidAfter)) {
+		_ = s.RevokeFamily(record.FamilyID)
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err := sessionService.ExtendSession(record.FamilyID); err != nil {
+		return nil, err
+	}
+
+	return s.issue(user.ID, user.Username, user.TenantID, record.FamilyID)
+}
+
+// RevokeFamily 吊销令牌族，并将族内仍有效的访问令牌加入黑名单
+func (s *TokenService) RevokeFamily(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+
+	store := GetTokenStore()
+	tokens, err := store.ListFamily(familyID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, token := range tokens {
+		if token.AccessJTI != "" && token.AccessExpiresAt.After(now) {
+			if err := store.DenyAccessToken(token.AccessJTI, token.AccessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return store.RevokeFamily(familyID)
+}
+
+// RevokeAccessToken 将访问令牌加入黑名单
+func (s *TokenService) RevokeAccessToken(claims *utils.CustomClaims) error {
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return GetTokenStore().DenyAccessToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// IsAccessTokenRevoked 检查访问令牌是否已被吊销
+func (s *TokenService) IsAccessTokenRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	return GetTokenStore().IsAccessTokenDenied(jti)
+}
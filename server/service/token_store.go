@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-react-admin/global"
+	"go-react-admin/model"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenNotFound 刷新令牌不存在或已过期
+var ErrRefreshTokenNotFound = errors.New("刷新令牌不存在或已过期")
+
+// TokenStore 令牌服务端存储
+type TokenStore interface {
+	// SaveRefreshToken 保存刷新令牌
+	SaveRefreshToken(token *model.RefreshToken) error
+	// GetRefreshToken 根据令牌哈希获取刷新令牌
+	GetRefreshToken(tokenHash string) (*model.RefreshToken, error)
+	// MarkRefreshTokenUsed 原子地标记刷新令牌已使用，已被使用过时返回false
+	MarkRefreshTokenUsed(tokenHash string) (bool, error)
+	// ListFamily 获取令牌族内的所有刷新令牌
+	ListFamily(familyID string) ([]model.RefreshToken, error)
+	// RevokeFamily 吊销整个令牌族
+	RevokeFamily(familyID string) error
+	// DenyAccessToken 将访问令牌加入黑名单直至其过期
+	DenyAccessToken(jti string, expiresAt time.Time) error
+	// IsAccessTokenDenied 检查访问令牌是否在黑名单中
+	IsAccessTokenDenied(jti string) (bool, error)
+}
+
+// GetTokenStore 获取令牌存储，配置了Redis时使用Redis，否则使用数据库
+func GetTokenStore() TokenStore {
+	if global.RedisClient != nil {
+		return &redisTokenStore{client: global.RedisClient}
+	}
+	return &dbTokenStore{}
+}
+
+// dbTokenStore 基于数据库的令牌存储
+type dbTokenStore struct{}
+
+func (s *dbTokenStore) SaveRefreshToken(token *model.RefreshToken) error {
+	return systemDB().Create(token).Error
+}
+
+func (s *dbTokenStore) GetRefreshToken(tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := systemDB().Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return &token, nil
+}
+
+func (s *dbTokenStore) MarkRefreshTokenUsed(tokenHash string) (bool, error) {
+	result := systemDB().Model(&model.RefreshToken{}).
+		Where("token_hash = ? AND used_at IS NULL", tokenHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *dbTokenStore) ListFamily(familyID string) ([]model.RefreshToken, error) {
+	var tokens []model.RefreshToken
+	err := systemDB().Where("family_id = ?", familyID).Find(&tokens).Error
+	return tokens, err
+}
+
+func (s *dbTokenStore) RevokeFamily(familyID string) error {
+	return systemDB().Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *dbTokenStore) DenyAccessToken(jti string, expiresAt time.Time) error {
+	// 顺带清理已过期的黑名单记录
+	systemDB().Where("expires_at < ?", time.Now()).Delete(&model.TokenDenylist{})
+
+	var count int64
+	systemDB().Model(&model.TokenDenylist{}).Where("jti = ?", jti).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	return systemDB().Create(&model.TokenDenylist{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (s *dbTokenStore) IsAccessTokenDenied(jti string) (bool, error) {
+	var count int64
+	err := systemDB().Model(&model.TokenDenylist{}).
+		Where("jti = ? AND expires_at > ?", jti, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// redisTokenStore 基于Redis的令牌存储
+type redisTokenStore struct {
+	client *redis.Client
+}
+
+const (
+	redisRefreshKeyPrefix = "auth:refresh:"
+	redisRefreshUsedKey   = "auth:refresh_used:"
+	redisFamilyKeyPrefix  = "auth:family:"
+	redisDenyKeyPrefix    = "auth:deny:"
+)
+
+func (s *redisTokenStore) SaveRefreshToken(token *model.RefreshToken) error {
+	ctx := context.Background()
+	token.CreatedAt = time.Now()
+	token.UpdatedAt = token.CreatedAt
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, redisRefreshKeyPrefix+token.TokenHash, data, ttl)
+	pipe.SAdd(ctx, redisFamilyKeyPrefix+token.FamilyID, token.TokenHash)
+	pipe.Expire(ctx, redisFamilyKeyPrefix+token.FamilyID, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisTokenStore) GetRefreshToken(tokenHash string) (*model.RefreshToken, error) {
+	ctx := context.Background()
+	data, err := s.client.Get(ctx, redisRefreshKeyPrefix+tokenHash).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	var token model.RefreshToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	// JSON中不包含令牌哈希
+	token.TokenHash = tokenHash
+
+	if usedAt, err := s.client.Get(ctx, redisRefreshUsedKey+tokenHash).Time(); err == nil {
+		token.UsedAt = &usedAt
+	}
+	return &token, nil
+}
+
+func (s *redisTokenStore) MarkRefreshTokenUsed(tokenHash string) (bool, error) {
+	ctx := context.Background()
+	ttl, err := s.client.TTL(ctx, redisRefreshKeyPrefix+tokenHash).Result()
+	if err != nil {
+		return false, err
+	}
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return s.client.SetNX(ctx, redisRefreshUsedKey+tokenHash, time.Now(), ttl).Result()
+}
+
+func (s *redisTokenStore) ListFamily(familyID string) ([]model.RefreshToken, error) {
+	ctx := context.Background()
+	hashes, err := s.client.SMembers(ctx, redisFamilyKeyPrefix+familyID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []model.RefreshToken
+	for _, hash := range hashes {
+		token, err := s.GetRefreshToken(hash)
+		if err != nil {
+			if errors.Is(err, ErrRefreshTokenNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, nil
+}
+
+func (s *redisTokenStore) RevokeFamily(familyID string) error {
+	ctx := context.Background()
+	hashes, err := s.client.SMembers(ctx, redisFamilyKeyPrefix+familyID).Result()
+	if err != nil {
+		return err
+	}
+
+	// 已吊销的令牌直接删除，之后的刷新请求将视为无效令牌
+	keys := []string{redisFamilyKeyPrefix + familyID}
+	for _, hash := range hashes {
+		keys = append(keys, redisRefreshKeyPrefix+hash, redisRefreshUsedKey+hash)
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *redisTokenStore) DenyAccessToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(context.Background(), redisDenyKeyPrefix+jti, 1, ttl).Err()
+}
+
+func (s *redisTokenStore) IsAccessTokenDenied(jti string) (bool, error) {
+	n, err := s.client.Exists(context.Background(), redisDenyKeyPrefix+jti).Result()
+	return n > 0, err
+}
@@ -6,13 +6,14 @@ import (
 	"go-react-admin/global"
 	"go-react-admin/initialize"
 	"go-react-admin/model"
+	"go-react-admin/router"
+	"go-react-admin/utils"
 	"log"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -37,6 +38,8 @@ func main() {
 	// 其他命令需要初始化
 	initialize.LoadConfig()
 	initialize.InitDB()
+	// 管理工具跨租户维护数据，跳过租户隔离
+	global.DB = global.DB.WithContext(global.SystemContext())
 	initialize.InitRedis()
 	initialize.InitCasbin()
 
@@ -44,10 +47,20 @@ func main() {
 	switch command {
 	case "setup-admin":
 		setupAdminTestData()
+	case "migrate-passwords":
+		migratePasswords()
+	case "rotate-jwt-key":
+		rotateJwtKey()
+	case "list-jwt-keys":
+		listJwtKeys()
 	case "check-permission":
 		checkPermissionsDetailed()
 	case "fix-permission":
 		fixBasicPermissions()
+	case "migrate-policies":
+		migrateCasbinPolicies()
+	case "sync-apis":
+		syncApis()
 	case "health-check":
 		healthCheck()
 	case "clean-logs":
@@ -86,6 +99,9 @@ admctl - Go-React-Admin 管理工具
     help              显示帮助信息
     init              初始化系统配置
     setup-admin       设置管理员账号和权限
+    migrate-passwords 将遗留的明文密码转换为哈希
+    rotate-jwt-key [RS256|EdDSA] 轮换JWT签名密钥
+    list-jwt-keys     列出JWT签名密钥
     health-check      系统健康检查
     system-info       显示系统信息
     clean-logs        清理系统日志
@@ -102,6 +118,8 @@ admctl - Go-React-Admin 管理工具
   权限管理:
     check-permission [用户ID] 检查用户权限详情
     fix-permission    修复权限问题
+    migrate-policies  改写Casbin策略并按角色授权补齐缺失的策略
+    sync-apis         按路由表同步各租户的API目录，报告已不存在的路由
 
 示例:
   admctl setup-admin          创建管理员账号和权限
@@ -109,11 +127,11 @@ admctl - Go-React-Admin 管理工具
   admctl system-info          显示系统配置信息
   admctl clean-logs           清理过期日志
   admctl backup-db            备份数据库
+  admctl rotate-jwt-key EdDSA 生成新的EdDSA签名密钥，原密钥在保留期内仍可验证
   admctl list-tables          列出所有动态表
   admctl show-table 1         显示ID为1的表详情
   admctl query-data test      查询test表的数据
-  admctl exec-sql "SELECT * FROM dynamic_tables" 执行自定义SQL
-`)
+  admctl exec-sql "SELECT * FROM dynamic_tables" 执行自定义SQL`)
 }
 
 func initializeSystem() {
@@ -334,11 +352,93 @@ func updateCasbinPolicies(userID uint, roleCode string, menus []model.Menu, apis
 }
 
 func hashPassword(password string) string {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		log.Fatalf("密码加密失败: %v", err)
 	}
-	return string(hashedPassword)
+	return hashedPassword
+}
+
+func migratePasswords() {
+	fmt.Println("正在迁移明文密码...")
+	count, err := initialize.MigratePlaintextPasswords()
+	if err != nil {
+		log.Fatalf("明文密码迁移失败: %v", err)
+	}
+	fmt.Printf("明文密码迁移完成，共处理 %d 个用户\n", count)
+}
+
+func rotateJwtKey() {
+	algorithm := global.GlobalConfig.Jwt.Algorithm
+	if len(os.Args) > 2 {
+		algorithm = os.Args[2]
+	}
+	if algorithm != utils.AlgRS256 && algorithm != utils.AlgEdDSA {
+		fmt.Println("用法: admctl rotate-jwt-key [RS256|EdDSA]")
+		return
+	}
+	if algorithm != global.GlobalConfig.Jwt.Algorithm {
+		fmt.Printf("警告: 当前JWT_ALGORITHM为%s，服务重启时会重新生成%s密钥\n", global.GlobalConfig.Jwt.Algorithm, global.GlobalConfig.Jwt.Algorithm)
+	}
+
+	if err := global.DB.AutoMigrate(&model.JwtSigningKey{}); err != nil {
+		log.Fatalf("迁移签名密钥表失败: %v", err)
+	}
+	key, err := initialize.RotateSigningKey(algorithm)
+	if err != nil {
+		log.Fatalf("轮换JWT签名密钥失败: %v", err)
+	}
+	fmt.Printf("已生成新的JWT签名密钥: kid=%s, 算法=%s\n", key.Kid, key.Algorithm)
+	fmt.Println("运行中的服务将在1分钟内改用新密钥签名，原密钥在访问令牌过期前仍可验证")
+}
+
+func listJwtKeys() {
+	var keys []model.JwtSigningKey
+	if err := global.DB.Order("id DESC").Find(&keys).Error; err != nil {
+		log.Printf("查询JWT签名密钥失败: %v", err)
+		return
+	}
+
+	fmt.Println("\nJWT签名密钥:")
+	fmt.Println("KID\t\t\t算法\t状态\t创建时间\t\t退役时间")
+	for _, key := range keys {
+		retiredAt := "-"
+		if key.RetiredAt != nil {
+			retiredAt = key.RetiredAt.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n", key.Kid, key.Algorithm, key.Status, key.CreatedAt.Format("2006-01-02 15:04"), retiredAt)
+	}
+	fmt.Printf("\n总计: %d 个密钥\n", len(keys))
+}
+
+func migrateCasbinPolicies() {
+	fmt.Println("正在迁移Casbin策略...")
+	count, err := initialize.MigrateCasbinPolicies()
+	if err != nil {
+		log.Fatalf("Casbin策略迁移失败: %v", err)
+	}
+	fmt.Printf("Casbin策略迁移完成，共改写或补齐 %d 条\n", count)
+}
+
+func syncApis() {
+	fmt.Println("正在同步API目录...")
+	report, err := initialize.SyncApiCatalogue(router.ApiCatalogue())
+	if err != nil {
+		log.Fatalf("同步API目录失败: %v", err)
+	}
+	fmt.Printf("新增 %d 条，更新 %d 条\n", report.Created, report.Updated)
+	if len(report.Stale) > 0 {
+		fmt.Printf("\n路由已不存在的API (%d):\n", len(report.Stale))
+		for _, api := range report.Stale {
+			fmt.Printf("  租户%d\t%s\t%s\t%s\n", api.TenantID, api.Method, api.Path, api.Description)
+		}
+	}
+	if len(report.StalePolicies) > 0 {
+		fmt.Printf("\n指向已不存在路由的Casbin策略 (%d):\n", len(report.StalePolicies))
+		for _, rule := range report.StalePolicies {
+			fmt.Printf("  %s\n", strings.Join(rule, ", "))
+		}
+	}
 }
 
 // 系统维护相关函数
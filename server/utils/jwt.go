@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"time"
 
 	"go-react-admin/global"
@@ -10,42 +13,99 @@ import (
 
 // CustomClaims 自定义JWT声明
 type CustomClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	TenantID uint   `json:"tenant_id"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	TenantID  uint   `json:"tenant_id"`
+	SessionID string `json:"sid,omitempty"` // 令牌族ID，同一次登录内轮换的令牌共享
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint, username string, tenantID uint) (string, error) {
-	// 设置过期时间
-	expireTime := time.Now().Add(time.Duration(global.GlobalConfig.Jwt.Expire) * time.Hour)
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	return time.Duration(global.GlobalConfig.Jwt.AccessExpire) * time.Minute
+}
+
+// RefreshTokenTTL 刷新令牌有效期
+func RefreshTokenTTL() time.Duration {
+	return time.Duration(global.GlobalConfig.Jwt.RefreshExpire) * time.Hour
+}
+
+// GenerateToken 生成JWT访问令牌，返回令牌及其声明
+func GenerateToken(userID uint, username string, tenantID uint, sessionID string) (string, *CustomClaims, error) {
+	now := time.Now()
+
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
 
 	// 创建声明
-	claims := CustomClaims{
-		UserID:   userID,
-		Username: username,
-		TenantID: tenantID,
+	claims := &CustomClaims{
+		UserID:    userID,
+		Username:  username,
+		TenantID:  tenantID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expireTime),
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
 			Issuer:    "go-react-admin",
 		},
 	}
 
-	// 创建token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// 创建token，非对称算法使用密钥环中的活动密钥并在头部写入kid
+	algorithm := global.GlobalConfig.Jwt.Algorithm
+	if algorithm == "" || algorithm == AlgHS256 {
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(global.GlobalConfig.Jwt.Secret))
+		if err != nil {
+			return "", nil, err
+		}
+		return tokenString, claims, nil
+	}
+
+	key, err := jwtKeyring.Active()
+	if err != nil {
+		return "", nil, err
+	}
+	method, err := SigningMethod(key.Algorithm)
+	if err != nil {
+		return "", nil, err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.Kid
 
 	// 签名并获得完整的编码后的字符串token
-	tokenString, err := token.SignedString([]byte(global.GlobalConfig.Jwt.Secret))
-	return tokenString, err
+	tokenString, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, claims, nil
 }
 
 // ParseToken 解析JWT Token
+// 配置为HS256时只接受共享密钥签名的令牌，否则只接受密钥环中的公钥能验证的令牌
 func ParseToken(tokenString string) (*CustomClaims, error) {
+	symmetric := global.GlobalConfig.Jwt.Algorithm == "" || global.GlobalConfig.Jwt.Algorithm == AlgHS256
+	validMethods := []string{AlgRS256, AlgEdDSA}
+	if symmetric {
+		validMethods = []string{AlgHS256}
+	}
+
 	// 解析token
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(global.GlobalConfig.Jwt.Secret), nil
-	})
+		if symmetric {
+			return []byte(global.GlobalConfig.Jwt.Secret), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, err := jwtKeyring.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("签名算法%s与密钥%s不匹配", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods(validMethods))
 
 	if err != nil {
 		return nil, err
@@ -58,3 +118,12 @@ func ParseToken(tokenString string) (*CustomClaims, error) {
 
 	return nil, err
 }
+
+// RandomToken 生成指定字节数的随机十六进制字符串
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
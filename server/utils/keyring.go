@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 访问令牌支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// keyringRefreshInterval 遇到未知kid时两次重新加载之间的最短间隔
+const keyringRefreshInterval = 10 * time.Second
+
+var (
+	// ErrKeyringEmpty 没有可用于签名的密钥
+	ErrKeyringEmpty = errors.New("没有可用的JWT签名密钥")
+	// ErrUnknownKeyID 令牌的kid不在密钥环中
+	ErrUnknownKeyID = errors.New("未知的JWT签名密钥")
+)
+
+// SigningKey 密钥环中的一个签名密钥
+type SigningKey struct {
+	Kid       string
+	Algorithm string
+	Private   crypto.Signer // 只用于验证的密钥可以为空
+	Public    crypto.PublicKey
+}
+
+// Keyring 访问令牌的签名密钥集合，活动密钥用于签名，已退役的密钥只用于验证
+type Keyring struct {
+	mu          sync.RWMutex
+	active      *SigningKey
+	keys        map[string]*SigningKey
+	order       []string
+	lastRefresh time.Time
+
+	// Refresh 遇到未知kid时重新加载密钥，用于其他实例轮换密钥后及时生效
+	Refresh func() error
+}
+
+// jwtKeyring 当前进程使用的密钥环
+var jwtKeyring = &Keyring{keys: make(map[string]*SigningKey)}
+
+// JwtKeyring 返回当前进程使用的密钥环
+func JwtKeyring() *Keyring {
+	return jwtKeyring
+}
+
+// Load 替换密钥环中的全部密钥，activeKid为空表示没有活动密钥
+func (k *Keyring) Load(keys []*SigningKey, activeKid string) error {
+	index := make(map[string]*SigningKey, len(keys))
+	order := make([]string, 0, len(keys))
+	var active *SigningKey
+	for _, key := range keys {
+		index[key.Kid] = key
+		order = append(order, key.Kid)
+		if key.Kid == activeKid {
+			active = key
+		}
+	}
+	if activeKid != "" && (active == nil || active.Private == nil) {
+		return fmt.Errorf("活动密钥%s不存在或缺少私钥", activeKid)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys, k.order, k.active = index, order, active
+	k.lastRefresh = time.Now()
+	return nil
+}
+
+// Active 返回用于签名的活动密钥
+func (k *Keyring) Active() (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == nil {
+		return nil, ErrKeyringEmpty
+	}
+	return k.active, nil
+}
+
+// Lookup 按kid查找验证密钥，找不到时尝试重新加载一次
+func (k *Keyring) Lookup(kid string) (*SigningKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	refresh := k.Refresh != nil && time.Since(k.lastRefresh) > keyringRefreshInterval
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if refresh {
+		if err := k.Refresh(); err != nil {
+			return nil, err
+		}
+		k.mu.RLock()
+		key, ok = k.keys[kid]
+		k.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// JWKS 返回全部公钥的JSON Web Key Set
+func (k *Keyring) JWKS() map[string]interface{} {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]map[string]string, 0, len(k.order))
+	for _, kid := range k.order {
+		if jwk := publicJWK(k.keys[kid]); jwk != nil {
+			keys = append(keys, jwk)
+		}
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// publicJWK 将公钥编码为JWK
+func publicJWK(key *SigningKey) map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": key.Algorithm,
+			"kid": key.Kid,
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"use": "sig",
+			"alg": key.Algorithm,
+			"kid": key.Kid,
+			"x":   b64(pub),
+		}
+	}
+	return nil
+}
+
+// SigningMethod 返回算法对应的签名方法
+func SigningMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	case AlgHS256:
+		return jwt.SigningMethodHS256, nil
+	}
+	return nil, fmt.Errorf("不支持的JWT签名算法: %s", algorithm)
+}
+
+// GenerateSigningKey 生成新的签名密钥，返回PKCS#8格式的私钥PEM
+func GenerateSigningKey(algorithm string) (*SigningKey, string, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, "", err
+		}
+		signer = key
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, "", err
+		}
+		signer = key
+	default:
+		return nil, "", fmt.Errorf("不支持的非对称签名算法: %s", algorithm)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, "", err
+	}
+	kid, err := RandomToken(8)
+	if err != nil {
+		return nil, "", err
+	}
+	key := &SigningKey{Kid: kid, Algorithm: algorithm, Private: signer, Public: signer.Public()}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParseSigningKey 从PKCS#8私钥PEM还原签名密钥
+func ParseSigningKey(kid, algorithm, privatePEM string) (*SigningKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("密钥%s的私钥格式错误", kid)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("密钥%s的私钥格式错误: %w", kid, err)
+	}
+
+	var signer crypto.Signer
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgRS256 {
+			return nil, fmt.Errorf("密钥%s的类型与算法%s不匹配", kid, algorithm)
+		}
+		signer = key
+	case ed25519.PrivateKey:
+		if algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("密钥%s的类型与算法%s不匹配", kid, algorithm)
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("密钥%s: 不支持的私钥类型", kid)
+	}
+	return &SigningKey{Kid: kid, Algorithm: algorithm, Private: signer, Public: signer.Public()}, nil
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-react-admin/global"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// PasswordAlgoBcrypt bcrypt哈希算法
+	PasswordAlgoBcrypt = "bcrypt"
+	// PasswordAlgoArgon2id argon2id哈希算法
+	PasswordAlgoArgon2id = "argon2id"
+
+	argon2idPrefix = "$argon2id$"
+	argon2SaltLen  = 16
+	argon2KeyLen   = 32
+)
+
+// passwordConfig 获取密码配置，未加载配置时使用默认值
+func passwordConfig() global.PasswordConfig {
+	if global.GlobalConfig != nil {
+		return global.GlobalConfig.Password
+	}
+	return global.PasswordConfig{
+		Algorithm:     PasswordAlgoBcrypt,
+		BcryptCost:    bcrypt.DefaultCost,
+		Argon2Memory:  64 * 1024,
+		Argon2Time:    1,
+		Argon2Threads: 4,
+	}
+}
+
+// HashPassword 按配置的算法和强度对密码进行哈希
+func HashPassword(password string) (string, error) {
+	cfg := passwordConfig()
+	switch cfg.Algorithm {
+	case PasswordAlgoArgon2id:
+		return hashArgon2id(password, cfg)
+	case PasswordAlgoBcrypt, "":
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	default:
+		return "", fmt.Errorf("不支持的密码哈希算法: %s", cfg.Algorithm)
+	}
+}
+
+// IsPasswordHashed 判断存储的密码是否为可识别的哈希格式
+func IsPasswordHashed(stored string) bool {
+	return isBcryptHash(stored) || strings.HasPrefix(stored, argon2idPrefix)
+}
+
+// VerifyPassword 校验密码
+// 返回值 needsRehash 表示存储值为明文、算法与当前配置不一致或强度低于当前配置，
+// 调用方应在校验成功后使用 HashPassword 重新生成哈希并回写
+func VerifyPassword(stored, password string) (ok bool, needsRehash bool) {
+	cfg := passwordConfig()
+
+	switch {
+	case isBcryptHash(stored):
+		if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+			return false, false
+		}
+		if cfg.Algorithm == PasswordAlgoArgon2id {
+			return true, true
+		}
+		cost, err := bcrypt.Cost([]byte(stored))
+		return true, err != nil || cost < cfg.BcryptCost
+	case strings.HasPrefix(stored, argon2idPrefix):
+		params, salt, key, err := decodeArgon2id(stored)
+		if err != nil {
+			return false, false
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(actual, key) != 1 {
+			return false, false
+		}
+		if cfg.Algorithm != PasswordAlgoArgon2id {
+			return true, true
+		}
+		weak := params.memory < cfg.Argon2Memory || params.time < cfg.Argon2Time
+		return true, weak
+	default:
+		// 历史遗留的明文密码
+		if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(password)) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+}
+
+// isBcryptHash 判断是否为bcrypt哈希
+func isBcryptHash(stored string) bool {
+	return len(stored) == 60 && (strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$"))
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// hashArgon2id 生成PHC格式的argon2id哈希
+func hashArgon2id(password string, cfg global.PasswordConfig) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, cfg.Argon2Time, cfg.Argon2Memory, cfg.Argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// decodeArgon2id 解析PHC格式的argon2id哈希
+func decodeArgon2id(encoded string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errors.New("无效的argon2id哈希格式")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, errors.New("不兼容的argon2版本")
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// 参数为0或盐、哈希为空时argon2会panic或使任意密码都能通过校验
+	if params.memory == 0 || params.time == 0 || params.threads == 0 {
+		return nil, nil, nil, errors.New("无效的argon2id参数")
+	}
+	if len(salt) == 0 || len(key) == 0 {
+		return nil, nil, nil, errors.New("argon2id哈希缺少盐或摘要")
+	}
+
+	return params, salt, key, nil
+}
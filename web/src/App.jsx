@@ -78,6 +78,7 @@ function App() {
           <Routes>
             {/* 公开路由 */}
             <Route path="/login" element={<Login />} />
+            <Route path="/oidc/callback" element={<Login />} />
             
             {/* 受保护的路由 */}
             <Route path="/" element={
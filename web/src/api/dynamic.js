@@ -89,16 +89,26 @@ export const dynamicImportExportApi = {
 
 // 动态数据权限API
 export const dynamicPermissionApi = {
-  // 获取表权限
-  getTablePermissions: (tableId) => api.get(`/dynamicPermission/table/${tableId}`),
-  // 设置表权限
-  setTablePermissions: (data) => api.post('/dynamicPermission/table', data),
-  // 检查表权限
-  checkTablePermission: (tableName, action) => api.get('/dynamicPermission/check', {
-    params: { tableName, action }
-  }),
-  // 获取用户表权限列表
-  getUserTablePermissions: (userId) => api.get(`/dynamicPermission/user/${userId}/tables`),
+  // 获取表权限，tableId为0时获取所有数据表的权限
+  getTablePermissions: (tableId) => api.get(`/tablePermission/list/${tableId}`),
+  // 设置角色对数据表的权限
+  setTablePermissions: (data) => api.post('/tablePermission/save', data),
+  // 删除表权限
+  deleteTablePermission: (id) => api.delete(`/tablePermission/delete/${id}`),
+  // 获取当前用户对数据表的权限
+  getMyTablePermission: (tableId) => api.get(`/tablePermission/mine/${tableId}`),
+};
+
+// 行级数据规则API
+export const dataRuleApi = {
+  // 获取数据表的数据规则，tableId为0时获取全部
+  getDataRules: (tableId) => api.get(`/dataRule/list/${tableId}`),
+  // 创建或更新数据规则
+  saveDataRule: (data) => api.post('/dataRule/save', data),
+  // 删除数据规则
+  deleteDataRule: (id) => api.delete(`/dataRule/delete/${id}`),
+  // 获取数据规则预设
+  getPresets: () => api.get('/dataRule/presets'),
 };
 
 // 动态数据视图API
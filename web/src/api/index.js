@@ -40,17 +40,57 @@ api.interceptors.request.use(
   }
 );
 
+// 正在进行的刷新请求，避免并发请求重复轮换刷新令牌
+let refreshPromise = null;
+
+const refreshAccessToken = () => {
+  if (!refreshPromise) {
+    const refreshToken = localStorage.getItem('refreshToken');
+    refreshPromise = axios
+      .post(`${api.defaults.baseURL}/token/refresh`, { refresh_token: refreshToken })
+      .then((res) => {
+        localStorage.setItem('token', res.data.token);
+        localStorage.setItem('refreshToken', res.data.refresh_token);
+        return res.data.token;
+      })
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+  return refreshPromise;
+};
+
 // 响应拦截器
 api.interceptors.response.use(
   (response) => {
     // 对响应数据做些什么
     return response;
   },
-  (error) => {
+  async (error) => {
     // 对响应错误做些什么
-    if (error.response && error.response.status === 401) {
+    const original = error.config;
+    // 登录、两步验证及单点登录接口的401由页面自行提示，不跳转
+    const isAuthRequest = original && original.url && (
+      original.url === '/login' ||
+      original.url.startsWith('/mfa/verify') ||
+      original.url.startsWith('/mfa/enroll') ||
+      original.url.startsWith('/oidc/')
+    );
+    if (error.response && error.response.status === 401 && !isAuthRequest) {
+      // 访问令牌过期时先尝试使用刷新令牌换取新令牌
+      if (original && !original._retry && localStorage.getItem('refreshToken')) {
+        original._retry = true;
+        try {
+          const token = await refreshAccessToken();
+          original.headers.Authorization = `Bearer ${token}`;
+          return api(original);
+        } catch (refreshError) {
+          // 刷新失败，走下方的登出逻辑
+        }
+      }
       // token过期或无效，清除token并跳转到登录页
       localStorage.removeItem('token');
+      localStorage.removeItem('refreshToken');
       window.location.href = '/login';
     }
     return Promise.reject(error);
@@ -60,15 +100,29 @@ api.interceptors.response.use(
 // 认证相关API
 export const authApi = {
   // 用户登录
-  login: (username, password) => api.post('/login', { username, password }),
+  login: (username, password, captcha) => api.post('/login', { username, password, ...captcha }),
+  // 获取登录验证码
+  getCaptcha: () => api.get('/captcha'),
   // 用户登出
   logout: () => api.post('/user/logout'),
+  // 完成两步验证
+  verifyMfa: (mfaToken, code) => api.post('/mfa/verify', { mfa_token: mfaToken, code }),
+  // 登录时绑定两步验证
+  setupMfaEnrollment: (mfaToken) => api.post('/mfa/enroll/setup', { mfa_token: mfaToken }),
+  // 登录时确认绑定两步验证
+  confirmMfaEnrollment: (mfaToken, code) => api.post('/mfa/enroll/confirm', { mfa_token: mfaToken, code }),
+  // 获取单点登录方式
+  getOidcProviders: () => api.get('/oidc/providers'),
+  // 发起单点登录，返回身份提供方授权地址
+  oidcAuthorize: (providerId) => api.get(`/oidc/authorize/${providerId}`),
+  // 单点登录回调，使用state和code换取令牌
+  oidcCallback: (state, code) => api.post('/oidc/callback', { state, code }),
 };
 
 // 用户相关API
 export const userApi = {
   // 获取用户列表
-  getUserList: () => api.get('/user/list'),
+  getUserList: (params) => api.get('/user/list', { params }),
   // 获取用户信息
   getUserInfo: () => api.get('/user/info'),
   // 获取当前用户信息
@@ -103,14 +157,45 @@ export const roleApi = {
   updateRole: (id, data) => api.put(`/role/update/${id}`, data),
   // 删除角色
   deleteRole: (id) => api.delete(`/role/delete/${id}`),
+  // 获取上级角色
+  getRoleParents: (id) => api.get(`/role/parents/${id}`),
+  // 设置上级角色
+  setRoleParents: (id, parentIds, reason) => api.post(`/role/parents/${id}`, { parent_ids: parentIds, reason }),
+  // 获取角色有效权限（含继承来源）
+  getEffectivePermissions: (id) => api.get(`/role/effective/${id}`),
+};
+
+// 部门相关API
+export const departmentApi = {
+  // 获取部门树
+  getDepartmentTree: () => api.get('/department/tree'),
+  // 创建部门
+  createDepartment: (data) => api.post('/department/create', data),
+  // 更新部门
+  updateDepartment: (id, data) => api.put(`/department/update/${id}`, data),
+  // 删除部门
+  deleteDepartment: (id) => api.delete(`/department/delete/${id}`),
+  // 移动部门到新的上级部门，parentId为0时移动为顶级部门
+  moveDepartment: (id, parentId) => api.post(`/department/move/${id}`, { parent_id: parentId }),
+  // 获取部门成员
+  getMembers: (id, includeChildren = false) => api.get(`/department/members/${id}`, { params: { include_children: includeChildren } }),
+  // 设置部门负责人
+  setManagers: (id, userIds) => api.post(`/department/managers/${id}`, { user_ids: userIds }),
+  // 获取用户所属部门
+  getUserDepartments: (userId) => api.get(`/user/departments/${userId}`),
+  // 设置用户的主部门和兼职部门
+  setUserDepartments: (userId, data) => api.post(`/user/departments/${userId}`, data),
 };
 
 // 菜单相关API
 export const menuApi = {
   // 获取菜单列表
   getMenuList: () => api.get('/menu/list'),
-  // 获取用户菜单
-  getUserMenus: () => api.get('/menus/user'),
+  // 获取用户菜单树，传入上次的ETag时菜单未变化返回304
+  getUserMenus: (etag) => api.get('/menus/user', {
+    headers: etag ? { 'If-None-Match': etag } : {},
+    validateStatus: (status) => status === 200 || status === 304,
+  }),
   // 获取当前用户菜单（别名）
   getCurrentUserMenus: () => api.get('/menus/user'),
   // 创建菜单
@@ -139,7 +224,7 @@ export const permissionApi = {
   assignRolePermissions: (data) => api.post('/permissions/role', data),
   // 获取角色权限
   getRolePermissions: (roleId) => api.get(`/permissions/role/${roleId}`),
-  // 分配用户角色
+  // 分配用户角色，data.validity可按角色ID指定 { valid_from, valid_until }
   assignUserRoles: (data) => api.post('/permissions/user', data),
   // 获取用户角色
   getUserRoles: (userId) => api.get(`/permissions/user/${userId}/roles`),
@@ -149,6 +234,106 @@ export const permissionApi = {
   checkPermission: (resource, action) => api.get(`/permissions/check?resource=${resource}&action=${action}`),
   // 批量检查权限
   batchCheckPermissions: (permissions) => api.post('/permissions/batch-check', { permissions }),
+  // 解释权限，data.what_if可评估假设的角色变更
+  explainPermission: (data) => api.post('/permissions/explain', data),
+};
+
+// 临时提权API
+export const elevationApi = {
+  // 申请临时角色
+  requestElevation: (data) => api.post('/elevation/request', data),
+  // 获取我的提权申请
+  getMyElevations: (params) => api.get('/elevation/mine', { params }),
+  // 撤回提权申请
+  cancelElevation: (id) => api.post(`/elevation/cancel/${id}`),
+  // 获取提权申请列表（审批人）
+  getElevationList: (params) => api.get('/elevation/list', { params }),
+  // 批准提权申请
+  approveElevation: (id, comment) => api.post(`/elevation/approve/${id}`, { comment }),
+  // 拒绝提权申请
+  rejectElevation: (id, comment) => api.post(`/elevation/reject/${id}`, { comment }),
+};
+
+// 权限变更审批API，启用审批后分配权限和角色的接口返回202和变更申请
+export const approvalApi = {
+  // 获取变更申请列表（审批人）
+  getRequestList: (params) => api.get('/approval/list', { params }),
+  // 获取我提交的变更申请
+  getMyRequests: (params) => api.get('/approval/mine', { params }),
+  // 获取变更申请详情和历史
+  getRequest: (id) => api.get(`/approval/get/${id}`),
+  // 同意变更申请
+  approveRequest: (id, comment) => api.post(`/approval/approve/${id}`, { comment }),
+  // 拒绝变更申请
+  rejectRequest: (id, comment) => api.post(`/approval/reject/${id}`, { comment }),
+  // 撤回变更申请
+  cancelRequest: (id) => api.post(`/approval/cancel/${id}`),
+  // 获取审批配置
+  getPolicies: () => api.get('/approval/policy'),
+  // 保存审批配置
+  savePolicy: (data) => api.post('/approval/policy', data),
+};
+
+// 登录会话API
+export const sessionApi = {
+  // 获取我的登录会话
+  getMySessions: () => api.get('/session/list'),
+  // 结束我的登录会话
+  revokeSession: (sessionId) => api.post('/session/revoke', { session_id: sessionId }),
+  // 结束其他登录会话
+  revokeOtherSessions: () => api.post('/session/revoke-others'),
+  // 获取租户登录会话（管理员）
+  getTenantSessions: (userId) => api.get('/admin/sessions', { params: userId ? { user_id: userId } : {} }),
+  // 强制用户下线（管理员）
+  forceLogout: (userId, sessionId) => api.post('/admin/sessions/logout', { user_id: userId, session_id: sessionId }),
+};
+
+// API Key相关API
+export const apiKeyApi = {
+  // 获取我的API Key
+  getMyKeys: () => api.get('/apikey/list'),
+  // 创建个人API Key，明文Key只在返回结果中出现一次
+  createKey: (data) => api.post('/apikey/create', data),
+  // 吊销个人API Key
+  revokeKey: (id) => api.post('/apikey/revoke', { id }),
+  // 获取租户API Key（管理员）
+  getTenantKeys: (userId) => api.get('/admin/apikeys', { params: userId ? { user_id: userId } : {} }),
+  // 吊销租户API Key（管理员）
+  revokeTenantKey: (id) => api.post('/admin/apikeys/revoke', { id }),
+  // 获取服务账号列表（管理员）
+  getServiceAccounts: () => api.get('/admin/service-accounts'),
+  // 创建服务账号（管理员）
+  createServiceAccount: (data) => api.post('/admin/service-accounts/create', data),
+  // 为服务账号创建API Key（管理员）
+  createServiceKey: (data) => api.post('/admin/service-accounts/apikey', data),
+};
+
+// LDAP目录API（管理员）
+export const ldapApi = {
+  // 获取LDAP配置
+  getConfig: () => api.get('/admin/ldap/config'),
+  // 保存LDAP配置，enabled为true时租户改为LDAP登录
+  saveConfig: (data) => api.post('/admin/ldap/config/save', data),
+  // 立即同步LDAP组
+  syncGroups: () => api.post('/admin/ldap/sync'),
+};
+
+// 租户管理API
+export const tenantApi = {
+  // 获取租户列表，deleted为true时获取已删除的租户
+  getTenants: (params) => api.get('/tenant/list', { params }),
+  // 创建租户，同时创建租户管理员
+  createTenant: (data) => api.post('/tenant/create', data),
+  // 更新租户
+  updateTenant: (id, data) => api.put(`/tenant/update/${id}`, data),
+  // 停用租户
+  suspendTenant: (id) => api.post(`/tenant/suspend/${id}`),
+  // 启用租户
+  resumeTenant: (id) => api.post(`/tenant/resume/${id}`),
+  // 删除租户，宽限期内可以恢复
+  deleteTenant: (id) => api.delete(`/tenant/delete/${id}`),
+  // 恢复已删除的租户
+  restoreTenant: (id) => api.post(`/tenant/restore/${id}`),
 };
 
 // 用户偏好设置API
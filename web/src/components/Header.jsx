@@ -4,6 +4,7 @@ import { SettingOutlined, UserOutlined, LogoutOutlined, DownOutlined } from '@an
 import { Dropdown, Menu, Avatar } from 'antd';
 import ThemeToggle from './ThemeToggle';
 import WatermarkSettings from './WatermarkSettings';
+import { authApi } from '../api';
 import '../assets/styles/Layout.css';
 
 const Header = () => {
@@ -19,8 +20,15 @@ const Header = () => {
     }
   }, []);
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    try {
+      // 通知服务端吊销当前令牌
+      await authApi.logout();
+    } catch (error) {
+      console.error('登出请求失败:', error);
+    }
     localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
     localStorage.removeItem('userId');
     localStorage.removeItem('user');
     localStorage.removeItem('userInfo');
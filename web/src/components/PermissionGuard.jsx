@@ -184,4 +184,19 @@ export const PermissionButton = ({
   );
 };
 
+// 检查当前用户是否拥有操作权限码，权限码在登录时随用户信息保存
+export const hasAction = (code) => {
+  try {
+    const userInfo = JSON.parse(localStorage.getItem('userInfo') || '{}');
+    return (userInfo.permissions || []).includes(code);
+  } catch (error) {
+    return false;
+  }
+};
+
+// 按操作权限码控制按钮等元素的显示
+export const ActionGuard = ({ code, children, fallback = null }) => {
+  return hasAction(code) ? children : fallback;
+};
+
 export default PermissionGuard;
\ No newline at end of file
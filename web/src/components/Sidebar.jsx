@@ -12,7 +12,6 @@ const Sidebar = () => {
     toggleSidebar,
     openTab,
     getFilteredMenus,
-    buildMenuTree,
     fetchUserMenus
   } = useMenu();
 
@@ -88,11 +87,8 @@ const Sidebar = () => {
     );
   };
 
-  // 获取要显示的菜单
-  const getDisplayMenus = () => {
-    const filteredMenus = getFilteredMenus();
-    return buildMenuTree(filteredMenus);
-  };
+  // 获取要显示的菜单，用户菜单已是按sort排序的树
+  const getDisplayMenus = () => getFilteredMenus();
 
   return (
     <aside className={`sidebar ${sidebarCollapsed ? 'collapsed' : ''}`}>
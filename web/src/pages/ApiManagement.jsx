@@ -115,7 +115,7 @@ const ApiManagement = () => {
                 <td>{api.method}</td>
                 <td>{api.category}</td>
                 <td>{api.description}</td>
-                <td>{api.status === 1 ? '启用' : '禁用'}</td>
+                <td>{api.stale ? '路由已删除' : api.status === 1 ? '启用' : '禁用'}</td>
                 <td>
                   <button 
                     style={{ marginRight: '5px', padding: '5px 10px', backgroundColor: '#007bff', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
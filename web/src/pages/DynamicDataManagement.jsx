@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import { useParams, useNavigate, useLocation } from 'react-router-dom';
 import { 
   Card, 
@@ -20,7 +20,8 @@ import {
   Row,
   Col,
   Divider,
-  Checkbox
+  Checkbox,
+  ConfigProvider
 } from 'antd';
 import messageUtils from '../utils/message';
 import { 
@@ -62,6 +63,18 @@ const DynamicDataManagement = () => {
   const [searchParams, setSearchParams] = useState({});
   const [form] = Form.useForm();
   const [searchForm] = Form.useForm();
+  // 当前用户对各字段的访问权限，来自表结构接口
+  const fieldAccessRef = useRef({});
+
+  // 按字段权限过滤启用的字段：隐藏不可见字段，并标记字段新增和更新时是否可编辑
+  const applyFieldAccess = (fieldList) => fieldList
+    .filter(field => field.status === 1)
+    .filter(field => fieldAccessRef.current[field.field_name]?.can_view !== false)
+    .map(field => ({
+      ...field,
+      can_create: fieldAccessRef.current[field.field_name]?.can_create !== false,
+      can_edit: fieldAccessRef.current[field.field_name]?.can_edit !== false,
+    }));
 
   // 获取表信息
   const fetchTableInfo = async () => {
@@ -77,6 +90,11 @@ const DynamicDataManagement = () => {
       console.log('Table info response:', response);
       
       if (response.data && response.data.data && response.data.data.table_info) {
+        const access = {};
+        (response.data.data.fields || []).forEach(field => {
+          access[field.field_name] = { can_view: field.can_view, can_create: field.can_create, can_edit: field.can_edit };
+        });
+        fieldAccessRef.current = access;
         setTableInfo(response.data.data.table_info);
         return response.data.data.table_info;
       } else {
@@ -98,7 +116,7 @@ const DynamicDataManagement = () => {
       const response = await dynamicFieldApi.getFieldsByTableID(tableId);
       if (response.data.success) {
         console.log('All table fields:', response.data.data);
-        const activeFields = response.data.data.filter(field => field.status === 1);
+        const activeFields = applyFieldAccess(response.data.data);
         console.log('Active fields:', activeFields);
         setFields(activeFields);
         return activeFields;
@@ -241,7 +259,7 @@ const DynamicDataManagement = () => {
       try {
         const response = await dynamicFieldApi.getFieldsByTableID(tableInfo.id);
         if (response.data.success) {
-          const activeFields = response.data.data.filter(field => field.status === 1);
+          const activeFields = applyFieldAccess(response.data.data);
           console.log('Updated active fields:', activeFields);
           setFields(activeFields);
           currentFields = activeFields; // 使用最新的字段数据
@@ -277,12 +295,20 @@ const DynamicDataManagement = () => {
     form.resetFields();
   };
 
+  // 字段在当前表单中是否可编辑，新增和更新分别判断
+  const isWritable = (field) => (editingData ? field.can_edit : field.can_create);
+
   // 提交表单
   const handleSubmit = async (values) => {
     try {
       // 处理日期字段和数字字段
       const processedValues = { ...values };
       fields.forEach(field => {
+        // 只读字段不提交，避免被后端拒绝
+        if (!isWritable(field)) {
+          delete processedValues[field.field_name];
+          return;
+        }
         if ((field.field_type === 'date' || field.field_type === 'datetime') && processedValues[field.field_name]) {
           processedValues[field.field_name] = processedValues[field.field_name].format(
             field.field_type === 'date' ? 'YYYY-MM-DD' : 'YYYY-MM-DD HH:mm:ss'
@@ -336,7 +362,7 @@ const DynamicDataManagement = () => {
         }
       }
     } catch (error) {
-      message.error(editingData ? '更新数据失败' : '创建数据失败');
+      message.error(error.response?.data?.message || (editingData ? '更新数据失败' : '创建数据失败'));
       console.error('Error submitting form:', error);
     }
   };
@@ -403,7 +429,7 @@ const DynamicDataManagement = () => {
     const { field_name, display_name, field_type, is_required, options, default_value } = field;
     
     const rules = [];
-    if (is_required) {
+    if (is_required && isWritable(field)) {
       rules.push({ required: true, message: `请输入${display_name}` });
     }
 
@@ -852,7 +878,9 @@ const DynamicDataManagement = () => {
           <Row gutter={16}>
             {fields.map((field, index) => (
               <Col span={index % 2 === 0 ? 12 : 12} key={field.field_name}>
-                {renderFormItem(field)}
+                <ConfigProvider componentDisabled={!isWritable(field)}>
+                  {renderFormItem(field)}
+                </ConfigProvider>
               </Col>
             ))}
           </Row>
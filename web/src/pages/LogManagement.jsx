@@ -19,6 +19,7 @@ const LogManagement = () => {
   const [searchParams, setSearchParams] = useState({
     username: '',
     method: '',
+    type: '',
     statusCode: '',
     dateRange: [],
   });
@@ -60,6 +61,7 @@ const LogManagement = () => {
     const params = {};
     if (searchParams.username) params.username = searchParams.username;
     if (searchParams.method) params.method = searchParams.method;
+    if (searchParams.type) params.type = searchParams.type;
     if (searchParams.statusCode) params.statusCode = searchParams.statusCode;
     if (searchParams.dateRange && searchParams.dateRange.length === 2) {
       params.startDate = searchParams.dateRange[0].format('YYYY-MM-DD');
@@ -75,6 +77,7 @@ const LogManagement = () => {
     setSearchParams({
       username: '',
       method: '',
+      type: '',
       statusCode: '',
       dateRange: [],
     });
@@ -218,6 +221,19 @@ const LogManagement = () => {
               <Option value="DELETE">DELETE</Option>
               <Option value="PATCH">PATCH</Option>
             </Select>
+            <Select
+              placeholder="日志类型"
+              value={searchParams.type}
+              onChange={(value) => setSearchParams({ ...searchParams, type: value })}
+              style={{ width: 120 }}
+              allowClear
+            >
+              <Option value="operation">操作</Option>
+              <Option value="login">登录</Option>
+              <Option value="logout">登出</Option>
+              <Option value="lockout">登录锁定</Option>
+              <Option value="unlock">解除锁定</Option>
+            </Select>
             <Select
               placeholder="状态码"
               value={searchParams.statusCode}
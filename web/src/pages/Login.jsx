@@ -7,7 +7,13 @@ const Login = () => {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [isLoading, setIsLoading] = useState(false);
+  const [mfaToken, setMfaToken] = useState('');
+  const [mfaEnroll, setMfaEnroll] = useState(null);
+  const [mfaCode, setMfaCode] = useState('');
+  const [captcha, setCaptcha] = useState(null);
+  const [captchaAnswer, setCaptchaAnswer] = useState('');
   const [particles, setParticles] = useState([]);
+  const [oidcProviders, setOidcProviders] = useState([]);
   const navigate = useNavigate();
 
   useEffect(() => {
@@ -20,36 +26,150 @@ const Login = () => {
       animationDuration: 10 + Math.random() * 10
     }));
     setParticles(newParticles);
+
+    // 身份提供方重定向回来时完成单点登录，否则加载可用的单点登录方式
+    const params = new URLSearchParams(window.location.search);
+    if (params.get('state') && (params.get('code') || params.get('error'))) {
+      window.history.replaceState(null, '', '/login');
+      if (params.get('error')) {
+        alert(`单点登录失败：${params.get('error_description') || params.get('error')}`);
+        return;
+      }
+      handleOidcCallback(params.get('state'), params.get('code'));
+      return;
+    }
+    authApi.getOidcProviders()
+      .then((response) => setOidcProviders(response.data.providers || []))
+      .catch((error) => console.error('获取单点登录方式失败:', error));
   }, []);
 
+  // 保存令牌并进入系统
+  const completeLogin = async (data) => {
+    const { token, refresh_token: refreshToken, userId, recovery_codes: recoveryCodes } = data;
+    if (!token) {
+      alert('登录失败：未获取到token');
+      return;
+    }
+
+    localStorage.setItem('token', token);
+    localStorage.setItem('refreshToken', refreshToken);
+    localStorage.setItem('userId', userId);
+
+    if (recoveryCodes && recoveryCodes.length > 0) {
+      alert(`两步验证已开启，请妥善保存以下恢复码（每个只能使用一次）：\n\n${recoveryCodes.join('\n')}`);
+    }
+
+    const userInfoResponse = await userApi.getUserInfo();
+    const user = userInfoResponse.data;
+    localStorage.setItem('user', JSON.stringify(user));
+    localStorage.setItem('userInfo', JSON.stringify(user));
+
+    navigate('/dashboard');
+  };
+
+  const handleError = (error, fallback) => {
+    console.error('登录错误:', error);
+    if (error.response) {
+      const data = error.response.data || {};
+      // 失败次数过多时需要输入验证码
+      if (data.captcha) {
+        setCaptcha(data.captcha);
+        setCaptchaAnswer('');
+      }
+      alert(data.retry_after ? `${data.message || fallback}（${data.retry_after}秒后可重试）` : (data.message || fallback));
+      // 挑战令牌失效后需要重新输入密码
+      if (error.response.status === 401 && mfaToken) {
+        setMfaToken('');
+        setMfaEnroll(null);
+        setMfaCode('');
+      }
+    } else {
+      alert('登录失败，请稍后重试');
+    }
+  };
+
   const handleSubmit = async (e) => {
     e.preventDefault();
     setIsLoading(true);
     
     try {
-      const loginResponse = await authApi.login(username, password);
-      const { token, userId } = loginResponse.data;
-
-      if (token) {
-        localStorage.setItem('token', token);
-        localStorage.setItem('userId', userId);
-        
-        const userInfoResponse = await userApi.getUserInfo();
-        const user = userInfoResponse.data;
-        localStorage.setItem('user', JSON.stringify(user));
-        localStorage.setItem('userInfo', JSON.stringify(user));
-        
-        navigate('/dashboard');
-      } else {
-        alert('登录失败：未获取到token');
-      }
+      const loginResponse = await authApi.login(
+        username,
+        password,
+        captcha ? { captcha_id: captcha.captcha_id, captcha_answer: captchaAnswer } : undefined
+      );
+      setCaptcha(null);
+      await handleLoginResult(loginResponse.data);
     } catch (error) {
-      console.error('登录错误:', error);
-      if (error.response) {
-        alert(error.response.data?.message || '登录失败');
-      } else {
-        alert('登录失败，请稍后重试');
+      handleError(error, '登录失败');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  // 处理登录结果，需要两步验证时进入验证步骤
+  const handleLoginResult = async (data) => {
+    if (data.mfa_pending) {
+      setMfaToken(data.mfa_token);
+      if (data.mfa_enroll_required) {
+        const setupResponse = await authApi.setupMfaEnrollment(data.mfa_token);
+        setMfaEnroll({
+          secret: setupResponse.data.secret,
+          otpauthUrl: setupResponse.data.otpauth_url
+        });
       }
+      return;
+    }
+
+    await completeLogin(data);
+  };
+
+  // 跳转到身份提供方登录
+  const handleOidcLogin = async (providerId) => {
+    setIsLoading(true);
+    try {
+      const response = await authApi.oidcAuthorize(providerId);
+      window.location.href = response.data.authorization_url;
+    } catch (error) {
+      handleError(error, '单点登录失败');
+      setIsLoading(false);
+    }
+  };
+
+  // 使用回调参数换取令牌
+  const handleOidcCallback = async (state, code) => {
+    setIsLoading(true);
+    try {
+      const response = await authApi.oidcCallback(state, code);
+      await handleLoginResult(response.data);
+    } catch (error) {
+      handleError(error, '单点登录失败');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const refreshCaptcha = async () => {
+    try {
+      const response = await authApi.getCaptcha();
+      setCaptcha(response.data.captcha);
+      setCaptchaAnswer('');
+    } catch (error) {
+      console.error('获取验证码失败:', error);
+    }
+  };
+
+  const handleMfaSubmit = async (e) => {
+    e.preventDefault();
+    setIsLoading(true);
+
+    try {
+      const response = mfaEnroll
+        ? await authApi.confirmMfaEnrollment(mfaToken, mfaCode)
+        : await authApi.verifyMfa(mfaToken, mfaCode);
+      await completeLogin(response.data);
+    } catch (error) {
+      handleError(error, '验证失败');
     } finally {
       setIsLoading(false);
     }
@@ -88,53 +208,138 @@ const Login = () => {
             </div>
           </div>
 
-          {/* 登录表单 */}
-          <form className="login-form" onSubmit={handleSubmit}>
-            <div className="form-group">
-              <label htmlFor="username">用户名</label>
-              <div className="input-wrapper">
-                <i className="fas fa-user"></i>
-                <input
-                  type="text"
-                  id="username"
-                  value={username}
-                  onChange={(e) => setUsername(e.target.value)}
-                  placeholder="请输入用户名"
-                  required
-                />
+          {/* 两步验证 */}
+          {mfaToken ? (
+            <form className="login-form" onSubmit={handleMfaSubmit}>
+              {mfaEnroll && (
+                <div className="form-group">
+                  <label>您的角色要求开启两步验证</label>
+                  <p>请在验证器App（如 Google Authenticator）中添加以下密钥，或使用该地址生成二维码：</p>
+                  <p style={{ wordBreak: 'break-all' }}><strong>{mfaEnroll.secret}</strong></p>
+                  <p style={{ wordBreak: 'break-all', fontSize: '12px' }}>{mfaEnroll.otpauthUrl}</p>
+                </div>
+              )}
+
+              <div className="form-group">
+                <label htmlFor="mfaCode">{mfaEnroll ? '验证码' : '验证码或恢复码'}</label>
+                <div className="input-wrapper">
+                  <i className="fas fa-shield-alt"></i>
+                  <input
+                    type="text"
+                    id="mfaCode"
+                    value={mfaCode}
+                    onChange={(e) => setMfaCode(e.target.value)}
+                    placeholder="请输入验证器App中的6位验证码"
+                    autoComplete="one-time-code"
+                    required
+                  />
+                </div>
               </div>
-            </div>
 
-            <div className="form-group">
-              <label htmlFor="password">密码</label>
-              <div className="input-wrapper">
-                <i className="fas fa-lock"></i>
-                <input
-                  type="password"
-                  id="password"
-                  value={password}
-                  onChange={(e) => setPassword(e.target.value)}
-                  placeholder="请输入密码"
-                  required
-                />
+              <button 
+                type="submit" 
+                className={`login-btn ${isLoading ? 'loading' : ''}`}
+                disabled={isLoading}
+              >
+                {isLoading ? (
+                  <>
+                    <span className="spinner"></span>
+                    验证中...
+                  </>
+                ) : (
+                  '验证'
+                )}
+              </button>
+            </form>
+          ) : (
+            <form className="login-form" onSubmit={handleSubmit}>
+              <div className="form-group">
+                <label htmlFor="username">用户名</label>
+                <div className="input-wrapper">
+                  <i className="fas fa-user"></i>
+                  <input
+                    type="text"
+                    id="username"
+                    value={username}
+                    onChange={(e) => setUsername(e.target.value)}
+                    placeholder="请输入用户名"
+                    required
+                  />
+                </div>
               </div>
-            </div>
 
-            <button 
-              type="submit" 
-              className={`login-btn ${isLoading ? 'loading' : ''}`}
-              disabled={isLoading}
-            >
-              {isLoading ? (
-                <>
-                  <span className="spinner"></span>
-                  登录中...
-                </>
-              ) : (
-                '登录'
+              <div className="form-group">
+                <label htmlFor="password">密码</label>
+                <div className="input-wrapper">
+                  <i className="fas fa-lock"></i>
+                  <input
+                    type="password"
+                    id="password"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    placeholder="请输入密码"
+                    required
+                  />
+                </div>
+              </div>
+
+              {captcha && (
+                <div className="form-group">
+                  <label htmlFor="captchaAnswer">验证码</label>
+                  <div className="input-wrapper" style={{ display: 'flex', alignItems: 'center' }}>
+                    <i className="fas fa-image"></i>
+                    <input
+                      type="text"
+                      id="captchaAnswer"
+                      value={captchaAnswer}
+                      onChange={(e) => setCaptchaAnswer(e.target.value)}
+                      placeholder="请输入图中数字"
+                      required
+                    />
+                    <img
+                      src={captcha.captcha_data}
+                      alt="验证码"
+                      title="点击刷新"
+                      onClick={refreshCaptcha}
+                      style={{ cursor: 'pointer', marginLeft: '8px', height: '44px' }}
+                    />
+                  </div>
+                </div>
+              )}
+
+              <button 
+                type="submit" 
+                className={`login-btn ${isLoading ? 'loading' : ''}`}
+                disabled={isLoading}
+              >
+                {isLoading ? (
+                  <>
+                    <span className="spinner"></span>
+                    登录中...
+                  </>
+                ) : (
+                  '登录'
+                )}
+              </button>
+
+              {oidcProviders.length > 0 && (
+                <div className="form-group" style={{ marginTop: '16px' }}>
+                  {oidcProviders.map((provider) => (
+                    <button
+                      key={provider.id}
+                      type="button"
+                      className="login-btn"
+                      style={{ marginTop: '8px' }}
+                      disabled={isLoading}
+                      onClick={() => handleOidcLogin(provider.id)}
+                    >
+                      <i className="fas fa-key"></i> 使用 {provider.name} 登录
+                    </button>
+                  ))}
+                </div>
               )}
-            </button>
-          </form>
+            </form>
+          )}
 
           {/* 技术支持提示 */}
           <div className="login-footer">
@@ -150,6 +150,7 @@ const RoleManagement = () => {
             name: formData.get('name'),
             description: formData.get('description'),
             status: parseInt(formData.get('status')) || 1,
+            require_mfa: formData.get('require_mfa') === 'on',
             tenant_id: 1 // 默认租户ID
           };
           
@@ -193,6 +194,17 @@ const RoleManagement = () => {
               <option value={2}>禁用</option>
             </select>
           </div>
+          <div style={{ marginBottom: '15px' }}>
+            <label>
+              <input 
+                type="checkbox" 
+                name="require_mfa" 
+                defaultChecked={currentRole?.require_mfa || false} 
+                style={{ marginRight: '8px' }}
+              />
+              要求两步验证
+            </label>
+          </div>
           <div>
             <button 
               type="submit" 
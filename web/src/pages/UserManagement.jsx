@@ -2,6 +2,7 @@ import React, { useState, useEffect } from 'react';
 import '../assets/styles/management.css'; // 引入样式文件
 import { userApi, roleApi, permissionApi } from '../api'; // 引入API
 import Modal from 'react-modal'; // 引入模态框组件
+import { ActionGuard } from '../components/PermissionGuard'; // 按操作权限码显示按钮
 
 const UserManagement = () => {
   const [users, setUsers] = useState([]);
@@ -151,12 +152,14 @@ const UserManagement = () => {
   return (
     <div className="management-container">
       <h2>用户管理</h2>
-      <button 
-        style={{ marginBottom: '10px', padding: '5px 10px', backgroundColor: '#28a745', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
-        onClick={handleCreateClick}
-      >
-        创建用户
-      </button>
+      <ActionGuard code="user:create">
+        <button 
+          style={{ marginBottom: '10px', padding: '5px 10px', backgroundColor: '#28a745', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
+          onClick={handleCreateClick}
+        >
+          创建用户
+        </button>
+      </ActionGuard>
       {loading ? (
         <p className="loading">加载中...</p>
       ) : (
@@ -182,24 +185,28 @@ const UserManagement = () => {
                 <td>{user.roles ? user.roles.map(r => r.name).join(', ') : ''}</td>
                 <td>{user.status === 1 ? '启用' : '禁用'}</td>
                 <td>
-                  <button 
-                    style={{ marginRight: '5px', padding: '5px 10px', backgroundColor: '#007bff', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
-                    onClick={() => {
-                      setCurrentUser(user);
-                      // 设置选中的角色ID
-                      const roleIds = user.roles ? user.roles.map(r => r.id) : [];
-                      setSelectedRoles(roleIds);
-                      setModalIsOpen(true);
-                    }}
-                  >
-                    编辑
-                  </button>
-                  <button 
-                    style={{ padding: '5px 10px', backgroundColor: '#dc3545', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
-                    onClick={() => handleDeleteUser(user.id)}
-                  >
-                    删除
-                  </button>
+                  <ActionGuard code="user:update">
+                    <button 
+                      style={{ marginRight: '5px', padding: '5px 10px', backgroundColor: '#007bff', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
+                      onClick={() => {
+                        setCurrentUser(user);
+                        // 设置选中的角色ID
+                        const roleIds = user.roles ? user.roles.map(r => r.id) : [];
+                        setSelectedRoles(roleIds);
+                        setModalIsOpen(true);
+                      }}
+                    >
+                      编辑
+                    </button>
+                  </ActionGuard>
+                  <ActionGuard code="user:delete">
+                    <button 
+                      style={{ padding: '5px 10px', backgroundColor: '#dc3545', color: 'white', border: 'none', borderRadius: '4px', cursor: 'pointer' }}
+                      onClick={() => handleDeleteUser(user.id)}
+                    >
+                      删除
+                    </button>
+                  </ActionGuard>
                 </td>
               </tr>
             ))}
@@ -1,6 +1,9 @@
 import React, { createContext, useContext, useReducer, useEffect } from 'react';
 import { menuApi, permissionApi } from '../api';
 
+// 用户菜单树缓存
+const USER_MENU_CACHE_KEY = 'userMenuCache';
+
 // 初始状态
 const initialState = {
   menus: [],
@@ -118,12 +121,26 @@ export const MenuProvider = ({ children }) => {
     }
   };
 
-  // 获取用户权限菜单
+  // 获取用户权限菜单树，缓存在sessionStorage中，菜单树未变化时服务端返回304
   const fetchUserMenus = async () => {
     try {
       dispatch({ type: ActionTypes.SET_LOADING, payload: true });
-      const response = await menuApi.getUserMenus();
+      let cached = null;
+      try {
+        cached = JSON.parse(sessionStorage.getItem(USER_MENU_CACHE_KEY));
+      } catch (e) {
+        cached = null;
+      }
+      const response = await menuApi.getUserMenus(cached && cached.etag);
+      if (response.status === 304 && cached) {
+        dispatch({ type: ActionTypes.SET_USER_MENUS, payload: cached.menus || [] });
+        return;
+      }
       const menus = response.data.data || [];
+      const etag = response.headers.etag;
+      if (etag) {
+        sessionStorage.setItem(USER_MENU_CACHE_KEY, JSON.stringify({ etag, menus }));
+      }
       dispatch({ type: ActionTypes.SET_USER_MENUS, payload: menus });
     } catch (error) {
       dispatch({ type: ActionTypes.SET_ERROR, payload: error.message });
@@ -176,9 +193,9 @@ export const MenuProvider = ({ children }) => {
     dispatch({ type: ActionTypes.REFRESH_TAB });
   };
 
-  // 过滤菜单（根据权限）
+  // 过滤菜单（根据权限），用户菜单已由服务端按角色过滤并组装成树
   const getFilteredMenus = () => {
-    return state.userMenus.length > 0 ? state.userMenus : state.menus;
+    return state.userMenus;
   };
 
   // 构建菜单树